@@ -0,0 +1,168 @@
+package llvm
+
+import (
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+	"tinygo.org/x/go-llvm"
+)
+
+const (
+	// Minimum time spent running each benchmark, the same default as Go's
+	// "-benchtime"
+	BENCH_TIME_NS = 1_000_000_000
+	// Maximum number of iterations of a benchmark
+	BENCH_MAX_N = 1_000_000_000
+
+	// CLOCK_MONOTONIC on Linux
+	CLOCK_MONOTONIC = 1
+)
+
+func (c *llvmCodegen) generateBenchDecl(bench *ast.BenchDecl) {
+	benchTy := llvm.FunctionType(c.context.VoidType(), nil, false)
+	benchValue := llvm.AddFunction(c.module, ".bench", benchTy)
+	benchValue.SetLinkage(llvm.InternalLinkage)
+	benchBlock := c.context.AddBasicBlock(benchValue, "entry")
+	benchFn := NewFunctionValue(benchValue, benchTy, &benchBlock)
+	c.builder.SetInsertPointAtEnd(benchBlock)
+
+	bench.BackendType = benchFn
+
+	_ = c.generateBlock(bench.Block, bench.Scope, nil, benchFn)
+	if !isTerminated(c.builder.GetInsertBlock()) {
+		c.builder.CreateRetVoid()
+	}
+	c.benches = append(c.benches, bench)
+}
+
+// Generates a function that returns the current time in nanoseconds, using
+// "clock_gettime" declared through an extern:
+//
+//	extern runtime {
+//	  fn clock_gettime(clock i32, ts *[2]i64) i32;
+//	}
+func (c *llvmCodegen) generateBenchNow() *Function {
+	i32 := &ast.BasicType{Kind: token.I32_TYPE}
+	i64 := &ast.BasicType{Kind: token.I64_TYPE}
+	// struct timespec { time_t tv_sec; long tv_nsec; }
+	timespec := &ast.ArrayType{Len: 2, Type: i64}
+
+	clockGettime := &ast.Proto{
+		Name: token.New([]byte("clock_gettime"), token.ID, token.Pos{}),
+		Params: &ast.FieldList{
+			Fields: []*ast.Field{
+				{Name: token.New([]byte("clock"), token.ID, token.Pos{}), Type: i32},
+				{Name: token.New([]byte("ts"), token.ID, token.Pos{}), Type: &ast.PointerType{Type: timespec}},
+			},
+		},
+		RetType: i32,
+	}
+	runtime := &ast.ExternDecl{
+		Name:       token.New([]byte("runtime"), token.ID, token.Pos{}),
+		Prototypes: []*ast.Proto{clockGettime},
+	}
+	c.generateExternDecl(runtime)
+	clockGettimeFn := clockGettime.BackendType.(*Function)
+
+	i64Ty := c.context.Int64Type()
+	nowTy := llvm.FunctionType(i64Ty, nil, false)
+	nowValue := llvm.AddFunction(c.module, ".bench.now", nowTy)
+	nowValue.SetLinkage(llvm.InternalLinkage)
+	entry := c.context.AddBasicBlock(nowValue, "entry")
+	c.builder.SetInsertPointAtEnd(entry)
+
+	timespecTy := c.getType(timespec)
+	ts := c.builder.CreateAlloca(timespecTy, ".ts")
+	clock := llvm.ConstInt(c.context.Int32Type(), CLOCK_MONOTONIC, false)
+	c.builder.CreateCall(clockGettimeFn.Ty, clockGettimeFn.Fn, []llvm.Value{clock, ts}, "")
+
+	zero := llvm.ConstInt(i64Ty, 0, false)
+	secPtr := c.builder.CreateInBoundsGEP(timespecTy, ts, []llvm.Value{zero, zero}, ".secptr")
+	nsecPtr := c.builder.CreateInBoundsGEP(timespecTy, ts, []llvm.Value{zero, llvm.ConstInt(i64Ty, 1, false)}, ".nsecptr")
+	sec := c.builder.CreateLoad(i64Ty, secPtr, ".sec")
+	nsec := c.builder.CreateLoad(i64Ty, nsecPtr, ".nsec")
+	now := c.builder.CreateMul(sec, llvm.ConstInt(i64Ty, 1_000_000_000, false), ".ns")
+	now = c.builder.CreateAdd(now, nsec, ".now")
+	c.builder.CreateRet(now)
+
+	return NewFunctionValue(nowValue, nowTy, &entry)
+}
+
+// Runs a benchmark with an increasing number of iterations until it takes at
+// least BENCH_TIME_NS, then prints the time per iteration. The number of
+// iterations grows just like Go's testing package: it predicts how many
+// iterations fit on the time budget, with a 20% margin, growing at most 100x
+// and at least by one on each round.
+func (c *llvmCodegen) generateBenchRun(
+	bench *ast.BenchDecl,
+	now *Function,
+	mainValue llvm.Value,
+) {
+	i64 := c.context.Int64Type()
+	one := llvm.ConstInt(i64, 1, false)
+	benchFn := bench.BackendType.(*Function)
+
+	n := c.builder.CreateAlloca(i64, ".n")
+	c.builder.CreateStore(one, n)
+	i := c.builder.CreateAlloca(i64, ".i")
+
+	roundBlock := llvm.AddBasicBlock(mainValue, ".benchround")
+	condBlock := llvm.AddBasicBlock(mainValue, ".benchcond")
+	bodyBlock := llvm.AddBasicBlock(mainValue, ".benchbody")
+	doneBlock := llvm.AddBasicBlock(mainValue, ".benchdone")
+	growBlock := llvm.AddBasicBlock(mainValue, ".benchgrow")
+	reportBlock := llvm.AddBasicBlock(mainValue, ".benchreport")
+
+	c.builder.CreateBr(roundBlock)
+	c.builder.SetInsertPointAtEnd(roundBlock)
+	c.builder.CreateStore(llvm.ConstInt(i64, 0, false), i)
+	start := c.builder.CreateCall(now.Ty, now.Fn, nil, ".start")
+	c.builder.CreateBr(condBlock)
+
+	c.builder.SetInsertPointAtEnd(condBlock)
+	iValue := c.builder.CreateLoad(i64, i, ".load")
+	nValue := c.builder.CreateLoad(i64, n, ".load")
+	more := c.builder.CreateICmp(llvm.IntULT, iValue, nValue, ".cmplt")
+	c.builder.CreateCondBr(more, bodyBlock, doneBlock)
+
+	c.builder.SetInsertPointAtEnd(bodyBlock)
+	c.builder.CreateCall(benchFn.Ty, benchFn.Fn, nil, "")
+	c.builder.CreateStore(c.builder.CreateAdd(iValue, one, ".inc"), i)
+	c.builder.CreateBr(condBlock)
+
+	c.builder.SetInsertPointAtEnd(doneBlock)
+	end := c.builder.CreateCall(now.Ty, now.Fn, nil, ".end")
+	elapsed := c.builder.CreateSub(end, start, ".elapsed")
+	nValue = c.builder.CreateLoad(i64, n, ".load")
+	tooShort := c.builder.CreateICmp(llvm.IntULT, elapsed, llvm.ConstInt(i64, BENCH_TIME_NS, false), ".cmplt")
+	belowMax := c.builder.CreateICmp(llvm.IntULT, nValue, llvm.ConstInt(i64, BENCH_MAX_N, false), ".cmplt")
+	c.builder.CreateCondBr(c.builder.CreateAnd(tooShort, belowMax, ".and"), growBlock, reportBlock)
+
+	c.builder.SetInsertPointAtEnd(growBlock)
+	isZero := c.builder.CreateICmp(llvm.IntEQ, elapsed, llvm.ConstInt(i64, 0, false), ".cmpeq")
+	divisor := c.builder.CreateSelect(isZero, one, elapsed, ".divisor")
+	predicted := c.builder.CreateMul(nValue, llvm.ConstInt(i64, BENCH_TIME_NS, false), ".mul")
+	predicted = c.builder.CreateUDiv(predicted, divisor, ".div")
+	margin := c.builder.CreateUDiv(predicted, llvm.ConstInt(i64, 5, false), ".div")
+	predicted = c.builder.CreateAdd(predicted, margin, ".add")
+	maxN := c.builder.CreateMul(nValue, llvm.ConstInt(i64, 100, false), ".mul")
+	minN := c.builder.CreateAdd(nValue, one, ".add")
+	tooBig := c.builder.CreateICmp(llvm.IntUGT, predicted, maxN, ".cmpgt")
+	predicted = c.builder.CreateSelect(tooBig, maxN, predicted, ".min")
+	tooSmall := c.builder.CreateICmp(llvm.IntULT, predicted, minN, ".cmplt")
+	predicted = c.builder.CreateSelect(tooSmall, minN, predicted, ".max")
+	c.builder.CreateStore(predicted, n)
+	c.builder.CreateBr(roundBlock)
+
+	c.builder.SetInsertPointAtEnd(reportBlock)
+	nsPerOp := c.builder.CreateUDiv(elapsed, nValue, ".nsop")
+	i8Ptr := llvm.PointerType(c.context.Int8Type(), 0)
+	printfTy := llvm.FunctionType(c.context.Int32Type(), []llvm.Type{i8Ptr}, true)
+	printf := c.getRuntimeFunction("printf", printfTy)
+	args := []llvm.Value{
+		c.getStringLiteral("%-30s %12lld %12lld ns/op\n"),
+		c.getStringLiteral(string(bench.Name.Lexeme)),
+		nValue,
+		nsPerOp,
+	}
+	c.builder.CreateCall(printf.Ty, printf.Fn, args, "")
+}
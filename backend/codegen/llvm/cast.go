@@ -0,0 +1,37 @@
+package llvm
+
+import (
+	"github.com/HicaroD/Telia/frontend/ast"
+	"tinygo.org/x/go-llvm"
+)
+
+// Generates "value as T". Integers are sign-extended when the source type is
+// signed and zero-extended otherwise, booleans included. Integers are cast to
+// booleans by comparing them to zero.
+func (c *llvmCodegen) getCastExpr(cast *ast.CastExpr, scope *ast.Scope) llvm.Value {
+	value := c.getExpr(cast.Value, scope)
+	toTy := c.getType(cast.Type)
+
+	if _, ok := cast.Type.(*ast.PointerType); ok {
+		return c.builder.CreateBitCast(value, toTy, ".cast")
+	}
+
+	from := cast.From.(*ast.BasicType)
+	to := cast.Type.(*ast.BasicType)
+	if to.IsBoolean() {
+		zero := llvm.ConstInt(value.Type(), 0, false)
+		return c.builder.CreateICmp(llvm.IntNE, value, zero, ".cast")
+	}
+	fromBits, toBits := from.Kind.BitSize(), to.Kind.BitSize()
+	switch {
+	case toBits < fromBits:
+		return c.builder.CreateTrunc(value, toTy, ".cast")
+	case toBits > fromBits && from.Kind.IsSigned():
+		return c.builder.CreateSExt(value, toTy, ".cast")
+	case toBits > fromBits:
+		return c.builder.CreateZExt(value, toTy, ".cast")
+	default:
+		// Only the signedness changes, which is not part of LLVM types
+		return value
+	}
+}
@@ -0,0 +1,113 @@
+package llvm
+
+import (
+	"github.com/HicaroD/Telia/frontend/ast"
+	"tinygo.org/x/go-llvm"
+)
+
+// Error unions are lowered to a pair of the error code, which is zero on
+// success, and the value. Error unions of void only hold the error code.
+
+func (c *llvmCodegen) getErrorUnionType(union *ast.ErrorUnionType) llvm.Type {
+	if union.Type.IsVoid() {
+		return c.context.StructType([]llvm.Type{c.context.Int32Type()}, false)
+	}
+	return c.context.StructType([]llvm.Type{c.context.Int32Type(), c.getType(union.Type)}, false)
+}
+
+func (c *llvmCodegen) getErrorExpr(errorExpr *ast.ErrorExpr) llvm.Value {
+	code := llvm.ConstInt(c.context.Int32Type(), errorExpr.Code, false)
+	union := llvm.Undef(c.getErrorUnionType(errorExpr.Type))
+	return c.builder.CreateInsertValue(union, code, 0, ".error")
+}
+
+// Converts a value of type T to !T
+func (c *llvmCodegen) getErrorUnionExpr(union *ast.ErrorUnionExpr, scope *ast.Scope) llvm.Value {
+	success := llvm.ConstNull(c.getErrorUnionType(union.Type))
+	if union.Type.Type.IsVoid() {
+		return success
+	}
+	value := c.getExpr(union.Value, scope)
+	return c.builder.CreateInsertValue(success, value, 1, ".ok")
+}
+
+// Returns an i1 set when the error union holds an error
+func (c *llvmCodegen) getIsError(union llvm.Value) llvm.Value {
+	code := c.builder.CreateExtractValue(union, 0, ".code")
+	return c.builder.CreateICmp(llvm.IntNE, code, llvm.ConstInt(c.context.Int32Type(), 0, false), ".iserror")
+}
+
+// Generates "try value", returning the error code from the current function
+// when there is one
+func (c *llvmCodegen) getTryExpr(try *ast.TryExpr, scope *ast.Scope) llvm.Value {
+	union := c.getExpr(try.Value, scope)
+
+	fn := c.builder.GetInsertBlock().Parent()
+	errorBlock := llvm.AddBasicBlock(fn, ".try.error")
+	okBlock := llvm.AddBasicBlock(fn, ".try.ok")
+	c.builder.CreateCondBr(c.getIsError(union), errorBlock, okBlock)
+
+	// Error codes are the same on every error union, so only the code is
+	// forwarded
+	c.builder.SetInsertPointAtEnd(errorBlock)
+	code := c.builder.CreateExtractValue(union, 0, ".code")
+	propagated := llvm.Undef(fn.GlobalValueType().ReturnType())
+	propagated = c.builder.CreateInsertValue(propagated, code, 0, ".error")
+	c.builder.CreateRet(propagated)
+
+	c.builder.SetInsertPointAtEnd(okBlock)
+	if try.Type.Type.IsVoid() {
+		return llvm.Value{}
+	}
+	return c.builder.CreateExtractValue(union, 1, ".unwrap")
+}
+
+// Generates "value catch default". The default value is only evaluated when
+// there is an error.
+func (c *llvmCodegen) getCatchExpr(expr *ast.BinaryExpr, scope *ast.Scope) llvm.Value {
+	unionTy := expr.OperandType.(*ast.ErrorUnionType)
+	union := c.getExpr(expr.Left, scope)
+
+	fn := c.builder.GetInsertBlock().Parent()
+	valueBlock := llvm.AddBasicBlock(fn, ".catch.value")
+	defaultBlock := llvm.AddBasicBlock(fn, ".catch.default")
+	endBlock := llvm.AddBasicBlock(fn, ".catch.end")
+	c.builder.CreateCondBr(c.getIsError(union), defaultBlock, valueBlock)
+
+	c.builder.SetInsertPointAtEnd(valueBlock)
+	value := c.builder.CreateExtractValue(union, 1, ".unwrap")
+	c.builder.CreateBr(endBlock)
+
+	c.builder.SetInsertPointAtEnd(defaultBlock)
+	defaultValue := c.getExpr(expr.Right, scope)
+	// The default value may have created blocks of its own
+	defaultEnd := c.builder.GetInsertBlock()
+	c.builder.CreateBr(endBlock)
+
+	c.builder.SetInsertPointAtEnd(endBlock)
+	result := c.builder.CreatePHI(c.getType(unionTy.Type), ".catch")
+	result.AddIncoming([]llvm.Value{value, defaultValue}, []llvm.BasicBlock{valueBlock, defaultEnd})
+	return result
+}
+
+// Generates "value catch { ... }". The block is only generated for the error
+// path, and the value is discarded.
+func (c *llvmCodegen) generateCatchStmt(
+	catch *ast.CatchStmt,
+	functionDecl *ast.FunctionDecl,
+	functionLlvm *Function,
+	scope *ast.Scope,
+) {
+	union := c.getExpr(catch.Value, scope)
+
+	errorBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".catch.error")
+	endBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".catch.end")
+	c.builder.CreateCondBr(c.getIsError(union), errorBlock, endBlock)
+
+	c.builder.SetInsertPointAtEnd(errorBlock)
+	stoppedOnReturn := c.generateBlock(catch.Block, scope, functionDecl, functionLlvm)
+	if !stoppedOnReturn {
+		c.builder.CreateBr(endBlock)
+	}
+	c.builder.SetInsertPointAtEnd(endBlock)
+}
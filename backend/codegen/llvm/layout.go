@@ -0,0 +1,55 @@
+package llvm
+
+import (
+	"log"
+
+	"github.com/HicaroD/Telia/frontend/ast"
+	"tinygo.org/x/go-llvm"
+)
+
+// Returns the triple and the data layout of the target, which is always the
+// host for now
+func getTarget() (string, llvm.TargetData) {
+	err := llvm.InitializeNativeTarget()
+	// TODO(errors)
+	if err != nil {
+		log.Fatal(err)
+	}
+	triple := llvm.DefaultTargetTriple()
+	target, err := llvm.GetTargetFromTriple(triple)
+	// TODO(errors)
+	if err != nil {
+		log.Fatal(err)
+	}
+	machine := target.CreateTargetMachine(
+		triple,
+		"",
+		"",
+		llvm.CodeGenLevelDefault,
+		llvm.RelocDefault,
+		llvm.CodeModelDefault,
+	)
+	return triple, machine.CreateTargetData()
+}
+
+// Sizes and alignments of types as lowered by the backend, used by semantic
+// analysis to evaluate builtins such as "sizeof"
+type DataLayout struct {
+	codegen *llvmCodegen
+}
+
+func NewDataLayout() *DataLayout {
+	return &DataLayout{codegen: NewCG("")}
+}
+
+func (layout *DataLayout) SizeOf(ty ast.ExprType) uint64 {
+	return layout.codegen.target.TypeAllocSize(layout.codegen.getType(ty))
+}
+
+func (layout *DataLayout) AlignOf(ty ast.ExprType) uint64 {
+	return uint64(layout.codegen.target.ABITypeAlignment(layout.codegen.getType(ty)))
+}
+
+func (layout *DataLayout) OffsetOf(tuple *ast.TupleType, index int) uint64 {
+	return layout.codegen.target.ElementOffset(layout.codegen.getType(tuple), index)
+}
@@ -0,0 +1,44 @@
+package llvm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/HicaroD/Telia/frontend/ast"
+)
+
+// Generates a static library, or a shared one if "shared" is true, to be
+// called from C. Only functions marked with "@export("name")" are visible
+// outside of it, using the name given by the attribute as their symbol. The
+// program's own "main" is not compiled.
+func (c *llvmCodegen) GenerateLibrary(program *ast.Program, output string, shared bool) error {
+	c.libMode = true
+	c.generateModule(program.Root)
+
+	if shared {
+		args := []string{"-O3", "-Wall", "-shared", "-fPIC", "-x", "ir", "-", "-o", output}
+		args = append(args, c.linkFlags()...)
+		return c.runClang(args)
+	}
+
+	// Libraries linked by extern blocks must be given when linking the
+	// program that uses the static library
+	dir, err := os.MkdirTemp("", "telia-lib")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	object := filepath.Join(dir, "lib.o")
+	err = c.runClang([]string{"-O3", "-Wall", "-fPIC", "-c", "-x", "ir", "-", "-o", object})
+	if err != nil {
+		return err
+	}
+	// ar appends to existing archives, so an old library is removed first
+	err = os.Remove(output)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return exec.Command("ar", "rcs", output, object).Run()
+}
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -20,9 +21,22 @@ type llvmCodegen struct {
 	context llvm.Context
 	module  llvm.Module
 	builder llvm.Builder
+	target  llvm.TargetData
 
-	// NOTE: temporary - find a better way of doing this ( preferebly don't do this :) )
+	// Globals of string literals by content. The whole program is lowered
+	// into a single module, so each literal is emitted only once.
 	strLiterals map[string]llvm.Value
+
+	// Libraries linked by extern blocks, such as "m" on 'extern m link "m"'.
+	// The same library may be linked by more than one extern.
+	libraries map[string]bool
+
+	libMode   bool             // true when generating a library
+	testMode  bool             // true when generating a test binary
+	benchMode bool             // true when the test binary also runs benchmarks
+	inTest    bool             // true when generating the body of a test block
+	tests     []*ast.TestDecl  // tests run by the generated "main"
+	benches   []*ast.BenchDecl // benchmarks run by the generated "main"
 }
 
 func NewCG(path string) *llvmCodegen {
@@ -32,51 +46,104 @@ func NewCG(path string) *llvmCodegen {
 	module := context.NewModule("tmpmod")
 	builder := context.NewBuilder()
 
+	triple, target := getTarget()
+	module.SetTarget(triple)
+	module.SetDataLayout(target.String())
+
 	return &llvmCodegen{
 		path: path,
 
 		context: context,
 		module:  module,
 		builder: builder,
+		target:  target,
 
 		strLiterals: map[string]llvm.Value{},
+		libraries:   map[string]bool{},
 	}
 }
 
 func (c *llvmCodegen) Generate(program *ast.Program) error {
 	c.generateModule(program.Root)
-	err := c.generateExecutable()
+	filenameNoExt := strings.TrimSuffix(filepath.Base(c.path), filepath.Ext(c.path))
+	err := c.generateExecutable(filenameNoExt)
 	return err
 }
 
+// Every function and extern of the program is declared before any body is
+// generated, so a call doesn't depend on where the callee is declared
 func (c *llvmCodegen) generateModule(module *ast.Module) {
-	// TODO: is this order correct? Does it even matter?
+	c.walkFiles(module, c.declareFile)
+	c.walkFiles(module, c.generateFile)
+}
+
+func (c *llvmCodegen) walkFiles(module *ast.Module, generate func(*ast.File)) {
 	for _, file := range module.Files {
-		c.generateFile(file)
+		generate(file)
 	}
 	for _, module := range module.Modules {
-		c.generateModule(module)
+		c.walkFiles(module, generate)
+	}
+}
+
+func (c *llvmCodegen) declareFile(file *ast.File) {
+	for _, node := range file.Body {
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			if c.skipFunction(n) {
+				continue
+			}
+			c.declareFn(n)
+		case *ast.ExternDecl:
+			c.generateExternDecl(n)
+		}
 	}
 }
 
+// Test binaries have their own "main" and libraries have none
+func (c *llvmCodegen) skipFunction(function *ast.FunctionDecl) bool {
+	return (c.testMode || c.libMode) && function.Name.Name() == "main"
+}
+
 func (c *llvmCodegen) generateFile(file *ast.File) {
 	for _, node := range file.Body {
 		switch n := node.(type) {
 		case *ast.FunctionDecl:
+			if c.skipFunction(n) {
+				continue
+			}
 			c.generateFnDecl(n)
 		case *ast.ExternDecl:
-			c.generateExternDecl(n)
+			// Declared before every function body
+			continue
+		case *ast.TestDecl:
+			if c.testMode {
+				c.generateTestDecl(n)
+			}
+		case *ast.BenchDecl:
+			if c.benchMode {
+				c.generateBenchDecl(n)
+			}
+		case *ast.ConstDecl, *ast.StaticAssert:
+			// Evaluated during semantic analysis
+			continue
 		default:
 			log.Fatalf("unimplemented: %s\n", reflect.TypeOf(node))
 		}
 	}
 }
 
-func (c *llvmCodegen) generateExecutable() error {
+func (c *llvmCodegen) generateExecutable(output string) error {
+	args := []string{"-O3", "-Wall", "-x", "ir", "-", "-o", output}
+	args = append(args, c.linkFlags()...)
+	return c.runClang(args)
+}
+
+// Runs clang with the module as its input
+func (c *llvmCodegen) runClang(args []string) error {
 	module := c.module.String()
 
-	filenameNoExt := strings.TrimSuffix(filepath.Base(c.path), filepath.Ext(c.path))
-	cmd := exec.Command("clang", "-O3", "-Wall", "-x", "ir", "-", "-o", filenameNoExt)
+	cmd := exec.Command("clang", args...)
 	cmd.Stdin = bytes.NewReader([]byte(module))
 
 	var stderr bytes.Buffer
@@ -86,20 +153,57 @@ func (c *llvmCodegen) generateExecutable() error {
 	return err
 }
 
-func (c *llvmCodegen) generateFnDecl(functionDecl *ast.FunctionDecl) {
+func (c *llvmCodegen) declareFn(functionDecl *ast.FunctionDecl) {
 	returnType := c.getType(functionDecl.RetType)
 	paramsTypes := c.getFieldListTypes(functionDecl.Params)
 	functionType := llvm.FunctionType(returnType, paramsTypes, functionDecl.Params.IsVariadic)
-	functionValue := llvm.AddFunction(c.module, functionDecl.Name.Name(), functionType)
-	functionBlock := c.context.AddBasicBlock(functionValue, "entry")
-	fnValue := NewFunctionValue(functionValue, functionType, &functionBlock)
-	c.builder.SetInsertPointAtEnd(functionBlock)
+	functionValue := llvm.AddFunction(c.module, functionDecl.Symbol(), functionType)
+	c.setFunctionAttributes(functionValue, functionDecl.Attributes)
+	if functionDecl.Name.Name() != "main" && ast.FindAttribute(functionDecl.Attributes, "export") == nil {
+		// Only "main" and exported functions are visible outside the module
+		functionValue.SetLinkage(llvm.InternalLinkage)
+	}
+	functionDecl.BackendType = NewFunctionValue(functionValue, functionType, nil)
+}
 
-	functionDecl.BackendType = fnValue
+// The function must be already declared by declareFn
+func (c *llvmCodegen) generateFnDecl(functionDecl *ast.FunctionDecl) {
+	fnValue := functionDecl.BackendType.(*Function)
+	functionBlock := c.context.AddBasicBlock(fnValue.Fn, "entry")
+	c.builder.SetInsertPointAtEnd(functionBlock)
 
-	c.generateParameters(fnValue, functionDecl, paramsTypes)
+	c.generateParameters(fnValue, functionDecl, fnValue.Ty.ParamTypes())
 
 	_ = c.generateBlock(functionDecl.Block, functionDecl.Scope, functionDecl, fnValue)
+	if !isTerminated(c.builder.GetInsertBlock()) {
+		if functionDecl.RetType.IsVoid() {
+			c.builder.CreateRetVoid()
+		} else {
+			// Sema checks that every path returns, so the end of the function,
+			// such as the block after an if-else where both branches
+			// return, can't be reached
+			c.builder.CreateUnreachable()
+		}
+	}
+}
+
+func (c *llvmCodegen) setFunctionAttributes(fn llvm.Value, attributes []*ast.Attribute) {
+	for _, attribute := range attributes {
+		var kind string
+		switch attribute.Name.Name() {
+		case "inline":
+			kind = "alwaysinline"
+		case "noinline":
+			kind = "noinline"
+		case "cold":
+			kind = "cold"
+		default:
+			// Other attributes, such as "@deprecated", are only used by sema
+			continue
+		}
+		attr := c.context.CreateEnumAttribute(llvm.AttributeKindID(kind), 0)
+		fn.AddFunctionAttr(attr)
+	}
 }
 
 func (c *llvmCodegen) generateBlock(
@@ -128,7 +232,10 @@ func (c *llvmCodegen) generateStmt(
 ) {
 	switch statement := stmt.(type) {
 	case *ast.FunctionCall:
-		c.generateFunctionCall(parentScope, statement)
+		// Builtins evaluated at compile time have no effect
+		if statement.Folded == nil {
+			c.generateFunctionCall(parentScope, statement)
+		}
 	case *ast.ReturnStmt:
 		c.generateReturnStmt(statement, parentScope)
 	case *ast.CondStmt:
@@ -138,11 +245,21 @@ func (c *llvmCodegen) generateStmt(
 	case *ast.MultiVarStmt:
 		c.generateMultiVar(statement, parentScope)
 	case *ast.FieldAccess:
-		c.generateFieldAccessStmt(statement, parentScope)
+		c.generateFieldAccess(statement, parentScope)
+	case *ast.TryExpr:
+		c.getTryExpr(statement, parentScope)
+	case *ast.CatchStmt:
+		c.generateCatchStmt(statement, functionDecl, functionLlvm, parentScope)
 	case *ast.ForLoop:
 		c.generateForLoop(statement, functionDecl, functionLlvm, parentScope)
+	case *ast.RangeForLoop:
+		c.generateRangeForLoop(statement, functionDecl, functionLlvm, parentScope)
 	case *ast.WhileLoop:
 		c.generateWhileLoop(statement, functionDecl, functionLlvm, parentScope)
+	case *ast.AssertStmt:
+		c.generateAssert(statement, parentScope, functionLlvm)
+	case *ast.ConstDecl, *ast.StaticAssert:
+		// Evaluated during semantic analysis
 	default:
 		log.Fatalf("unimplemented block statement: %s", statement)
 	}
@@ -164,30 +281,60 @@ func (c *llvmCodegen) generateMultiVar(
 	varDecl *ast.MultiVarStmt,
 	scope *ast.Scope,
 ) {
-	for _, variable := range varDecl.Variables {
-		c.generateVar(variable, scope)
+	values := make([]llvm.Value, len(varDecl.Variables))
+	if varDecl.Tuple != nil {
+		tuple := c.getExpr(varDecl.Tuple, scope)
+		for i := range varDecl.Variables {
+			values[i] = c.builder.CreateExtractValue(tuple, i, ".elem")
+		}
+	} else {
+		// Every value is evaluated before any store, so "a, b = b, a" swaps
+		// both variables
+		for i, variable := range varDecl.Variables {
+			values[i] = c.getExpr(variable.Value, scope)
+		}
+	}
+
+	for i, variable := range varDecl.Variables {
+		c.storeVar(variable, values[i], scope)
 	}
 }
 
 func (c *llvmCodegen) generateVar(
 	varStmt *ast.VarStmt,
 	scope *ast.Scope,
+) {
+	// Declared without a value, sema makes sure it's assigned before being
+	// read
+	if varStmt.Value == nil {
+		c.generateVarDecl(varStmt, llvm.Value{})
+		return
+	}
+	value := c.getExpr(varStmt.Value, scope)
+	c.storeVar(varStmt, value, scope)
+}
+
+func (c *llvmCodegen) storeVar(
+	varStmt *ast.VarStmt,
+	value llvm.Value,
+	scope *ast.Scope,
 ) {
 	if varStmt.Decl {
-		c.generateVarDecl(varStmt, scope)
+		c.generateVarDecl(varStmt, value)
 	} else {
-		c.generateVarReassign(varStmt, scope)
+		c.generateVarReassign(varStmt, value, scope)
 	}
 }
 
 func (c *llvmCodegen) generateVarDecl(
 	varDecl *ast.VarStmt,
-	scope *ast.Scope,
+	varExpr llvm.Value,
 ) {
 	varTy := c.getType(varDecl.Type)
 	varPtr := c.builder.CreateAlloca(varTy, ".ptr")
-	varExpr := c.getExpr(varDecl.Value, scope)
-	c.builder.CreateStore(varExpr, varPtr)
+	if !varExpr.IsNil() {
+		c.builder.CreateStore(varExpr, varPtr)
+	}
 
 	variableLlvm := &Variable{
 		Ty:  varTy,
@@ -198,9 +345,9 @@ func (c *llvmCodegen) generateVarDecl(
 
 func (c *llvmCodegen) generateVarReassign(
 	varDecl *ast.VarStmt,
+	expr llvm.Value,
 	scope *ast.Scope,
 ) {
-	expr := c.getExpr(varDecl.Value, scope)
 	symbol, _ := scope.LookupAcrossScopes(varDecl.Name.Name())
 
 	var variable *Variable
@@ -237,6 +384,13 @@ func (c *llvmCodegen) generateFunctionCall(
 	functionScope *ast.Scope,
 	functionCall *ast.FunctionCall,
 ) llvm.Value {
+	if functionCall.Folded != nil {
+		return c.getExpr(functionCall.Folded, functionScope)
+	}
+	if functionCall.Builtin {
+		return c.generateBuiltinCall(functionScope, functionCall)
+	}
+
 	symbol, _ := functionScope.LookupAcrossScopes(functionCall.Name.Name())
 
 	calledFunction := symbol.(*ast.FunctionDecl)
@@ -246,17 +400,66 @@ func (c *llvmCodegen) generateFunctionCall(
 	return c.builder.CreateCall(calledFunctionLlvm.Ty, calledFunctionLlvm.Fn, args, "")
 }
 
+func (c *llvmCodegen) generateBuiltinCall(
+	scope *ast.Scope,
+	call *ast.FunctionCall,
+) llvm.Value {
+	switch call.Name.Name() {
+	case "len":
+		length := c.getLength(call.Args[0], scope)
+		return c.builder.CreateIntCast(length, c.getType(call.Type), ".len")
+	case "cstr":
+		return c.generateCStr(call.Args[0], scope)
+	default:
+		log.Fatalf("unimplemented builtin: %s", call.Name.Name())
+	}
+	return llvm.Value{}
+}
+
 func (c *llvmCodegen) generateExternDecl(external *ast.ExternDecl) {
+	if external.Link != nil {
+		c.libraries[string(external.Link.Lexeme)] = true
+	}
 	for i := range external.Prototypes {
 		c.generatePrototype(external.Prototypes[i])
 	}
+	for _, variable := range external.Vars {
+		c.generateExternVar(variable)
+	}
+}
+
+// Returns the "-l" flags of the libraries linked by extern blocks
+func (c *llvmCodegen) linkFlags() []string {
+	var flags []string
+	for library := range c.libraries {
+		flags = append(flags, "-l"+library)
+	}
+	// Map iteration order is random, but the command must be reproducible
+	sort.Strings(flags)
+	return flags
+}
+
+// Declares a global variable defined by a C library, such as "stderr"
+func (c *llvmCodegen) generateExternVar(variable *ast.ExternVar) {
+	ty := c.getType(variable.Type)
+	global := c.module.NamedGlobal(variable.Symbol())
+	if global.IsNil() {
+		global = llvm.AddGlobal(c.module, ty, variable.Symbol())
+	}
+	variable.BackendType = NewVariableValue(ty, global)
 }
 
 func (c *llvmCodegen) generatePrototype(prototype *ast.Proto) {
 	returnTy := c.getType(prototype.RetType)
 	paramsTypes := c.getFieldListTypes(prototype.Params)
 	ty := llvm.FunctionType(returnTy, paramsTypes, prototype.Params.IsVariadic)
-	protoValue := llvm.AddFunction(c.module, prototype.Name.Name(), ty)
+	// The same C function may be declared on more than one extern
+	protoValue := c.getCFunction(prototype.Symbol(), ty)
+	if protoValue.IsAFunction().IsNil() {
+		prototype.BackendType = NewFunctionValue(protoValue, ty, nil)
+		return
+	}
+	c.setFunctionAttributes(protoValue, prototype.Attributes)
 	proto := NewFunctionValue(protoValue, ty, nil)
 
 	prototype.BackendType = proto
@@ -282,6 +485,8 @@ func (c *llvmCodegen) getType(ty ast.ExprType) llvm.Type {
 			return c.context.Int64Type()
 		case token.VOID_TYPE:
 			return c.context.VoidType()
+		case token.STRING_TYPE:
+			return c.getStringType()
 		default:
 			log.Fatalf("invalid basic type token: '%s'", exprTy.Kind)
 		}
@@ -289,6 +494,25 @@ func (c *llvmCodegen) getType(ty ast.ExprType) llvm.Type {
 		underlyingExprType := c.getType(exprTy.Type)
 		// TODO: learn about how to properly define a pointer address space
 		return llvm.PointerType(underlyingExprType, 0)
+	case *ast.TupleType:
+		// Tuples are lowered to anonymous structs
+		types := make([]llvm.Type, len(exprTy.Types))
+		for i := range exprTy.Types {
+			types[i] = c.getType(exprTy.Types[i])
+		}
+		return c.context.StructType(types, false)
+	case *ast.ArrayType:
+		elemTy := c.getType(exprTy.Type)
+		return llvm.ArrayType(elemTy, exprTy.Len)
+	case *ast.OptionalType:
+		return c.getOptionalType(exprTy)
+	case *ast.ErrorUnionType:
+		return c.getErrorUnionType(exprTy)
+	case *ast.SliceType:
+		// Slices are lowered to a pair of pointer to the first element and
+		// length
+		elemTy := c.getType(exprTy.Type)
+		return c.context.StructType([]llvm.Type{llvm.PointerType(elemTy, 0), c.context.Int64Type()}, false)
 	default:
 		log.Fatalf("invalid type: %s", reflect.TypeOf(exprTy))
 	}
@@ -323,23 +547,19 @@ func (c *llvmCodegen) getExpr(
 	case *ast.LiteralExpr:
 		switch ty := currentExpr.Type.(type) {
 		case *ast.BasicType:
+			if ty.IsString() {
+				return c.getStringValue(string(currentExpr.Value))
+			}
 			integerValue, bitSize := c.getIntegerValue(currentExpr, ty)
 			return llvm.ConstInt(c.context.IntType(bitSize), integerValue, false)
+		case *ast.OptionalType:
+			return c.getNil(ty)
 		case *ast.PointerType:
 			switch ptrTy := ty.Type.(type) {
 			case *ast.BasicType:
 				switch ptrTy.Kind {
 				case token.U8_TYPE:
-					str := string(currentExpr.Value)
-					// NOTE: huge string literals can affect performance because it
-					// creates a new entry on the map
-					globalStrLiteral, ok := c.strLiterals[str]
-					if ok {
-						return globalStrLiteral
-					}
-					globalStrPtr := c.builder.CreateGlobalStringPtr(str, ".str")
-					c.strLiterals[str] = globalStrPtr
-					return globalStrPtr
+					return c.getStringLiteral(string(currentExpr.Value))
 				default:
 					log.Fatalf("unimplemented ptr basic type: %s", ptrTy.Kind)
 				}
@@ -350,6 +570,11 @@ func (c *llvmCodegen) getExpr(
 		varName := currentExpr.Name.Name()
 		symbol, _ := scope.LookupAcrossScopes(varName)
 
+		// Constants are already folded into literals
+		if constDecl, ok := symbol.(*ast.ConstDecl); ok {
+			return c.getExpr(constDecl.Value, scope)
+		}
+
 		var localVar *Variable
 
 		switch symbol.(type) {
@@ -364,37 +589,95 @@ func (c *llvmCodegen) getExpr(
 		loadedVariable := c.builder.CreateLoad(localVar.Ty, localVar.Ptr, ".load")
 		return loadedVariable
 	case *ast.BinaryExpr:
+		switch currentExpr.OperandType.(type) {
+		case *ast.OptionalType:
+			return c.getOptionalBinaryExpr(currentExpr, scope)
+		case *ast.ErrorUnionType:
+			return c.getCatchExpr(currentExpr, scope)
+		}
 		lhs := c.getExpr(currentExpr.Left, scope)
 		rhs := c.getExpr(currentExpr.Right, scope)
 
+		if currentExpr.OperandType != nil && currentExpr.OperandType.IsString() {
+			switch currentExpr.Op {
+			case token.PLUS:
+				return c.generateStringConcat(lhs, rhs)
+			case token.EQUAL_EQUAL:
+				return c.generateStringEq(lhs, rhs)
+			case token.BANG_EQUAL:
+				return c.builder.CreateNot(c.generateStringEq(lhs, rhs), ".strne")
+			}
+		}
+
+		signed := isSigned(currentExpr.OperandType)
 		switch currentExpr.Op {
-		// TODO: deal with signed or unsigned operations
-		// I'm assuming all unsigned for now
 		case token.EQUAL_EQUAL:
 			// TODO: there a list of IntPredicate, I could map token kind to these
 			// for code reability
 			// See https://github.com/tinygo-org/go-llvm/blob/master/ir.go#L302
 			return c.builder.CreateICmp(llvm.IntEQ, lhs, rhs, ".cmpeq")
+		case token.BANG_EQUAL:
+			return c.builder.CreateICmp(llvm.IntNE, lhs, rhs, ".cmpne")
 		case token.STAR:
 			return c.builder.CreateMul(lhs, rhs, ".mul")
+		case token.SLASH:
+			if signed {
+				return c.builder.CreateSDiv(lhs, rhs, ".div")
+			}
+			return c.builder.CreateUDiv(lhs, rhs, ".div")
+		case token.PERCENT:
+			if signed {
+				return c.builder.CreateSRem(lhs, rhs, ".rem")
+			}
+			return c.builder.CreateURem(lhs, rhs, ".rem")
 		case token.MINUS:
 			return c.builder.CreateSub(lhs, rhs, ".sub")
 		case token.PLUS:
 			return c.builder.CreateAdd(lhs, rhs, ".add")
 		case token.LESS:
-			return c.builder.CreateICmp(llvm.IntULT, lhs, rhs, ".cmplt")
+			return c.builder.CreateICmp(comparePredicate(llvm.IntULT, signed), lhs, rhs, ".cmplt")
 		case token.LESS_EQ:
-			return c.builder.CreateICmp(llvm.IntULE, lhs, rhs, ".cmple")
+			return c.builder.CreateICmp(comparePredicate(llvm.IntULE, signed), lhs, rhs, ".cmple")
 		case token.GREATER:
-			return c.builder.CreateICmp(llvm.IntUGT, lhs, rhs, ".cmpgt")
+			return c.builder.CreateICmp(comparePredicate(llvm.IntUGT, signed), lhs, rhs, ".cmpgt")
 		case token.GREATER_EQ:
-			return c.builder.CreateICmp(llvm.IntUGE, lhs, rhs, ".cmpge")
+			return c.builder.CreateICmp(comparePredicate(llvm.IntUGE, signed), lhs, rhs, ".cmpge")
 		default:
 			log.Fatalf("unimplemented binary operator: %s", currentExpr.Op)
 		}
 	case *ast.FunctionCall:
 		call := c.generateFunctionCall(scope, currentExpr)
 		return call
+	case *ast.FieldAccess:
+		return c.generateFieldAccess(currentExpr, scope)
+	case *ast.OptionalExpr:
+		return c.getOptionalExpr(currentExpr, scope)
+	case *ast.ErrorUnionExpr:
+		return c.getErrorUnionExpr(currentExpr, scope)
+	case *ast.ErrorExpr:
+		return c.getErrorExpr(currentExpr)
+	case *ast.TryExpr:
+		return c.getTryExpr(currentExpr, scope)
+	case *ast.CastExpr:
+		return c.getCastExpr(currentExpr, scope)
+	case *ast.TupleExpr:
+		tupleTy := c.getType(currentExpr.Type)
+		tuple := llvm.Undef(tupleTy)
+		for i, elem := range currentExpr.Exprs {
+			value := c.getExpr(elem, scope)
+			tuple = c.builder.CreateInsertValue(tuple, value, i, ".tuple")
+		}
+		return tuple
+	case *ast.ArrayLiteral:
+		arrayTy := c.getType(currentExpr.Type)
+		array := llvm.Undef(arrayTy)
+		for i, elem := range currentExpr.Values {
+			value := c.getExpr(elem, scope)
+			array = c.builder.CreateInsertValue(array, value, i, ".array")
+		}
+		return array
+	case *ast.IndexExpr:
+		return c.getIndexExpr(currentExpr, scope)
 	case *ast.UnaryExpr:
 		switch currentExpr.Op {
 		case token.MINUS:
@@ -412,6 +695,212 @@ func (c *llvmCodegen) getExpr(
 	return llvm.Value{}
 }
 
+func (c *llvmCodegen) getIndexExpr(
+	index *ast.IndexExpr,
+	scope *ast.Scope,
+) llvm.Value {
+	if rangeExpr, ok := index.Index.(*ast.RangeExpr); ok {
+		return c.getSliceExpr(index, rangeExpr, scope)
+	}
+
+	elemTy := c.getType(index.Type)
+	idx := c.getIndexValue(index.Index, scope)
+	elemPtr := c.getElementPtr(index.Value, idx, scope)
+	return c.builder.CreateLoad(elemTy, elemPtr, ".elem")
+}
+
+// Returns a pointer to the element at "idx" of an array, slice or string value
+func (c *llvmCodegen) getElementPtr(
+	value ast.Expr,
+	idx llvm.Value,
+	scope *ast.Scope,
+) llvm.Value {
+	switch ty := c.getExprType(value, scope).(type) {
+	case *ast.ArrayType:
+		arrayTy := c.getType(ty)
+		arrayPtr := c.getExprAddr(value, arrayTy, scope)
+		zero := llvm.ConstInt(c.context.Int64Type(), 0, false)
+		return c.builder.CreateInBoundsGEP(arrayTy, arrayPtr, []llvm.Value{zero, idx}, ".elemptr")
+	case *ast.SliceType:
+		slice := c.getExpr(value, scope)
+		dataPtr := c.builder.CreateExtractValue(slice, 0, ".data")
+		return c.builder.CreateInBoundsGEP(c.getType(ty.Type), dataPtr, []llvm.Value{idx}, ".elemptr")
+	case *ast.BasicType:
+		if ty.IsString() {
+			str := c.getExpr(value, scope)
+			dataPtr := c.builder.CreateExtractValue(str, 0, ".data")
+			return c.builder.CreateInBoundsGEP(c.context.Int8Type(), dataPtr, []llvm.Value{idx}, ".elemptr")
+		}
+		log.Fatalf("invalid indexed type: %s", ty)
+	default:
+		log.Fatalf("invalid indexed type: %s", reflect.TypeOf(ty))
+	}
+	return llvm.Value{}
+}
+
+func (c *llvmCodegen) getSliceExpr(
+	index *ast.IndexExpr,
+	rangeExpr *ast.RangeExpr,
+	scope *ast.Scope,
+) llvm.Value {
+	i64 := c.context.Int64Type()
+
+	low := llvm.ConstInt(i64, 0, false)
+	if rangeExpr.Start != nil {
+		low = c.getIndexValue(rangeExpr.Start, scope)
+	}
+
+	var high llvm.Value
+	if rangeExpr.End != nil {
+		high = c.getIndexValue(rangeExpr.End, scope)
+		if rangeExpr.Inclusive {
+			high = c.builder.CreateAdd(high, llvm.ConstInt(i64, 1, false), ".inc")
+		}
+	} else {
+		high = c.getLength(index.Value, scope)
+	}
+
+	dataPtr := c.getElementPtr(index.Value, low, scope)
+	length := c.builder.CreateSub(high, low, ".len")
+
+	sliceTy := c.getType(index.Type)
+	slice := llvm.Undef(sliceTy)
+	slice = c.builder.CreateInsertValue(slice, dataPtr, 0, ".slice")
+	slice = c.builder.CreateInsertValue(slice, length, 1, ".slice")
+	return slice
+}
+
+// Returns the length of an array, slice or string value as i64
+func (c *llvmCodegen) getLength(value ast.Expr, scope *ast.Scope) llvm.Value {
+	switch ty := c.getExprType(value, scope).(type) {
+	case *ast.ArrayType:
+		return llvm.ConstInt(c.context.Int64Type(), uint64(ty.Len), false)
+	case *ast.SliceType, *ast.BasicType:
+		slice := c.getExpr(value, scope)
+		return c.builder.CreateExtractValue(slice, 1, ".len")
+	default:
+		log.Fatalf("invalid type for length: %s", reflect.TypeOf(ty))
+	}
+	return llvm.Value{}
+}
+
+// Index values are extended (or truncated) to i64
+func (c *llvmCodegen) getIndexValue(expr ast.Expr, scope *ast.Scope) llvm.Value {
+	value := c.getExpr(expr, scope)
+	return c.extendInt(value, c.getExprType(expr, scope), c.context.Int64Type(), ".idx")
+}
+
+// Converts an integer to another integer type, sign extending signed values
+// and zero extending unsigned ones
+func (c *llvmCodegen) extendInt(value llvm.Value, ty ast.ExprType, to llvm.Type, name string) llvm.Value {
+	fromBits := value.Type().IntTypeWidth()
+	toBits := to.IntTypeWidth()
+	switch {
+	case fromBits == toBits:
+		return value
+	case fromBits > toBits:
+		return c.builder.CreateTrunc(value, to, name)
+	case isSigned(ty):
+		return c.builder.CreateSExt(value, to, name)
+	default:
+		return c.builder.CreateZExt(value, to, name)
+	}
+}
+
+// Returns the address of a value. Variables already live on memory, other
+// values are stored on a temporary allocation.
+func (c *llvmCodegen) getExprAddr(
+	expr ast.Expr,
+	ty llvm.Type,
+	scope *ast.Scope,
+) llvm.Value {
+	if id, ok := expr.(*ast.IdExpr); ok {
+		symbol, _ := scope.LookupAcrossScopes(id.Name.Name())
+		switch variable := symbol.(type) {
+		case *ast.VarStmt:
+			return variable.BackendType.(*Variable).Ptr
+		case *ast.Field:
+			return variable.BackendType.(*Variable).Ptr
+		}
+	}
+	value := c.getExpr(expr, scope)
+	tmp := c.builder.CreateAlloca(ty, ".tmp")
+	c.builder.CreateStore(value, tmp)
+	return tmp
+}
+
+// Returns the type of an expression already analyzed by sema
+func (c *llvmCodegen) getExprType(expr ast.Expr, scope *ast.Scope) ast.ExprType {
+	switch currentExpr := expr.(type) {
+	case *ast.IdExpr:
+		symbol, _ := scope.LookupAcrossScopes(currentExpr.Name.Name())
+		switch variable := symbol.(type) {
+		case *ast.VarStmt:
+			return variable.Type
+		case *ast.Field:
+			return variable.Type
+		case *ast.ConstDecl:
+			return variable.Type
+		}
+	case *ast.LiteralExpr:
+		return currentExpr.Type
+	case *ast.ArrayLiteral:
+		return currentExpr.Type
+	case *ast.IndexExpr:
+		return currentExpr.Type
+	case *ast.FunctionCall:
+		return currentExpr.Type
+	case *ast.TryExpr:
+		return currentExpr.Type.Type
+	case *ast.CastExpr:
+		return currentExpr.Type
+	case *ast.BinaryExpr:
+		// Arithmetic and concatenation result on a value of the same type as
+		// their operands
+		switch currentExpr.Op {
+		case token.PLUS, token.MINUS, token.STAR, token.SLASH, token.PERCENT:
+			return currentExpr.OperandType
+		}
+	case *ast.UnaryExpr:
+		if currentExpr.Op == token.MINUS {
+			return c.getExprType(currentExpr.Value, scope)
+		}
+	case *ast.FieldAccess:
+		symbol, _ := scope.LookupAcrossScopes(currentExpr.Left.(*ast.IdExpr).Name.Name())
+		extern := symbol.(*ast.ExternDecl)
+		switch right := currentExpr.Right.(type) {
+		case *ast.FunctionCall:
+			prototype, _ := extern.Scope.LookupCurrentScope(right.Name.Name())
+			return prototype.(*ast.Proto).RetType
+		case *ast.IdExpr:
+			variable, _ := extern.Scope.LookupCurrentScope(right.Name.Name())
+			return variable.(*ast.ExternVar).Type
+		}
+	}
+	log.Fatalf("unable to get type of expression: %s", reflect.TypeOf(expr))
+	return nil
+}
+
+// Returns a pointer to the first byte of a NUL-terminated global holding the
+// string literal
+func (c *llvmCodegen) getStringLiteral(str string) llvm.Value {
+	globalStrPtr, ok := c.strLiterals[str]
+	if ok {
+		return globalStrPtr
+	}
+	value := c.context.ConstString(str, true)
+	global := llvm.AddGlobal(c.module, value.Type(), ".str")
+	global.SetInitializer(value)
+	global.SetLinkage(llvm.PrivateLinkage)
+	global.SetGlobalConstant(true)
+	global.SetUnnamedAddr(true)
+
+	zero := llvm.ConstInt(c.context.Int64Type(), 0, false)
+	globalStrPtr = llvm.ConstInBoundsGEP(value.Type(), global, []llvm.Value{zero, zero})
+	c.strLiterals[str] = globalStrPtr
+	return globalStrPtr
+}
+
 func (c *llvmCodegen) getIntegerValue(
 	expr *ast.LiteralExpr,
 	ty *ast.BasicType,
@@ -437,10 +926,18 @@ func (c *llvmCodegen) generateCondStmt(
 	endBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".end")
 
 	ifExpr := c.getExpr(condStmt.IfStmt.Expr, parentScope)
+	optional := ifExpr
+	unwrap := condStmt.IfStmt.Unwrap
+	if unwrap != nil {
+		ifExpr = c.getOptionalHasValue(optional, &ast.OptionalType{Type: unwrap.Type})
+	}
 	c.builder.CreateCondBr(ifExpr, ifBlock, elseBlock)
 	c.builder.SetInsertPointAtEnd(ifBlock)
+	if unwrap != nil {
+		c.generateVarDecl(unwrap, c.getOptionalValue(optional, &ast.OptionalType{Type: unwrap.Type}))
+	}
 
-	stoppedOnReturn := c.generateBlock(condStmt.IfStmt.Block, parentScope, functionDecl, functionLlvm)
+	stoppedOnReturn := c.generateBlock(condStmt.IfStmt.Block, condStmt.IfStmt.Scope, functionDecl, functionLlvm)
 	if !stoppedOnReturn {
 		c.builder.CreateBr(endBlock)
 	}
@@ -465,10 +962,10 @@ func (c *llvmCodegen) generateCondStmt(
 	c.builder.SetInsertPointAtEnd(endBlock)
 }
 
-func (c *llvmCodegen) generateFieldAccessStmt(
+func (c *llvmCodegen) generateFieldAccess(
 	fieldAccess *ast.FieldAccess,
 	scope *ast.Scope,
-) {
+) llvm.Value {
 	idExpr := fieldAccess.Left.(*ast.IdExpr)
 	id := idExpr.Name.Name()
 
@@ -478,7 +975,11 @@ func (c *llvmCodegen) generateFieldAccessStmt(
 	case *ast.ExternDecl:
 		switch right := fieldAccess.Right.(type) {
 		case *ast.FunctionCall:
-			c.generatePrototypeCall(left, right, scope)
+			return c.generatePrototypeCall(left, right, scope)
+		case *ast.IdExpr:
+			symbol, _ := left.Scope.LookupCurrentScope(right.Name.Name())
+			variable := symbol.(*ast.ExternVar).BackendType.(*Variable)
+			return c.builder.CreateLoad(variable.Ty, variable.Ptr, "")
 		default:
 			// TODO(errors)
 			log.Fatalf("unimplemented %s on field access statement", right)
@@ -487,6 +988,7 @@ func (c *llvmCodegen) generateFieldAccessStmt(
 		// TODO(errors)
 		log.Fatalf("unimplemented %s on extern", left)
 	}
+	return llvm.Value{}
 }
 
 func (c *llvmCodegen) generatePrototypeCall(
@@ -498,10 +1000,28 @@ func (c *llvmCodegen) generatePrototypeCall(
 	proto := prototype.(*ast.Proto)
 	protoLlvm := proto.BackendType.(*Function)
 	args := c.getExprList(callScope, call.Args)
+	for i := len(proto.Params.Fields); i < len(args); i++ {
+		args[i] = c.promoteVariadicArg(args[i], call.Args[i], callScope)
+	}
 
 	return c.builder.CreateCall(protoLlvm.Ty, protoLlvm.Fn, args, "")
 }
 
+// Applies C's default argument promotion to variadic arguments: integers
+// smaller than int, booleans included, are extended to i32, so "%d" on
+// printf reads them correctly
+func (c *llvmCodegen) promoteVariadicArg(value llvm.Value, arg ast.Expr, scope *ast.Scope) llvm.Value {
+	ty := value.Type()
+	if ty.TypeKind() != llvm.IntegerTypeKind || ty.IntTypeWidth() >= 32 {
+		return value
+	}
+	i32 := c.context.Int32Type()
+	if ty.IntTypeWidth() == 1 {
+		return c.builder.CreateZExt(value, i32, ".promote")
+	}
+	return c.extendInt(value, c.getExprType(arg, scope), i32, ".promote")
+}
+
 func (c *llvmCodegen) generateForLoop(
 	forLoop *ast.ForLoop,
 	functionDecl *ast.FunctionDecl,
@@ -539,6 +1059,153 @@ func (c *llvmCodegen) generateForLoop(
 	c.builder.SetInsertPointAtEnd(endBlock)
 }
 
+func (c *llvmCodegen) generateRangeForLoop(
+	forLoop *ast.RangeForLoop,
+	functionDecl *ast.FunctionDecl,
+	functionLlvm *Function,
+	parentScope *ast.Scope,
+) {
+	forScope := forLoop.Scope
+
+	forPrepBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".forprep")
+	forInitBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".forinit")
+	forBodyBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".forbody")
+	forUpdateBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".forupdate")
+	endBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".forend")
+
+	c.builder.CreateBr(forPrepBlock)
+	c.builder.SetInsertPointAtEnd(forPrepBlock)
+
+	if rangeExpr, ok := forLoop.Iterable.(*ast.RangeExpr); ok {
+		// The end of the range is evaluated only once
+		start := c.getExpr(rangeExpr.Start, parentScope)
+		end := c.getExpr(rangeExpr.End, parentScope)
+		c.generateVarDecl(forLoop.Value, start)
+		counter := forLoop.Value.BackendType.(*Variable)
+
+		c.builder.CreateBr(forInitBlock)
+		c.builder.SetInsertPointAtEnd(forInitBlock)
+		current := c.builder.CreateLoad(counter.Ty, counter.Ptr, ".load")
+		predicate := getRangePredicate(forLoop.Value.Type, rangeExpr.Inclusive)
+		cond := c.builder.CreateICmp(predicate, current, end, ".cmp")
+		c.builder.CreateCondBr(cond, forBodyBlock, endBlock)
+
+		c.builder.SetInsertPointAtEnd(forBodyBlock)
+		c.generateBlock(forLoop.Block, forScope, functionDecl, functionLlvm)
+
+		c.builder.CreateBr(forUpdateBlock)
+		c.builder.SetInsertPointAtEnd(forUpdateBlock)
+		current = c.builder.CreateLoad(counter.Ty, counter.Ptr, ".load")
+		if rangeExpr.Inclusive {
+			// Stops before incrementing past the end, so "0...n" terminates
+			// even if "n" is the maximum value of its type
+			forIncBlock := llvm.InsertBasicBlock(endBlock, ".forinc")
+			last := c.builder.CreateICmp(llvm.IntEQ, current, end, ".last")
+			c.builder.CreateCondBr(last, endBlock, forIncBlock)
+			c.builder.SetInsertPointAtEnd(forIncBlock)
+		}
+		next := c.builder.CreateAdd(current, llvm.ConstInt(counter.Ty, 1, false), ".inc")
+		c.builder.CreateStore(next, counter.Ptr)
+	} else {
+		// Arrays and slices are iterated through a hidden i64 index
+		i64 := c.context.Int64Type()
+		length := c.getLength(forLoop.Iterable, parentScope)
+		idxPtr := c.builder.CreateAlloca(i64, ".idx")
+		c.builder.CreateStore(llvm.ConstInt(i64, 0, false), idxPtr)
+
+		elemTy := c.getType(forLoop.Value.Type)
+		c.generateVarDecl(forLoop.Value, llvm.ConstNull(elemTy))
+		element := forLoop.Value.BackendType.(*Variable)
+		var index *Variable
+		if forLoop.Index != nil {
+			c.generateVarDecl(forLoop.Index, llvm.ConstNull(c.getType(forLoop.Index.Type)))
+			index = forLoop.Index.BackendType.(*Variable)
+		}
+
+		// Arrays are stored once, so the loop doesn't copy them on every
+		// iteration
+		var dataPtr llvm.Value
+		var arrayTy llvm.Type
+		isArray := false
+		switch ty := c.getExprType(forLoop.Iterable, parentScope).(type) {
+		case *ast.ArrayType:
+			isArray = true
+			arrayTy = c.getType(ty)
+			dataPtr = c.getExprAddr(forLoop.Iterable, arrayTy, parentScope)
+		case *ast.SliceType:
+			slice := c.getExpr(forLoop.Iterable, parentScope)
+			dataPtr = c.builder.CreateExtractValue(slice, 0, ".data")
+		}
+
+		c.builder.CreateBr(forInitBlock)
+		c.builder.SetInsertPointAtEnd(forInitBlock)
+		current := c.builder.CreateLoad(i64, idxPtr, ".load")
+		cond := c.builder.CreateICmp(llvm.IntULT, current, length, ".cmp")
+		c.builder.CreateCondBr(cond, forBodyBlock, endBlock)
+
+		c.builder.SetInsertPointAtEnd(forBodyBlock)
+		var elemPtr llvm.Value
+		if isArray {
+			zero := llvm.ConstInt(i64, 0, false)
+			elemPtr = c.builder.CreateInBoundsGEP(arrayTy, dataPtr, []llvm.Value{zero, current}, ".elemptr")
+		} else {
+			elemPtr = c.builder.CreateInBoundsGEP(elemTy, dataPtr, []llvm.Value{current}, ".elemptr")
+		}
+		elem := c.builder.CreateLoad(elemTy, elemPtr, ".elem")
+		c.builder.CreateStore(elem, element.Ptr)
+		if index != nil {
+			c.builder.CreateStore(c.builder.CreateIntCast(current, index.Ty, ".idx"), index.Ptr)
+		}
+		c.generateBlock(forLoop.Block, forScope, functionDecl, functionLlvm)
+
+		c.builder.CreateBr(forUpdateBlock)
+		c.builder.SetInsertPointAtEnd(forUpdateBlock)
+		next := c.builder.CreateAdd(current, llvm.ConstInt(i64, 1, false), ".inc")
+		c.builder.CreateStore(next, idxPtr)
+	}
+
+	c.builder.CreateBr(forInitBlock)
+
+	c.builder.SetInsertPointAtEnd(endBlock)
+}
+
+func getRangePredicate(ty ast.ExprType, inclusive bool) llvm.IntPredicate {
+	signed := isSigned(ty)
+	switch {
+	case signed && inclusive:
+		return llvm.IntSLE
+	case signed:
+		return llvm.IntSLT
+	case inclusive:
+		return llvm.IntULE
+	default:
+		return llvm.IntULT
+	}
+}
+
+func isSigned(ty ast.ExprType) bool {
+	basicTy, ok := ty.(*ast.BasicType)
+	return ok && basicTy.IsSigned()
+}
+
+// Returns the signed version of an unsigned comparison predicate if signed is
+// true
+func comparePredicate(unsigned llvm.IntPredicate, signed bool) llvm.IntPredicate {
+	if !signed {
+		return unsigned
+	}
+	switch unsigned {
+	case llvm.IntULT:
+		return llvm.IntSLT
+	case llvm.IntULE:
+		return llvm.IntSLE
+	case llvm.IntUGT:
+		return llvm.IntSGT
+	default:
+		return llvm.IntSGE
+	}
+}
+
 func (c *llvmCodegen) generateWhileLoop(
 	whileLoop *ast.WhileLoop,
 	functionDecl *ast.FunctionDecl,
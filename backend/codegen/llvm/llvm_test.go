@@ -0,0 +1,184 @@
+package llvm
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer"
+	"github.com/HicaroD/Telia/frontend/parser"
+	"github.com/HicaroD/Telia/middleend/sema"
+	"tinygo.org/x/go-llvm"
+)
+
+type instructionTest struct {
+	input        string
+	instructions []string
+}
+
+func TestSignedInstructions(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []instructionTest{
+		{
+			input:        "fn f(a int, b int) int { return a / b; }",
+			instructions: []string{"sdiv i64"},
+		},
+		{
+			input:        "fn f(a int, b int) int { return a % b; }",
+			instructions: []string{"srem i64"},
+		},
+		{
+			input:        "fn f(a i8, b i8) bool { return a < b; }",
+			instructions: []string{"icmp slt i8"},
+		},
+		{
+			input:        "fn f(a i8) int { return a; }",
+			instructions: []string{"sext i8"},
+		},
+		{
+			input:        "fn f(a u32, b u32) u32 { return a / b + a % b; }",
+			instructions: []string{"udiv i32", "urem i32"},
+		},
+		{
+			input:        "fn f(a u8, b u8) bool { return a < b; }",
+			instructions: []string{"icmp ult i8"},
+		},
+		{
+			input:        "fn f(a u8) i32 { return a; }",
+			instructions: []string{"zext i8"},
+		},
+		{
+			input:        "fn f(a u8) bool { return a as bool; }",
+			instructions: []string{"icmp ne i8"},
+		},
+		{
+			input:        "fn f(a bool) i32 { return a as i32; }",
+			instructions: []string{"zext i1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestSignedInstructions('%s')", test.input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			lex := lexer.New(filename, []byte(test.input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = sema.NewWithLayout(collector, NewDataLayout()).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+
+			codegen := NewCG(filename)
+			codegen.generateModule(program.Root)
+			ir := codegen.module.String()
+			for _, instruction := range test.instructions {
+				if !strings.Contains(ir, instruction) {
+					t.Fatalf("expected '%s' on\n%s", instruction, ir)
+				}
+			}
+		})
+	}
+}
+
+// C functions used by the generated code, such as "malloc" on string
+// concatenation, may also be declared by the program with another type
+func TestRuntimeFunctionsDeclaredByExterns(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []string{
+		"extern libc { fn malloc(size u32) *u8; }\nfn f(a string, b string) string { return a + b; }\nfn g() { _p := libc.malloc(1); }",
+		"fn f(a string, b string) string { return a + b; }\nextern libc { fn malloc(size u32) *u8; }\nfn g() { _p := libc.malloc(1); }",
+	}
+
+	for _, input := range tests {
+		t.Run(fmt.Sprintf("TestRuntimeFunctionsDeclaredByExterns('%s')", input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			lex := lexer.New(filename, []byte(input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = sema.NewWithLayout(collector, NewDataLayout()).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+
+			codegen := NewCG(filename)
+			codegen.generateModule(program.Root)
+			err = llvm.VerifyModule(codegen.module, llvm.ReturnStatusAction)
+			if err != nil {
+				t.Fatalf("%s\n%s", err, codegen.module.String())
+			}
+		})
+	}
+}
+
+// Builds and runs the test blocks of every example, such as the ones on
+// "examples/signed.t". Requires clang to link the test binaries.
+func TestExamples(t *testing.T) {
+	if _, err := exec.LookPath("clang"); err != nil {
+		t.Skip("clang is not available")
+	}
+
+	paths, err := filepath.Glob("../../../examples/*.t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			collector := diagnostics.New()
+
+			parentDirName := filepath.Base(filepath.Dir(path))
+			lex, err := lexer.NewFromFilePath(parentDirName, path, collector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasTests(program) {
+				t.Skip("no test blocks")
+			}
+			err = sema.NewWithLayout(collector, NewDataLayout()).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+
+			binary := filepath.Join(t.TempDir(), "test")
+			err = NewCG(path).GenerateTests(program, binary, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var output bytes.Buffer
+			cmd := exec.Command(binary)
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("%s\n%s", err, output.String())
+			}
+		})
+	}
+}
+
+func hasTests(program *ast.Program) bool {
+	for _, file := range program.Root.Files {
+		for _, node := range file.Body {
+			if _, ok := node.(*ast.TestDecl); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package llvm
+
+import (
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+	"tinygo.org/x/go-llvm"
+)
+
+// Optional pointers are lowered to the pointer itself, using null for nil.
+// Other optionals are lowered to a pair of a flag, set when there is a value,
+// and the value.
+
+func (c *llvmCodegen) getOptionalType(optional *ast.OptionalType) llvm.Type {
+	if _, ok := optional.Type.(*ast.PointerType); ok {
+		return c.getType(optional.Type)
+	}
+	return c.context.StructType([]llvm.Type{c.context.Int1Type(), c.getType(optional.Type)}, false)
+}
+
+func (c *llvmCodegen) getNil(optional *ast.OptionalType) llvm.Value {
+	return llvm.ConstNull(c.getOptionalType(optional))
+}
+
+// Converts a value of type T to ?T
+func (c *llvmCodegen) getOptionalExpr(optional *ast.OptionalExpr, scope *ast.Scope) llvm.Value {
+	value := c.getExpr(optional.Value, scope)
+	if _, ok := optional.Type.Type.(*ast.PointerType); ok {
+		return value
+	}
+	wrapped := llvm.Undef(c.getOptionalType(optional.Type))
+	wrapped = c.builder.CreateInsertValue(wrapped, llvm.ConstInt(c.context.Int1Type(), 1, false), 0, ".some")
+	return c.builder.CreateInsertValue(wrapped, value, 1, ".some")
+}
+
+// Returns an i1 set when the optional is not nil
+func (c *llvmCodegen) getOptionalHasValue(value llvm.Value, optional *ast.OptionalType) llvm.Value {
+	if _, ok := optional.Type.(*ast.PointerType); ok {
+		return c.builder.CreateIsNotNull(value, ".hasvalue")
+	}
+	return c.builder.CreateExtractValue(value, 0, ".hasvalue")
+}
+
+// Returns the value of an optional known to not be nil
+func (c *llvmCodegen) getOptionalValue(value llvm.Value, optional *ast.OptionalType) llvm.Value {
+	if _, ok := optional.Type.(*ast.PointerType); ok {
+		return value
+	}
+	return c.builder.CreateExtractValue(value, 1, ".unwrap")
+}
+
+// Generates "opt orelse default", "opt == nil" and "opt != nil". The default
+// value is only evaluated when the optional is nil.
+func (c *llvmCodegen) getOptionalBinaryExpr(expr *ast.BinaryExpr, scope *ast.Scope) llvm.Value {
+	optionalTy := expr.OperandType.(*ast.OptionalType)
+
+	if expr.Op != token.ORELSE {
+		// Literals are never optional, except for nil itself
+		optionalExpr := expr.Left
+		if _, ok := optionalExpr.(*ast.LiteralExpr); ok {
+			optionalExpr = expr.Right
+		}
+		hasValue := c.getOptionalHasValue(c.getExpr(optionalExpr, scope), optionalTy)
+		if expr.Op == token.EQUAL_EQUAL {
+			return c.builder.CreateNot(hasValue, ".isnil")
+		}
+		return hasValue
+	}
+
+	optional := c.getExpr(expr.Left, scope)
+	hasValue := c.getOptionalHasValue(optional, optionalTy)
+
+	fn := c.builder.GetInsertBlock().Parent()
+	valueBlock := llvm.AddBasicBlock(fn, ".orelse.value")
+	defaultBlock := llvm.AddBasicBlock(fn, ".orelse.default")
+	endBlock := llvm.AddBasicBlock(fn, ".orelse.end")
+	c.builder.CreateCondBr(hasValue, valueBlock, defaultBlock)
+
+	c.builder.SetInsertPointAtEnd(valueBlock)
+	value := c.getOptionalValue(optional, optionalTy)
+	c.builder.CreateBr(endBlock)
+
+	c.builder.SetInsertPointAtEnd(defaultBlock)
+	defaultValue := c.getExpr(expr.Right, scope)
+	// The default value may have created blocks of its own
+	defaultEnd := c.builder.GetInsertBlock()
+	c.builder.CreateBr(endBlock)
+
+	c.builder.SetInsertPointAtEnd(endBlock)
+	result := c.builder.CreatePHI(c.getType(optionalTy.Type), ".orelse")
+	result.AddIncoming([]llvm.Value{value, defaultValue}, []llvm.BasicBlock{valueBlock, defaultEnd})
+	return result
+}
@@ -0,0 +1,50 @@
+package llvm
+
+import (
+	"tinygo.org/x/go-llvm"
+)
+
+// Returns the declaration of a C function used by the generated code, such as
+// "printf". If the program already declares it through an extern, the same
+// declaration is reused.
+func (c *llvmCodegen) getRuntimeFunction(name string, ty llvm.Type) *Function {
+	return NewFunctionValue(c.getCFunction(name, ty), ty, nil)
+}
+
+// Declares a C function with the given type. A function may already be
+// declared with another type, such as "malloc" taking an u32 on an extern, so
+// it is cast to the type expected by the caller.
+func (c *llvmCodegen) getCFunction(name string, ty llvm.Type) llvm.Value {
+	fn := c.module.NamedFunction(name)
+	if fn.IsNil() {
+		return llvm.AddFunction(c.module, name, ty)
+	}
+	if fn.GlobalValueType() != ty {
+		return llvm.ConstBitCast(fn, llvm.PointerType(ty, 0))
+	}
+	return fn
+}
+
+// Prints a message known at compile time to stdout
+func (c *llvmCodegen) generatePrint(message string) {
+	i8Ptr := llvm.PointerType(c.context.Int8Type(), 0)
+	printfTy := llvm.FunctionType(c.context.Int32Type(), []llvm.Type{i8Ptr}, true)
+	printf := c.getRuntimeFunction("printf", printfTy)
+
+	format := c.getStringLiteral("%s")
+	str := c.getStringLiteral(message)
+	c.builder.CreateCall(printf.Ty, printf.Fn, []llvm.Value{format, str}, "")
+}
+
+// Flushes every output stream and aborts the program
+func (c *llvmCodegen) generateAbort() {
+	i8Ptr := llvm.PointerType(c.context.Int8Type(), 0)
+	fflushTy := llvm.FunctionType(c.context.Int32Type(), []llvm.Type{i8Ptr}, false)
+	fflush := c.getRuntimeFunction("fflush", fflushTy)
+	c.builder.CreateCall(fflush.Ty, fflush.Fn, []llvm.Value{llvm.ConstPointerNull(i8Ptr)}, "")
+
+	abortTy := llvm.FunctionType(c.context.VoidType(), nil, false)
+	abort := c.getRuntimeFunction("abort", abortTy)
+	c.builder.CreateCall(abort.Ty, abort.Fn, nil, "")
+	c.builder.CreateUnreachable()
+}
@@ -0,0 +1,142 @@
+package llvm
+
+import (
+	"github.com/HicaroD/Telia/frontend/ast"
+	"tinygo.org/x/go-llvm"
+)
+
+// Strings are lowered to a pair of pointer to the first byte and length, just
+// like slices of u8. They are not NUL-terminated, so "cstr" copies them to be
+// passed to C functions.
+//
+// Strings created at runtime, by concatenation or "cstr", are allocated with
+// "malloc" and never freed.
+
+func (c *llvmCodegen) getStringType() llvm.Type {
+	i8Ptr := llvm.PointerType(c.context.Int8Type(), 0)
+	return c.context.StructType([]llvm.Type{i8Ptr, c.context.Int64Type()}, false)
+}
+
+// Returns a constant string value pointing to the global of a string literal
+func (c *llvmCodegen) getStringValue(str string) llvm.Value {
+	data := c.getStringLiteral(str)
+	length := llvm.ConstInt(c.context.Int64Type(), uint64(len(str)), false)
+	return c.context.ConstStruct([]llvm.Value{data, length}, false)
+}
+
+func (c *llvmCodegen) generateStringConcat(lhs, rhs llvm.Value) llvm.Value {
+	concat := c.getStringHelper(".string.concat", c.getStringType(), c.generateStringConcatBody)
+	return c.builder.CreateCall(concat.Ty, concat.Fn, []llvm.Value{lhs, rhs}, ".concat")
+}
+
+func (c *llvmCodegen) generateStringEq(lhs, rhs llvm.Value) llvm.Value {
+	eq := c.getStringHelper(".string.eq", c.context.Int1Type(), c.generateStringEqBody)
+	return c.builder.CreateCall(eq.Ty, eq.Fn, []llvm.Value{lhs, rhs}, ".streq")
+}
+
+// Returns a NUL-terminated copy of a string. String literals are already
+// NUL-terminated, so their global is used directly.
+func (c *llvmCodegen) generateCStr(value ast.Expr, scope *ast.Scope) llvm.Value {
+	if literal, ok := value.(*ast.LiteralExpr); ok {
+		return c.getStringLiteral(string(literal.Value))
+	}
+
+	str := c.getExpr(value, scope)
+	data := c.builder.CreateExtractValue(str, 0, ".data")
+	length := c.builder.CreateExtractValue(str, 1, ".len")
+
+	i8 := c.context.Int8Type()
+	size := c.builder.CreateAdd(length, llvm.ConstInt(c.context.Int64Type(), 1, false), ".size")
+	cstr := c.generateMalloc(size)
+	c.generateMemcpy(cstr, data, length)
+	end := c.builder.CreateInBoundsGEP(i8, cstr, []llvm.Value{length}, ".end")
+	c.builder.CreateStore(llvm.ConstInt(i8, 0, false), end)
+	return cstr
+}
+
+// Returns an internal function that takes two strings, generating it on its
+// first use
+func (c *llvmCodegen) getStringHelper(
+	name string,
+	retTy llvm.Type,
+	generateBody func(fn llvm.Value),
+) *Function {
+	strTy := c.getStringType()
+	fnTy := llvm.FunctionType(retTy, []llvm.Type{strTy, strTy}, false)
+	fn := c.module.NamedFunction(name)
+	if !fn.IsNil() {
+		return NewFunctionValue(fn, fnTy, nil)
+	}
+
+	fn = llvm.AddFunction(c.module, name, fnTy)
+	fn.SetLinkage(llvm.InternalLinkage)
+	entry := c.context.AddBasicBlock(fn, "entry")
+
+	// Helpers are generated in the middle of another function
+	current := c.builder.GetInsertBlock()
+	c.builder.SetInsertPointAtEnd(entry)
+	generateBody(fn)
+	c.builder.SetInsertPointAtEnd(current)
+
+	return NewFunctionValue(fn, fnTy, &entry)
+}
+
+func (c *llvmCodegen) generateStringConcatBody(fn llvm.Value) {
+	lhs, rhs := fn.Param(0), fn.Param(1)
+	lhsData := c.builder.CreateExtractValue(lhs, 0, ".lhsdata")
+	lhsLen := c.builder.CreateExtractValue(lhs, 1, ".lhslen")
+	rhsData := c.builder.CreateExtractValue(rhs, 0, ".rhsdata")
+	rhsLen := c.builder.CreateExtractValue(rhs, 1, ".rhslen")
+
+	length := c.builder.CreateAdd(lhsLen, rhsLen, ".len")
+	data := c.generateMalloc(length)
+	c.generateMemcpy(data, lhsData, lhsLen)
+	rest := c.builder.CreateInBoundsGEP(c.context.Int8Type(), data, []llvm.Value{lhsLen}, ".rest")
+	c.generateMemcpy(rest, rhsData, rhsLen)
+
+	str := llvm.Undef(c.getStringType())
+	str = c.builder.CreateInsertValue(str, data, 0, ".str")
+	str = c.builder.CreateInsertValue(str, length, 1, ".str")
+	c.builder.CreateRet(str)
+}
+
+// Strings are equal when they have the same length and the same bytes
+func (c *llvmCodegen) generateStringEqBody(fn llvm.Value) {
+	lhs, rhs := fn.Param(0), fn.Param(1)
+	lhsLen := c.builder.CreateExtractValue(lhs, 1, ".lhslen")
+	rhsLen := c.builder.CreateExtractValue(rhs, 1, ".rhslen")
+
+	sameLenBlock := c.context.AddBasicBlock(fn, ".samelen")
+	differentBlock := c.context.AddBasicBlock(fn, ".different")
+	sameLen := c.builder.CreateICmp(llvm.IntEQ, lhsLen, rhsLen, ".cmplen")
+	c.builder.CreateCondBr(sameLen, sameLenBlock, differentBlock)
+
+	c.builder.SetInsertPointAtEnd(differentBlock)
+	c.builder.CreateRet(llvm.ConstInt(c.context.Int1Type(), 0, false))
+
+	c.builder.SetInsertPointAtEnd(sameLenBlock)
+	i8Ptr := llvm.PointerType(c.context.Int8Type(), 0)
+	i64 := c.context.Int64Type()
+	i32 := c.context.Int32Type()
+	memcmpTy := llvm.FunctionType(i32, []llvm.Type{i8Ptr, i8Ptr, i64}, false)
+	memcmp := c.getRuntimeFunction("memcmp", memcmpTy)
+	lhsData := c.builder.CreateExtractValue(lhs, 0, ".lhsdata")
+	rhsData := c.builder.CreateExtractValue(rhs, 0, ".rhsdata")
+	cmp := c.builder.CreateCall(memcmp.Ty, memcmp.Fn, []llvm.Value{lhsData, rhsData, lhsLen}, ".memcmp")
+	eq := c.builder.CreateICmp(llvm.IntEQ, cmp, llvm.ConstInt(i32, 0, false), ".eq")
+	c.builder.CreateRet(eq)
+}
+
+func (c *llvmCodegen) generateMalloc(size llvm.Value) llvm.Value {
+	i8Ptr := llvm.PointerType(c.context.Int8Type(), 0)
+	mallocTy := llvm.FunctionType(i8Ptr, []llvm.Type{c.context.Int64Type()}, false)
+	malloc := c.getRuntimeFunction("malloc", mallocTy)
+	return c.builder.CreateCall(malloc.Ty, malloc.Fn, []llvm.Value{size}, ".malloc")
+}
+
+func (c *llvmCodegen) generateMemcpy(dst, src, size llvm.Value) {
+	i8Ptr := llvm.PointerType(c.context.Int8Type(), 0)
+	memcpyTy := llvm.FunctionType(i8Ptr, []llvm.Type{i8Ptr, i8Ptr, c.context.Int64Type()}, false)
+	memcpy := c.getRuntimeFunction("memcpy", memcpyTy)
+	c.builder.CreateCall(memcpy.Ty, memcpy.Fn, []llvm.Value{dst, src, size}, "")
+}
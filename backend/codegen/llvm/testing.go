@@ -0,0 +1,157 @@
+package llvm
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/frontend/ast"
+	"tinygo.org/x/go-llvm"
+)
+
+// Generates a test binary. Test blocks are compiled as functions and a
+// generated "main" runs every one of them, reporting pass or fail for each
+// test. If "bench" is true and every test passes, benchmarks are run after
+// the tests. The program's own "main" is not compiled.
+func (c *llvmCodegen) GenerateTests(program *ast.Program, output string, bench bool) error {
+	c.testMode = true
+	c.benchMode = bench
+	c.generateModule(program.Root)
+	c.generateTestMain()
+	err := c.generateExecutable(output)
+	return err
+}
+
+func (c *llvmCodegen) generateTestDecl(test *ast.TestDecl) {
+	testTy := llvm.FunctionType(c.context.VoidType(), nil, false)
+	testValue := llvm.AddFunction(c.module, ".test", testTy)
+	testValue.SetLinkage(llvm.InternalLinkage)
+	testBlock := c.context.AddBasicBlock(testValue, "entry")
+	testFn := NewFunctionValue(testValue, testTy, &testBlock)
+	c.builder.SetInsertPointAtEnd(testBlock)
+
+	test.BackendType = testFn
+
+	c.inTest = true
+	_ = c.generateBlock(test.Block, test.Scope, nil, testFn)
+	c.inTest = false
+
+	if !isTerminated(c.builder.GetInsertBlock()) {
+		c.builder.CreateRetVoid()
+	}
+	c.tests = append(c.tests, test)
+}
+
+// Inside a test, a failed assertion marks the test as failed and returns from
+// it. Anywhere else, it aborts the program.
+func (c *llvmCodegen) generateAssert(
+	assert *ast.AssertStmt,
+	scope *ast.Scope,
+	functionLlvm *Function,
+) {
+	cond := c.getExpr(assert.Cond, scope)
+
+	failBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".assertfail")
+	okBlock := llvm.AddBasicBlock(functionLlvm.Fn, ".assertok")
+	c.builder.CreateCondBr(cond, okBlock, failBlock)
+
+	c.builder.SetInsertPointAtEnd(failBlock)
+	pos := assert.Assert.Pos
+	message := fmt.Sprintf("%s:%d:%d: assertion failed\n", pos.Filename, pos.Line, pos.Column)
+	if c.inTest {
+		c.generatePrint("    " + message)
+		c.builder.CreateStore(llvm.ConstInt(c.context.Int1Type(), 1, false), c.getTestFailedFlag())
+		c.builder.CreateRetVoid()
+	} else {
+		c.generatePrint(message)
+		c.generateAbort()
+	}
+
+	c.builder.SetInsertPointAtEnd(okBlock)
+}
+
+func (c *llvmCodegen) generateTestMain() {
+	i1 := c.context.Int1Type()
+	i32 := c.context.Int32Type()
+
+	var now *Function
+	if len(c.benches) > 0 {
+		now = c.generateBenchNow()
+	}
+
+	mainTy := llvm.FunctionType(i32, nil, false)
+	mainValue := llvm.AddFunction(c.module, "main", mainTy)
+	entry := c.context.AddBasicBlock(mainValue, "entry")
+	c.builder.SetInsertPointAtEnd(entry)
+
+	failed := c.getTestFailedFlag()
+	failures := c.builder.CreateAlloca(i32, ".failures")
+	c.builder.CreateStore(llvm.ConstInt(i32, 0, false), failures)
+
+	for _, test := range c.tests {
+		pos := test.Name.Pos
+		name := string(test.Name.Lexeme)
+
+		c.generatePrint(fmt.Sprintf("=== RUN   %s\n", name))
+		c.builder.CreateStore(llvm.ConstInt(i1, 0, false), failed)
+		testFn := test.BackendType.(*Function)
+		c.builder.CreateCall(testFn.Ty, testFn.Fn, nil, "")
+
+		passBlock := llvm.AddBasicBlock(mainValue, ".testpass")
+		failBlock := llvm.AddBasicBlock(mainValue, ".testfail")
+		nextBlock := llvm.AddBasicBlock(mainValue, ".testnext")
+		testFailed := c.builder.CreateLoad(i1, failed, ".failed")
+		c.builder.CreateCondBr(testFailed, failBlock, passBlock)
+
+		c.builder.SetInsertPointAtEnd(passBlock)
+		c.generatePrint(fmt.Sprintf("--- PASS: %s (%s:%d)\n", name, pos.Filename, pos.Line))
+		c.builder.CreateBr(nextBlock)
+
+		c.builder.SetInsertPointAtEnd(failBlock)
+		c.generatePrint(fmt.Sprintf("--- FAIL: %s (%s:%d)\n", name, pos.Filename, pos.Line))
+		count := c.builder.CreateLoad(i32, failures, ".load")
+		count = c.builder.CreateAdd(count, llvm.ConstInt(i32, 1, false), ".inc")
+		c.builder.CreateStore(count, failures)
+		c.builder.CreateBr(nextBlock)
+
+		c.builder.SetInsertPointAtEnd(nextBlock)
+	}
+
+	passBlock := llvm.AddBasicBlock(mainValue, ".pass")
+	failBlock := llvm.AddBasicBlock(mainValue, ".fail")
+	count := c.builder.CreateLoad(i32, failures, ".load")
+	anyFailure := c.builder.CreateICmp(llvm.IntNE, count, llvm.ConstInt(i32, 0, false), ".cmpne")
+	c.builder.CreateCondBr(anyFailure, failBlock, passBlock)
+
+	c.builder.SetInsertPointAtEnd(passBlock)
+	for _, bench := range c.benches {
+		c.generateBenchRun(bench, now, mainValue)
+	}
+	c.generatePrint("PASS\n")
+	c.builder.CreateRet(llvm.ConstInt(i32, 0, false))
+
+	c.builder.SetInsertPointAtEnd(failBlock)
+	c.generatePrint("FAIL\n")
+	c.builder.CreateRet(llvm.ConstInt(i32, 1, false))
+}
+
+// Global flag set by failed assertions of the running test
+func (c *llvmCodegen) getTestFailedFlag() llvm.Value {
+	flag := c.module.NamedGlobal(".test.failed")
+	if !flag.IsNil() {
+		return flag
+	}
+	i1 := c.context.Int1Type()
+	flag = llvm.AddGlobal(c.module, i1, ".test.failed")
+	flag.SetInitializer(llvm.ConstInt(i1, 0, false))
+	flag.SetLinkage(llvm.InternalLinkage)
+	return flag
+}
+
+func isTerminated(block llvm.BasicBlock) bool {
+	last := block.LastInstruction()
+	if last.IsNil() {
+		return false
+	}
+	return !last.IsAReturnInst().IsNil() ||
+		!last.IsABranchInst().IsNil() ||
+		!last.IsAUnreachableInst().IsNil()
+}
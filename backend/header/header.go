@@ -0,0 +1,134 @@
+// Package header prints the C header of a library built by "telia build
+// --lib --emit-header".
+//
+// The header declares the prototype of every function exported with
+// "@export("name")", in the order they appear on the program. Semantic
+// analysis guarantees their signatures only use types with a C equivalent.
+package header
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Prints the header of the library with the given name, such as "math" for
+// "libmath.a"
+func Generate(program *ast.Program, name string) []byte {
+	var out bytes.Buffer
+	guard := includeGuard(name)
+
+	fmt.Fprintf(&out, "// Code generated by \"telia build --lib --emit-header\". DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "#ifndef %s\n#define %s\n\n", guard, guard)
+	out.WriteString("#include <stdbool.h>\n#include <stdint.h>\n\n")
+	out.WriteString("#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		function, ok := node.(*ast.FunctionDecl)
+		if !ok {
+			return true
+		}
+		if ast.FindAttribute(function.Attributes, "export") != nil {
+			out.WriteString(prototype(function))
+			out.WriteString("\n")
+		}
+		// Functions can't be declared inside of other functions
+		return false
+	})
+
+	out.WriteString("\n#ifdef __cplusplus\n}\n#endif\n\n")
+	fmt.Fprintf(&out, "#endif // %s\n", guard)
+	return out.Bytes()
+}
+
+func prototype(function *ast.FunctionDecl) string {
+	var params []string
+	for _, param := range function.Params.Fields {
+		params = append(params, declaration(param.Type, param.Name.Name()))
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+	return fmt.Sprintf("%s(%s);", declaration(function.RetType, function.Symbol()), strings.Join(params, ", "))
+}
+
+// Declaration of a name of the given type, such as "uint8_t *name"
+func declaration(ty ast.ExprType, name string) string {
+	cType := typeName(ty)
+	if strings.HasSuffix(cType, "*") {
+		return cType + name
+	}
+	return cType + " " + name
+}
+
+func typeName(ty ast.ExprType) string {
+	switch ty := ty.(type) {
+	case *ast.BasicType:
+		return basicTypeName(ty.Kind)
+	case *ast.PointerType:
+		elem := typeName(ty.Type)
+		if strings.HasSuffix(elem, "*") {
+			return elem + "*"
+		}
+		return elem + " *"
+	case *ast.OptionalType:
+		// Only optional pointers can be exported, which are nullable pointers
+		return typeName(ty.Type)
+	default:
+		panic(fmt.Sprintf("type %s has no C equivalent", ty))
+	}
+}
+
+func basicTypeName(kind token.Kind) string {
+	switch kind {
+	case token.VOID_TYPE:
+		return "void"
+	case token.BOOL_TYPE:
+		return "bool"
+	// int and uint have the size of a pointer
+	case token.INT_TYPE:
+		return "intptr_t"
+	case token.UINT_TYPE:
+		return "uintptr_t"
+	case token.I8_TYPE:
+		return "int8_t"
+	case token.I16_TYPE:
+		return "int16_t"
+	case token.I32_TYPE:
+		return "int32_t"
+	case token.I64_TYPE:
+		return "int64_t"
+	case token.U8_TYPE:
+		return "uint8_t"
+	case token.U16_TYPE:
+		return "uint16_t"
+	case token.U32_TYPE:
+		return "uint32_t"
+	case token.U64_TYPE:
+		return "uint64_t"
+	default:
+		panic(fmt.Sprintf("type %s has no C equivalent", kind))
+	}
+}
+
+// Turns the library name into a valid macro name, such as "MY_LIB_H" for
+// "my-lib"
+func includeGuard(name string) string {
+	guard := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if guard == "" || (guard[0] >= '0' && guard[0] <= '9') {
+		guard = "LIB_" + guard
+	}
+	return guard + "_H"
+}
@@ -0,0 +1,65 @@
+package header
+
+import (
+	"testing"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/lexer"
+	"github.com/HicaroD/Telia/frontend/parser"
+	"github.com/HicaroD/Telia/middleend/sema"
+)
+
+type headerTest struct {
+	input      string
+	prototypes string
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []headerTest{
+		{
+			input:      "fn f() {}",
+			prototypes: "",
+		},
+		{
+			input:      "@export(\"lib_f\") fn f() {}\nfn g() {}",
+			prototypes: "void lib_f(void);\n",
+		},
+		{
+			input: "@export(\"add\") fn add(a int, b uint) i64 { return 0; }\n" +
+				"@export(\"check\") fn check(ok bool, b u8, c i16) bool { return ok; }",
+			prototypes: "int64_t add(intptr_t a, uintptr_t b);\n" +
+				"bool check(bool ok, uint8_t b, int16_t c);\n",
+		},
+		{
+			input:      "@export(\"find\") fn find(s ?*u8, p **u32) ?*u8 { return s; }",
+			prototypes: "uint8_t *find(uint8_t *s, uint32_t **p);\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			collector := diagnostics.New()
+			lex := lexer.New("test.tt", []byte(test.input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = sema.New(collector).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+
+			expected := "// Code generated by \"telia build --lib --emit-header\". DO NOT EDIT.\n\n" +
+				"#ifndef MY_LIB_H\n#define MY_LIB_H\n\n" +
+				"#include <stdbool.h>\n#include <stdint.h>\n\n" +
+				"#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n" +
+				test.prototypes +
+				"\n#ifdef __cplusplus\n}\n#endif\n\n" +
+				"#endif // MY_LIB_H\n"
+			got := string(Generate(program, "my-lib"))
+			if got != expected {
+				t.Fatalf("\nexp: %q\ngot: %q\n", expected, got)
+			}
+		})
+	}
+}
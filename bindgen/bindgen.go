@@ -0,0 +1,285 @@
+// Package bindgen generates Telia bindings of C headers, used by "telia
+// bindgen".
+//
+// It parses a practical subset of C declarations: functions, global
+// variables, structs, unions, enums, typedefs and "#define" integer
+// constants. Macros are not expanded and conditional compilation is ignored,
+// so headers that depend on them should be preprocessed first, for example
+// with "clang -E -dD".
+//
+// Functions and variables are declared on an extern block named after the
+// header. Enumerators and macros become constants. Telia has no structs, so
+// structs passed through pointers are represented by tuples, which have the
+// same layout. Declarations without a Telia equivalent, such as the ones
+// using floating point types, are kept as comments explaining why they were
+// skipped.
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/format"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Generates the bindings of the header on path. If link is not empty, the
+// extern block links the library with that name.
+func Generate(path string, src []byte, link string) ([]byte, error) {
+	header, err := parseHeader(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s", path, err)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by \"telia bindgen %s\". DO NOT EDIT.\n\n", filepath.Base(path))
+	writeConsts(&out, header)
+
+	name := externName(path)
+	fmt.Fprintf(&out, "extern %s ", name)
+	if link != "" {
+		fmt.Fprintf(&out, "link %q ", link)
+	}
+	out.WriteString("{\n")
+	seen := map[string]bool{}
+	for _, decl := range header.decls {
+		if seen[decl.name] {
+			continue
+		}
+		seen[decl.name] = true
+		out.WriteString(translateDecl(decl))
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n")
+
+	// The bindings are printed in the canonical style, which also checks
+	// they are valid Telia code
+	collector := diagnostics.New()
+	formatted, err := format.Source(name+".t", out.Bytes(), collector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bindings of %s: %v", path, collector.Diags)
+	}
+	return formatted, nil
+}
+
+func writeConsts(out *bytes.Buffer, header *cHeader) {
+	consts := append(append([]cConst{}, header.consts...), header.skippedConsts...)
+	sort.SliceStable(consts, func(i, j int) bool { return consts[i].line < consts[j].line })
+	if len(consts) == 0 {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, constant := range consts {
+		if seen[constant.name] {
+			continue
+		}
+		seen[constant.name] = true
+		switch {
+		case constant.value == nil:
+			fmt.Fprintf(out, "// skipped '%s': not an integer constant\n", constant.name)
+		case isReserved(constant.name) || token.KEYWORDS[constant.name] != 0:
+			fmt.Fprintf(out, "// skipped '%s': name is a keyword\n", constant.name)
+		case !constant.value.IsInt64():
+			// Constants are int by default, which can't hold such values
+			fmt.Fprintf(out, "const %s u64 = %s;\n", constant.name, constant.value)
+		default:
+			fmt.Fprintf(out, "const %s = %s;\n", constant.name, constant.value)
+		}
+	}
+	out.WriteString("\n")
+}
+
+// Names the extern block after the header, such as "my_lib" for "my-lib.h"
+func externName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, base)
+	if name == "" || isDigit(name[0]) {
+		name = "c_" + name
+	}
+	return identifier(name)
+}
+
+// Names that are keywords on Telia, such as "in" or "test", get a trailing _
+func identifier(name string) string {
+	if _, ok := token.KEYWORDS[name]; ok {
+		return name + "_"
+	}
+	return name
+}
+
+func translateDecl(decl cDecl) string {
+	name := identifier(decl.name)
+	alias := decl.alias
+	if alias == "" && name != decl.name {
+		alias = decl.name
+	}
+	aliasSuffix := ""
+	if alias != "" {
+		aliasSuffix = fmt.Sprintf(" = %q", alias)
+	}
+
+	fn, ok := decl.ty.(cFunc)
+	if !ok {
+		ty, reason := teliaType(decl.ty, POSITION_GLOBAL, nil)
+		if reason != "" {
+			return fmt.Sprintf("// skipped '%s': %s", decl.name, reason)
+		}
+		return fmt.Sprintf("%s %s%s;", name, ty, aliasSuffix)
+	}
+
+	var params []string
+	for i, param := range fn.params {
+		ty, reason := teliaType(param.ty, POSITION_PARAM, nil)
+		if reason != "" {
+			return fmt.Sprintf("// skipped '%s': %s", decl.name, reason)
+		}
+		paramName := identifier(param.name)
+		if param.name == "" {
+			paramName = fmt.Sprintf("arg%d", i)
+		}
+		params = append(params, paramName+" "+ty)
+	}
+	if fn.variadic {
+		params = append(params, "...")
+	}
+	ret, reason := teliaType(fn.ret, POSITION_RETURN, nil)
+	if reason != "" {
+		return fmt.Sprintf("// skipped '%s': %s", decl.name, reason)
+	}
+	if ret == "void" {
+		ret = ""
+	} else {
+		ret = " " + ret
+	}
+	return fmt.Sprintf("fn %s(%s)%s%s;", name, strings.Join(params, ", "), ret, aliasSuffix)
+}
+
+type position int
+
+const (
+	POSITION_PARAM position = iota
+	POSITION_RETURN
+	POSITION_FIELD
+	POSITION_GLOBAL
+)
+
+// Returns the Telia type of a C type, or the reason why there is none.
+// Structs being translated are skipped, so self-referential ones, such as
+// linked lists, end up as opaque pointers.
+func teliaType(ty cType, pos position, translating map[*cStruct]bool) (string, string) {
+	switch ty := ty.(type) {
+	case cBasic:
+		switch {
+		case ty.telia == "":
+			return "", fmt.Sprintf("type %s is not supported", ty.name)
+		case ty.telia == "void" && pos != POSITION_RETURN:
+			return "", "void variables are not supported"
+		}
+		return ty.telia, ""
+	case cEnum:
+		return "i32", ""
+	case cPointer:
+		return "*" + pointeeType(ty.elem, translating), ""
+	case cArray:
+		// Arrays are passed as pointers to their first element
+		if pos == POSITION_PARAM {
+			return "*" + pointeeType(ty.elem, translating), ""
+		}
+		if ty.len < 0 {
+			return "", "arrays without length are not supported"
+		}
+		elem, reason := teliaType(ty.elem, POSITION_FIELD, translating)
+		if reason != "" {
+			return "", reason
+		}
+		return fmt.Sprintf("[%d]%s", ty.len, elem), ""
+	case cFunc:
+		// Functions are passed as pointers
+		if pos == POSITION_PARAM {
+			return "*u8", ""
+		}
+		return "", "function types are not supported"
+	case *cStruct:
+		if pos != POSITION_FIELD {
+			return "", fmt.Sprintf("%s is passed by value", structName(ty))
+		}
+		return tupleType(ty, translating)
+	case cOpaque:
+		return "", fmt.Sprintf("type %s is only supported through pointers", ty.name)
+	case cUnknown:
+		return "", fmt.Sprintf("type %s is not declared on the header", ty.name)
+	case cVaList:
+		return "", "type va_list is not supported"
+	}
+	return "", "unsupported type"
+}
+
+// Pointers to types without a Telia equivalent, such as "FILE *" or "void *",
+// are opaque pointers to bytes
+func pointeeType(elem cType, translating map[*cStruct]bool) string {
+	if basic, ok := elem.(cBasic); ok && basic.telia == "void" {
+		return "u8"
+	}
+	ty, reason := teliaType(elem, POSITION_FIELD, translating)
+	if reason != "" {
+		return "u8"
+	}
+	return ty
+}
+
+// Structs are tuples with the types of their fields, which have the same
+// layout
+func tupleType(st *cStruct, translating map[*cStruct]bool) (string, string) {
+	switch {
+	case translating[st]:
+		return "", fmt.Sprintf("%s refers to itself", structName(st))
+	case !st.complete:
+		return "", fmt.Sprintf("%s is not declared on the header", structName(st))
+	case st.union:
+		return "", fmt.Sprintf("%s has no equivalent", structName(st))
+	case st.bitFields:
+		return "", fmt.Sprintf("%s has bit fields", structName(st))
+	case len(st.fields) == 0:
+		return "", fmt.Sprintf("%s is empty", structName(st))
+	}
+
+	if translating == nil {
+		translating = map[*cStruct]bool{}
+	}
+	translating[st] = true
+	defer delete(translating, st)
+
+	var fields []string
+	for _, field := range st.fields {
+		ty, reason := teliaType(field.ty, POSITION_FIELD, translating)
+		if reason != "" {
+			return "", reason
+		}
+		fields = append(fields, ty)
+	}
+	if len(fields) == 1 {
+		return fields[0], ""
+	}
+	return "(" + strings.Join(fields, ", ") + ")", ""
+}
+
+func structName(st *cStruct) string {
+	kind := "struct"
+	if st.union {
+		kind = "union"
+	}
+	if st.tag == "" {
+		return "anonymous " + kind
+	}
+	return kind + " " + st.tag
+}
@@ -0,0 +1,147 @@
+package bindgen
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/lexer"
+	"github.com/HicaroD/Telia/frontend/parser"
+	"github.com/HicaroD/Telia/middleend/sema"
+)
+
+type bindgenTest struct {
+	header   string
+	expected string
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []bindgenTest{
+		{
+			header:   "int puts(const char *s);\nint printf(const char *restrict format, ...);",
+			expected: "extern api {\n  fn puts(s *u8) i32;\n  fn printf(format *u8, ...) i32;\n}\n",
+		},
+		{
+			header:   "void *memcpy(void *dest, const void *src, size_t n);\nvoid abort(void) __attribute__((noreturn));",
+			expected: "extern api {\n  fn memcpy(dest *u8, src *u8, n uint) *u8;\n  fn abort();\n}\n",
+		},
+		{
+			header:   "unsigned long long a(signed char b, unsigned short c, long d, _Bool e, unsigned f);",
+			expected: "extern api {\n  fn a(b i8, c u16, d i64, e bool, f u32) u64;\n}\n",
+		},
+		{
+			header:   "int test(int, int in);\nint renamed(void) __asm__(\"real_name\");",
+			expected: "extern api {\n  fn test_(arg0 i32, in_ i32) i32 = \"test\";\n  fn renamed() i32 = \"real_name\";\n}\n",
+		},
+		{
+			header:   "extern int count;\nextern FILE *log_file;\nextern const char *names[4];",
+			expected: "extern api {\n  count i32;\n  log_file *u8;\n  names [4]*u8;\n}\n",
+		},
+		{
+			header:   "typedef unsigned int uint;\ntypedef uint flags_t;\nint set(flags_t flags);",
+			expected: "extern api {\n  fn set(flags u32) i32;\n}\n",
+		},
+		{
+			header: "typedef struct point { int x; int y; } point_t;\npoint_t *make_point(int x, int y);\nint distance(point_t a, point_t b);",
+			expected: "extern api {\n  fn make_point(x i32, y i32) *(i32, i32);\n" +
+				"  // skipped 'distance': struct point is passed by value\n}\n",
+		},
+		{
+			header:   "struct node { int value; struct node *next; char name[8]; };\nint count(struct node *list, int values[]);",
+			expected: "extern api {\n  fn count(list *(i32, *u8, [8]u8), values *i32) i32;\n}\n",
+		},
+		{
+			header:   "union number { int i; long l; };\ntypedef struct opaque opaque_t;\nint f(union number *n, opaque_t *o, int (*compare)(int, int));",
+			expected: "extern api {\n  fn f(n *u8, o *u8, compare *u8) i32;\n}\n",
+		},
+		{
+			header: "void (*signal(int sig, void (*func)(int)))(int);\ndouble sqrt(double x);\nint chmod(const char *path, mode_t mode);",
+			expected: "extern api {\n  fn signal(sig i32, func *u8) *u8;\n" +
+				"  // skipped 'sqrt': type double is not supported\n" +
+				"  // skipped 'chmod': type mode_t is not declared on the header\n}\n",
+		},
+		{
+			header: "#ifndef TEST_H\n#define TEST_H\n#define SIZE 8192\n#define FLAG (1 << 4)\n#define FLAGS (FLAG | 0x1UL)\n" +
+				"#define PI 3.14\n#define MAX(a, b) ((a) > (b) ? (a) : (b))\n#define ALL 0xFFFFFFFFFFFFFFFFULL\n" +
+				"enum color { RED, GREEN = SIZE / 2, BLUE };\n#define LAST_COLOR BLUE\nint paint(enum color color);\n#endif",
+			expected: "const SIZE = 8192;\nconst FLAG = 16;\nconst FLAGS = 17;\n" +
+				"// skipped 'PI': not an integer constant\nconst ALL u64 = 18446744073709551615;\n" +
+				"const RED = 0;\nconst GREEN = 4096;\nconst BLUE = 4097;\nconst LAST_COLOR = 4097;\n\n" +
+				"extern api {\n  fn paint(color i32) i32;\n}\n",
+		},
+		{
+			header: "#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n" +
+				"/* multiline\n comment */\nint f(void); // trailing\n" +
+				"static inline int helper(int x) { return x + 1; }\nint f(void);\n\n" +
+				"#ifdef __cplusplus\n}\n#endif\n",
+			expected: "extern api {\n  fn f() i32;\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestGenerate('%s')", test.header), func(t *testing.T) {
+			out, err := Generate("include/api.h", []byte(test.header), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := "// Code generated by \"telia bindgen api.h\". DO NOT EDIT.\n\n" + test.expected
+			if string(out) != expected {
+				t.Fatalf("\nexp: %s\ngot: %s\n", expected, out)
+			}
+		})
+	}
+}
+
+func TestGenerateLink(t *testing.T) {
+	out, err := Generate("my-lib.h", []byte("int f(void);"), "mylib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "// Code generated by \"telia bindgen my-lib.h\". DO NOT EDIT.\n\n" +
+		"extern my_lib link \"mylib\" {\n  fn f() i32;\n}\n"
+	if string(out) != expected {
+		t.Fatalf("\nexp: %s\ngot: %s\n", expected, out)
+	}
+}
+
+// The bindings must be accepted by semantic analysis as they are
+func TestGeneratedBindingsAreValid(t *testing.T) {
+	header := "#define SIZE 16\nstruct node { int value; struct node *next; };\n" +
+		"extern FILE *log_file;\nint fprintf(FILE *stream, const char *format, ...);\n" +
+		"struct node *find(struct node *list, int value, unsigned char key[SIZE]);\n"
+	out, err := Generate("test.h", []byte(header), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := diagnostics.New()
+	lex := lexer.New("test.t", out, collector)
+	program, err := parser.New(collector).ParseFileAsProgram(lex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sema.New(collector).Check(program)
+	if err != nil {
+		t.Fatalf("unexpected error: %s %s\n%s", err, collector.Diags, out)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		header string
+		err    string
+	}{
+		{header: "int f(int a;", err: "test.h:1: expected ), not ;"},
+		{header: "struct point { int x;", err: "test.h:1: expected }, not end of file"},
+		{header: "/* unterminated", err: "test.h:1: unterminated comment"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestSyntaxErrors('%s')", test.header), func(t *testing.T) {
+			_, err := Generate("test.h", []byte(test.header), "")
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("\nexp: %s\ngot: %v\n", test.err, err)
+			}
+		})
+	}
+}
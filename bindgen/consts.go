@@ -0,0 +1,185 @@
+package bindgen
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Evaluates object-like macros that expand to integer constants, such as
+// "#define BUFSIZ 8192" or "#define FLAG (1 << 4)". Other macros, such as
+// include guards or strings, are skipped.
+//
+// Defines are evaluated before the declarations, so enumerators can use them,
+// and the ones that couldn't be evaluated are returned. Those may refer to
+// enumerators, so they're evaluated again with final set after the
+// declarations are parsed.
+func (p *cParser) evalDefines(defines []cDefine, final bool) []cDefine {
+	var pending []cDefine
+	for _, define := range defines {
+		if len(define.value) == 0 || strings.HasPrefix(define.name, "_") {
+			continue
+		}
+		value, ok := p.evalTokens(define.value)
+		if !ok {
+			if final {
+				p.header.skippedConsts = append(p.header.skippedConsts, cConst{name: define.name, line: define.line})
+			} else {
+				pending = append(pending, define)
+			}
+			continue
+		}
+		p.values[define.name] = value
+		p.header.consts = append(p.header.consts, cConst{name: define.name, value: value, line: define.line})
+	}
+	return pending
+}
+
+// Evaluates an integer constant expression, such as "(1 << 4) | FLAG". Only
+// constants declared before it may be used.
+func (p *cParser) evalTokens(tokens []cToken) (*big.Int, bool) {
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	eval := &evaluator{tokens: tokens, values: p.values}
+	value, ok := eval.binary(0)
+	if !ok || eval.pos != len(tokens) {
+		return nil, false
+	}
+	return value, true
+}
+
+type evaluator struct {
+	tokens []cToken
+	pos    int
+	values map[string]*big.Int
+}
+
+// Precedence of binary operators, from the lowest to the highest
+var binaryPrecedence = map[string]int{
+	"|":  1,
+	"^":  2,
+	"&":  3,
+	"<<": 4, ">>": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+func (e *evaluator) peek() (cToken, bool) {
+	if e.pos >= len(e.tokens) {
+		return cToken{}, false
+	}
+	return e.tokens[e.pos], true
+}
+
+func (e *evaluator) binary(minPrecedence int) (*big.Int, bool) {
+	left, ok := e.unary()
+	if !ok {
+		return nil, false
+	}
+	for {
+		tok, ok := e.peek()
+		if !ok || tok.kind != C_PUNCT {
+			return left, true
+		}
+		precedence, isBinary := binaryPrecedence[tok.text]
+		if !isBinary || precedence <= minPrecedence {
+			return left, true
+		}
+		e.pos++
+		right, ok := e.binary(precedence)
+		if !ok {
+			return nil, false
+		}
+		result := new(big.Int)
+		switch tok.text {
+		case "|":
+			result.Or(left, right)
+		case "^":
+			result.Xor(left, right)
+		case "&":
+			result.And(left, right)
+		case "<<", ">>":
+			if !right.IsUint64() || right.Uint64() > 64 {
+				return nil, false
+			}
+			if tok.text == "<<" {
+				result.Lsh(left, uint(right.Uint64()))
+			} else {
+				result.Rsh(left, uint(right.Uint64()))
+			}
+		case "+":
+			result.Add(left, right)
+		case "-":
+			result.Sub(left, right)
+		case "*":
+			result.Mul(left, right)
+		case "/", "%":
+			if right.Sign() == 0 {
+				return nil, false
+			}
+			// C division truncates towards zero
+			if tok.text == "/" {
+				result.Quo(left, right)
+			} else {
+				result.Rem(left, right)
+			}
+		}
+		left = result
+	}
+}
+
+func (e *evaluator) unary() (*big.Int, bool) {
+	tok, ok := e.peek()
+	if !ok {
+		return nil, false
+	}
+	e.pos++
+	switch {
+	case tok.kind == C_NUMBER:
+		return parseInteger(tok.text)
+	case tok.kind == C_CHAR && len(tok.text) == 1:
+		return big.NewInt(int64(tok.text[0])), true
+	case tok.kind == C_IDENT:
+		value, ok := e.values[tok.text]
+		return value, ok
+	case tok.text == "(":
+		value, ok := e.binary(0)
+		if next, more := e.peek(); !ok || !more || next.text != ")" {
+			return nil, false
+		}
+		e.pos++
+		return value, true
+	case tok.text == "-" || tok.text == "+" || tok.text == "~":
+		value, ok := e.unary()
+		if !ok {
+			return nil, false
+		}
+		switch tok.text {
+		case "-":
+			return new(big.Int).Neg(value), true
+		case "~":
+			return new(big.Int).Not(value), true
+		}
+		return value, true
+	default:
+		return nil, false
+	}
+}
+
+// Parses integer literals such as "42", "0x2A", "052" and "42UL"
+func parseInteger(literal string) (*big.Int, bool) {
+	literal = strings.TrimRight(strings.ToLower(literal), "ul")
+	base := 10
+	switch {
+	case strings.HasPrefix(literal, "0x"):
+		base = 16
+		literal = literal[2:]
+	case strings.HasPrefix(literal, "0b"):
+		base = 2
+		literal = literal[2:]
+	case len(literal) > 1 && literal[0] == '0':
+		base = 8
+		literal = literal[1:]
+	}
+	return new(big.Int).SetString(literal, base)
+}
@@ -0,0 +1,212 @@
+package bindgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+type cTokenKind int
+
+const (
+	C_EOF cTokenKind = iota
+	C_IDENT
+	C_NUMBER
+	C_STRING
+	C_CHAR
+	C_PUNCT
+)
+
+type cToken struct {
+	kind cTokenKind
+	text string
+	line int
+}
+
+func (tok cToken) String() string {
+	if tok.kind == C_EOF {
+		return "end of file"
+	}
+	return tok.text
+}
+
+// Object-like macro, such as "#define BUFSIZ 8192"
+type cDefine struct {
+	name  string
+	value []cToken
+	line  int
+}
+
+// Splits a C header into tokens. Preprocessor directives are not expanded:
+// object-like macros are collected, so integer constants can be translated,
+// and every other directive is ignored.
+func tokenize(src string) ([]cToken, []cDefine, error) {
+	var tokens []cToken
+	var defines []cDefine
+
+	line := 1
+	lineStart := true
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			lineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+			continue
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			line++
+			i += 2
+			continue
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, nil, fmt.Errorf("%d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+			continue
+		case c == '#' && lineStart:
+			directive, next, lines := readDirective(src, i)
+			define, err := parseDefine(directive, line)
+			if err != nil {
+				return nil, nil, err
+			}
+			if define != nil {
+				defines = append(defines, *define)
+			}
+			line += lines
+			i = next
+			continue
+		}
+
+		lineStart = false
+		tok, next, err := readToken(src, i, line)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokens = append(tokens, tok)
+		i = next
+	}
+	tokens = append(tokens, cToken{kind: C_EOF, line: line})
+	return tokens, defines, nil
+}
+
+// Reads a directive until the end of its line, joining continued lines and
+// dropping comments. Returns the directive without "#", the position after it
+// and the number of lines joined.
+func readDirective(src string, start int) (string, int, int) {
+	var directive strings.Builder
+	lines := 0
+	i := start + 1
+	for i < len(src) && src[i] != '\n' {
+		switch {
+		case src[i] == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			lines++
+			i += 2
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return directive.String(), len(src), lines
+			}
+			lines += strings.Count(src[i:i+2+end], "\n")
+			directive.WriteByte(' ')
+			i += end + 4
+		default:
+			directive.WriteByte(src[i])
+			i++
+		}
+	}
+	return directive.String(), i, lines
+}
+
+func parseDefine(directive string, line int) (*cDefine, error) {
+	directive = strings.TrimSpace(directive)
+	if !strings.HasPrefix(directive, "define") {
+		return nil, nil
+	}
+	tokens, _, err := tokenize(directive[len("define"):])
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind != C_IDENT {
+		return nil, nil
+	}
+	name := tokens[0]
+	body := directive[len("define"):]
+	// Function-like macros have "(" right after the name
+	if i := strings.Index(body, name.text) + len(name.text); i < len(body) && body[i] == '(' {
+		return nil, nil
+	}
+	return &cDefine{name: name.text, value: tokens[1 : len(tokens)-1], line: line}, nil
+}
+
+var punctuators = []string{
+	"...", "<<=", ">>=",
+	"->", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"*=", "/=", "%=", "+=", "-=", "&=", "^=", "|=", "##",
+}
+
+func readToken(src string, i int, line int) (cToken, int, error) {
+	c := src[i]
+	switch {
+	case isIdentStart(c):
+		start := i
+		for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+			i++
+		}
+		return cToken{kind: C_IDENT, text: src[start:i], line: line}, i, nil
+	case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+		start := i
+		for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i]) || src[i] == '.') {
+			i++
+		}
+		return cToken{kind: C_NUMBER, text: src[start:i], line: line}, i, nil
+	case c == '"' || c == '\'':
+		start := i
+		i++
+		for i < len(src) && src[i] != c {
+			if src[i] == '\\' {
+				i++
+			}
+			if i < len(src) && src[i] == '\n' {
+				return cToken{}, 0, fmt.Errorf("%d: unterminated literal", line)
+			}
+			i++
+		}
+		if i >= len(src) {
+			return cToken{}, 0, fmt.Errorf("%d: unterminated literal", line)
+		}
+		kind := C_STRING
+		if c == '\'' {
+			kind = C_CHAR
+		}
+		// The quotes are not part of the text
+		return cToken{kind: kind, text: src[start+1 : i], line: line}, i + 1, nil
+	}
+	for _, punct := range punctuators {
+		if strings.HasPrefix(src[i:], punct) {
+			return cToken{kind: C_PUNCT, text: punct, line: line}, i + len(punct), nil
+		}
+	}
+	return cToken{kind: C_PUNCT, text: string(c), line: line}, i + 1, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,693 @@
+package bindgen
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Types of C declarations. They are translated into Telia types only after the
+// whole header is parsed, so structs declared later are still known.
+type cType interface{ cType() }
+
+// Types with a direct Telia equivalent, such as "int" or "uint8_t", and the
+// floating point ones, which have none
+type cBasic struct {
+	telia string // such as "i32", empty for unsupported types
+	name  string // C name of unsupported types, such as "double"
+}
+
+type cPointer struct{ elem cType }
+
+type cArray struct {
+	elem cType
+	len  int // -1 if unknown, such as on "int values[]"
+}
+
+type cFunc struct {
+	ret      cType
+	params   []cParam
+	variadic bool
+}
+
+type cParam struct {
+	name string
+	ty   cType
+}
+
+// Struct or union, incomplete until its fields are declared
+type cStruct struct {
+	tag       string
+	union     bool
+	bitFields bool
+	complete  bool
+	fields    []cParam
+}
+
+type cEnum struct{}
+
+func (cBasic) cType()   {}
+func (cPointer) cType() {}
+func (cArray) cType()   {}
+func (cFunc) cType()    {}
+func (*cStruct) cType() {}
+func (cEnum) cType()    {}
+func (cOpaque) cType()  {}
+func (cUnknown) cType() {}
+func (cVaList) cType()  {}
+
+// Types only used through pointers, such as "FILE"
+type cOpaque struct{ name string }
+
+// Types not declared on the header, such as a typedef from another header
+type cUnknown struct{ name string }
+
+type cVaList struct{}
+
+// Declaration of a function or a global variable
+type cDecl struct {
+	name  string
+	ty    cType
+	alias string // symbol given by '__asm__("name")', if any
+	line  int
+}
+
+// Integer constant, such as an enumerator or an object-like macro
+type cConst struct {
+	name  string
+	value *big.Int
+	line  int
+}
+
+type cHeader struct {
+	decls  []cDecl
+	consts []cConst
+	// Constants that couldn't be evaluated, such as "#define PI 3.14"
+	skippedConsts []cConst
+}
+
+type cParser struct {
+	tokens []cToken
+	pos    int
+
+	typedefs map[string]cType
+	structs  map[string]*cStruct
+	values   map[string]*big.Int // values of constants already declared
+	header   *cHeader
+}
+
+// Typedefs of the standard library, which are usually declared on other
+// headers
+var standardTypedefs = map[string]cType{
+	"size_t":    cBasic{telia: "uint"},
+	"ssize_t":   cBasic{telia: "int"},
+	"ptrdiff_t": cBasic{telia: "int"},
+	"intptr_t":  cBasic{telia: "int"},
+	"uintptr_t": cBasic{telia: "uint"},
+	"int8_t":    cBasic{telia: "i8"},
+	"int16_t":   cBasic{telia: "i16"},
+	"int32_t":   cBasic{telia: "i32"},
+	"int64_t":   cBasic{telia: "i64"},
+	"uint8_t":   cBasic{telia: "u8"},
+	"uint16_t":  cBasic{telia: "u16"},
+	"uint32_t":  cBasic{telia: "u32"},
+	"uint64_t":  cBasic{telia: "u64"},
+	"bool":      cBasic{telia: "bool"},
+	"wchar_t":   cBasic{telia: "i32"},
+	"off_t":     cBasic{telia: "i64"},
+	"time_t":    cBasic{telia: "i64"},
+	"pid_t":     cBasic{telia: "i32"},
+	"FILE":      cOpaque{name: "FILE"},
+	"va_list":   cVaList{},
+}
+
+// Words that change nothing about the translated type
+var ignoredWords = map[string]bool{
+	"const": true, "volatile": true, "restrict": true, "register": true,
+	"__const": true, "__restrict": true, "__restrict__": true, "__volatile__": true,
+	"__extension__": true, "_Noreturn": true, "__inline": true, "__inline__": true,
+	"inline": true, "extern": true, "auto": true, "_Nonnull": true, "_Nullable": true,
+	"__signed__": true,
+}
+
+func parseHeader(src string) (*cHeader, error) {
+	tokens, defines, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &cParser{
+		tokens:   tokens,
+		typedefs: map[string]cType{},
+		structs:  map[string]*cStruct{},
+		values:   map[string]*big.Int{},
+		header:   &cHeader{},
+	}
+	pending := p.evalDefines(defines, false)
+	err = p.parseTopLevel()
+	if err != nil {
+		return nil, err
+	}
+	p.evalDefines(pending, true)
+	return p.header, nil
+}
+
+func (p *cParser) peek() cToken { return p.tokens[p.pos] }
+
+func (p *cParser) peekAt(n int) cToken {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *cParser) next() cToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != C_EOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *cParser) is(text string) bool {
+	tok := p.peek()
+	return (tok.kind == C_PUNCT || tok.kind == C_IDENT) && tok.text == text
+}
+
+func (p *cParser) accept(text string) bool {
+	if p.is(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *cParser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		return fmt.Errorf("%d: expected %s, not %s", tok.line, text, tok)
+	}
+	return nil
+}
+
+// Skips a group, such as "(...)", including nested ones. The next token must
+// be the opening one.
+func (p *cParser) skipBalanced() error {
+	open := p.next()
+	closing := map[string]string{"(": ")", "[": "]", "{": "}"}[open.text]
+	depth := 1
+	for depth > 0 {
+		tok := p.next()
+		switch {
+		case tok.kind == C_EOF:
+			return fmt.Errorf("%d: expected %s, not end of file", open.line, closing)
+		case tok.kind != C_PUNCT:
+		case tok.text == open.text:
+			depth++
+		case tok.text == closing:
+			depth--
+		}
+	}
+	return nil
+}
+
+// Skips GNU extensions, such as '__attribute__((nonnull))'
+func (p *cParser) skipAttributes() error {
+	for p.is("__attribute__") || p.is("__attribute") || p.is("__declspec") {
+		p.next()
+		if p.is("(") {
+			err := p.skipBalanced()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *cParser) parseTopLevel() error {
+	// Depth of 'extern "C" {' blocks, whose } is not part of any declaration
+	externC := 0
+	for p.peek().kind != C_EOF {
+		switch {
+		case p.accept(";"):
+		case p.is("extern") && p.peekAt(1).kind == C_STRING:
+			p.next() // extern
+			p.next() // "C"
+			if p.accept("{") {
+				externC++
+			}
+		case externC > 0 && p.accept("}"):
+			externC--
+		default:
+			err := p.parseDeclaration()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type specifiers struct {
+	ty        cType
+	isTypedef bool
+	isStatic  bool
+}
+
+// Parses a declaration, such as "int puts(const char *s);" or "typedef struct
+// point point_t;". Function definitions of the header, which are always
+// static or inline, are skipped.
+func (p *cParser) parseDeclaration() error {
+	line := p.peek().line
+	specs, err := p.parseSpecifiers()
+	if err != nil {
+		return err
+	}
+
+	for !p.accept(";") {
+		name, ty, err := p.parseDeclarator(specs.ty)
+		if err != nil {
+			return err
+		}
+		alias, err := p.parseDeclaratorSuffix()
+		if err != nil {
+			return err
+		}
+
+		if p.is("{") {
+			// Function definition
+			return p.skipBalanced()
+		}
+		if p.accept("=") {
+			// Initializers are only found on definitions, which are skipped
+			for !p.is(",") && !p.is(";") && p.peek().kind != C_EOF {
+				if p.is("(") || p.is("{") {
+					err := p.skipBalanced()
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				p.next()
+			}
+		}
+
+		switch {
+		case name == "":
+		case specs.isTypedef:
+			p.typedefs[name] = ty
+		case !specs.isStatic:
+			p.header.decls = append(p.header.decls, cDecl{name: name, ty: ty, alias: alias, line: line})
+		}
+
+		if !p.accept(",") {
+			return p.expect(";")
+		}
+	}
+	return nil
+}
+
+// Parses what may follow a declarator: attributes, the symbol name given by
+// '__asm__("name")' and macros that are not expanded, such as "__THROW"
+func (p *cParser) parseDeclaratorSuffix() (string, error) {
+	alias := ""
+	for {
+		err := p.skipAttributes()
+		if err != nil {
+			return "", err
+		}
+		switch tok := p.peek(); {
+		case p.is("__asm__") || p.is("__asm") || p.is("asm"):
+			p.next()
+			if err := p.expect("("); err != nil {
+				return "", err
+			}
+			for p.peek().kind == C_STRING {
+				alias += p.next().text
+			}
+			if err := p.expect(")"); err != nil {
+				return "", err
+			}
+		case tok.kind == C_IDENT:
+			p.next()
+			if p.is("(") {
+				err := p.skipBalanced()
+				if err != nil {
+					return "", err
+				}
+			}
+		default:
+			return alias, nil
+		}
+	}
+}
+
+func (p *cParser) parseSpecifiers() (specifiers, error) {
+	var specs specifiers
+	var words []string
+	for {
+		err := p.skipAttributes()
+		if err != nil {
+			return specs, err
+		}
+		tok := p.peek()
+		if tok.kind != C_IDENT {
+			break
+		}
+		switch {
+		case tok.text == "typedef":
+			specs.isTypedef = true
+		case tok.text == "static":
+			specs.isStatic = true
+		case ignoredWords[tok.text]:
+		case tok.text == "struct" || tok.text == "union":
+			if specs.ty != nil || len(words) > 0 {
+				return specs, fmt.Errorf("%d: unexpected %s", tok.line, tok)
+			}
+			p.next()
+			specs.ty, err = p.parseStruct(tok.text == "union")
+			if err != nil {
+				return specs, err
+			}
+			continue
+		case tok.text == "enum":
+			p.next()
+			specs.ty, err = p.parseEnum()
+			if err != nil {
+				return specs, err
+			}
+			continue
+		case isBasicWord(tok.text):
+			words = append(words, tok.text)
+		default:
+			// A name is only a type if no type was given yet, otherwise it is
+			// the name being declared
+			if specs.ty != nil || len(words) > 0 {
+				return p.finishSpecifiers(specs, words)
+			}
+			if ty, ok := p.typedefs[tok.text]; ok {
+				specs.ty = ty
+			} else if ty, ok := standardTypedefs[tok.text]; ok {
+				specs.ty = ty
+			} else if p.isUnknownTypeName() {
+				specs.ty = cUnknown{name: tok.text}
+			} else {
+				return p.finishSpecifiers(specs, words)
+			}
+		}
+		p.next()
+	}
+	return p.finishSpecifiers(specs, words)
+}
+
+// Reports whether the next name is a type declared on another header, such as
+// "mode_t" on "int chmod(const char *path, mode_t mode);". It is a type if a
+// declarator follows it.
+func (p *cParser) isUnknownTypeName() bool {
+	next := p.peekAt(1)
+	return next.kind == C_IDENT || (next.kind == C_PUNCT && (next.text == "*" || next.text == ")" || next.text == ","))
+}
+
+func (p *cParser) finishSpecifiers(specs specifiers, words []string) (specifiers, error) {
+	if len(words) > 0 {
+		ty, err := basicType(words)
+		if err != nil {
+			return specs, fmt.Errorf("%d: %s", p.peek().line, err)
+		}
+		specs.ty = ty
+	}
+	if specs.ty == nil {
+		// Implicit int, as on "unsigned" alone or old declarations
+		specs.ty = cBasic{telia: "i32"}
+	}
+	return specs, nil
+}
+
+func isBasicWord(word string) bool {
+	switch word {
+	case "void", "char", "short", "int", "long", "signed", "unsigned", "_Bool",
+		"float", "double", "__int128", "_Complex":
+		return true
+	}
+	return false
+}
+
+// Translates combinations such as "unsigned long long" for LP64 targets, where
+// long is 64 bits
+func basicType(words []string) (cType, error) {
+	count := map[string]int{}
+	for _, word := range words {
+		count[word]++
+	}
+	unsigned := count["unsigned"] > 0
+	signed := count["signed"] > 0
+	switch {
+	case count["void"] > 0:
+		return cBasic{telia: "void"}, nil
+	case count["_Bool"] > 0:
+		return cBasic{telia: "bool"}, nil
+	case count["float"] > 0 || count["double"] > 0 || count["_Complex"] > 0 || count["__int128"] > 0:
+		name := ""
+		for i, word := range words {
+			if i > 0 {
+				name += " "
+			}
+			name += word
+		}
+		return cBasic{name: name}, nil
+	case count["char"] > 0:
+		// Plain char is a byte, just like the string literals of Telia
+		if signed {
+			return cBasic{telia: "i8"}, nil
+		}
+		return cBasic{telia: "u8"}, nil
+	case count["short"] > 0:
+		return integer(16, unsigned), nil
+	case count["long"] > 0:
+		return integer(64, unsigned), nil
+	default:
+		return integer(32, unsigned), nil
+	}
+}
+
+func integer(bits int, unsigned bool) cType {
+	if unsigned {
+		return cBasic{telia: fmt.Sprintf("u%d", bits)}
+	}
+	return cBasic{telia: fmt.Sprintf("i%d", bits)}
+}
+
+func (p *cParser) parseStruct(union bool) (cType, error) {
+	err := p.skipAttributes()
+	if err != nil {
+		return nil, err
+	}
+	tag := ""
+	if p.peek().kind == C_IDENT {
+		tag = p.next().text
+	}
+
+	// "struct point" refers to the same struct before and after its fields
+	// are declared
+	var st *cStruct
+	if tag != "" {
+		st = p.structs[tag]
+	}
+	if st == nil {
+		st = &cStruct{tag: tag, union: union}
+		if tag != "" {
+			p.structs[tag] = st
+		}
+	}
+	if !p.accept("{") {
+		return st, nil
+	}
+
+	var fields []cParam
+	for !p.accept("}") {
+		if p.peek().kind == C_EOF {
+			return nil, fmt.Errorf("%d: expected }, not end of file", p.peek().line)
+		}
+		specs, err := p.parseSpecifiers()
+		if err != nil {
+			return nil, err
+		}
+		for !p.accept(";") {
+			name, ty, err := p.parseDeclarator(specs.ty)
+			if err != nil {
+				return nil, err
+			}
+			if p.accept(":") {
+				// Bit fields have no equivalent, so the struct is opaque
+				st.bitFields = true
+				p.next()
+			}
+			if err := p.skipAttributes(); err != nil {
+				return nil, err
+			}
+			fields = append(fields, cParam{name: name, ty: ty})
+			if !p.accept(",") {
+				if err := p.expect(";"); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+	st.fields = fields
+	st.complete = true
+	return st, p.skipAttributes()
+}
+
+func (p *cParser) parseEnum() (cType, error) {
+	err := p.skipAttributes()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == C_IDENT {
+		p.next() // tag
+	}
+	if !p.accept("{") {
+		return cEnum{}, nil
+	}
+
+	value := big.NewInt(-1)
+	for !p.accept("}") {
+		name := p.next()
+		if name.kind != C_IDENT {
+			return nil, fmt.Errorf("%d: expected enumerator, not %s", name.line, name)
+		}
+		if err := p.skipAttributes(); err != nil {
+			return nil, err
+		}
+		if p.accept("=") {
+			start := p.pos
+			for !p.is(",") && !p.is("}") && p.peek().kind != C_EOF {
+				p.next()
+			}
+			evaluated, ok := p.evalTokens(p.tokens[start:p.pos])
+			if !ok {
+				return nil, fmt.Errorf("%d: can't evaluate value of %s", name.line, name)
+			}
+			value = evaluated
+		} else {
+			value = new(big.Int).Add(value, big.NewInt(1))
+		}
+		p.values[name.text] = value
+		p.header.consts = append(p.header.consts, cConst{name: name.text, value: value, line: name.line})
+		if !p.accept(",") {
+			if err := p.expect("}"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return cEnum{}, nil
+}
+
+// Parses a declarator, such as "*name", "name[4]", "name(int a)" or
+// "(*name)(int)", applying it to the base type. The name is empty for
+// abstract declarators, such as the "*" on "void f(int *)".
+func (p *cParser) parseDeclarator(base cType) (string, cType, error) {
+	for p.accept("*") {
+		base = cPointer{elem: base}
+		for ignoredWords[p.peek().text] && p.peek().kind == C_IDENT {
+			p.next()
+		}
+		if err := p.skipAttributes(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// Nested declarator, such as "(*name)" on function pointers. The suffixes
+	// after it apply first, so it is parsed last.
+	if p.is("(") && (p.peekAt(1).text == "*" || p.peekAt(1).text == "(" || p.peekAt(1).text == "^") {
+		p.next() // (
+		start := p.pos
+		p.pos--
+		if err := p.skipBalanced(); err != nil {
+			return "", nil, err
+		}
+		ty, err := p.parseDeclaratorSuffixes(base)
+		if err != nil {
+			return "", nil, err
+		}
+		end := p.pos
+
+		p.pos = start
+		name, ty, err := p.parseDeclarator(ty)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return "", nil, err
+		}
+		p.pos = end
+		return name, ty, nil
+	}
+
+	name := ""
+	if tok := p.peek(); tok.kind == C_IDENT && !isReserved(tok.text) {
+		name = p.next().text
+	}
+	ty, err := p.parseDeclaratorSuffixes(base)
+	return name, ty, err
+}
+
+func isReserved(word string) bool {
+	return word == "__attribute__" || word == "__asm__" || word == "__asm" || word == "asm"
+}
+
+func (p *cParser) parseDeclaratorSuffixes(base cType) (cType, error) {
+	switch {
+	case p.accept("["):
+		start := p.pos
+		for !p.is("]") && p.peek().kind != C_EOF {
+			p.next()
+		}
+		length := -1
+		if value, ok := p.evalTokens(p.tokens[start:p.pos]); ok && value.IsInt64() {
+			length = int(value.Int64())
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseDeclaratorSuffixes(base)
+		if err != nil {
+			return nil, err
+		}
+		return cArray{elem: elem, len: length}, nil
+	case p.accept("("):
+		fn := cFunc{ret: base}
+		// "f(void)" has no parameters
+		if p.is("void") && p.peekAt(1).text == ")" {
+			p.next()
+		}
+		for !p.accept(")") {
+			if p.accept("...") {
+				fn.variadic = true
+				continue
+			}
+			specs, err := p.parseSpecifiers()
+			if err != nil {
+				return nil, err
+			}
+			name, ty, err := p.parseDeclarator(specs.ty)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.skipAttributes(); err != nil {
+				return nil, err
+			}
+			fn.params = append(fn.params, cParam{name: name, ty: ty})
+			if !p.accept(",") {
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		return fn, nil
+	default:
+		return base, nil
+	}
+}
@@ -10,6 +10,18 @@ type Command int
 
 const (
 	COMMAND_BUILD Command = iota
+	COMMAND_TEST
+	COMMAND_DUMP
+	COMMAND_FMT
+	COMMAND_BINDGEN
+)
+
+type DumpKind int
+
+const (
+	DUMP_TOKENS DumpKind = iota
+	DUMP_AST
+	DUMP_SCOPES
 )
 
 type CliResult struct {
@@ -18,8 +30,34 @@ type CliResult struct {
 	IsModuleBuild bool   // true if 'Command' is build and 'Path' is directory
 	ParentDirName string // name of parent dir
 	Path          string // path to directory / file (treated as module)
+
+	Lib        bool // true if 'Command' is build and the output is a library
+	Shared     bool // true if the library is shared instead of static
+	EmitHeader bool // true if the C header of the library is also generated
+
+	Bench bool // true if 'Command' is test and benchmarks should run
+
+	Dump     DumpKind // what to print if 'Command' is dump
+	DumpJSON bool     // true if 'Command' is dump and the output is JSON
+
+	FmtWrite bool // true if 'Command' is fmt and files should be overwritten
+	FmtCheck bool // true if 'Command' is fmt and unformatted files are an error
+
+	Link   string // library linked by the bindings if 'Command' is bindgen
+	Output string // file the bindings are written to if 'Command' is bindgen
 }
 
+// C headers are parsed as they are, so the ones using macros or compiler
+// extensions, such as system headers, need to be preprocessed first
+const BINDGEN_USAGE = `usage: telia bindgen [--link library] [-o output.t] header.h
+
+Prints the Telia bindings of a C header, or writes them to the output file.
+Macros are not expanded, so headers using them, such as system headers, need
+to be preprocessed first:
+
+    clang -E -dD /usr/include/string.h > string.h
+    telia bindgen -o string.t string.h`
+
 func cli() CliResult {
 	result := CliResult{}
 
@@ -34,27 +72,132 @@ func cli() CliResult {
 	case "build":
 		result.Command = COMMAND_BUILD
 
-		fileOrDir := "."
-		if len(args) >= 2 {
-			fileOrDir = args[1]
+		var rest []string
+		for _, arg := range args[1:] {
+			switch arg {
+			case "--lib":
+				result.Lib = true
+			case "--shared":
+				result.Shared = true
+			case "--emit-header":
+				result.EmitHeader = true
+			default:
+				rest = append(rest, arg)
+			}
+		}
+		if (result.Shared || result.EmitHeader) && !result.Lib {
+			log.Fatal("expected --lib with --shared or --emit-header")
 		}
+		setPath(&result, rest)
+	case "test":
+		result.Command = COMMAND_TEST
 
-		info, err := os.Stat(fileOrDir)
-		if err != nil {
-			log.Fatalf("os.Stat error: %s\n", err)
+		var rest []string
+		for _, arg := range args[1:] {
+			if arg == "--bench" {
+				result.Bench = true
+				continue
+			}
+			rest = append(rest, arg)
 		}
+		setPath(&result, rest)
+	case "dump":
+		result.Command = COMMAND_DUMP
 
-		path, err := filepath.Abs(fileOrDir)
-		if err != nil {
-			log.Fatal(err)
+		var rest []string
+		hasKind := false
+		for _, arg := range args[1:] {
+			switch arg {
+			case "--tokens", "--ast", "--scopes":
+				if hasKind {
+					log.Fatal("expected only one of --tokens, --ast or --scopes")
+				}
+				hasKind = true
+				result.Dump = map[string]DumpKind{
+					"--tokens": DUMP_TOKENS,
+					"--ast":    DUMP_AST,
+					"--scopes": DUMP_SCOPES,
+				}[arg]
+			case "--json":
+				result.DumpJSON = true
+			default:
+				rest = append(rest, arg)
+			}
+		}
+		if !hasKind {
+			log.Fatal("expected one of --tokens, --ast or --scopes")
 		}
+		setPath(&result, rest)
+	case "fmt":
+		result.Command = COMMAND_FMT
 
-		result.Path = path
-		result.IsModuleBuild = info.Mode().IsDir()
-		result.ParentDirName = filepath.Base(filepath.Dir(path))
+		var rest []string
+		for _, arg := range args[1:] {
+			switch arg {
+			case "-w":
+				result.FmtWrite = true
+			case "--check":
+				result.FmtCheck = true
+			default:
+				rest = append(rest, arg)
+			}
+		}
+		setPath(&result, rest)
+	case "bindgen":
+		result.Command = COMMAND_BINDGEN
+
+		var rest []string
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--link":
+				if i+1 >= len(args) {
+					log.Fatal("expected library name after --link")
+				}
+				result.Link = args[i+1]
+				i++
+			case "-o":
+				if i+1 >= len(args) {
+					log.Fatal("expected output file after -o")
+				}
+				result.Output = args[i+1]
+				i++
+			default:
+				rest = append(rest, args[i])
+			}
+		}
+		if len(rest) == 0 {
+			log.Fatalf("expected C header\n\n%s", BINDGEN_USAGE)
+		}
+		setPath(&result, rest)
+		if result.IsModuleBuild {
+			log.Fatal("expected C header, not directory")
+		}
 	default:
 		log.Fatal("TODO: show help - list of commands")
 	}
 
 	return result
 }
+
+// Sets the file or directory given to the command. The current directory is
+// used if none is given.
+func setPath(result *CliResult, args []string) {
+	fileOrDir := "."
+	if len(args) >= 1 {
+		fileOrDir = args[0]
+	}
+
+	info, err := os.Stat(fileOrDir)
+	if err != nil {
+		log.Fatalf("os.Stat error: %s\n", err)
+	}
+
+	path, err := filepath.Abs(fileOrDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result.Path = path
+	result.IsModuleBuild = info.Mode().IsDir()
+	result.ParentDirName = filepath.Base(filepath.Dir(path))
+}
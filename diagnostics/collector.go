@@ -3,6 +3,7 @@ package diagnostics
 import (
 	"errors"
 	"fmt"
+	"os"
 )
 
 var (
@@ -19,7 +20,9 @@ func New() *Collector {
 	}
 }
 
+// Diagnostics are printed to stderr, so they don't mix with the output of
+// commands such as "telia dump"
 func (collector *Collector) ReportAndSave(diag Diag) {
-	fmt.Println(diag.Message)
+	fmt.Fprintln(os.Stderr, diag)
 	collector.Diags = append(collector.Diags, diag)
 }
@@ -1,9 +1,35 @@
 package diagnostics
 
+import "regexp"
+
+type Severity int
+
+const (
+	// Errors stop the compilation, so it's the default severity
+	ERROR Severity = iota
+	// Warnings are reported, but don't fail the build
+	WARNING
+)
+
+func (severity Severity) String() string {
+	if severity == WARNING {
+		return "warning"
+	}
+	return "error"
+}
+
 type Diag struct {
+	Severity Severity
+	// Messages start with the position they refer to, such as
+	// "main.t:1:5: unused variable 'a'"
 	Message string
 }
 
+var position = regexp.MustCompile(`^.*?:\d+:\d+: `)
+
+// Formats the diagnostic with its severity right after the position, such as
+// "main.t:1:5: warning: unused variable 'a'"
 func (diag Diag) String() string {
-	return diag.Message
+	pos := position.FindString(diag.Message)
+	return pos + diag.Severity.String() + ": " + diag.Message[len(pos):]
 }
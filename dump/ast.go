@@ -0,0 +1,122 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+var (
+	tokenType = reflect.TypeOf(token.Token{})
+	posType   = reflect.TypeOf(token.Pos{})
+	kindType  = reflect.TypeOf(token.Kind(0))
+	spanType  = reflect.TypeOf(ast.Span{})
+)
+
+func (d *Dumper) AST(program *ast.Program) *Node {
+	return &Node{Type: "Program", Children: []*Node{d.module("Root", program.Root)}}
+}
+
+func (d *Dumper) module(field string, module *ast.Module) *Node {
+	node := &Node{Type: "Module", Field: field, Value: module.Name}
+	for _, file := range module.Files {
+		fileNode := &Node{Type: "File", Value: d.path(file.Path)}
+		d.span(fileNode, file.Loc)
+		for _, decl := range file.Body {
+			fileNode.Children = append(fileNode.Children, d.node("", reflect.ValueOf(decl)))
+		}
+		node.Children = append(node.Children, fileNode)
+	}
+	for _, inner := range module.Modules {
+		node.Children = append(node.Children, d.module("", inner))
+	}
+	return node
+}
+
+// Builds the tree of any AST node by walking the fields listed on its schema.
+// Nil fields are omitted.
+func (d *Dumper) node(field string, value reflect.Value) *Node {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Interface, reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+		return d.node(field, value.Elem())
+	case reflect.Map:
+		return nil
+	case reflect.Slice:
+		if value.IsNil() {
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return &Node{Type: "Bytes", Field: field, Value: string(value.Bytes())}
+		}
+		list := &Node{Type: "List", Field: field}
+		for i := 0; i < value.Len(); i++ {
+			if elem := d.node("", value.Index(i)); elem != nil {
+				list.Children = append(list.Children, elem)
+			}
+		}
+		return list
+	case reflect.Struct:
+		switch value.Type() {
+		case tokenType:
+			tok := value.Interface().(token.Token)
+			return d.token(field, &tok)
+		case posType:
+			return &Node{Type: "Pos", Field: field, Pos: d.pos(value.Interface().(token.Pos))}
+		}
+
+		schema, ok := astSchema[value.Type()]
+		if !ok {
+			panic(fmt.Sprintf("dump: no schema for %s", value.Type()))
+		}
+		node := &Node{Type: schema.name, Field: field}
+		if loc := value.FieldByName("Loc"); loc.IsValid() && loc.Type() == spanType {
+			d.span(node, loc.Interface().(ast.Span))
+		}
+		for _, schemaField := range schema.fields {
+			fieldValue := value.FieldByName(schemaField.goField)
+			if !fieldValue.IsValid() {
+				panic(fmt.Sprintf("dump: %s has no field %s", value.Type(), schemaField.goField))
+			}
+			if child := d.node(schemaField.name, fieldValue); child != nil {
+				node.Children = append(node.Children, child)
+			}
+		}
+		return node
+	}
+
+	if value.Type() == kindType {
+		return &Node{Type: "Kind", Field: field, Value: value.Interface().(token.Kind).String()}
+	}
+	return &Node{Type: valueType(value.Kind()), Field: field, Value: fmt.Sprint(value.Interface())}
+}
+
+// Name of values that aren't AST nodes, such as "Inclusive" on RangeExpr
+func valueType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	}
+	panic(fmt.Sprintf("dump: no schema for %s", kind))
+}
+
+// Sets the position of a node to the span of the AST node it represents. Nodes
+// created by the compiler have no span and no position.
+func (d *Dumper) span(node *Node, span ast.Span) {
+	if span.IsZero() {
+		return
+	}
+	node.Pos = d.pos(span.Start)
+	node.End = d.pos(span.End)
+}
@@ -0,0 +1,105 @@
+// Package dump prints the internal representations of the compiler, such as
+// the token stream, the AST and the scope tree, used by "telia dump".
+//
+// Every representation is a tree of Node. Its JSON form is stable: names of AST
+// nodes and fields come from the schema on schema.go instead of the Go names,
+// empty fields are omitted and file paths are relative to the directory being
+// dumped, so it is safe to diff it in tests.
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+type Node struct {
+	Type     string  `json:"type"`            // such as "FunctionDecl", "Token" or "Scope"
+	Field    string  `json:"field,omitempty"` // field of the parent node that holds this node
+	Kind     string  `json:"kind,omitempty"`  // kind of tokens
+	Value    string  `json:"value,omitempty"`
+	Pos      *Pos    `json:"pos,omitempty"`
+	End      *Pos    `json:"end,omitempty"` // end of the source code covered by AST nodes
+	Children []*Node `json:"children,omitempty"`
+}
+
+type Pos struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+type Dumper struct {
+	// Directory that file paths are relative to
+	base string
+}
+
+func New(base string) *Dumper {
+	return &Dumper{base: base}
+}
+
+func (d *Dumper) path(path string) string {
+	if d.base == "" {
+		return path
+	}
+	rel, err := filepath.Rel(d.base, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (d *Dumper) pos(pos token.Pos) *Pos {
+	return &Pos{File: d.path(pos.Filename), Line: pos.Line, Column: pos.Column}
+}
+
+func WriteJSON(w io.Writer, node *Node) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(node)
+}
+
+// Writes one node per line, indented by depth, such as:
+//
+//	FunctionDecl 1:1-1:15
+//	  Name: Token identifier "main" 1:4
+func WriteText(w io.Writer, node *Node) error {
+	return writeText(w, node, 0)
+}
+
+func writeText(w io.Writer, node *Node, depth int) error {
+	var line strings.Builder
+	line.WriteString(strings.Repeat("  ", depth))
+	if node.Field != "" {
+		line.WriteString(node.Field + ": ")
+	}
+	line.WriteString(node.Type)
+	if node.Kind != "" {
+		line.WriteString(" " + node.Kind)
+	}
+	if node.Value != "" {
+		line.WriteString(fmt.Sprintf(" %q", node.Value))
+	}
+	if node.Pos != nil {
+		line.WriteString(fmt.Sprintf(" %d:%d", node.Pos.Line, node.Pos.Column))
+	}
+	if node.End != nil {
+		line.WriteString(fmt.Sprintf("-%d:%d", node.End.Line, node.End.Column))
+	}
+	_, err := fmt.Fprintln(w, line.String())
+	if err != nil {
+		return err
+	}
+
+	for _, child := range node.Children {
+		err := writeText(w, child, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
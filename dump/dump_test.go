@@ -0,0 +1,193 @@
+package dump
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer"
+	"github.com/HicaroD/Telia/frontend/parser"
+	"github.com/HicaroD/Telia/middleend/sema"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files on testdata")
+
+func parseFrom(t *testing.T, input, filename string) *ast.Program {
+	collector := diagnostics.New()
+	lex := lexer.New(filename, []byte(input), collector)
+	program, err := parser.New(collector).ParseFileAsProgram(lex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestTokensJSON(t *testing.T) {
+	filename := "test.tt"
+	input := "fn f"
+
+	collector := diagnostics.New()
+	tokens, err := lexer.New(filename, []byte(input), collector).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "type": "Tokens",
+  "children": [
+    {
+      "type": "File",
+      "value": "test.tt",
+      "children": [
+        {
+          "type": "Token",
+          "kind": "fn",
+          "value": "fn",
+          "pos": {
+            "file": "test.tt",
+            "line": 1,
+            "column": 1
+          }
+        },
+        {
+          "type": "Token",
+          "kind": "identifier",
+          "value": "f",
+          "pos": {
+            "file": "test.tt",
+            "line": 1,
+            "column": 4
+          }
+        },
+        {
+          "type": "Token",
+          "kind": "end of file",
+          "pos": {
+            "file": "test.tt",
+            "line": 1,
+            "column": 5
+          }
+        }
+      ]
+    }
+  ]
+}
+`
+
+	var out bytes.Buffer
+	err = WriteJSON(&out, New("").Tokens([]TokenFile{{Path: filename, Tokens: tokens}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected {
+		t.Fatalf("\nexp: %s\ngot: %s\n", expected, out.String())
+	}
+}
+
+func TestASTText(t *testing.T) {
+	program := parseFrom(t, "const N = -1;", "test.tt")
+
+	expected := `Program
+  Root: Module
+    File "test.tt" 1:1-1:14
+      ConstDecl 1:1-1:13
+        Const: Token const "const" 1:1
+        Name: Token identifier "N" 1:7
+        Value: UnaryExpr 1:11-1:13
+          Op: Kind "-"
+          Value: LiteralExpr 1:12-1:13
+            Type: BasicType
+              Kind: Kind "integer literal"
+            Value: Bytes "1"
+`
+
+	var out bytes.Buffer
+	err := WriteText(&out, New("").AST(program))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected {
+		t.Fatalf("\nexp: %s\ngot: %s\n", expected, out.String())
+	}
+}
+
+// The JSON form of the AST is pinned by a golden file, so any change to it is
+// deliberate. Run "go test ./dump -update" to rewrite it.
+func TestASTGolden(t *testing.T) {
+	path := filepath.Join("testdata", "ast.t")
+	golden := filepath.Join("testdata", "ast.json")
+
+	collector := diagnostics.New()
+	lex, err := lexer.NewFromFilePath("testdata", path, collector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := parser.New(collector).ParseFileAsProgram(lex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = WriteJSON(&out, New("testdata").AST(program))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		err := os.WriteFile(golden, out.Bytes(), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(expected) {
+		t.Fatalf("the AST dump doesn't match %s, run \"go test ./dump -update\" if the change is intended", golden)
+	}
+}
+
+// Fields of the AST listed on the schema must exist, so renaming one of them
+// fails here instead of when dumping
+func TestASTSchemaFields(t *testing.T) {
+	for ty, schema := range astSchema {
+		for _, field := range schema.fields {
+			if _, ok := ty.FieldByName(field.goField); !ok {
+				t.Errorf("%s has no field %s, used by %q on the dump", ty, field.goField, field.name)
+			}
+		}
+	}
+}
+
+func TestScopesText(t *testing.T) {
+	program := parseFrom(t, "fn main() { x := 1; if x > 0 { y := 2; } }", "test.tt")
+	err := sema.New(diagnostics.New()).Check(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Scopes
+  Scope "universe"
+    Scope "module"
+      Symbol FunctionDecl "main" 1:4
+      Scope "file test.tt"
+        Scope "fn main"
+          Symbol VarStmt "x" 1:13
+          Scope "if"
+            Symbol VarStmt "y" 1:32
+`
+
+	var out bytes.Buffer
+	err = WriteText(&out, New("").Scopes(program))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected {
+		t.Fatalf("\nexp: %s\ngot: %s\n", expected, out.String())
+	}
+}
@@ -0,0 +1,389 @@
+package dump
+
+import (
+	"reflect"
+
+	"github.com/HicaroD/Telia/frontend/ast"
+)
+
+// Names of the AST nodes and of the fields they dump. They are the schema of
+// the dump, so they don't follow the AST by accident: renaming a field of the
+// AST doesn't rename it on the dump, and new fields are only dumped once they
+// are listed here.
+type nodeSchema struct {
+	name   string
+	fields []schemaField
+}
+
+type schemaField struct {
+	name    string // name on the dump
+	goField string // name of the field on the AST node
+}
+
+var astSchema = map[reflect.Type]nodeSchema{
+	reflect.TypeOf(ast.ArrayLiteral{}): {
+		name: "ArrayLiteral",
+		fields: []schemaField{
+			{"Open", "Open"},
+			{"Values", "Values"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.ArrayType{}): {
+		name: "ArrayType",
+		fields: []schemaField{
+			{"Len", "Len"},
+			{"LenExpr", "LenExpr"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.AssertStmt{}): {
+		name: "AssertStmt",
+		fields: []schemaField{
+			{"Assert", "Assert"},
+			{"Cond", "Cond"},
+		},
+	},
+	reflect.TypeOf(ast.Attribute{}): {
+		name: "Attribute",
+		fields: []schemaField{
+			{"At", "At"},
+			{"Name", "Name"},
+			{"Args", "Args"},
+		},
+	},
+	reflect.TypeOf(ast.BasicType{}): {
+		name: "BasicType",
+		fields: []schemaField{
+			{"Kind", "Kind"},
+		},
+	},
+	reflect.TypeOf(ast.BenchDecl{}): {
+		name: "BenchDecl",
+		fields: []schemaField{
+			{"Bench", "Bench"},
+			{"Name", "Name"},
+			{"Block", "Block"},
+		},
+	},
+	reflect.TypeOf(ast.BinaryExpr{}): {
+		name: "BinaryExpr",
+		fields: []schemaField{
+			{"Left", "Left"},
+			{"Op", "Op"},
+			{"Right", "Right"},
+			{"OperandType", "OperandType"},
+		},
+	},
+	reflect.TypeOf(ast.BlockStmt{}): {
+		name: "BlockStmt",
+		fields: []schemaField{
+			{"OpenCurly", "OpenCurly"},
+			{"Statements", "Statements"},
+			{"CloseCurly", "CloseCurly"},
+		},
+	},
+	reflect.TypeOf(ast.CastExpr{}): {
+		name: "CastExpr",
+		fields: []schemaField{
+			{"Value", "Value"},
+			{"Type", "Type"},
+			{"From", "From"},
+		},
+	},
+	reflect.TypeOf(ast.CatchStmt{}): {
+		name: "CatchStmt",
+		fields: []schemaField{
+			{"Value", "Value"},
+			{"Block", "Block"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.CondStmt{}): {
+		name: "CondStmt",
+		fields: []schemaField{
+			{"IfStmt", "IfStmt"},
+			{"ElifStmts", "ElifStmts"},
+			{"ElseStmt", "ElseStmt"},
+		},
+	},
+	reflect.TypeOf(ast.ConstDecl{}): {
+		name: "ConstDecl",
+		fields: []schemaField{
+			{"Const", "Const"},
+			{"Name", "Name"},
+			{"Type", "Type"},
+			{"Value", "Value"},
+		},
+	},
+	reflect.TypeOf(ast.ElseCond{}): {
+		name: "ElseCond",
+		fields: []schemaField{
+			{"Else", "Else"},
+			{"Block", "Block"},
+		},
+	},
+	reflect.TypeOf(ast.ErrorExpr{}): {
+		name: "ErrorExpr",
+		fields: []schemaField{
+			{"Name", "Name"},
+			{"Type", "Type"},
+			{"Code", "Code"},
+		},
+	},
+	reflect.TypeOf(ast.ErrorUnionExpr{}): {
+		name: "ErrorUnionExpr",
+		fields: []schemaField{
+			{"Value", "Value"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.ErrorUnionType{}): {
+		name: "ErrorUnionType",
+		fields: []schemaField{
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.ExternDecl{}): {
+		name: "ExternDecl",
+		fields: []schemaField{
+			{"Name", "Name"},
+			{"Link", "Link"},
+			{"Prototypes", "Prototypes"},
+			{"Vars", "Vars"},
+		},
+	},
+	reflect.TypeOf(ast.ExternVar{}): {
+		name: "ExternVar",
+		fields: []schemaField{
+			{"Name", "Name"},
+			{"Type", "Type"},
+			{"Alias", "Alias"},
+		},
+	},
+	reflect.TypeOf(ast.Field{}): {
+		name: "Field",
+		fields: []schemaField{
+			{"Name", "Name"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.FieldAccess{}): {
+		name: "FieldAccess",
+		fields: []schemaField{
+			{"Left", "Left"},
+			{"Right", "Right"},
+		},
+	},
+	reflect.TypeOf(ast.FieldList{}): {
+		name: "FieldList",
+		fields: []schemaField{
+			{"Open", "Open"},
+			{"Fields", "Fields"},
+			{"IsVariadic", "IsVariadic"},
+			{"Close", "Close"},
+		},
+	},
+	reflect.TypeOf(ast.ForLoop{}): {
+		name: "ForLoop",
+		fields: []schemaField{
+			{"Init", "Init"},
+			{"Cond", "Cond"},
+			{"Update", "Update"},
+			{"Block", "Block"},
+		},
+	},
+	reflect.TypeOf(ast.FunctionCall{}): {
+		name: "FunctionCall",
+		fields: []schemaField{
+			{"Name", "Name"},
+			{"Args", "Args"},
+			{"Type", "Type"},
+			{"Builtin", "Builtin"},
+			{"Folded", "Folded"},
+		},
+	},
+	reflect.TypeOf(ast.FunctionDecl{}): {
+		name: "FunctionDecl",
+		fields: []schemaField{
+			{"Attributes", "Attributes"},
+			{"Name", "Name"},
+			{"Params", "Params"},
+			{"RetType", "RetType"},
+			{"Block", "Block"},
+		},
+	},
+	reflect.TypeOf(ast.IdExpr{}): {
+		name: "IdExpr",
+		fields: []schemaField{
+			{"Name", "Name"},
+		},
+	},
+	reflect.TypeOf(ast.IdType{}): {
+		name: "IdType",
+		fields: []schemaField{
+			{"Name", "Name"},
+		},
+	},
+	reflect.TypeOf(ast.IfElifCond{}): {
+		name: "IfElifCond",
+		fields: []schemaField{
+			{"If", "If"},
+			{"Unwrap", "Unwrap"},
+			{"Expr", "Expr"},
+			{"Block", "Block"},
+		},
+	},
+	reflect.TypeOf(ast.IndexExpr{}): {
+		name: "IndexExpr",
+		fields: []schemaField{
+			{"Value", "Value"},
+			{"Open", "Open"},
+			{"Index", "Index"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.LiteralExpr{}): {
+		name: "LiteralExpr",
+		fields: []schemaField{
+			{"Type", "Type"},
+			{"Value", "Value"},
+		},
+	},
+	reflect.TypeOf(ast.MultiVarStmt{}): {
+		name: "MultiVarStmt",
+		fields: []schemaField{
+			{"IsDecl", "IsDecl"},
+			{"Variables", "Variables"},
+			{"Tuple", "Tuple"},
+		},
+	},
+	reflect.TypeOf(ast.OptionalExpr{}): {
+		name: "OptionalExpr",
+		fields: []schemaField{
+			{"Value", "Value"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.OptionalType{}): {
+		name: "OptionalType",
+		fields: []schemaField{
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.PointerType{}): {
+		name: "PointerType",
+		fields: []schemaField{
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.Proto{}): {
+		name: "Proto",
+		fields: []schemaField{
+			{"Attributes", "Attributes"},
+			{"Name", "Name"},
+			{"Params", "Params"},
+			{"RetType", "RetType"},
+			{"Alias", "Alias"},
+		},
+	},
+	reflect.TypeOf(ast.RangeExpr{}): {
+		name: "RangeExpr",
+		fields: []schemaField{
+			{"Start", "Start"},
+			{"End", "End"},
+			{"Inclusive", "Inclusive"},
+		},
+	},
+	reflect.TypeOf(ast.RangeForLoop{}): {
+		name: "RangeForLoop",
+		fields: []schemaField{
+			{"Index", "Index"},
+			{"Value", "Value"},
+			{"Iterable", "Iterable"},
+			{"Block", "Block"},
+		},
+	},
+	reflect.TypeOf(ast.ReturnStmt{}): {
+		name: "ReturnStmt",
+		fields: []schemaField{
+			{"Return", "Return"},
+			{"Value", "Value"},
+		},
+	},
+	reflect.TypeOf(ast.SliceType{}): {
+		name: "SliceType",
+		fields: []schemaField{
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.StaticAssert{}): {
+		name: "StaticAssert",
+		fields: []schemaField{
+			{"StaticAssert", "StaticAssert"},
+			{"Cond", "Cond"},
+			{"Message", "Message"},
+		},
+	},
+	reflect.TypeOf(ast.TestDecl{}): {
+		name: "TestDecl",
+		fields: []schemaField{
+			{"Test", "Test"},
+			{"Name", "Name"},
+			{"Block", "Block"},
+		},
+	},
+	reflect.TypeOf(ast.TryExpr{}): {
+		name: "TryExpr",
+		fields: []schemaField{
+			{"Value", "Value"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.TupleExpr{}): {
+		name: "TupleExpr",
+		fields: []schemaField{
+			{"Exprs", "Exprs"},
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.TupleType{}): {
+		name: "TupleType",
+		fields: []schemaField{
+			{"Types", "Types"},
+		},
+	},
+	reflect.TypeOf(ast.TypeExpr{}): {
+		name: "TypeExpr",
+		fields: []schemaField{
+			{"Type", "Type"},
+		},
+	},
+	reflect.TypeOf(ast.UnaryExpr{}): {
+		name: "UnaryExpr",
+		fields: []schemaField{
+			{"Op", "Op"},
+			{"Value", "Value"},
+		},
+	},
+	reflect.TypeOf(ast.VarStmt{}): {
+		name: "VarStmt",
+		fields: []schemaField{
+			{"Decl", "Decl"},
+			{"Name", "Name"},
+			{"Type", "Type"},
+			{"Value", "Value"},
+			{"NeedsInference", "NeedsInference"},
+		},
+	},
+	reflect.TypeOf(ast.VoidExpr{}): {
+		name: "VoidExpr",
+	},
+	reflect.TypeOf(ast.WhileLoop{}): {
+		name: "WhileLoop",
+		fields: []schemaField{
+			{"Cond", "Cond"},
+			{"Block", "Block"},
+		},
+	},
+}
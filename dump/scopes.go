@@ -0,0 +1,135 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+type scopeEntry struct {
+	label    string
+	children []*ast.Scope
+}
+
+// Collects every scope of an analyzed program, keeping them in the order they
+// were found
+type scopeCollector struct {
+	entries map[*ast.Scope]*scopeEntry
+	roots   []*ast.Scope
+}
+
+// Builds the scope tree of a program that went through semantic analysis.
+// Each scope lists its symbols, sorted by name, followed by its inner scopes.
+func (d *Dumper) Scopes(program *ast.Program) *Node {
+	collector := &scopeCollector{entries: make(map[*ast.Scope]*scopeEntry)}
+	collector.module(program.Root, d)
+
+	root := &Node{Type: "Scopes"}
+	for _, scope := range collector.roots {
+		root.Children = append(root.Children, d.scope(scope, collector))
+	}
+	return root
+}
+
+func (d *Dumper) scope(scope *ast.Scope, collector *scopeCollector) *Node {
+	entry := collector.entries[scope]
+	node := &Node{Type: "Scope", Value: entry.label}
+
+	names := make([]string, 0, len(scope.Nodes))
+	for name := range scope.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		symbol := scope.Nodes[name]
+		symbolNode := &Node{
+			Type:  "Symbol",
+			Kind:  reflect.Indirect(reflect.ValueOf(symbol)).Type().Name(),
+			Value: name,
+		}
+		if tok := symbolName(symbol); tok != nil {
+			symbolNode.Pos = d.pos(tok.Pos)
+		}
+		node.Children = append(node.Children, symbolNode)
+	}
+
+	for _, child := range entry.children {
+		node.Children = append(node.Children, d.scope(child, collector))
+	}
+	return node
+}
+
+// Returns the token that names a symbol, if it has one
+func symbolName(symbol ast.Node) *token.Token {
+	value := reflect.Indirect(reflect.ValueOf(symbol))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	name := value.FieldByName("Name")
+	if !name.IsValid() {
+		return nil
+	}
+	tok, _ := name.Interface().(*token.Token)
+	return tok
+}
+
+func (collector *scopeCollector) register(scope *ast.Scope, label string) {
+	if scope == nil {
+		return
+	}
+	if entry, ok := collector.entries[scope]; ok {
+		if label != "" {
+			entry.label = label
+		}
+		return
+	}
+
+	if label == "" && scope.Parent == nil {
+		label = "universe"
+	}
+	collector.entries[scope] = &scopeEntry{label: label}
+	if scope.Parent == nil {
+		collector.roots = append(collector.roots, scope)
+		return
+	}
+	collector.register(scope.Parent, "")
+	parent := collector.entries[scope.Parent]
+	parent.children = append(parent.children, scope)
+}
+
+func (collector *scopeCollector) module(module *ast.Module, d *Dumper) {
+	ast.Inspect(module, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Module:
+			label := "module"
+			if n.Name != "" {
+				label = fmt.Sprintf("module %s", n.Name)
+			}
+			if n.IsRoot && n.Scope.Parent == nil {
+				label = "universe"
+			}
+			collector.register(n.Scope, label)
+		case *ast.File:
+			collector.register(n.Scope, fmt.Sprintf("file %s", d.path(n.Path)))
+		case *ast.FunctionDecl:
+			collector.register(n.Scope, fmt.Sprintf("fn %s", n.Name.Name()))
+		case *ast.ExternDecl:
+			collector.register(n.Scope, fmt.Sprintf("extern %s", n.Name.Name()))
+		case *ast.TestDecl:
+			collector.register(n.Scope, fmt.Sprintf("test %s", n.Name.Lexeme))
+		case *ast.BenchDecl:
+			collector.register(n.Scope, fmt.Sprintf("bench %s", n.Name.Lexeme))
+		case *ast.IfElifCond:
+			collector.register(n.Scope, "if")
+		case *ast.ElseCond:
+			collector.register(n.Scope, "else")
+		case *ast.RangeForLoop:
+			collector.register(n.Scope, "for")
+		}
+		return true
+	})
+}
@@ -0,0 +1,65 @@
+extern libc link "c" {
+  @nonnull fn print(s *u8) i32 = "puts";
+  err *u8 = "stderr";
+}
+
+const N u8 = 2 * 3;
+static_assert(N > 1, "N is too small");
+
+@export("half")
+fn half(n int) !int {
+  if n % 2 != 0 {
+    return error.Odd;
+  }
+  return n / 2;
+}
+
+fn first(values []int) ?int {
+  for i, v in values {
+    return v;
+  }
+  return nil;
+}
+
+fn pair() (int, int) {
+  return 1, 2;
+}
+
+fn main() i32 {
+  a, b := 1, 2;
+  c, d := pair();
+  arr [3]int = [1, 2, 3];
+  s := arr[0..2];
+  x := first(s) orelse -1;
+  for (i := 0; i < 3; i = i + 1) {
+    x = x + i as int;
+  }
+  while x > 10 {
+    x = x - 1;
+  }
+  if y := first(s) {
+    x = y;
+  } elif x == 0 {
+    x = 1;
+  } else {
+    x = 2;
+  }
+  for j in 0...N {
+    assert(j <= N);
+  }
+  half(x) catch {
+    return 1;
+  }
+  n := try half(x) catch 0;
+  libc.print("done");
+  return sizeof(int) as i32;
+}
+
+test "half" {
+  assert(half(4) catch 0 == 2);
+}
+
+bench "pair" {
+  pair();
+  return;
+}
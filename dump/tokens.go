@@ -0,0 +1,33 @@
+package dump
+
+import (
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Token stream of a single file, as returned by "Lexer.Tokenize"
+type TokenFile struct {
+	Path   string
+	Tokens []*token.Token
+}
+
+func (d *Dumper) Tokens(files []TokenFile) *Node {
+	root := &Node{Type: "Tokens"}
+	for _, file := range files {
+		fileNode := &Node{Type: "File", Value: d.path(file.Path)}
+		for _, tok := range file.Tokens {
+			fileNode.Children = append(fileNode.Children, d.token("", tok))
+		}
+		root.Children = append(root.Children, fileNode)
+	}
+	return root
+}
+
+func (d *Dumper) token(field string, tok *token.Token) *Node {
+	return &Node{
+		Type:  "Token",
+		Field: field,
+		Kind:  tok.Kind.String(),
+		Value: string(tok.Lexeme),
+		Pos:   d.pos(tok.Pos),
+	}
+}
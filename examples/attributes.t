@@ -0,0 +1,32 @@
+extern libc {
+  @cold fn abort();
+  fn printf(format *u8, ...) i32;
+}
+
+@inline
+fn square(x int) int {
+  return x * x;
+}
+
+@noinline @must_use
+fn cube(x int) int {
+  return x * x * x;
+}
+
+@export("telia_add")
+fn add(a int, b int) int {
+  return a + b;
+}
+
+@deprecated("use add instead")
+fn plus(a int, b int) int {
+  return a + b;
+}
+
+fn main() i32 {
+  a := square(3);
+  b := cube(2);
+  c := plus(a, b);
+  libc.printf("%d %d %d", a, b, add(c, 1));
+  return 0;
+}
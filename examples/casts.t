@@ -0,0 +1,22 @@
+extern libc {
+  fn printf(format *u8, ...) i32;
+}
+
+fn main() i32 {
+  small u8 := 200;
+  libc.printf("%lu ", small as u64);
+
+  negative i8 := -5;
+  libc.printf("%ld ", negative as i64);
+  libc.printf("%u ", negative as u8 as u32);
+
+  big := 300;
+  libc.printf("%d ", big as u8 as i32);
+
+  done := true;
+  libc.printf("%d ", done as i32);
+
+  name *u8 := "telia";
+  libc.printf("%s", name as *i8);
+  return 0;
+}
@@ -0,0 +1,40 @@
+extern libc {
+  fn printf(format *u8, ...) i32;
+}
+
+fn factorial(n int) int {
+  if n <= 1 {
+    return 1;
+  }
+  return n * factorial(n - 1);
+}
+
+fn fib(n int) int {
+  a := 0;
+  b := 1;
+  for i in 0..n {
+    a, b = b, a + b;
+  }
+  return a;
+}
+
+const N = 4;
+const FACT_5 = factorial(5);
+const MIN i32 = -2147483648;
+const DEBUG = N > 3 and not false;
+
+static_assert(FACT_5 == 120, "factorial is evaluated at compile time");
+static_assert(fib(10) == 55, "so is fib");
+
+fn main() i32 {
+  const SIZE = N * 2 - 1;
+  static_assert(SIZE == 7, "local constants fold too");
+
+  squares [SIZE]int := [0, 1, 4, 9, 16, 25, 36];
+  for i, s in squares {
+    libc.printf("%d:%d ", i, s);
+  }
+
+  libc.printf("%d %d %d %d", FACT_5, MIN, DEBUG, fib(N));
+  return 0;
+}
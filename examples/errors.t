@@ -0,0 +1,44 @@
+extern libc {
+  fn printf(format *u8, ...) i32;
+}
+
+fn checked_div(a int, b int) !int {
+  if b == 0 {
+    return error.DivisionByZero;
+  }
+  return a / b;
+}
+
+fn average(total int, count int) !int {
+  avg := try checked_div(total, count);
+  return avg;
+}
+
+fn check_age(age int) !void {
+  if age > 150 {
+    return error.InvalidAge;
+  }
+  return;
+}
+
+fn is_valid_age(age int) !bool {
+  try check_age(age);
+  return true;
+}
+
+fn main() i32 {
+  libc.printf("%d ", average(10, 2) catch 0);
+  libc.printf("%d ", average(10, 0) catch 0);
+  if is_valid_age(30) catch false {
+    libc.printf("30 is valid ");
+  }
+  if is_valid_age(200) catch false {
+    libc.printf("200 is valid");
+  } else {
+    libc.printf("200 is not valid");
+  }
+  check_age(151) catch {
+    libc.printf(" and neither is 151");
+  }
+  return 0;
+}
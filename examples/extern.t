@@ -0,0 +1,29 @@
+extern libc {
+  fn fprintf(stream *u8, format *u8, ...) i32;
+  @nonnull fn print(s *u8) i32 = "puts";
+
+  stdout *u8;
+  err *u8 = "stderr";
+}
+
+extern pthread link "pthread" {
+  fn self() u64 = "pthread_self";
+}
+
+fn main() i32 {
+  libc.print("hello from puts");
+  // Pointers declared by C may be null, so they are unwrapped before being
+  // passed to parameters that aren't optional
+  if stdout := libc.stdout {
+    libc.fprintf(stdout, "%s ", "to stdout");
+  }
+  if stderr := libc.err {
+    libc.fprintf(stderr, "to stderr ");
+  }
+  if stdout := libc.stdout {
+    if pthread.self() != 0 {
+      libc.fprintf(stdout, "on a thread");
+    }
+  }
+  return 0;
+}
@@ -10,7 +10,7 @@ fn fib(n int) int {
 }
 
 fn main() i32 {
-  for(i := 0; i < 10; i = i + 1) {
+  for (i := 0; i < 10; i = i + 1) {
     result := fib(i);
     libc.printf("%d ", result);
   }
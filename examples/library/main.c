@@ -0,0 +1,9 @@
+#include <stdio.h>
+
+#include "mathlib.h"
+
+int main(void) {
+  uint8_t *name = mathlib_or_default(NULL, (uint8_t *)"telia");
+  printf("%lu %d %s\n", mathlib_gcd(12, 18), mathlib_is_even(7), (char *)name);
+  return 0;
+}
@@ -0,0 +1,26 @@
+// Build with "telia build --lib --emit-header mathlib.t", then link main.c
+// against the library:
+//
+//   cc main.c libmathlib.a -o main
+@export("mathlib_gcd")
+fn gcd(a u64, b u64) u64 {
+  while b != 0 {
+    a, b = b, a - a / b * b;
+  }
+  return a;
+}
+
+@export("mathlib_is_even")
+fn is_even(n i32) bool {
+  even := n / 2 * 2 == n;
+  return even;
+}
+
+@export("mathlib_or_default")
+fn or_default(s ?*u8, fallback *u8) *u8 {
+  return s orelse fallback;
+}
+
+fn main() i32 {
+  return 0;
+}
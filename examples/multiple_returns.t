@@ -0,0 +1,19 @@
+extern libc {
+  fn printf(format *u8, ...) i32;
+}
+
+fn minmax(a int, b int) (int, int) {
+  if a < b {
+    return a, b;
+  }
+  return b, a;
+}
+
+fn main() i32 {
+  min, max := minmax(7, 2);
+  libc.printf("%d %d ", min, max);
+
+  min, max = max, min;
+  libc.printf("%d %d", min, max);
+  return 0;
+}
@@ -5,7 +5,6 @@ extern C {
 
 fn no_return() {
   C.puts("No return");
-  return;
 }
 
 fn main() i32 {
@@ -0,0 +1,42 @@
+extern libc {
+  fn puts(s *u8) i32;
+  fn printf(format *u8, ...) i32;
+  fn getenv(name *u8) *u8;
+}
+
+fn find(values []int, target int) ?int {
+  for i, value in values {
+    if value == target {
+      return i;
+    }
+  }
+  return nil;
+}
+
+fn main() i32 {
+  primes := [2, 3, 5, 7, 11];
+  if i := find(primes[..], 7) {
+    libc.printf("found 7 at %d ", i);
+  }
+
+  missing := find(primes[..], 4);
+  if missing == nil {
+    libc.printf("4 is not prime ");
+  }
+  libc.printf("%d ", missing orelse -1);
+
+  if home := libc.getenv("TELIA_UNSET_VARIABLE") {
+    libc.puts(home);
+  } else {
+    libc.puts("not set");
+  }
+  return 0;
+}
+
+test "orelse chains" {
+  primes := [2, 3, 5, 7, 11];
+  missing := find(primes[..], 4);
+  found := find(primes[..], 7);
+  assert((missing orelse found orelse -1) == 3);
+  assert((missing orelse missing orelse -1) == -1);
+}
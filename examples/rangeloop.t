@@ -0,0 +1,35 @@
+extern libc {
+  fn printf(format *u8, ...) i32;
+}
+
+fn main() i32 {
+  for i in 0..3 {
+    libc.printf("%d ", i);
+  }
+
+  for i in 1...3 {
+    libc.printf("%d ", i);
+  }
+
+  primes := [2, 3, 5, 7, 11];
+  for i, p in primes {
+    libc.printf("%d:%d ", i, p);
+  }
+
+  for p in primes[1..3] {
+    libc.printf("%d ", p);
+  }
+
+  last := primes[4];
+  libc.printf("%d", last);
+  return 0;
+}
+
+test "inclusive range ending at the maximum value" {
+  n u8 := 255;
+  total := 0;
+  for i in 0...n {
+    total = total + i;
+  }
+  assert(total == 32640);
+}
@@ -0,0 +1,66 @@
+extern libc {
+  fn printf(format *u8, ...) i32;
+}
+
+fn abs(n int) int {
+  if n < 0 {
+    return -n;
+  }
+  return n;
+}
+
+fn clamp(n i8, low i8, high i8) i8 {
+  if n < low {
+    return low;
+  }
+  if n > high {
+    return high;
+  }
+  return n;
+}
+
+fn main() i32 {
+  a := -7;
+  b := 2;
+  libc.printf("%d %d %d ", a / b, a % b, abs(a));
+
+  small i8 := -128;
+  big u8 := 200;
+  libc.printf("%d %d %d ", small, big, clamp(-100, -10, 10));
+
+  values := [10, 20, 30];
+  offset i8 := -1;
+  libc.printf("%d ", values[3 + offset]);
+
+  half u32 := 4000000000 / 2;
+  libc.printf("%u", half);
+  return 0;
+}
+
+test "comparisons of negative numbers" {
+  a := -1;
+  assert(a < 0);
+  assert(a < 1);
+  assert(-10 <= a);
+  assert(0 > a);
+}
+
+test "truncated division and remainder" {
+  a := -7;
+  assert(a / 2 == -3);
+  assert(a % 2 == -1);
+  assert(7 % -2 == 1);
+}
+
+test "unsigned values above the signed range" {
+  big u8 := 200;
+  assert(big > 100);
+  assert(big / 3 == 66);
+  assert(big % 7 == 4);
+}
+
+test "sign extension" {
+  small i8 := -2;
+  assert(small as int == -2);
+  assert(small as u8 as int == 254);
+}
@@ -0,0 +1,20 @@
+extern libc {
+  fn printf(format *u8, ...) i32;
+  @nonnull fn malloc(size u64) *u8;
+  @nonnull fn memcpy(dest *u8, src *u8, n u64) *u8;
+  @nonnull fn free(ptr *u8);
+}
+
+fn main() i32 {
+  libc.printf("%lu %lu ", sizeof(i32), alignof(*u8));
+  libc.printf("%lu %lu ", sizeof((u8, int)), offsetof((u8, int), 1));
+
+  name *u8 := "telia";
+  copy := libc.malloc(sizeof([6]u8));
+  libc.memcpy(copy, name, sizeof([6]u8));
+  libc.printf("%s ", copy);
+  libc.free(copy);
+
+  libc.printf("%s", cstr(typename(?*u8)));
+  return 0;
+}
@@ -0,0 +1,27 @@
+extern libc {
+  fn puts(s *u8) i32;
+  fn printf(format *u8, ...) i32;
+}
+
+fn greet(name string) string {
+  return "Hello, " + name + "!";
+}
+
+fn main() i32 {
+  greeting := greet("world");
+  libc.puts(cstr(greeting));
+  libc.printf("length: %d", len(greeting));
+  libc.puts("");
+
+  world := greeting[7..12];
+  if world == "world" {
+    libc.puts(cstr(world));
+  }
+  if greeting[0] == 72 {
+    libc.puts("starts with H");
+  }
+  if world != "hello" {
+    libc.puts(cstr("done"));
+  }
+  return 0;
+}
@@ -0,0 +1,29 @@
+fn factorial(n int) int {
+  if n == 0 {
+    return 1;
+  }
+  return n * factorial(n - 1);
+}
+
+fn main() i32 {
+  return 0;
+}
+
+test "factorial of zero" {
+  assert(factorial(0) == 1);
+}
+
+test "factorial of five" {
+  assert(factorial(5) == 120);
+}
+
+test "early return" {
+  if factorial(1) == 1 {
+    return;
+  }
+  assert(false);
+}
+
+bench "factorial of ten" {
+  factorial(10);
+}
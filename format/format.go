@@ -0,0 +1,660 @@
+// Package format prints source code in the canonical style, used by
+// "telia fmt".
+//
+// The canonical style indents blocks with two spaces, puts spaces around
+// binary operators and after commas, prints one declaration per line inside
+// extern blocks and separates top-level blocks, such as functions, with a
+// blank line. Blank lines between statements are kept, but never more than
+// one in a row. Comments are kept close to the code they were written next
+// to.
+package format
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+	"github.com/HicaroD/Telia/frontend/parser"
+)
+
+const INDENT = "  "
+
+// Formats the source code of a single file. Syntax errors are reported to the
+// collector.
+func Source(path string, src []byte, collector *diagnostics.Collector) ([]byte, error) {
+	program, err := parser.New(collector).ParseFileAsProgram(lexer.New(path, src, collector))
+	if err != nil {
+		return nil, err
+	}
+
+	// The parser never sees comments, so they are read on a separate pass
+	lex := lexer.New(path, src, collector)
+	lex.KeepComments = true
+	tokens, err := lex.Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	var comments []*token.Token
+	for _, tok := range tokens {
+		if tok.Kind == token.COMMENT {
+			comments = append(comments, tok)
+		}
+	}
+
+	return File(program.Root.Files[0], comments), nil
+}
+
+// Prints a parsed file in the canonical style. Comments are the COMMENT tokens
+// of the file, in the order they appear on the source code.
+func File(file *ast.File, comments []*token.Token) []byte {
+	p := &printer{comments: comments, first: true}
+	p.file(file)
+	return p.buf.Bytes()
+}
+
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+
+	comments []*token.Token
+	next     int // index of the next comment to be printed
+
+	lastLine     int  // line of the source code where the last printed item ends
+	afterComment bool // true if the last printed item is a comment on its own line
+	first        bool // true if the next item is the first one of its block
+	blank        bool // true if the next item must be preceded by a blank line
+}
+
+func (p *printer) write(s string) {
+	p.buf.WriteString(s)
+}
+
+// Starts the line of an item, such as a declaration, a statement or a
+// comment, that begins at the given line of the source code. A blank line is
+// kept before the item if there was one on the source code.
+func (p *printer) startLine(line int) {
+	if !p.first && (p.blank || line > p.lastLine+1) {
+		p.write("\n")
+	}
+	p.first = false
+	p.blank = false
+	p.write(strings.Repeat(INDENT, p.indent))
+}
+
+// Prints, each one on its own line, the comments that come before pos
+func (p *printer) commentsBefore(pos token.Pos) {
+	for p.next < len(p.comments) && before(p.comments[p.next].Pos, pos) {
+		comment := p.comments[p.next]
+		p.startLine(comment.Pos.Line)
+		p.write(commentText(comment))
+		p.write("\n")
+		p.lastLine = comment.Pos.Line
+		p.afterComment = true
+		p.next++
+	}
+}
+
+// Ends the line of an item that ends at end on the source code. A comment
+// written right after the item, on the same line and before limit, is kept on
+// the same line.
+func (p *printer) endLine(end, limit token.Pos) {
+	if p.next < len(p.comments) {
+		comment := p.comments[p.next]
+		if comment.Pos.Line == end.Line && !before(comment.Pos, end) && before(comment.Pos, limit) {
+			p.write(" ")
+			p.write(commentText(comment))
+			p.next++
+		}
+	}
+	p.write("\n")
+	p.lastLine = end.Line
+	p.afterComment = false
+}
+
+func (p *printer) hasCommentsBefore(pos token.Pos) bool {
+	return p.next < len(p.comments) && before(p.comments[p.next].Pos, pos)
+}
+
+func before(a, b token.Pos) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func commentText(comment *token.Token) string {
+	return strings.TrimRight(string(comment.Lexeme), " \t\r")
+}
+
+// Returns the position where the item after the i-th one starts, or limit if
+// it is the last one
+func nextStart[T ast.Node](items []T, i int, limit token.Pos) token.Pos {
+	if i+1 < len(items) {
+		return items[i+1].Span().Start
+	}
+	return limit
+}
+
+func (p *printer) file(file *ast.File) {
+	eof := file.Span().End
+	for i, decl := range file.Body {
+		span := decl.Span()
+
+		// Blocks, such as functions, are always surrounded by blank lines,
+		// unless a comment is attached right above them
+		if isBlockDecl(decl) && !p.afterComment {
+			p.blank = true
+		}
+		p.commentsBefore(span.Start)
+		p.startLine(span.Start.Line)
+		p.decl(decl)
+		p.endLine(span.End, nextStart(file.Body, i, eof))
+		if isBlockDecl(decl) {
+			p.blank = true
+		}
+	}
+	p.commentsBefore(eof)
+}
+
+func isBlockDecl(decl ast.Node) bool {
+	switch decl.(type) {
+	case *ast.FunctionDecl, *ast.ExternDecl, *ast.TestDecl, *ast.BenchDecl:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *printer) decl(decl ast.Node) {
+	switch decl := decl.(type) {
+	case *ast.FunctionDecl:
+		if len(decl.Attributes) > 0 {
+			p.attributes(decl.Attributes)
+			p.write("\n")
+			p.write(strings.Repeat(INDENT, p.indent))
+		}
+		p.write("fn ")
+		p.write(decl.Name.Name())
+		p.signature(decl.Params, decl.RetType)
+		p.write(" ")
+		p.block(decl.Block)
+	case *ast.ExternDecl:
+		p.write("extern ")
+		p.write(decl.Name.Name())
+		p.write(" ")
+		if decl.Link != nil {
+			p.write("link ")
+			p.write(quote(decl.Link))
+			p.write(" ")
+		}
+		p.extern(decl)
+	case *ast.TestDecl:
+		p.write("test ")
+		p.write(quote(decl.Name))
+		p.write(" ")
+		p.block(decl.Block)
+	case *ast.BenchDecl:
+		p.write("bench ")
+		p.write(quote(decl.Name))
+		p.write(" ")
+		p.block(decl.Block)
+	case *ast.ConstDecl, *ast.StaticAssert:
+		p.stmt(decl.(ast.Stmt))
+	}
+}
+
+func (p *printer) extern(extern *ast.ExternDecl) {
+	// Extern blocks end with "}"
+	closeCurly := extern.Span().End
+	closeCurly.Column--
+
+	items := externItems(extern)
+	if len(items) == 0 && !p.hasCommentsBefore(closeCurly) {
+		p.write("{}")
+		return
+	}
+
+	p.write("{\n")
+	p.indent++
+	p.first = true
+	p.lastLine = extern.Name.Pos.Line
+	for i, item := range items {
+		span := item.Span()
+		p.commentsBefore(span.Start)
+		p.startLine(span.Start.Line)
+		switch item := item.(type) {
+		case *ast.Proto:
+			if len(item.Attributes) > 0 {
+				p.attributes(item.Attributes)
+				p.write(" ")
+			}
+			p.write("fn ")
+			p.write(item.Name.Name())
+			p.signature(item.Params, item.RetType)
+			p.alias(item.Alias)
+		case *ast.ExternVar:
+			p.write(item.Name.Name())
+			p.write(" ")
+			p.exprType(item.Type)
+			p.alias(item.Alias)
+		}
+		p.write(";")
+		p.endLine(span.End, nextStart(items, i, closeCurly))
+	}
+	p.commentsBefore(closeCurly)
+	p.indent--
+	p.write(strings.Repeat(INDENT, p.indent))
+	p.write("}")
+}
+
+func (p *printer) alias(alias *token.Token) {
+	if alias != nil {
+		p.write(" = ")
+		p.write(quote(alias))
+	}
+}
+
+// Prototypes and variables of an extern block in the order they were written
+func externItems(extern *ast.ExternDecl) []ast.Node {
+	items := make([]ast.Node, 0, len(extern.Prototypes)+len(extern.Vars))
+	protos, vars := extern.Prototypes, extern.Vars
+	for len(protos) > 0 || len(vars) > 0 {
+		if len(vars) == 0 || (len(protos) > 0 && before(protos[0].Span().Start, vars[0].Span().Start)) {
+			items = append(items, protos[0])
+			protos = protos[1:]
+		} else {
+			items = append(items, vars[0])
+			vars = vars[1:]
+		}
+	}
+	return items
+}
+
+func (p *printer) attributes(attributes []*ast.Attribute) {
+	for i, attribute := range attributes {
+		if i > 0 {
+			p.write(" ")
+		}
+		p.write("@")
+		p.write(attribute.Name.Name())
+		if len(attribute.Args) > 0 {
+			p.write("(")
+			p.exprList(attribute.Args)
+			p.write(")")
+		}
+	}
+}
+
+func (p *printer) signature(params *ast.FieldList, retType ast.ExprType) {
+	p.write("(")
+	for i, field := range params.Fields {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(field.Name.Name())
+		p.write(" ")
+		p.exprType(field.Type)
+	}
+	if params.IsVariadic {
+		if len(params.Fields) > 0 {
+			p.write(", ")
+		}
+		p.write("...")
+	}
+	p.write(")")
+
+	if basic, ok := retType.(*ast.BasicType); ok && basic.IsVoid() {
+		return
+	}
+	p.write(" ")
+	p.exprType(retType)
+}
+
+func (p *printer) block(block *ast.BlockStmt) {
+	if len(block.Statements) == 0 && !p.hasCommentsBefore(block.CloseCurly) {
+		p.write("{}")
+		return
+	}
+
+	p.write("{\n")
+	p.indent++
+	p.first = true
+	p.lastLine = block.OpenCurly.Line
+	for i, stmt := range block.Statements {
+		span := stmt.Span()
+		p.commentsBefore(span.Start)
+		p.startLine(span.Start.Line)
+		p.stmt(stmt)
+		p.endLine(span.End, nextStart(block.Statements, i, block.CloseCurly))
+	}
+	p.commentsBefore(block.CloseCurly)
+	p.indent--
+	p.write(strings.Repeat(INDENT, p.indent))
+	p.write("}")
+}
+
+// Prints a statement, including its semicolon
+func (p *printer) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.CondStmt:
+		p.write("if ")
+		p.cond(stmt.IfStmt)
+		for _, elif := range stmt.ElifStmts {
+			p.write(" elif ")
+			p.cond(elif)
+		}
+		if stmt.ElseStmt != nil {
+			p.write(" else ")
+			p.block(stmt.ElseStmt.Block)
+		}
+	case *ast.ForLoop:
+		p.write("for (")
+		p.simpleStmt(stmt.Init)
+		p.write("; ")
+		p.expr(stmt.Cond, 0)
+		p.write("; ")
+		p.simpleStmt(stmt.Update)
+		p.write(") ")
+		p.block(stmt.Block)
+	case *ast.RangeForLoop:
+		p.write("for ")
+		if stmt.Index != nil {
+			p.write(stmt.Index.Name.Name())
+			p.write(", ")
+		}
+		p.write(stmt.Value.Name.Name())
+		p.write(" in ")
+		p.expr(stmt.Iterable, 0)
+		p.write(" ")
+		p.block(stmt.Block)
+	case *ast.WhileLoop:
+		p.write("while ")
+		p.expr(stmt.Cond, 0)
+		p.write(" ")
+		p.block(stmt.Block)
+	case *ast.CatchStmt:
+		p.expr(stmt.Value, 0)
+		p.write(" catch ")
+		p.block(stmt.Block)
+	default:
+		p.simpleStmt(stmt)
+		p.write(";")
+	}
+}
+
+// Prints a statement without its semicolon, such as the ones on the header of
+// for loops
+func (p *printer) simpleStmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.VarStmt:
+		p.variable(stmt)
+		if stmt.Value == nil {
+			return
+		}
+		p.assignOp(stmt.Decl)
+		p.expr(stmt.Value, 0)
+	case *ast.MultiVarStmt:
+		for i, variable := range stmt.Variables {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.variable(variable)
+		}
+		p.assignOp(stmt.IsDecl)
+		if stmt.Tuple != nil {
+			p.expr(stmt.Tuple, 0)
+			return
+		}
+		for i, variable := range stmt.Variables {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(variable.Value, 0)
+		}
+	case *ast.ReturnStmt:
+		p.write("return")
+		if !stmt.Value.IsVoid() {
+			p.write(" ")
+			p.expr(stmt.Value, 0)
+		}
+	case *ast.AssertStmt:
+		p.write("assert(")
+		p.expr(stmt.Cond, 0)
+		p.write(")")
+	case *ast.ConstDecl:
+		p.write("const ")
+		p.write(stmt.Name.Name())
+		if stmt.Type != nil {
+			p.write(" ")
+			p.exprType(stmt.Type)
+		}
+		p.write(" = ")
+		p.expr(stmt.Value, 0)
+	case *ast.StaticAssert:
+		p.write("static_assert(")
+		p.expr(stmt.Cond, 0)
+		p.write(", ")
+		p.write(quote(stmt.Message))
+		p.write(")")
+	case *ast.FunctionCall:
+		p.expr(stmt, 0)
+	case *ast.FieldAccess:
+		p.expr(stmt, 0)
+	case *ast.TryExpr:
+		p.expr(stmt, 0)
+	}
+}
+
+func (p *printer) variable(variable *ast.VarStmt) {
+	p.write(variable.Name.Name())
+	if variable.Type != nil {
+		p.write(" ")
+		p.exprType(variable.Type)
+	}
+}
+
+func (p *printer) assignOp(isDecl bool) {
+	if isDecl {
+		p.write(" := ")
+	} else {
+		p.write(" = ")
+	}
+}
+
+func (p *printer) cond(cond *ast.IfElifCond) {
+	if cond.Unwrap != nil {
+		p.write(cond.Unwrap.Name.Name())
+		p.write(" := ")
+	}
+	p.expr(cond.Expr, PREC_LOWEST)
+	p.write(" ")
+	p.block(cond.Block)
+}
+
+// Precedence of each expression, from the loosest to the tightest binding,
+// following the parser
+const (
+	PREC_LOWEST = iota
+	PREC_ORELSE
+	PREC_LOGICAL
+	PREC_COMPARASION
+	PREC_TERM
+	PREC_FACTOR
+	PREC_CAST
+	PREC_UNARY
+	PREC_PRIMARY
+)
+
+func precedence(expr ast.Expr) int {
+	switch expr := expr.(type) {
+	case *ast.BinaryExpr:
+		switch {
+		case expr.Op == token.ORELSE, expr.Op == token.CATCH:
+			return PREC_ORELSE
+		case ast.LOGICAL[expr.Op]:
+			return PREC_LOGICAL
+		case ast.COMPARASION[expr.Op]:
+			return PREC_COMPARASION
+		case ast.TERM[expr.Op]:
+			return PREC_TERM
+		default:
+			return PREC_FACTOR
+		}
+	case *ast.CastExpr:
+		return PREC_CAST
+	case *ast.UnaryExpr, *ast.TryExpr:
+		return PREC_UNARY
+	case *ast.RangeExpr, *ast.TupleExpr:
+		return PREC_LOWEST
+	default:
+		return PREC_PRIMARY
+	}
+}
+
+// Prints an expression, wrapping it on parentheses if it binds looser than
+// prec. Parentheses are not part of the AST, so only the required ones are
+// printed.
+func (p *printer) expr(expr ast.Expr, prec int) {
+	if precedence(expr) < prec {
+		p.write("(")
+		p.expr(expr, PREC_LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch expr := expr.(type) {
+	case *ast.LiteralExpr:
+		if basic, ok := expr.Type.(*ast.BasicType); ok && basic.Kind == token.STRING_LITERAL {
+			p.write("\"" + string(expr.Value) + "\"")
+			return
+		}
+		p.write(string(expr.Value))
+	case *ast.IdExpr:
+		p.write(expr.Name.Name())
+	case *ast.FieldAccess:
+		p.expr(expr.Left, PREC_PRIMARY)
+		p.write(".")
+		p.expr(expr.Right, PREC_PRIMARY)
+	case *ast.UnaryExpr:
+		p.write(expr.Op.String())
+		if expr.Op == token.NOT {
+			p.write(" ")
+		}
+		// Keeps "-(-1)" from being printed as "--1"
+		if value, ok := expr.Value.(*ast.UnaryExpr); ok && value.Op == expr.Op && expr.Op != token.NOT {
+			p.write("(")
+			p.expr(expr.Value, PREC_LOWEST)
+			p.write(")")
+			return
+		}
+		p.expr(expr.Value, PREC_UNARY)
+	case *ast.TryExpr:
+		p.write("try ")
+		p.expr(expr.Value, PREC_UNARY)
+	case *ast.TypeExpr:
+		p.exprType(expr.Type)
+	case *ast.CastExpr:
+		p.expr(expr.Value, PREC_CAST)
+		p.write(" as ")
+		p.exprType(expr.Type)
+	case *ast.ErrorExpr:
+		p.write("error.")
+		p.write(expr.Name.Name())
+	case *ast.BinaryExpr:
+		// Binary expressions are left-associative, except for "orelse" and
+		// "catch"
+		prec := precedence(expr)
+		leftPrec, rightPrec := prec, prec+1
+		if prec == PREC_ORELSE {
+			leftPrec, rightPrec = prec+1, prec
+		}
+		p.expr(expr.Left, leftPrec)
+		p.write(" ")
+		p.write(expr.Op.String())
+		p.write(" ")
+		p.expr(expr.Right, rightPrec)
+	case *ast.FunctionCall:
+		p.write(expr.Name.Name())
+		p.write("(")
+		p.exprList(expr.Args)
+		p.write(")")
+	case *ast.TupleExpr:
+		p.exprList(expr.Exprs)
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.exprList(expr.Values)
+		p.write("]")
+	case *ast.IndexExpr:
+		p.expr(expr.Value, PREC_PRIMARY)
+		p.write("[")
+		p.expr(expr.Index, PREC_LOWEST)
+		p.write("]")
+	case *ast.RangeExpr:
+		if expr.Start != nil {
+			p.expr(expr.Start, PREC_LOGICAL)
+		}
+		if expr.Inclusive {
+			p.write("...")
+		} else {
+			p.write("..")
+		}
+		if expr.End != nil {
+			p.expr(expr.End, PREC_LOGICAL)
+		}
+	case *ast.VoidExpr:
+	}
+}
+
+func (p *printer) exprList(exprs []ast.Expr) {
+	for i, expr := range exprs {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(expr, PREC_ORELSE)
+	}
+}
+
+func (p *printer) exprType(ty ast.ExprType) {
+	switch ty := ty.(type) {
+	case *ast.BasicType:
+		p.write(ty.Kind.String())
+	case *ast.IdType:
+		p.write(ty.Name.Name())
+	case *ast.PointerType:
+		p.write("*")
+		p.exprType(ty.Type)
+	case *ast.TupleType:
+		p.write("(")
+		for i, elem := range ty.Types {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.exprType(elem)
+		}
+		p.write(")")
+	case *ast.ArrayType:
+		p.write("[")
+		if ty.LenExpr != nil {
+			p.expr(ty.LenExpr, PREC_LOWEST)
+		} else {
+			p.write(strconv.Itoa(ty.Len))
+		}
+		p.write("]")
+		p.exprType(ty.Type)
+	case *ast.SliceType:
+		p.write("[]")
+		p.exprType(ty.Type)
+	case *ast.OptionalType:
+		p.write("?")
+		p.exprType(ty.Type)
+	case *ast.ErrorUnionType:
+		p.write("!")
+		p.exprType(ty.Type)
+	}
+}
+
+// Returns a string literal token as written on the source code
+func quote(literal *token.Token) string {
+	return "\"" + string(literal.Lexeme) + "\""
+}
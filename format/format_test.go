@@ -0,0 +1,126 @@
+package format
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HicaroD/Telia/diagnostics"
+)
+
+type formatTest struct {
+	input    string
+	expected string
+}
+
+func TestFormat(t *testing.T) {
+	tests := []formatTest{
+		{
+			input:    "fn main() i32 {return 0;}",
+			expected: "fn main() i32 {\n  return 0;\n}\n",
+		},
+		{
+			input:    "fn f(a int,b int)int{\nreturn (a+b)*2-(a-b);\n}",
+			expected: "fn f(a int, b int) int {\n  return (a + b) * 2 - (a - b);\n}\n",
+		},
+		{
+			input:    "fn f() bool { return ((a)) and (b or c) and not (d == e); }",
+			expected: "fn f() bool {\n  return a and (b or c) and not (d == e);\n}\n",
+		},
+		{
+			input:    "extern libc { fn puts(s *u8) i32; @cold fn abort(); fn printf(format *u8, ...) i32; }",
+			expected: "extern libc {\n  fn puts(s *u8) i32;\n  @cold fn abort();\n  fn printf(format *u8, ...) i32;\n}\n",
+		},
+		{
+			input:    "extern m link \"m\" { fn sqrt(x u64) u64 = \"sqrtl\"; err *u8=\"stderr\"; fn abort()=\"abort\"; stdout *u8; }",
+			expected: "extern m link \"m\" {\n  fn sqrt(x u64) u64 = \"sqrtl\";\n  err *u8 = \"stderr\";\n  fn abort() = \"abort\";\n  stdout *u8;\n}\n",
+		},
+		{
+			input:    "// header\n\n\n\nfn f() {\n  a := 1; // trailing\n\n\n  // above b\n  b := 2;\n}\nfn g() {}",
+			expected: "// header\n\nfn f() {\n  a := 1; // trailing\n\n  // above b\n  b := 2;\n}\n\nfn g() {}\n",
+		},
+		{
+			input:    "fn f() {\n  if a {b();}\n  elif c {}\n  else {d:=\"x\\n\";}\n}",
+			expected: "fn f() {\n  if a {\n    b();\n  } elif c {} else {\n    d := \"x\\n\";\n  }\n}\n",
+		},
+		{
+			input:    "fn f(a ?*u8) int {if s:=a {return 1;} return (a orelse nil) == nil;}",
+			expected: "fn f(a ?*u8) int {\n  if s := a {\n    return 1;\n  }\n  return (a orelse nil) == nil;\n}\n",
+		},
+		{
+			input:    "fn f(a ?int,b ?int) int {return a orelse (b orelse 0);}",
+			expected: "fn f(a ?int, b ?int) int {\n  return a orelse b orelse 0;\n}\n",
+		},
+		{
+			input:    "fn f(a int) !void {try g(a);if a>1 {return error.TooBig;} return;}\nfn h() int { return (try f(1) catch 0) + 1; }",
+			expected: "fn f(a int) !void {\n  try g(a);\n  if a > 1 {\n    return error.TooBig;\n  }\n  return;\n}\n\nfn h() int {\n  return (try f(1) catch 0) + 1;\n}\n",
+		},
+		{
+			input:    "fn h() int { f(1) catch {return 1;} return 0; }",
+			expected: "fn h() int {\n  f(1) catch {\n    return 1;\n  }\n  return 0;\n}\n",
+		},
+		{
+			input:    "fn f(c bool) int {x int;if c {x=1;} else {x=2;} return x;}",
+			expected: "fn f(c bool) int {\n  x int;\n  if c {\n    x = 1;\n  } else {\n    x = 2;\n  }\n  return x;\n}\n",
+		},
+		{
+			input:    "fn f(a int,p *u8) u8 {q:=p as *i8; return (a+1) as u8*-a as u8;}",
+			expected: "fn f(a int, p *u8) u8 {\n  q := p as *i8;\n  return (a + 1) as u8 * -a as u8;\n}\n",
+		},
+		{
+			input:    "fn f() int {return sizeof([4]?*u8)+offsetof( (u8,int),1 );}",
+			expected: "fn f() int {\n  return sizeof([4]?*u8) + offsetof((u8, int), 1);\n}\n",
+		},
+		{
+			input:    "fn g() int {return 1;}\nfn f() int {return g();}",
+			expected: "fn g() int {\n  return 1;\n}\n\nfn f() int {\n  return g();\n}\n",
+		},
+		{
+			input:    "fn f(a int) int {b:=-(-1);return -(-a)+ -b+not not true;}",
+			expected: "fn f(a int) int {\n  b := -(-1);\n  return -(-a) + -b + not not true;\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestFormat('%s')", test.input), func(t *testing.T) {
+			got, err := Source("test.tt", []byte(test.input), diagnostics.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.expected {
+				t.Fatalf("\nexp: %q\ngot: %q\n", test.expected, got)
+			}
+
+			again, err := Source("test.tt", got, diagnostics.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(got) {
+				t.Fatalf("formatting is not idempotent\nfirst: %q\nsecond: %q\n", got, again)
+			}
+		})
+	}
+}
+
+func TestExamplesAreFormatted(t *testing.T) {
+	paths, err := filepath.Glob("../examples/*.t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Source(path, src, diagnostics.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(src) {
+				t.Fatalf("\nexp: %q\ngot: %q\n", src, got)
+			}
+		})
+	}
+}
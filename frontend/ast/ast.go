@@ -2,5 +2,6 @@
 package ast
 
 type Node interface {
+	Span() Span
 	astNode()
 }
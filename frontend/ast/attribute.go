@@ -0,0 +1,33 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Attribute attached to a declaration, such as "@inline" or
+// "@export("name")"
+type Attribute struct {
+	Loc  Span
+	At   token.Pos
+	Name *token.Token
+	Args []Expr
+}
+
+func (attribute Attribute) astNode()   {}
+func (attribute Attribute) Span() Span { return attribute.Loc }
+
+func (attribute Attribute) String() string {
+	return fmt.Sprintf("@%s%s", attribute.Name.Name(), attribute.Args)
+}
+
+// Returns the first attribute with the given name or nil if there is none
+func FindAttribute(attributes []*Attribute, name string) *Attribute {
+	for _, attribute := range attributes {
+		if attribute.Name.Name() == name {
+			return attribute
+		}
+	}
+	return nil
+}
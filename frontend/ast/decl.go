@@ -13,6 +13,8 @@ type Decl interface {
 
 type FunctionDecl struct {
 	Decl
+	Loc         Span
+	Attributes  []*Attribute
 	Scope       *Scope
 	Name        *token.Token
 	Params      *FieldList
@@ -23,7 +25,8 @@ type FunctionDecl struct {
 
 func (fnDecl FunctionDecl) String() string {
 	return fmt.Sprintf(
-		"Scope: %s\nName: %s\nParams: %s\nRetType: %s\nBlock: %s\n",
+		"Attributes: %s\nScope: %s\nName: %s\nParams: %s\nRetType: %s\nBlock: %s\n",
+		fnDecl.Attributes,
 		fnDecl.Scope,
 		fnDecl.Name,
 		fnDecl.Params,
@@ -31,32 +34,166 @@ func (fnDecl FunctionDecl) String() string {
 		fnDecl.Block,
 	)
 }
-func (fnDecl FunctionDecl) astNode()  {}
-func (fnDecl FunctionDecl) declNode() {}
+func (fnDecl FunctionDecl) astNode()   {}
+func (fnDecl FunctionDecl) Span() Span { return fnDecl.Loc }
+func (fnDecl FunctionDecl) declNode()  {}
 
+// Exported functions use the symbol name given by "@export("name")"
+func (fnDecl *FunctionDecl) Symbol() string {
+	export := FindAttribute(fnDecl.Attributes, "export")
+	if export == nil {
+		return fnDecl.Name.Name()
+	}
+	// Sema guarantees the argument is a string literal
+	return string(export.Args[0].(*LiteralExpr).Value)
+}
+
+// External declarations, such as 'extern m link "m" { fn sqrt(x u64) u64; }'.
+// The name is only a namespace, the library is the one given by "link", if
+// any.
 type ExternDecl struct {
 	Decl
+	Loc         Span
 	Scope       *Scope
 	Name        *token.Token
+	Link        *token.Token // string literal
 	Prototypes  []*Proto
+	Vars        []*ExternVar
 	BackendType any // LLVM: *values.Extern
 }
 
 func (extern ExternDecl) String() string {
 	return fmt.Sprintf("EXTERN: %s", extern.Name)
 }
-func (extern ExternDecl) astNode()  {}
-func (extern ExternDecl) declNode() {}
+func (extern ExternDecl) astNode()   {}
+func (extern ExternDecl) Span() Span { return extern.Loc }
+func (extern ExternDecl) declNode()  {}
+
+// Test block, such as 'test "parses header" { ... }'. Tests are only
+// compiled by "telia test".
+type TestDecl struct {
+	Decl
+	Loc         Span
+	Test        *token.Token
+	Name        *token.Token // string literal
+	Scope       *Scope
+	Block       *BlockStmt
+	BackendType any // LLVM: *values.Function
+}
+
+func (test TestDecl) String() string {
+	return fmt.Sprintf("TEST: %s %s", test.Name, test.Block)
+}
+func (test TestDecl) astNode()   {}
+func (test TestDecl) Span() Span { return test.Loc }
+func (test TestDecl) declNode()  {}
+
+// Benchmark block, such as 'bench "parses header" { ... }'. Benchmarks are
+// only compiled by "telia test --bench".
+type BenchDecl struct {
+	Decl
+	Loc         Span
+	Bench       *token.Token
+	Name        *token.Token // string literal
+	Scope       *Scope
+	Block       *BlockStmt
+	BackendType any // LLVM: *values.Function
+}
+
+func (bench BenchDecl) String() string {
+	return fmt.Sprintf("BENCH: %s %s", bench.Name, bench.Block)
+}
+func (bench BenchDecl) astNode()   {}
+func (bench BenchDecl) Span() Span { return bench.Loc }
+func (bench BenchDecl) declNode()  {}
+
+// Constant declaration, such as "const N = 10;", allowed both on global scope
+// and inside blocks. After semantic analysis, Value holds the folded value of
+// the initializer.
+type ConstDecl struct {
+	Decl
+	Stmt
+	Loc   Span
+	Const *token.Token
+	Name  *token.Token
+	Type  ExprType
+	Value Expr
+}
+
+func (constDecl ConstDecl) String() string {
+	return fmt.Sprintf("CONST: %s %s = %s", constDecl.Name, constDecl.Type, constDecl.Value)
+}
+func (constDecl ConstDecl) IsReturn() bool { return false }
+func (constDecl ConstDecl) astNode()       {}
+func (constDecl ConstDecl) Span() Span     { return constDecl.Loc }
+func (constDecl ConstDecl) declNode()      {}
+func (constDecl ConstDecl) stmtNode()      {}
+
+// Assertion checked at compile time, such as 'static_assert(N > 0, "msg");',
+// allowed both on global scope and inside blocks
+type StaticAssert struct {
+	Decl
+	Stmt
+	Loc          Span
+	StaticAssert *token.Token
+	Cond         Expr
+	Message      *token.Token // string literal
+}
+
+func (staticAssert StaticAssert) String() string {
+	return fmt.Sprintf("STATIC_ASSERT: %s %s", staticAssert.Cond, staticAssert.Message)
+}
+func (staticAssert StaticAssert) IsReturn() bool { return false }
+func (staticAssert StaticAssert) astNode()       {}
+func (staticAssert StaticAssert) Span() Span     { return staticAssert.Loc }
+func (staticAssert StaticAssert) declNode()      {}
+func (staticAssert StaticAssert) stmtNode()      {}
 
 // NOTE: Proto implementing AstNode is temporary
 type Proto struct {
 	Node
-	Name    *token.Token
-	Params  *FieldList
-	RetType ExprType
+	Loc        Span
+	Attributes []*Attribute
+	Name       *token.Token
+	Params     *FieldList
+	RetType    ExprType
+	Alias      *token.Token // string literal, such as "puts" on 'fn print(s *u8) i32 = "puts";'
 
 	BackendType any // LLVM: *values.Function
 }
 
+// Global variable of a C library, such as "stderr *u8;" or
+// 'err *u8 = "stderr";' inside of an extern block
+type ExternVar struct {
+	Node
+	Loc   Span
+	Name  *token.Token
+	Type  ExprType
+	Alias *token.Token // string literal
+
+	BackendType any // LLVM: *values.Variable
+}
+
+func (variable ExternVar) String() string { return fmt.Sprintf("EXTERN VAR: %s", variable.Name) }
+func (variable ExternVar) astNode()       {}
+func (variable ExternVar) Span() Span     { return variable.Loc }
+
+// Returns the name of the symbol, which is the alias if there is one
+func (variable *ExternVar) Symbol() string {
+	if variable.Alias != nil {
+		return string(variable.Alias.Lexeme)
+	}
+	return variable.Name.Name()
+}
+
 func (proto Proto) String() string { return fmt.Sprintf("PROTO: %s", proto.Name) }
 func (proto Proto) astNode()       {}
+func (proto Proto) Span() Span     { return proto.Loc }
+
+// Returns the name of the symbol, which is the alias if there is one
+func (proto *Proto) Symbol() string {
+	if proto.Alias != nil {
+		return string(proto.Alias.Lexeme)
+	}
+	return proto.Name.Name()
+}
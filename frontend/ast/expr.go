@@ -26,8 +26,9 @@ var TERM map[token.Kind]bool = map[token.Kind]bool{
 }
 
 var FACTOR map[token.Kind]bool = map[token.Kind]bool{
-	token.SLASH: true,
-	token.STAR:  true,
+	token.SLASH:   true,
+	token.STAR:    true,
+	token.PERCENT: true,
 }
 
 var UNARY map[token.Kind]bool = map[token.Kind]bool{
@@ -46,6 +47,7 @@ type Expr interface {
 // Used on empty return
 type VoidExpr struct {
 	Expr
+	Loc Span
 }
 
 func (void VoidExpr) String() string {
@@ -55,9 +57,11 @@ func (void VoidExpr) IsId() bool          { return false }
 func (void VoidExpr) IsVoid() bool        { return true }
 func (void VoidExpr) IsFieldAccess() bool { return false }
 func (void VoidExpr) exprNode()           {}
+func (void VoidExpr) Span() Span          { return void.Loc }
 
 type LiteralExpr struct {
 	Expr
+	Loc   Span
 	Type  ExprType
 	Value []byte
 }
@@ -69,9 +73,11 @@ func (literal LiteralExpr) IsId() bool          { return false }
 func (literal LiteralExpr) IsVoid() bool        { return false }
 func (literal LiteralExpr) IsFieldAccess() bool { return false }
 func (literal LiteralExpr) exprNode()           {}
+func (literal LiteralExpr) Span() Span          { return literal.Loc }
 
 type IdExpr struct {
 	Expr
+	Loc  Span
 	Name *token.Token
 }
 
@@ -83,10 +89,12 @@ func (idExpr IdExpr) IsId() bool          { return true }
 func (idExpr IdExpr) IsVoid() bool        { return false }
 func (idExpr IdExpr) IsFieldAccess() bool { return false }
 func (idExpr IdExpr) exprNode()           {}
+func (idExpr IdExpr) Span() Span          { return idExpr.Loc }
 
 type FieldAccess struct {
 	Stmt
 	Expr
+	Loc   Span
 	Left  Expr
 	Right Expr
 }
@@ -95,14 +103,17 @@ func (fieldAccess FieldAccess) String() string {
 	return fmt.Sprintf("%s.%s", fieldAccess.Left, fieldAccess.Right)
 }
 func (fieldAccess FieldAccess) IsId() bool          { return false }
+func (fieldAccess FieldAccess) IsVoid() bool        { return false }
 func (fieldAccess FieldAccess) IsReturn() bool      { return false }
 func (fieldAccess FieldAccess) IsFieldAccess() bool { return true }
 func (fieldAccess FieldAccess) astNode()            {}
+func (fieldAccess FieldAccess) Span() Span          { return fieldAccess.Loc }
 func (fieldAccess FieldAccess) stmtNode()           {}
 func (fieldAccess FieldAccess) exprNode()           {}
 
 type UnaryExpr struct {
 	Expr
+	Loc   Span
 	Op    token.Kind
 	Value Expr
 }
@@ -114,12 +125,17 @@ func (unary UnaryExpr) IsId() bool          { return false }
 func (unary UnaryExpr) IsVoid() bool        { return false }
 func (unary UnaryExpr) IsFieldAccess() bool { return false }
 func (unary UnaryExpr) exprNode()           {}
+func (unary UnaryExpr) Span() Span          { return unary.Loc }
 
 type BinaryExpr struct {
 	Expr
+	Loc   Span
 	Left  Expr
 	Op    token.Kind
 	Right Expr
+
+	// Type of both operands, set by semantic analysis
+	OperandType ExprType
 }
 
 func (binExpr BinaryExpr) String() string {
@@ -129,3 +145,213 @@ func (binExpr BinaryExpr) IsId() bool          { return false }
 func (binExpr BinaryExpr) IsVoid() bool        { return false }
 func (binExpr BinaryExpr) IsFieldAccess() bool { return false }
 func (binExpr BinaryExpr) exprNode()           {}
+func (binExpr BinaryExpr) Span() Span          { return binExpr.Loc }
+
+// Used on multiple return values, such as "return q, r;"
+type TupleExpr struct {
+	Expr
+	Loc   Span
+	Exprs []Expr
+	Type  ExprType
+}
+
+func (tuple TupleExpr) String() string {
+	return fmt.Sprintf("TUPLE: %s", tuple.Exprs)
+}
+func (tuple TupleExpr) IsId() bool          { return false }
+func (tuple TupleExpr) IsVoid() bool        { return false }
+func (tuple TupleExpr) IsFieldAccess() bool { return false }
+func (tuple TupleExpr) exprNode()           {}
+func (tuple TupleExpr) Span() Span          { return tuple.Loc }
+
+// Array literal, such as "[1, 2, 3]"
+type ArrayLiteral struct {
+	Expr
+	Loc    Span
+	Open   token.Pos
+	Values []Expr
+	Type   ExprType
+}
+
+func (array ArrayLiteral) String() string {
+	return fmt.Sprintf("ARRAY: %s", array.Values)
+}
+func (array ArrayLiteral) IsId() bool          { return false }
+func (array ArrayLiteral) IsVoid() bool        { return false }
+func (array ArrayLiteral) IsFieldAccess() bool { return false }
+func (array ArrayLiteral) exprNode()           {}
+func (array ArrayLiteral) Span() Span          { return array.Loc }
+
+// Used on indexing, such as "arr[i]", and slicing, such as "arr[1..3]". When
+// slicing, the index is a *RangeExpr.
+type IndexExpr struct {
+	Expr
+	Loc   Span
+	Value Expr
+	Open  token.Pos
+	Index Expr
+	Type  ExprType
+}
+
+func (index IndexExpr) String() string {
+	return fmt.Sprintf("%s[%s]", index.Value, index.Index)
+}
+func (index IndexExpr) IsId() bool          { return false }
+func (index IndexExpr) IsVoid() bool        { return false }
+func (index IndexExpr) IsFieldAccess() bool { return false }
+func (index IndexExpr) exprNode()           {}
+func (index IndexExpr) Span() Span          { return index.Loc }
+
+// Used on range-based for loops and slicing, such as "0..10" (exclusive) or
+// "0...10" (inclusive). Start and End can be nil when slicing, such as
+// "arr[..3]".
+type RangeExpr struct {
+	Expr
+	Loc       Span
+	Start     Expr
+	End       Expr
+	Inclusive bool
+}
+
+func (rangeExpr RangeExpr) String() string {
+	op := token.DOT_DOT
+	if rangeExpr.Inclusive {
+		op = token.DOT_DOT_DOT
+	}
+	return fmt.Sprintf("%s%s%s", rangeExpr.Start, op, rangeExpr.End)
+}
+func (rangeExpr RangeExpr) IsId() bool          { return false }
+func (rangeExpr RangeExpr) IsVoid() bool        { return false }
+func (rangeExpr RangeExpr) IsFieldAccess() bool { return false }
+func (rangeExpr RangeExpr) exprNode()           {}
+func (rangeExpr RangeExpr) Span() Span          { return rangeExpr.Loc }
+
+// Value of type T converted to the optional type ?T. It is never written on
+// the source code, semantic analysis wraps a value with it wherever a T is
+// used as a ?T, such as "x ?int := 10".
+type OptionalExpr struct {
+	Expr
+	Loc   Span
+	Value Expr
+	Type  *OptionalType
+}
+
+func (optional OptionalExpr) String() string {
+	return fmt.Sprintf("%s(%s)", optional.Type, optional.Value)
+}
+func (optional OptionalExpr) IsId() bool          { return false }
+func (optional OptionalExpr) IsVoid() bool        { return false }
+func (optional OptionalExpr) IsFieldAccess() bool { return false }
+func (optional OptionalExpr) exprNode()           {}
+func (optional OptionalExpr) Span() Span          { return optional.Loc }
+
+// Value of type T converted to the error union !T, meaning it succeeded. Just
+// like OptionalExpr, semantic analysis creates it wherever a T is used as a
+// !T, such as "return 10;" on a function returning "!int".
+type ErrorUnionExpr struct {
+	Expr
+	Loc   Span
+	Value Expr
+	Type  *ErrorUnionType
+}
+
+func (union ErrorUnionExpr) String() string {
+	return fmt.Sprintf("%s(%s)", union.Type, union.Value)
+}
+func (union ErrorUnionExpr) IsId() bool          { return false }
+func (union ErrorUnionExpr) IsVoid() bool        { return false }
+func (union ErrorUnionExpr) IsFieldAccess() bool { return false }
+func (union ErrorUnionExpr) exprNode()           {}
+func (union ErrorUnionExpr) Span() Span          { return union.Loc }
+
+// Named error, such as "error.NotFound". Errors don't need to be declared,
+// every name is given an unique non-zero code by semantic analysis.
+type ErrorExpr struct {
+	Expr
+	Loc  Span
+	Name *token.Token
+
+	// Set by semantic analysis
+	Type *ErrorUnionType
+	Code uint64
+}
+
+func (errorExpr ErrorExpr) String() string {
+	return fmt.Sprintf("error.%s", errorExpr.Name.Name())
+}
+func (errorExpr ErrorExpr) IsId() bool          { return false }
+func (errorExpr ErrorExpr) IsVoid() bool        { return false }
+func (errorExpr ErrorExpr) IsFieldAccess() bool { return false }
+func (errorExpr ErrorExpr) exprNode()           {}
+func (errorExpr ErrorExpr) Span() Span          { return errorExpr.Loc }
+
+// Unwraps an error union, returning the error to the caller if there is one,
+// such as "n := try parse(s);". It can also be used as a statement, such as
+// "try write(s);".
+type TryExpr struct {
+	Stmt
+	Expr
+	Loc   Span
+	Value Expr
+
+	// Error union of Value, set by semantic analysis
+	Type *ErrorUnionType
+}
+
+func (try TryExpr) String() string {
+	return fmt.Sprintf("try %s", try.Value)
+}
+func (try TryExpr) IsId() bool          { return false }
+func (try TryExpr) IsVoid() bool        { return false }
+func (try TryExpr) IsFieldAccess() bool { return false }
+func (try TryExpr) IsReturn() bool      { return false }
+func (try TryExpr) astNode()            {}
+func (try TryExpr) stmtNode()           {}
+func (try TryExpr) exprNode()           {}
+func (try TryExpr) Span() Span          { return try.Loc }
+
+// Explicit conversion between numeric types or between pointer types, such as
+// "x as u8"
+type CastExpr struct {
+	Expr
+	Loc   Span
+	Value Expr
+	Type  ExprType
+
+	// Type of Value, set by semantic analysis
+	From ExprType
+}
+
+func (cast CastExpr) String() string {
+	return fmt.Sprintf("%s as %s", cast.Value, cast.Type)
+}
+func (cast CastExpr) IsId() bool          { return false }
+func (cast CastExpr) IsVoid() bool        { return false }
+func (cast CastExpr) IsFieldAccess() bool { return false }
+func (cast CastExpr) exprNode()           {}
+func (cast CastExpr) Span() Span          { return cast.Loc }
+
+// Builtins whose first argument is a type instead of a value, such as
+// "sizeof(int)"
+var TYPE_BUILTINS map[string]bool = map[string]bool{
+	"sizeof":   true,
+	"alignof":  true,
+	"offsetof": true,
+	"typename": true,
+}
+
+// Type passed as an argument to one of the TYPE_BUILTINS
+type TypeExpr struct {
+	Expr
+	Loc  Span
+	Type ExprType
+}
+
+func (typeExpr TypeExpr) String() string {
+	return fmt.Sprintf("%s", typeExpr.Type)
+}
+func (typeExpr TypeExpr) IsId() bool          { return false }
+func (typeExpr TypeExpr) IsVoid() bool        { return false }
+func (typeExpr TypeExpr) IsFieldAccess() bool { return false }
+func (typeExpr TypeExpr) exprNode()           {}
+func (typeExpr TypeExpr) Span() Span          { return typeExpr.Loc }
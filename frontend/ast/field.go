@@ -8,6 +8,7 @@ import (
 
 type Field struct {
 	Node
+	Loc         Span
 	Name        *token.Token
 	Type        ExprType
 	BackendType any // LLVM: *values.Variable
@@ -16,16 +17,21 @@ type Field struct {
 func (field Field) String() string {
 	return fmt.Sprintf("Name: %s\nType: %s", field.Name, field.Type)
 }
-func (field Field) astNode() {}
+func (field Field) astNode()   {}
+func (field Field) Span() Span { return field.Loc }
 
 // Field list for function parameters
 type FieldList struct {
+	Loc        Span
 	Open       *token.Token
 	Fields     []*Field
 	IsVariadic bool
 	Close      *token.Token
 }
 
+func (fieldList FieldList) astNode()   {}
+func (fieldList FieldList) Span() Span { return fieldList.Loc }
+
 func (fieldList FieldList) String() string {
 	return fmt.Sprintf(
 		"\n'%s' %s\n%s\nIsVariadic: %t\n'%s' %s\n",
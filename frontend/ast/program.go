@@ -10,6 +10,9 @@ type Program struct {
 
 func (program Program) astNode() {}
 
+// A program may be made of several files, so it has no span
+func (program Program) Span() Span { return Span{} }
+
 type Module struct {
 	Name    string
 	Files   []*File
@@ -20,11 +23,16 @@ type Module struct {
 
 func (module Module) astNode() {}
 
+// A module may be made of several files, so it has no span
+func (module Module) Span() Span { return Span{} }
+
 type File struct {
+	Loc   Span
 	Dir   string
 	Path  string
 	Body  []Node
 	Scope *Scope
 }
 
-func (file File) astNode() {}
+func (file File) astNode()   {}
+func (file File) Span() Span { return file.Loc }
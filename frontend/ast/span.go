@@ -0,0 +1,43 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Range of source code covered by a node. Start is the position of its first
+// character and End is the position right after its last one.
+//
+// Parentheses around an expression and the semicolon at the end of a
+// statement are not part of any span. Nodes created by the compiler itself,
+// such as the implicit void return type or the types inferred by semantic
+// analysis, have a zero span.
+type Span struct {
+	Start token.Pos
+	End   token.Pos
+}
+
+func (span Span) IsZero() bool { return span == Span{} }
+
+func (span Span) String() string {
+	return fmt.Sprintf("[%s:%d:%d-%d:%d]", span.Start.Filename, span.Start.Line, span.Start.Column, span.End.Line, span.End.Column)
+}
+
+// Useful for testing
+//
+// Sets the span of every node of the tree to zero, so trees can be compared
+// without caring about positions
+func ClearSpans(node Node) {
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		loc := reflect.ValueOf(n).Elem().FieldByName("Loc")
+		if loc.IsValid() {
+			loc.Set(reflect.Zero(loc.Type()))
+		}
+		return true
+	})
+}
@@ -13,6 +13,7 @@ type Stmt interface {
 
 type BlockStmt struct {
 	Stmt
+	Loc        Span
 	OpenCurly  token.Pos
 	Statements []Stmt
 	CloseCurly token.Pos
@@ -23,23 +24,30 @@ func (block BlockStmt) String() string {
 }
 func (block BlockStmt) IsReturn() bool { return false }
 func (block BlockStmt) astNode()       {}
+func (block BlockStmt) Span() Span     { return block.Loc }
 func (block BlockStmt) stmtNode()      {}
 
 type MultiVarStmt struct {
 	Stmt
+	Loc       Span
 	IsDecl    bool
 	Variables []*VarStmt
+	// Set when a single tuple-valued expression is destructured, such as
+	// "q, r := divmod(7, 2);". In that case, each variable has a nil value.
+	Tuple Expr
 }
 
 func (multi MultiVarStmt) String() string {
-	return fmt.Sprintf("Multi: %v %v", multi.IsDecl, multi.Variables)
+	return fmt.Sprintf("Multi: %v %v %v", multi.IsDecl, multi.Variables, multi.Tuple)
 }
 func (multi MultiVarStmt) IsReturn() bool { return false }
 func (multi MultiVarStmt) astNode()       {}
+func (multi MultiVarStmt) Span() Span     { return multi.Loc }
 func (multi MultiVarStmt) stmtNode()      {}
 
 type VarStmt struct {
 	Stmt
+	Loc            Span
 	Decl           bool
 	Name           *token.Token
 	Type           ExprType
@@ -54,10 +62,12 @@ func (variable VarStmt) String() string {
 }
 func (variable VarStmt) IsReturn() bool { return false }
 func (variable VarStmt) astNode()       {}
+func (variable VarStmt) Span() Span     { return variable.Loc }
 func (variable VarStmt) stmtNode()      {}
 
 type ReturnStmt struct {
 	Stmt
+	Loc    Span
 	Return *token.Token
 	Value  Expr
 }
@@ -67,27 +77,57 @@ func (ret ReturnStmt) String() string {
 }
 func (ret ReturnStmt) IsReturn() bool { return true }
 func (ret ReturnStmt) astNode()       {}
+func (ret ReturnStmt) Span() Span     { return ret.Loc }
 func (ret ReturnStmt) stmtNode()      {}
 
+// Built-in assertion, such as "assert(a == b);"
+type AssertStmt struct {
+	Stmt
+	Loc    Span
+	Assert *token.Token
+	Cond   Expr
+}
+
+func (assert AssertStmt) String() string {
+	return fmt.Sprintf("ASSERT: %s", assert.Cond)
+}
+func (assert AssertStmt) IsReturn() bool { return false }
+func (assert AssertStmt) astNode()       {}
+func (assert AssertStmt) Span() Span     { return assert.Loc }
+func (assert AssertStmt) stmtNode()      {}
+
 type FunctionCall struct {
 	Stmt
 	Expr
+	Loc  Span
 	Name *token.Token
 	Args []Expr
 
+	// Set by semantic analysis. Builtin is true when the called function is
+	// one of the builtins, such as "len", instead of a declared one. Folded is
+	// the literal of builtins evaluated at compile time, such as "sizeof".
+	Type    ExprType
+	Builtin bool
+	Folded  *LiteralExpr
+
 	BackendType any
 }
 
 func (call FunctionCall) String() string {
 	return fmt.Sprintf("CALL: %s - ARGS: %s", call.Name, call.Args)
 }
-func (call FunctionCall) IsReturn() bool { return false }
-func (call FunctionCall) astNode()       {}
-func (call FunctionCall) stmtNode()      {}
-func (call FunctionCall) exprNode()      {}
+func (call FunctionCall) IsReturn() bool      { return false }
+func (call FunctionCall) IsId() bool          { return false }
+func (call FunctionCall) IsVoid() bool        { return false }
+func (call FunctionCall) IsFieldAccess() bool { return false }
+func (call FunctionCall) astNode()            {}
+func (call FunctionCall) Span() Span          { return call.Loc }
+func (call FunctionCall) stmtNode()           {}
+func (call FunctionCall) exprNode()           {}
 
 type CondStmt struct {
 	Stmt
+	Loc       Span
 	IfStmt    *IfElifCond
 	ElifStmts []*IfElifCond
 	ElseStmt  *ElseCond
@@ -98,21 +138,36 @@ func (condStmt CondStmt) String() string {
 }
 func (cond CondStmt) IsReturn() bool { return false }
 func (cond CondStmt) astNode()       {}
+func (cond CondStmt) Span() Span     { return cond.Loc }
 func (cond CondStmt) stmtNode()      {}
 
+// When Unwrap is set, such as on "if x := opt { ... }", the block runs only
+// when the optional Expr is not nil, with its value on the Unwrap variable
 type IfElifCond struct {
-	If    *token.Pos
-	Expr  Expr
-	Block *BlockStmt
+	Loc    Span
+	If     *token.Pos
+	Unwrap *VarStmt
+	Expr   Expr
+	Block  *BlockStmt
+	Scope  *Scope
 }
 
+func (cond IfElifCond) astNode()   {}
+func (cond IfElifCond) Span() Span { return cond.Loc }
+
 type ElseCond struct {
+	Loc   Span
 	Else  *token.Pos
 	Block *BlockStmt
+	Scope *Scope
 }
 
+func (cond ElseCond) astNode()   {}
+func (cond ElseCond) Span() Span { return cond.Loc }
+
 type ForLoop struct {
 	Stmt
+	Loc    Span
 	Init   Stmt
 	Cond   Expr
 	Update Stmt
@@ -130,10 +185,41 @@ func (forLoop ForLoop) String() string {
 }
 func (forLoop ForLoop) IsReturn() bool { return false }
 func (forLoop ForLoop) astNode()       {}
+func (forLoop ForLoop) Span() Span     { return forLoop.Loc }
 func (forLoop ForLoop) stmtNode()      {}
 
+// Range-based for loop, such as "for i in 0..10 {}" or "for i, x in arr {}".
+// Index is only allowed when iterating over arrays and slices.
+type RangeForLoop struct {
+	Stmt
+	Loc      Span
+	Index    *VarStmt
+	Value    *VarStmt
+	Iterable Expr
+	Block    *BlockStmt
+	Scope    *Scope
+}
+
+func (forLoop RangeForLoop) String() string {
+	if forLoop.Index != nil {
+		return fmt.Sprintf(
+			"for %s, %s in %s %s",
+			forLoop.Index.Name.Name(),
+			forLoop.Value.Name.Name(),
+			forLoop.Iterable,
+			forLoop.Block,
+		)
+	}
+	return fmt.Sprintf("for %s in %s %s", forLoop.Value.Name.Name(), forLoop.Iterable, forLoop.Block)
+}
+func (forLoop RangeForLoop) IsReturn() bool { return false }
+func (forLoop RangeForLoop) astNode()       {}
+func (forLoop RangeForLoop) Span() Span     { return forLoop.Loc }
+func (forLoop RangeForLoop) stmtNode()      {}
+
 type WhileLoop struct {
 	Stmt
+	Loc   Span
 	Cond  Expr
 	Block *BlockStmt
 }
@@ -147,4 +233,26 @@ func (whileLoop WhileLoop) String() string {
 }
 func (whileLoop WhileLoop) IsReturn() bool { return false }
 func (whileLoop WhileLoop) astNode()       {}
+func (whileLoop WhileLoop) Span() Span     { return whileLoop.Loc }
 func (whileLoop WhileLoop) stmtNode()      {}
+
+// Handles the error of an error union with a block, such as
+// "write(s) catch { return 1; }". The block only runs if there is an error and
+// the value, if any, is discarded, so it also handles errors of "!void".
+type CatchStmt struct {
+	Stmt
+	Loc   Span
+	Value Expr
+	Block *BlockStmt
+
+	// Error union of Value, set by semantic analysis
+	Type *ErrorUnionType
+}
+
+func (catch CatchStmt) String() string {
+	return fmt.Sprintf("%s catch %s", catch.Value, catch.Block)
+}
+func (catch CatchStmt) IsReturn() bool { return false }
+func (catch CatchStmt) astNode()       {}
+func (catch CatchStmt) Span() Span     { return catch.Loc }
+func (catch CatchStmt) stmtNode()      {}
@@ -2,20 +2,24 @@ package ast
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/HicaroD/Telia/frontend/lexer/token"
 )
 
 type ExprType interface {
+	Node
 	IsVoid() bool
 	IsBoolean() bool
 	IsNumeric() bool
+	IsString() bool
 	exprTypeNode()
 }
 
-// void, bool, int, i8, i16, i32, i64, uint, u8, u16, u32, u64
+// void, bool, int, i8, i16, i32, i64, uint, u8, u16, u32, u64, string
 type BasicType struct {
 	ExprType
+	Loc  Span
 	Kind token.Kind
 }
 
@@ -25,33 +29,193 @@ func (basicType BasicType) IsNumeric() bool {
 }
 func (basicType BasicType) IsBoolean() bool { return basicType.Kind == token.BOOL_TYPE }
 func (basicType BasicType) IsVoid() bool    { return basicType.Kind == token.VOID_TYPE }
+func (basicType BasicType) IsString() bool  { return basicType.Kind == token.STRING_TYPE }
 func (basicType BasicType) exprTypeNode()   {}
+func (basicType BasicType) astNode()        {}
+func (basicType BasicType) Span() Span      { return basicType.Loc }
 func (basicType BasicType) String() string {
 	return basicType.Kind.String()
 }
 
+// Signed integers use signed comparison, division and extension
+func (basicType BasicType) IsSigned() bool { return basicType.Kind.IsSigned() }
+
 type IdType struct {
 	ExprType
+	Loc  Span
 	Name *token.Token
 }
 
 func (idType IdType) IsNumeric() bool { return false }
 func (idType IdType) IsBoolean() bool { return false }
 func (idType IdType) IsVoid() bool    { return false }
+func (idType IdType) IsString() bool  { return false }
 func (idType IdType) exprTypeNode()   {}
+func (idType IdType) astNode()        {}
+func (idType IdType) Span() Span      { return idType.Loc }
 func (idType IdType) String() string {
 	return fmt.Sprintf("IdType: %s", idType.Name.Lexeme)
 }
 
 type PointerType struct {
 	ExprType
+	Loc  Span
 	Type ExprType
 }
 
 func (pointer PointerType) IsNumeric() bool { return false }
 func (pointer PointerType) IsBoolean() bool { return false }
 func (pointer PointerType) IsVoid() bool    { return false }
+func (pointer PointerType) IsString() bool  { return false }
 func (pointer PointerType) exprTypeNode()   {}
+func (pointer PointerType) astNode()        {}
+func (pointer PointerType) Span() Span      { return pointer.Loc }
 func (pointer PointerType) String() string {
 	return fmt.Sprintf("*%s", pointer.Type)
 }
+
+// Used on functions that return multiple values, such as "(int, int)"
+type TupleType struct {
+	ExprType
+	Loc   Span
+	Types []ExprType
+}
+
+func (tuple TupleType) IsNumeric() bool { return false }
+func (tuple TupleType) IsBoolean() bool { return false }
+func (tuple TupleType) IsVoid() bool    { return false }
+func (tuple TupleType) IsString() bool  { return false }
+func (tuple TupleType) exprTypeNode()   {}
+func (tuple TupleType) astNode()        {}
+func (tuple TupleType) Span() Span      { return tuple.Loc }
+func (tuple TupleType) String() string {
+	types := make([]string, len(tuple.Types))
+	for i := range tuple.Types {
+		types[i] = fmt.Sprintf("%s", tuple.Types[i])
+	}
+	return fmt.Sprintf("(%s)", strings.Join(types, ", "))
+}
+
+// Fixed-size array, such as "[3]int". When the length is not an integer
+// literal, such as "[N * 2]int", it is kept on LenExpr until semantic analysis
+// evaluates it.
+type ArrayType struct {
+	ExprType
+	Loc     Span
+	Len     int
+	LenExpr Expr
+	Type    ExprType
+}
+
+func (array ArrayType) IsNumeric() bool { return false }
+func (array ArrayType) IsBoolean() bool { return false }
+func (array ArrayType) IsVoid() bool    { return false }
+func (array ArrayType) IsString() bool  { return false }
+func (array ArrayType) exprTypeNode()   {}
+func (array ArrayType) astNode()        {}
+func (array ArrayType) Span() Span      { return array.Loc }
+func (array ArrayType) String() string {
+	return fmt.Sprintf("[%d]%s", array.Len, array.Type)
+}
+
+// Pointer and length pair that refers to a section of an array, such as
+// "[]int"
+type SliceType struct {
+	ExprType
+	Loc  Span
+	Type ExprType
+}
+
+func (slice SliceType) IsNumeric() bool { return false }
+func (slice SliceType) IsBoolean() bool { return false }
+func (slice SliceType) IsVoid() bool    { return false }
+func (slice SliceType) IsString() bool  { return false }
+func (slice SliceType) exprTypeNode()   {}
+func (slice SliceType) astNode()        {}
+func (slice SliceType) Span() Span      { return slice.Loc }
+func (slice SliceType) String() string {
+	return fmt.Sprintf("[]%s", slice.Type)
+}
+
+// Either a value of the underlying type or nil, such as "?int". Optional
+// pointers, such as "?*u8", are represented by a null pointer when nil.
+type OptionalType struct {
+	ExprType
+	Loc  Span
+	Type ExprType
+}
+
+func (optional OptionalType) IsNumeric() bool { return false }
+func (optional OptionalType) IsBoolean() bool { return false }
+func (optional OptionalType) IsVoid() bool    { return false }
+func (optional OptionalType) IsString() bool  { return false }
+func (optional OptionalType) exprTypeNode()   {}
+func (optional OptionalType) astNode()        {}
+func (optional OptionalType) Span() Span      { return optional.Loc }
+func (optional OptionalType) String() string {
+	return fmt.Sprintf("?%s", optional.Type)
+}
+
+// Either an error or a value of the underlying type, such as "!int". Functions
+// returning it can fail with "return error.Name;".
+type ErrorUnionType struct {
+	ExprType
+	Loc  Span
+	Type ExprType
+}
+
+func (union ErrorUnionType) IsNumeric() bool { return false }
+func (union ErrorUnionType) IsBoolean() bool { return false }
+func (union ErrorUnionType) IsVoid() bool    { return false }
+func (union ErrorUnionType) IsString() bool  { return false }
+func (union ErrorUnionType) exprTypeNode()   {}
+func (union ErrorUnionType) astNode()        {}
+func (union ErrorUnionType) Span() Span      { return union.Loc }
+func (union ErrorUnionType) String() string {
+	return fmt.Sprintf("!%s", union.Type)
+}
+
+// Reports whether two types are the same, regardless of where they were
+// written on the source code
+func SameType(a, b ExprType) bool {
+	if isNil(a) || isNil(b) {
+		return isNil(a) && isNil(b)
+	}
+
+	switch a := a.(type) {
+	case *BasicType:
+		b, ok := b.(*BasicType)
+		return ok && a.Kind == b.Kind
+	case *IdType:
+		b, ok := b.(*IdType)
+		return ok && a.Name.Name() == b.Name.Name()
+	case *PointerType:
+		b, ok := b.(*PointerType)
+		return ok && SameType(a.Type, b.Type)
+	case *TupleType:
+		b, ok := b.(*TupleType)
+		if !ok || len(a.Types) != len(b.Types) {
+			return false
+		}
+		for i := range a.Types {
+			if !SameType(a.Types[i], b.Types[i]) {
+				return false
+			}
+		}
+		return true
+	case *ArrayType:
+		b, ok := b.(*ArrayType)
+		return ok && a.Len == b.Len && SameType(a.Type, b.Type)
+	case *SliceType:
+		b, ok := b.(*SliceType)
+		return ok && SameType(a.Type, b.Type)
+	case *OptionalType:
+		b, ok := b.(*OptionalType)
+		return ok && SameType(a.Type, b.Type)
+	case *ErrorUnionType:
+		b, ok := b.(*ErrorUnionType)
+		return ok && SameType(a.Type, b.Type)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,415 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Traverses an AST in depth-first order. It starts by calling v.Visit(node);
+// node must not be nil. If the visitor w returned by v.Visit(node) is not nil,
+// Walk is invoked recursively with visitor w for each of the non-nil children
+// of node, followed by a call of w.Visit(nil).
+//
+// Children are visited in the order they appear on the source code. Types
+// inferred by semantic analysis, such as the type of a literal, are visited as
+// well.
+func Walk(v Visitor, node Node) {
+	if isNil(node) {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Program structure
+	case *Program:
+		Walk(v, n.Root)
+	case *Module:
+		for _, file := range n.Files {
+			Walk(v, file)
+		}
+		for _, module := range n.Modules {
+			Walk(v, module)
+		}
+	case *File:
+		for _, decl := range n.Body {
+			Walk(v, decl)
+		}
+
+	// Declarations
+	case *FunctionDecl:
+		for _, attribute := range n.Attributes {
+			Walk(v, attribute)
+		}
+		Walk(v, n.Params)
+		Walk(v, n.RetType)
+		Walk(v, n.Block)
+	case *ExternDecl:
+		for _, proto := range n.Prototypes {
+			Walk(v, proto)
+		}
+		for _, variable := range n.Vars {
+			Walk(v, variable)
+		}
+	case *ExternVar:
+		Walk(v, n.Type)
+	case *Proto:
+		for _, attribute := range n.Attributes {
+			Walk(v, attribute)
+		}
+		Walk(v, n.Params)
+		Walk(v, n.RetType)
+	case *TestDecl:
+		Walk(v, n.Block)
+	case *BenchDecl:
+		Walk(v, n.Block)
+	case *ConstDecl:
+		Walk(v, n.Type)
+		Walk(v, n.Value)
+	case *StaticAssert:
+		Walk(v, n.Cond)
+	case *Attribute:
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+	case *FieldList:
+		for _, field := range n.Fields {
+			Walk(v, field)
+		}
+	case *Field:
+		Walk(v, n.Type)
+
+	// Statements
+	case *BlockStmt:
+		for _, stmt := range n.Statements {
+			Walk(v, stmt)
+		}
+	case *MultiVarStmt:
+		for _, variable := range n.Variables {
+			Walk(v, variable)
+		}
+		Walk(v, n.Tuple)
+	case *VarStmt:
+		Walk(v, n.Type)
+		Walk(v, n.Value)
+	case *ReturnStmt:
+		Walk(v, n.Value)
+	case *AssertStmt:
+		Walk(v, n.Cond)
+	case *FunctionCall:
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+	case *CondStmt:
+		Walk(v, n.IfStmt)
+		for _, elif := range n.ElifStmts {
+			Walk(v, elif)
+		}
+		Walk(v, n.ElseStmt)
+	case *IfElifCond:
+		Walk(v, n.Unwrap)
+		Walk(v, n.Expr)
+		Walk(v, n.Block)
+	case *ElseCond:
+		Walk(v, n.Block)
+	case *ForLoop:
+		Walk(v, n.Init)
+		Walk(v, n.Cond)
+		Walk(v, n.Update)
+		Walk(v, n.Block)
+	case *RangeForLoop:
+		Walk(v, n.Index)
+		Walk(v, n.Value)
+		Walk(v, n.Iterable)
+		Walk(v, n.Block)
+	case *WhileLoop:
+		Walk(v, n.Cond)
+		Walk(v, n.Block)
+	case *CatchStmt:
+		Walk(v, n.Value)
+		Walk(v, n.Block)
+
+	// Expressions
+	case *VoidExpr, *IdExpr, *ErrorExpr:
+		// leaves
+	case *LiteralExpr:
+		Walk(v, n.Type)
+	case *FieldAccess:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *UnaryExpr:
+		Walk(v, n.Value)
+	case *BinaryExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *TupleExpr:
+		for _, expr := range n.Exprs {
+			Walk(v, expr)
+		}
+		Walk(v, n.Type)
+	case *ArrayLiteral:
+		for _, value := range n.Values {
+			Walk(v, value)
+		}
+		Walk(v, n.Type)
+	case *IndexExpr:
+		Walk(v, n.Value)
+		Walk(v, n.Index)
+		Walk(v, n.Type)
+	case *RangeExpr:
+		Walk(v, n.Start)
+		Walk(v, n.End)
+	case *OptionalExpr:
+		Walk(v, n.Value)
+		Walk(v, n.Type)
+	case *ErrorUnionExpr:
+		Walk(v, n.Value)
+		Walk(v, n.Type)
+	case *TryExpr:
+		Walk(v, n.Value)
+	case *CastExpr:
+		Walk(v, n.Value)
+		Walk(v, n.Type)
+	case *TypeExpr:
+		Walk(v, n.Type)
+
+	// Types
+	case *BasicType, *IdType:
+		// leaves
+	case *PointerType:
+		Walk(v, n.Type)
+	case *TupleType:
+		for _, ty := range n.Types {
+			Walk(v, ty)
+		}
+	case *ArrayType:
+		Walk(v, n.LenExpr)
+		Walk(v, n.Type)
+	case *SliceType:
+		Walk(v, n.Type)
+	case *OptionalType:
+		Walk(v, n.Type)
+	case *ErrorUnionType:
+		Walk(v, n.Type)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Traverses an AST in depth-first order, just like Walk. It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Traverses an AST in depth-first order and replaces every node with the
+// result of f(node). Children are rewritten before their parent, so f always
+// sees a node whose children were already rewritten. Returning the node itself
+// keeps it unchanged.
+//
+// The node returned by f must fit on the same place of the tree, such as an
+// Expr in place of an Expr, otherwise Rewrite panics. Rewrite returns the new
+// root of the tree.
+func Rewrite(node Node, f func(Node) Node) Node {
+	if isNil(node) {
+		return node
+	}
+
+	switch n := node.(type) {
+	// Program structure
+	case *Program:
+		n.Root = rewrite(n.Root, f)
+	case *Module:
+		rewriteList(n.Files, f)
+		rewriteList(n.Modules, f)
+	case *File:
+		rewriteList(n.Body, f)
+
+	// Declarations
+	case *FunctionDecl:
+		rewriteList(n.Attributes, f)
+		n.Params = rewrite(n.Params, f)
+		n.RetType = rewrite(n.RetType, f)
+		n.Block = rewrite(n.Block, f)
+	case *ExternDecl:
+		rewriteList(n.Prototypes, f)
+		rewriteList(n.Vars, f)
+	case *ExternVar:
+		n.Type = rewrite(n.Type, f)
+	case *Proto:
+		rewriteList(n.Attributes, f)
+		n.Params = rewrite(n.Params, f)
+		n.RetType = rewrite(n.RetType, f)
+	case *TestDecl:
+		n.Block = rewrite(n.Block, f)
+	case *BenchDecl:
+		n.Block = rewrite(n.Block, f)
+	case *ConstDecl:
+		n.Type = rewrite(n.Type, f)
+		n.Value = rewrite(n.Value, f)
+	case *StaticAssert:
+		n.Cond = rewrite(n.Cond, f)
+	case *Attribute:
+		rewriteList(n.Args, f)
+	case *FieldList:
+		rewriteList(n.Fields, f)
+	case *Field:
+		n.Type = rewrite(n.Type, f)
+
+	// Statements
+	case *BlockStmt:
+		rewriteList(n.Statements, f)
+	case *MultiVarStmt:
+		rewriteList(n.Variables, f)
+		n.Tuple = rewrite(n.Tuple, f)
+	case *VarStmt:
+		n.Type = rewrite(n.Type, f)
+		n.Value = rewrite(n.Value, f)
+	case *ReturnStmt:
+		n.Value = rewrite(n.Value, f)
+	case *AssertStmt:
+		n.Cond = rewrite(n.Cond, f)
+	case *FunctionCall:
+		rewriteList(n.Args, f)
+	case *CondStmt:
+		n.IfStmt = rewrite(n.IfStmt, f)
+		rewriteList(n.ElifStmts, f)
+		n.ElseStmt = rewrite(n.ElseStmt, f)
+	case *IfElifCond:
+		n.Unwrap = rewrite(n.Unwrap, f)
+		n.Expr = rewrite(n.Expr, f)
+		n.Block = rewrite(n.Block, f)
+	case *ElseCond:
+		n.Block = rewrite(n.Block, f)
+	case *ForLoop:
+		n.Init = rewrite(n.Init, f)
+		n.Cond = rewrite(n.Cond, f)
+		n.Update = rewrite(n.Update, f)
+		n.Block = rewrite(n.Block, f)
+	case *RangeForLoop:
+		n.Index = rewrite(n.Index, f)
+		n.Value = rewrite(n.Value, f)
+		n.Iterable = rewrite(n.Iterable, f)
+		n.Block = rewrite(n.Block, f)
+	case *WhileLoop:
+		n.Cond = rewrite(n.Cond, f)
+		n.Block = rewrite(n.Block, f)
+
+	// Expressions
+	case *VoidExpr, *IdExpr, *ErrorExpr:
+		// leaves
+	case *LiteralExpr:
+		n.Type = rewrite(n.Type, f)
+	case *FieldAccess:
+		n.Left = rewrite(n.Left, f)
+		n.Right = rewrite(n.Right, f)
+	case *UnaryExpr:
+		n.Value = rewrite(n.Value, f)
+	case *BinaryExpr:
+		n.Left = rewrite(n.Left, f)
+		n.Right = rewrite(n.Right, f)
+	case *TupleExpr:
+		rewriteList(n.Exprs, f)
+		n.Type = rewrite(n.Type, f)
+	case *ArrayLiteral:
+		rewriteList(n.Values, f)
+		n.Type = rewrite(n.Type, f)
+	case *IndexExpr:
+		n.Value = rewrite(n.Value, f)
+		n.Index = rewrite(n.Index, f)
+		n.Type = rewrite(n.Type, f)
+	case *RangeExpr:
+		n.Start = rewrite(n.Start, f)
+		n.End = rewrite(n.End, f)
+	case *OptionalExpr:
+		n.Value = rewrite(n.Value, f)
+		n.Type = rewrite(n.Type, f)
+	case *ErrorUnionExpr:
+		n.Value = rewrite(n.Value, f)
+		n.Type = rewrite(n.Type, f)
+	case *TryExpr:
+		n.Value = rewrite(n.Value, f)
+	case *CastExpr:
+		n.Value = rewrite(n.Value, f)
+		n.Type = rewrite(n.Type, f)
+	case *TypeExpr:
+		n.Type = rewrite(n.Type, f)
+
+	// Types
+	case *BasicType, *IdType:
+		// leaves
+	case *PointerType:
+		n.Type = rewrite(n.Type, f)
+	case *TupleType:
+		rewriteList(n.Types, f)
+	case *ArrayType:
+		n.LenExpr = rewrite(n.LenExpr, f)
+		n.Type = rewrite(n.Type, f)
+	case *SliceType:
+		n.Type = rewrite(n.Type, f)
+	case *OptionalType:
+		n.Type = rewrite(n.Type, f)
+	case *ErrorUnionType:
+		n.Type = rewrite(n.Type, f)
+
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+// Rewrites a child node, making sure the result fits on the field that holds
+// it
+func rewrite[T Node](node T, f func(Node) Node) T {
+	if isNil(node) {
+		return node
+	}
+	result := Rewrite(node, f)
+	if result == nil {
+		var zero T
+		return zero
+	}
+	typed, ok := result.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: can't replace %T with %T", node, result))
+	}
+	return typed
+}
+
+func rewriteList[T Node](nodes []T, f func(Node) Node) {
+	for i := range nodes {
+		nodes[i] = rewrite(nodes[i], f)
+	}
+}
+
+// Reports whether node is nil or a nil pointer, such as a missing else branch
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
@@ -0,0 +1,179 @@
+package ast_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+	"github.com/HicaroD/Telia/frontend/parser"
+)
+
+func parseFrom(t *testing.T, input string) *ast.Program {
+	collector := diagnostics.New()
+	lex := lexer.New("test.tt", []byte(input), collector)
+	program, err := parser.New(collector).ParseFileAsProgram(lex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+type inspectTest struct {
+	input string
+	nodes []string
+}
+
+func TestInspect(t *testing.T) {
+	tests := []inspectTest{
+		{
+			input: "fn f(a int) int { return a + 1; }",
+			nodes: []string{
+				"Program", "Module", "File", "FunctionDecl",
+				"FieldList", "Field", "BasicType",
+				"BasicType",
+				"BlockStmt", "ReturnStmt", "BinaryExpr", "IdExpr", "LiteralExpr", "BasicType",
+			},
+		},
+		{
+			input: "fn f() { if a { b(); } elif c { } else { d := 1; } }",
+			nodes: []string{
+				"Program", "Module", "File", "FunctionDecl",
+				"FieldList", "BasicType",
+				"BlockStmt", "CondStmt",
+				"IfElifCond", "IdExpr", "BlockStmt", "FunctionCall",
+				"IfElifCond", "IdExpr", "BlockStmt",
+				"ElseCond", "BlockStmt", "VarStmt", "LiteralExpr", "BasicType",
+			},
+		},
+		{
+			input: "const N [2]u8 = 0;\nstatic_assert(true, \"\");\nextern libc { @cold fn abort(); }",
+			nodes: []string{
+				"Program", "Module", "File",
+				"ConstDecl", "ArrayType", "BasicType", "LiteralExpr", "BasicType",
+				"StaticAssert", "LiteralExpr", "BasicType",
+				"ExternDecl", "Proto", "Attribute", "FieldList", "BasicType",
+			},
+		},
+		{
+			input: "fn f() { for i, x in a[1..] { g(x); } for (i := 0; i < 3; i = i + 1) {} while true {} }",
+			nodes: []string{
+				"Program", "Module", "File", "FunctionDecl",
+				"FieldList", "BasicType",
+				"BlockStmt",
+				"RangeForLoop", "VarStmt", "VarStmt", "IndexExpr", "IdExpr", "RangeExpr", "LiteralExpr", "BasicType",
+				"BlockStmt", "FunctionCall", "IdExpr",
+				"ForLoop", "VarStmt", "LiteralExpr", "BasicType", "BinaryExpr", "IdExpr", "LiteralExpr", "BasicType",
+				"VarStmt", "BinaryExpr", "IdExpr", "LiteralExpr", "BasicType", "BlockStmt",
+				"WhileLoop", "LiteralExpr", "BasicType", "BlockStmt",
+			},
+		},
+		{
+			input: "fn f(a ?int) { if x := a { } elif a == nil { } b := a orelse 0; }",
+			nodes: []string{
+				"Program", "Module", "File", "FunctionDecl",
+				"FieldList", "Field", "OptionalType", "BasicType",
+				"BasicType",
+				"BlockStmt", "CondStmt",
+				"IfElifCond", "VarStmt", "IdExpr", "BlockStmt",
+				"IfElifCond", "BinaryExpr", "IdExpr", "LiteralExpr", "BasicType", "BlockStmt",
+				"VarStmt", "BinaryExpr", "IdExpr", "LiteralExpr", "BasicType",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestInspect('%s')", test.input), func(t *testing.T) {
+			program := parseFrom(t, test.input)
+
+			var nodes []string
+			ast.Inspect(program, func(node ast.Node) bool {
+				if node != nil {
+					nodes = append(nodes, reflect.TypeOf(node).Elem().Name())
+				}
+				return true
+			})
+
+			if !reflect.DeepEqual(nodes, test.nodes) {
+				t.Fatalf("\nexp: %s\ngot: %s\n", test.nodes, nodes)
+			}
+		})
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parseFrom(t, "fn f() { a := 1; }\nfn g() { b := 2; }")
+
+	var variables []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			return n.Name.Name() != "f"
+		case *ast.VarStmt:
+			variables = append(variables, n.Name.Name())
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(variables, []string{"b"}) {
+		t.Fatalf("expected only variables of 'g', but got %s", variables)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	program := parseFrom(t, "fn f() int { return N * (N + 1); }")
+
+	ast.Rewrite(program, func(node ast.Node) ast.Node {
+		if id, ok := node.(*ast.IdExpr); ok && id.Name.Name() == "N" {
+			return &ast.LiteralExpr{
+				Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				Value: []byte("3"),
+			}
+		}
+		return node
+	})
+
+	fn := program.Root.Files[0].Body[0].(*ast.FunctionDecl)
+	ret := fn.Block.Statements[0].(*ast.ReturnStmt)
+	ast.ClearSpans(ret.Value)
+	expected := &ast.BinaryExpr{
+		Left: &ast.LiteralExpr{
+			Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+			Value: []byte("3"),
+		},
+		Op: token.STAR,
+		Right: &ast.BinaryExpr{
+			Left: &ast.LiteralExpr{
+				Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				Value: []byte("3"),
+			},
+			Op: token.PLUS,
+			Right: &ast.LiteralExpr{
+				Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				Value: []byte("1"),
+			},
+		},
+	}
+	if !reflect.DeepEqual(ret.Value, expected) {
+		t.Fatalf("\nexp: %s\ngot: %s\n", expected, ret.Value)
+	}
+}
+
+func TestRewriteMismatchedNode(t *testing.T) {
+	program := parseFrom(t, "fn f() { g(); }")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Rewrite to panic when replacing a statement with a type")
+		}
+	}()
+	ast.Rewrite(program, func(node ast.Node) ast.Node {
+		if _, ok := node.(*ast.FunctionCall); ok {
+			return &ast.BasicType{Kind: token.INT_TYPE}
+		}
+		return node
+	})
+}
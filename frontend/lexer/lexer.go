@@ -15,10 +15,18 @@ type Lexer struct {
 	ParentDirName string
 	Path          string
 
+	// If true, comments are returned as COMMENT tokens instead of being
+	// skipped, such as when formatting source code
+	KeepComments bool
+
 	src    []byte
 	offset int
 	pos    token.Pos
 
+	// If true, the next newline is returned as a semicolon, because the last
+	// token may be the end of a statement
+	insertSemi bool
+
 	collector *diagnostics.Collector
 }
 
@@ -49,17 +57,20 @@ func NewFromFilePath(parentDirName, path string, collector *diagnostics.Collecto
 func (lex *Lexer) Peek() *token.Token {
 	prevPos := lex.pos
 	prevOffset := lex.offset
+	prevInsertSemi := lex.insertSemi
 
 	token := lex.next()
 
 	lex.pos.SetPosition(prevPos)
 	lex.offset = prevOffset
+	lex.insertSemi = prevInsertSemi
 	return token
 }
 
 func (lex *Lexer) Peek1() *token.Token {
 	prevPos := lex.pos
 	prevOffset := lex.offset
+	prevInsertSemi := lex.insertSemi
 
 	var token *token.Token
 
@@ -68,10 +79,16 @@ func (lex *Lexer) Peek1() *token.Token {
 
 	lex.pos.SetPosition(prevPos)
 	lex.offset = prevOffset
+	lex.insertSemi = prevInsertSemi
 
 	return token
 }
 
+// Returns the position right after the last consumed token
+func (lex *Lexer) Pos() token.Pos {
+	return lex.pos
+}
+
 func (lex *Lexer) Skip() {
 	lex.next()
 }
@@ -81,14 +98,49 @@ func (lex *Lexer) NextIs(expectedKind token.Kind) bool {
 	return token.Kind == expectedKind
 }
 
+// Semicolons are inserted the same way Go does: if the last token of a line
+// is an identifier, a literal, a type, "return" or a closing bracket, the
+// newline is returned as a semicolon. Inserted semicolons have "\n" as their
+// lexeme, so they can be told apart from explicit ones.
+//
+// Unlike Go, no semicolon is inserted at the end of the file, the parser
+// accepts the end of the file in place of the last one.
 func (lex *Lexer) next() *token.Token {
-	lex.skipWhitespace()
+	insertSemi := lex.insertSemi
+	lex.insertSemi = false
+
+	lex.skipWhitespace(insertSemi)
+	for !lex.KeepComments && lex.isComment() {
+		lex.getComment()
+		lex.skipWhitespace(insertSemi)
+	}
 	character := lex.peekChar()
+	if insertSemi && character == '\n' {
+		semicolon := lex.consumeToken([]byte("\n"), token.SEMICOLON)
+		lex.nextChar()
+		return semicolon
+	}
 	if character == eof {
 		return lex.consumeToken(nil, token.EOF)
 	}
-	token := lex.getToken(character)
-	return token
+
+	tok := lex.getToken(character)
+	if tok.Kind == token.COMMENT {
+		// A comment does not end a statement, but the newline after it does
+		lex.insertSemi = insertSemi
+	} else {
+		lex.insertSemi = endsStatement(tok.Kind)
+	}
+	return tok
+}
+
+// Reports whether a statement may end with a token of the given kind
+func endsStatement(kind token.Kind) bool {
+	switch kind {
+	case token.ID, token.RETURN, token.CLOSE_PAREN, token.CLOSE_BRACKET, token.CLOSE_CURLY:
+		return true
+	}
+	return token.LITERAL_KIND[kind] || kind.IsBasicType()
 }
 
 // Useful for testing
@@ -125,14 +177,26 @@ func (lex *Lexer) getToken(ch byte) *token.Token {
 	case '}':
 		tok = lex.consumeToken(nil, token.CLOSE_CURLY)
 		lex.nextChar()
+	case '[':
+		tok = lex.consumeToken(nil, token.OPEN_BRACKET)
+		lex.nextChar()
+	case ']':
+		tok = lex.consumeToken(nil, token.CLOSE_BRACKET)
+		lex.nextChar()
 	case '"':
 		tok = lex.getStringLiteral()
 	case ',':
 		tok = lex.consumeToken(nil, token.COMMA)
 		lex.nextChar()
+	case '@':
+		tok = lex.consumeToken(nil, token.AT)
+		lex.nextChar()
 	case ';':
 		tok = lex.consumeToken(nil, token.SEMICOLON)
 		lex.nextChar()
+	case '?':
+		tok = lex.consumeToken(nil, token.QUESTION)
+		lex.nextChar()
 	case '+':
 		tok = lex.consumeToken(nil, token.PLUS)
 		lex.nextChar()
@@ -143,34 +207,27 @@ func (lex *Lexer) getToken(ch byte) *token.Token {
 		tok = lex.consumeToken(nil, token.STAR)
 		lex.nextChar()
 	case '/':
+		if lex.isComment() {
+			tok = lex.getComment()
+			break
+		}
 		tok = lex.consumeToken(nil, token.SLASH)
 		lex.nextChar()
+	case '%':
+		tok = lex.consumeToken(nil, token.PERCENT)
+		lex.nextChar()
 	case '!':
 		tok.Pos = lex.pos
-		lex.nextChar()
 
-		invalidCharacter := diagnostics.Diag{
-			Message: fmt.Sprintf(
-				"%s:%d:%d: invalid character !",
-				tok.Pos.Filename,
-				tok.Pos.Line,
-				tok.Pos.Column,
-			),
-		}
+		tok.Kind = token.BANG
+		lex.nextChar() // !
 
 		next := lex.peekChar()
-		if next == eof {
-			lex.collector.ReportAndSave(invalidCharacter)
-			return tok
-		}
-		if next == '=' {
-			lex.nextChar()
-			tok.Kind = token.BANG_EQUAL
+		if next != '=' {
 			return tok
 		}
-
-		lex.collector.ReportAndSave(invalidCharacter)
-		return tok
+		lex.nextChar() // =
+		tok.Kind = token.BANG_EQUAL
 	case '>':
 		tok.Pos = lex.pos
 
@@ -300,6 +357,18 @@ func (lex *Lexer) getStringLiteral() *token.Token {
 	return tok
 }
 
+func (lex *Lexer) isComment() bool {
+	return lex.peekChar() == '/' && lex.offset+1 < len(lex.src) && lex.src[lex.offset+1] == '/'
+}
+
+// Reads a comment until the end of the line. The lexeme includes the leading
+// "//".
+func (lex *Lexer) getComment() *token.Token {
+	position := lex.pos
+	comment := lex.readWhile(func(ch byte) bool { return ch != '\n' })
+	return token.New(comment, token.COMMENT, position)
+}
+
 func (lex *Lexer) getNumberLiteral(position token.Pos) *token.Token {
 	number := lex.readWhile(
 		func(chr byte) bool { return (chr >= '0' && chr <= '9') || chr == '_' },
@@ -324,8 +393,9 @@ func (lex *Lexer) consumeToken(lexeme []byte, kind token.Kind) *token.Token {
 	return token.New(lexeme, kind, lex.pos)
 }
 
-func (lex *Lexer) skipWhitespace() {
-	lex.readWhile(func(ch byte) bool { return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' })
+// Skips whitespace, except for newlines if a semicolon may be inserted
+func (lex *Lexer) skipWhitespace(stopAtNewline bool) {
+	lex.readWhile(func(ch byte) bool { return ch == ' ' || ch == '\t' || ch == '\r' || (ch == '\n' && !stopAtNewline) })
 }
 
 func (lex *Lexer) readWhile(isValid func(byte) bool) []byte {
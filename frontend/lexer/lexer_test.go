@@ -28,6 +28,18 @@ func TestTokenKinds(t *testing.T) {
 		{"elif", token.ELIF},
 		{"else", token.ELSE},
 		{"not", token.NOT},
+		{"in", token.IN},
+		{"test", token.TEST},
+		{"bench", token.BENCH},
+		{"assert", token.ASSERT},
+		{"const", token.CONST},
+		{"static_assert", token.STATIC_ASSERT},
+		{"orelse", token.ORELSE},
+		{"try", token.TRY},
+		{"catch", token.CATCH},
+		{"error", token.ERROR},
+		{"as", token.AS},
+		{"link", token.LINK},
 
 		// Types
 		{"bool", token.BOOL_TYPE},
@@ -43,19 +55,26 @@ func TestTokenKinds(t *testing.T) {
 		{"u16", token.U16_TYPE},
 		{"u32", token.U32_TYPE},
 		{"u64", token.U64_TYPE},
+		{"string", token.STRING_TYPE},
+		{"void", token.VOID_TYPE},
 
 		// Other tokens
 		{"(", token.OPEN_PAREN},
 		{")", token.CLOSE_PAREN},
 		{"{", token.OPEN_CURLY},
 		{"}", token.CLOSE_CURLY},
+		{"[", token.OPEN_BRACKET},
+		{"]", token.CLOSE_BRACKET},
 		{",", token.COMMA},
+		{"@", token.AT},
 		{";", token.SEMICOLON},
+		{"?", token.QUESTION},
 		{".", token.DOT},
 		{"..", token.DOT_DOT},
 		{"...", token.DOT_DOT_DOT},
 		{"=", token.EQUAL},
 		{":=", token.COLON_EQUAL},
+		{"!", token.BANG},
 		{"!=", token.BANG_EQUAL},
 		{"==", token.EQUAL_EQUAL},
 		{">", token.GREATER},
@@ -66,6 +85,7 @@ func TestTokenKinds(t *testing.T) {
 		{"-", token.MINUS},
 		{"*", token.STAR},
 		{"/", token.SLASH},
+		{"%", token.PERCENT},
 	}
 
 	for _, test := range tests {
@@ -115,6 +135,7 @@ func TestTokenPos(t *testing.T) {
 			{Filename: "test.tt", Line: 1, Column: 1},
 			{Filename: "test.tt", Line: 2, Column: 1},
 			{Filename: "test.tt", Line: 2, Column: 7},
+			{Filename: "test.tt", Line: 2, Column: 12},
 			{Filename: "test.tt", Line: 3, Column: 1},
 			{Filename: "test.tt", Line: 3, Column: 2}},
 		},
@@ -158,6 +179,118 @@ func TestTokenPos(t *testing.T) {
 	}
 }
 
+type commentTest struct {
+	keepComments bool
+	tokens       []*token.Token
+}
+
+func TestComments(t *testing.T) {
+	filename := "test.tt"
+	input := "a // one\n// two\nb / c"
+
+	tests := []commentTest{
+		{
+			keepComments: false,
+			tokens: []*token.Token{
+				token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
+				token.New([]byte("\n"), token.SEMICOLON, token.NewPosition(filename, 9, 1)),
+				token.New([]byte("b"), token.ID, token.NewPosition(filename, 1, 3)),
+				token.New(nil, token.SLASH, token.NewPosition(filename, 3, 3)),
+				token.New([]byte("c"), token.ID, token.NewPosition(filename, 5, 3)),
+				token.New(nil, token.EOF, token.NewPosition(filename, 6, 3)),
+			},
+		},
+		{
+			keepComments: true,
+			tokens: []*token.Token{
+				token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
+				token.New([]byte("// one"), token.COMMENT, token.NewPosition(filename, 3, 1)),
+				token.New([]byte("\n"), token.SEMICOLON, token.NewPosition(filename, 9, 1)),
+				token.New([]byte("// two"), token.COMMENT, token.NewPosition(filename, 1, 2)),
+				token.New([]byte("b"), token.ID, token.NewPosition(filename, 1, 3)),
+				token.New(nil, token.SLASH, token.NewPosition(filename, 3, 3)),
+				token.New([]byte("c"), token.ID, token.NewPosition(filename, 5, 3)),
+				token.New(nil, token.EOF, token.NewPosition(filename, 6, 3)),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestComments(keepComments=%v)", test.keepComments), func(t *testing.T) {
+			collector := diagnostics.New()
+			lexer := New(filename, []byte(input), collector)
+			lexer.KeepComments = test.keepComments
+
+			tokens, err := lexer.Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tokens, test.tokens) {
+				t.Fatalf("\nexp: %v\ngot: %v\n", test.tokens, tokens)
+			}
+		})
+	}
+}
+
+type semicolonTest struct {
+	input string
+	kinds []token.Kind
+}
+
+func TestSemicolonInsertion(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []semicolonTest{
+		{"a\n", []token.Kind{token.ID, token.SEMICOLON, token.EOF}},
+		{"1\n\"a\"\ntrue\n", []token.Kind{
+			token.INTEGER_LITERAL, token.SEMICOLON,
+			token.STRING_LITERAL, token.SEMICOLON,
+			token.TRUE_BOOL_LITERAL, token.SEMICOLON,
+			token.EOF,
+		}},
+		{"return\n", []token.Kind{token.RETURN, token.SEMICOLON, token.EOF}},
+		{"fn f() i32\n", []token.Kind{token.FN, token.ID, token.OPEN_PAREN, token.CLOSE_PAREN, token.I32_TYPE, token.SEMICOLON, token.EOF}},
+		{"f()\na[0]\n}\n", []token.Kind{
+			token.ID, token.OPEN_PAREN, token.CLOSE_PAREN, token.SEMICOLON,
+			token.ID, token.OPEN_BRACKET, token.INTEGER_LITERAL, token.CLOSE_BRACKET, token.SEMICOLON,
+			token.CLOSE_CURLY, token.SEMICOLON,
+			token.EOF,
+		}},
+		// Only one semicolon is inserted for several newlines
+		{"a\n\n\nb", []token.Kind{token.ID, token.SEMICOLON, token.ID, token.EOF}},
+		// An explicit semicolon already ends the statement
+		{"a;\n", []token.Kind{token.ID, token.SEMICOLON, token.EOF}},
+		// A comment at the end of the line does not prevent the insertion
+		{"a // comment\n", []token.Kind{token.ID, token.SEMICOLON, token.EOF}},
+		// A line ending with an operator continues on the next one
+		{"a +\nb", []token.Kind{token.ID, token.PLUS, token.ID, token.EOF}},
+		{"f(a,\nb)", []token.Kind{token.ID, token.OPEN_PAREN, token.ID, token.COMMA, token.ID, token.CLOSE_PAREN, token.EOF}},
+		{"if a {\n", []token.Kind{token.IF, token.ID, token.OPEN_CURLY, token.EOF}},
+		// No semicolon is inserted at the end of the file
+		{"a", []token.Kind{token.ID, token.EOF}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestSemicolonInsertion(%q)", test.input), func(t *testing.T) {
+			collector := diagnostics.New()
+			lexer := New(filename, []byte(test.input), collector)
+
+			tokens, err := lexer.Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var kinds []token.Kind
+			for _, tok := range tokens {
+				kinds = append(kinds, tok.Kind)
+			}
+			if !reflect.DeepEqual(kinds, test.kinds) {
+				t.Fatalf("\nexp: %v\ngot: %v\n", test.kinds, kinds)
+			}
+		})
+	}
+}
+
 type tokenIdentTest struct {
 	lexeme string
 	isId   bool
@@ -185,6 +318,13 @@ func TestIsIdentifier(t *testing.T) {
 
 		{"true", false},
 		{"false", false},
+		{"nil", false},
+		{"orelse", false},
+		{"try", false},
+		{"catch", false},
+		{"error", false},
+		{"as", false},
+		{"link", false},
 		{"fn", false},
 		{"for", false},
 		{"while", false},
@@ -204,6 +344,7 @@ func TestIsIdentifier(t *testing.T) {
 		{"u16", false},
 		{"u32", false},
 		{"u64", false},
+		{"string", false},
 	}
 
 	for _, test := range tests {
@@ -253,6 +394,7 @@ func TestIsLiteral(t *testing.T) {
 		{"\"Hello world\"", token.STRING_LITERAL},
 		{"true", token.TRUE_BOOL_LITERAL},
 		{"false", token.FALSE_BOOL_LITERAL},
+		{"nil", token.NIL_LITERAL},
 	}
 
 	for _, test := range tests {
@@ -289,18 +431,10 @@ func TestLexicalErrors(t *testing.T) {
 
 	tests := []lexicalErrorTest{
 		{
-			input: "!",
-			diags: []diagnostics.Diag{
-				{
-					Message: "test.tt:1:1: invalid character !",
-				},
-			},
-		},
-		{
-			input: "!!",
+			input: "#",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:1: invalid character !",
+					Message: "test.tt:1:1: invalid character #",
 				},
 			},
 		},
@@ -321,10 +455,10 @@ func TestLexicalErrors(t *testing.T) {
 			},
 		},
 		{
-			input: "?",
+			input: "$",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:1: invalid character ?",
+					Message: "test.tt:1:1: invalid character $",
 				},
 			},
 		},
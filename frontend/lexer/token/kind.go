@@ -15,11 +15,16 @@ const (
 	// Identifier
 	ID
 
+	// Line comment, such as "// comment". Only produced when the lexer keeps
+	// comments.
+	COMMENT
+
 	// Literals
 	INTEGER_LITERAL
 	STRING_LITERAL
 	TRUE_BOOL_LITERAL
 	FALSE_BOOL_LITERAL
+	NIL_LITERAL
 
 	// Keywords
 	FN
@@ -33,6 +38,18 @@ const (
 	NOT
 	AND
 	OR
+	IN
+	TEST
+	BENCH
+	ASSERT
+	CONST
+	STATIC_ASSERT
+	ORELSE
+	TRY
+	CATCH
+	ERROR
+	AS
+	LINK
 
 	// Types
 	BOOL_TYPE // bool
@@ -49,9 +66,11 @@ const (
 	U32_TYPE  // u32
 	U64_TYPE  // u64
 
-	// This type is not explicit. We don't have a keyword for this, the absence
-	// of an explicit type means a void type
-	VOID_TYPE
+	STRING_TYPE // string
+
+	// This type is usually not explicit, the absence of an explicit type means
+	// a void type. The keyword is only needed on error unions, such as "!void"
+	VOID_TYPE // void
 
 	// (
 	OPEN_PAREN
@@ -63,12 +82,23 @@ const (
 	// }
 	CLOSE_CURLY
 
+	// [
+	OPEN_BRACKET
+	// ]
+	CLOSE_BRACKET
+
 	// ,
 	COMMA
 
+	// @
+	AT
+
 	// ;
 	SEMICOLON
 
+	// ?
+	QUESTION
+
 	// .
 	DOT
 	// ..
@@ -80,6 +110,8 @@ const (
 	EQUAL
 	// :=
 	COLON_EQUAL
+	// !
+	BANG
 	// !=
 	BANG_EQUAL
 	// ==
@@ -102,23 +134,38 @@ const (
 	STAR
 	// /
 	SLASH
+	// %
+	PERCENT
 )
 
 var KEYWORDS map[string]Kind = map[string]Kind{
-	"fn":     FN,
-	"for":    FOR,
-	"while":  WHILE,
-	"return": RETURN,
-	"extern": EXTERN,
-	"if":     IF,
-	"elif":   ELIF,
-	"else":   ELSE,
-	"not":    NOT,
-	"and":    AND,
-	"or":     OR,
+	"fn":            FN,
+	"for":           FOR,
+	"while":         WHILE,
+	"return":        RETURN,
+	"extern":        EXTERN,
+	"if":            IF,
+	"elif":          ELIF,
+	"else":          ELSE,
+	"not":           NOT,
+	"and":           AND,
+	"or":            OR,
+	"in":            IN,
+	"test":          TEST,
+	"bench":         BENCH,
+	"assert":        ASSERT,
+	"const":         CONST,
+	"static_assert": STATIC_ASSERT,
+	"orelse":        ORELSE,
+	"try":           TRY,
+	"catch":         CATCH,
+	"error":         ERROR,
+	"as":            AS,
+	"link":          LINK,
 
 	"true":  TRUE_BOOL_LITERAL,
 	"false": FALSE_BOOL_LITERAL,
+	"nil":   NIL_LITERAL,
 
 	"bool": BOOL_TYPE,
 
@@ -133,6 +180,9 @@ var KEYWORDS map[string]Kind = map[string]Kind{
 	"u16":  U16_TYPE,
 	"u32":  U32_TYPE,
 	"u64":  U64_TYPE,
+
+	"string": STRING_TYPE,
+	"void":   VOID_TYPE,
 }
 
 var BASIC_TYPES map[Kind]bool = map[Kind]bool{
@@ -148,6 +198,8 @@ var BASIC_TYPES map[Kind]bool = map[Kind]bool{
 	U16_TYPE:  true,
 	U32_TYPE:  true,
 	U64_TYPE:  true,
+
+	STRING_TYPE: true,
 }
 
 var LITERAL_KIND map[Kind]bool = map[Kind]bool{
@@ -155,6 +207,7 @@ var LITERAL_KIND map[Kind]bool = map[Kind]bool{
 	STRING_LITERAL:     true,
 	TRUE_BOOL_LITERAL:  true,
 	FALSE_BOOL_LITERAL: true,
+	NIL_LITERAL:        true,
 }
 
 var NUMERIC_TYPES map[Kind]bool = map[Kind]bool{
@@ -200,6 +253,15 @@ func (kind Kind) BitSize() int {
 	}
 }
 
+func (kind Kind) IsSigned() bool {
+	switch kind {
+	case INT_TYPE, I8_TYPE, I16_TYPE, I32_TYPE, I64_TYPE:
+		return true
+	default:
+		return false
+	}
+}
+
 func (kind Kind) IsBasicType() bool {
 	_, ok := BASIC_TYPES[kind]
 	return ok
@@ -213,6 +275,8 @@ func (kind Kind) String() string {
 		return "INVALID"
 	case ID:
 		return "identifier"
+	case COMMENT:
+		return "comment"
 	case INTEGER_LITERAL:
 		return "integer literal"
 	case STRING_LITERAL:
@@ -221,6 +285,8 @@ func (kind Kind) String() string {
 		return "true"
 	case FALSE_BOOL_LITERAL:
 		return "false"
+	case NIL_LITERAL:
+		return "nil"
 	case FN:
 		return "fn"
 	case FOR:
@@ -243,6 +309,30 @@ func (kind Kind) String() string {
 		return "and"
 	case OR:
 		return "or"
+	case IN:
+		return "in"
+	case TEST:
+		return "test"
+	case BENCH:
+		return "bench"
+	case ASSERT:
+		return "assert"
+	case CONST:
+		return "const"
+	case STATIC_ASSERT:
+		return "static_assert"
+	case ORELSE:
+		return "orelse"
+	case TRY:
+		return "try"
+	case CATCH:
+		return "catch"
+	case ERROR:
+		return "error"
+	case AS:
+		return "as"
+	case LINK:
+		return "link"
 	case BOOL_TYPE:
 		return "bool"
 	case INT_TYPE:
@@ -265,6 +355,8 @@ func (kind Kind) String() string {
 		return "u32"
 	case U64_TYPE:
 		return "u64"
+	case STRING_TYPE:
+		return "string"
 	case VOID_TYPE:
 		return "void"
 	case OPEN_PAREN:
@@ -275,10 +367,18 @@ func (kind Kind) String() string {
 		return "{"
 	case CLOSE_CURLY:
 		return "}"
+	case OPEN_BRACKET:
+		return "["
+	case CLOSE_BRACKET:
+		return "]"
 	case COMMA:
 		return ","
+	case AT:
+		return "@"
 	case SEMICOLON:
 		return ";"
+	case QUESTION:
+		return "?"
 	case DOT:
 		return "."
 	case DOT_DOT:
@@ -289,6 +389,8 @@ func (kind Kind) String() string {
 		return "="
 	case COLON_EQUAL:
 		return ":="
+	case BANG:
+		return "!"
 	case BANG_EQUAL:
 		return "!="
 	case EQUAL_EQUAL:
@@ -309,6 +411,8 @@ func (kind Kind) String() string {
 		return "*"
 	case SLASH:
 		return "/"
+	case PERCENT:
+		return "%"
 	default:
 		log.Fatalf("String() method not defined for the following token kind '%d'", kind)
 	}
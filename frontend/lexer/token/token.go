@@ -10,9 +10,24 @@ func New(lexeme []byte, kind Kind, position Pos) *Token {
 	return &Token{Lexeme: lexeme, Kind: kind, Pos: position}
 }
 
+// Describes the token on diagnostics. Inserted semicolons are described as
+// newlines, since they are not written on the source code.
+func (token *Token) String() string {
+	if token.IsAutoSemicolon() {
+		return "newline"
+	}
+	return token.Kind.String()
+}
+
 func (token *Token) Name() string {
 	if token.Kind == ID {
 		return string(token.Lexeme)
 	}
 	return token.Kind.String()
 }
+
+// Reports whether the token is a semicolon inserted by the lexer at the end of
+// a line, instead of one written on the source code
+func (token *Token) IsAutoSemicolon() bool {
+	return token.Kind == SEMICOLON && string(token.Lexeme) == "\n"
+}
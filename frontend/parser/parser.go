@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/HicaroD/Telia/diagnostics"
 	"github.com/HicaroD/Telia/frontend/ast"
@@ -77,11 +78,13 @@ func (p *Parser) parseFile(lex *lexer.Lexer, moduleScope *ast.Scope) (*ast.File,
 	p.lex = lex
 	p.moduleScope = moduleScope
 
+	start := lex.Pos()
 	nodes, err := p.parseFileNodes()
 	if err != nil {
 		return nil, err
 	}
 	file.Body = nodes
+	file.Loc = p.spanFrom(start)
 
 	return file, nil
 }
@@ -146,6 +149,7 @@ func (p *Parser) processModuleEntries(path string, handler func(entry os.DirEntr
 func (p *Parser) next() (ast.Node, bool, error) {
 	eof := false
 
+	p.skipAutoSemicolons()
 	tok := p.lex.Peek()
 	if tok.Kind == token.EOF {
 		eof = true
@@ -158,6 +162,45 @@ func (p *Parser) next() (ast.Node, bool, error) {
 	case token.EXTERN:
 		externDecl, err := p.parseExternDecl()
 		return externDecl, eof, err
+	case token.TEST:
+		testDecl, err := p.parseTestDecl()
+		return testDecl, eof, err
+	case token.BENCH:
+		benchDecl, err := p.parseBenchDecl()
+		return benchDecl, eof, err
+	case token.CONST:
+		constDecl, err := p.parseConstDecl()
+		return constDecl, eof, err
+	case token.STATIC_ASSERT:
+		staticAssert, err := p.parseStaticAssert()
+		return staticAssert, eof, err
+	case token.AT:
+		attributes, err := p.parseAttributes()
+		if err != nil {
+			return nil, eof, err
+		}
+		fn := p.lex.Peek()
+		if fn.Kind != token.FN {
+			pos := fn.Pos
+			expectedFunction := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected function declaration after attributes, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					fn,
+				),
+			}
+			p.collector.ReportAndSave(expectedFunction)
+			return nil, eof, diagnostics.COMPILER_ERROR_FOUND
+		}
+		fnDecl, err := p.parseFnDecl()
+		if err != nil {
+			return nil, eof, err
+		}
+		fnDecl.Attributes = attributes
+		fnDecl.Loc.Start = attributes[0].Loc.Start
+		return fnDecl, eof, nil
 	default:
 		pos := tok.Pos
 		unexpectedTokenOnGlobalScope := diagnostics.Diag{
@@ -189,7 +232,7 @@ func ParseExprFrom(expr, filename string) (ast.Expr, error) {
 }
 
 func (p *Parser) parseExternDecl() (*ast.ExternDecl, error) {
-	_, ok := p.expect(token.EXTERN)
+	extern, ok := p.expect(token.EXTERN)
 	if !ok {
 		return nil, fmt.Errorf("expected 'extern'")
 	}
@@ -203,13 +246,34 @@ func (p *Parser) parseExternDecl() (*ast.ExternDecl, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				name.Kind,
+				name,
 			),
 		}
 		p.collector.ReportAndSave(expectedName)
 		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
 
+	// The library to link, such as 'extern m link "m"'
+	var link *token.Token
+	if p.lex.NextIs(token.LINK) {
+		p.lex.Skip() // link
+		link, ok = p.expect(token.STRING_LITERAL)
+		if !ok {
+			pos := link.Pos
+			expectedLibrary := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected library name, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					link,
+				),
+			}
+			p.collector.ReportAndSave(expectedLibrary)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+	}
+
 	openCurly, ok := p.expect(token.OPEN_CURLY)
 	if !ok {
 		pos := openCurly.Pos
@@ -219,7 +283,7 @@ func (p *Parser) parseExternDecl() (*ast.ExternDecl, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				openCurly.Kind,
+				openCurly,
 			),
 		}
 		p.collector.ReportAndSave(expectedOpenCurly)
@@ -227,11 +291,21 @@ func (p *Parser) parseExternDecl() (*ast.ExternDecl, error) {
 	}
 
 	var prototypes []*ast.Proto
+	var vars []*ast.ExternVar
 	for {
+		p.skipAutoSemicolons()
 		if p.lex.NextIs(token.CLOSE_CURLY) {
 			break
 		}
 
+		if p.lex.NextIs(token.ID) {
+			variable, err := p.parseExternVar()
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, variable)
+			continue
+		}
 		proto, err := p.parsePrototype()
 		if err != nil {
 			return nil, err
@@ -249,17 +323,97 @@ func (p *Parser) parseExternDecl() (*ast.ExternDecl, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				closeCurly.Kind,
+				closeCurly,
 			),
 		}
 		p.collector.ReportAndSave(expectedCloseCurly)
 		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
 
-	return &ast.ExternDecl{Scope: nil, Name: name, Prototypes: prototypes}, nil
+	return &ast.ExternDecl{
+		Loc:        p.spanFrom(extern.Pos),
+		Scope:      nil,
+		Name:       name,
+		Link:       link,
+		Prototypes: prototypes,
+		Vars:       vars,
+	}, nil
+}
+
+// Parses a list of attributes, such as "@inline @export("name")"
+func (p *Parser) parseAttributes() ([]*ast.Attribute, error) {
+	var attributes []*ast.Attribute
+	for p.lex.NextIs(token.AT) {
+		at, _ := p.expect(token.AT)
+
+		name, ok := p.expect(token.ID)
+		if !ok {
+			pos := name.Pos
+			expectedAttributeName := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected attribute name, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name,
+				),
+			}
+			p.collector.ReportAndSave(expectedAttributeName)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+
+		attribute := &ast.Attribute{At: at.Pos, Name: name}
+		if p.lex.NextIs(token.OPEN_PAREN) {
+			p.lex.Skip() // (
+			args, err := p.parseExprList([]token.Kind{token.CLOSE_PAREN})
+			if err != nil {
+				return nil, err
+			}
+			closeParen, ok := p.expect(token.CLOSE_PAREN)
+			if !ok {
+				pos := closeParen.Pos
+				expectedCloseParen := diagnostics.Diag{
+					Message: fmt.Sprintf(
+						"%s:%d:%d: expected ), not %s",
+						pos.Filename,
+						pos.Line,
+						pos.Column,
+						closeParen,
+					),
+				}
+				p.collector.ReportAndSave(expectedCloseParen)
+				return nil, diagnostics.COMPILER_ERROR_FOUND
+			}
+			attribute.Args = args
+		}
+		attribute.Loc = p.spanFrom(at.Pos)
+		attributes = append(attributes, attribute)
+
+		// Attributes are usually written on the line above the function
+		p.skipAutoSemicolons()
+	}
+	return attributes, nil
+}
+
+// Useful for testing
+func ParseAttributesFrom(input, filename string) ([]*ast.Attribute, error) {
+	collector := diagnostics.New()
+
+	src := []byte(input)
+	lex := lexer.New(filename, src, collector)
+	parser := NewWithLex(lex, collector)
+
+	attributes, err := parser.parseAttributes()
+	return attributes, err
 }
 
 func (p *Parser) parsePrototype() (*ast.Proto, error) {
+	start := p.lex.Peek().Pos
+	attributes, err := p.parseAttributes()
+	if err != nil {
+		return nil, err
+	}
+
 	fn, ok := p.expect(token.FN)
 	if !ok {
 		pos := fn.Pos
@@ -269,7 +423,7 @@ func (p *Parser) parsePrototype() (*ast.Proto, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				fn.Kind,
+				fn,
 			),
 		}
 		p.collector.ReportAndSave(expectedCloseCurly)
@@ -285,7 +439,7 @@ func (p *Parser) parsePrototype() (*ast.Proto, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				name.Kind,
+				name,
 			),
 		}
 		p.collector.ReportAndSave(expectedName)
@@ -301,8 +455,13 @@ func (p *Parser) parsePrototype() (*ast.Proto, error) {
 	if err != nil {
 		return nil, err
 	}
+	alias, err := p.parseSymbolAlias()
+	if err != nil {
+		return nil, err
+	}
+	loc := p.spanFrom(start)
 
-	semicolon, ok := p.expect(token.SEMICOLON)
+	semicolon, ok := p.expectSemicolon()
 	if !ok {
 		pos := semicolon.Pos
 		expectedSemicolon := diagnostics.Diag{
@@ -311,20 +470,105 @@ func (p *Parser) parsePrototype() (*ast.Proto, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				semicolon.Kind,
+				semicolon,
+			),
+		}
+		p.collector.ReportAndSave(expectedSemicolon)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	return &ast.Proto{
+		Loc:        loc,
+		Attributes: attributes,
+		Name:       name,
+		Params:     params,
+		RetType:    returnType,
+		Alias:      alias,
+	}, nil
+}
+
+// Parses a global variable of an extern block, such as "stderr *u8;" or
+// 'err *u8 = "stderr";'
+func (p *Parser) parseExternVar() (*ast.ExternVar, error) {
+	name, _ := p.expect(token.ID)
+
+	ty, err := p.parseExprType()
+	if err != nil {
+		tok := p.lex.Peek()
+		pos := tok.Pos
+		expectedType := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected type for '%s', not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				name.Name(),
+				tok,
+			),
+		}
+		p.collector.ReportAndSave(expectedType)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	alias, err := p.parseSymbolAlias()
+	if err != nil {
+		return nil, err
+	}
+	loc := p.spanFrom(name.Pos)
+
+	semicolon, ok := p.expectSemicolon()
+	if !ok {
+		pos := semicolon.Pos
+		expectedSemicolon := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ; at the end of variable, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				semicolon,
 			),
 		}
 		p.collector.ReportAndSave(expectedSemicolon)
 		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
 
-	return &ast.Proto{Name: name, Params: params, RetType: returnType}, nil
+	return &ast.ExternVar{
+		Loc:   loc,
+		Name:  name,
+		Type:  ty,
+		Alias: alias,
+	}, nil
+}
+
+// Parses the optional name of the symbol of an external declaration, such as
+// '= "puts"'. It returns nil if there is none.
+func (p *Parser) parseSymbolAlias() (*token.Token, error) {
+	if !p.lex.NextIs(token.EQUAL) {
+		return nil, nil
+	}
+	p.lex.Skip() // =
+
+	alias, ok := p.expect(token.STRING_LITERAL)
+	if !ok {
+		pos := alias.Pos
+		expectedSymbol := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected symbol name, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				alias,
+			),
+		}
+		p.collector.ReportAndSave(expectedSymbol)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	return alias, nil
 }
 
 func (p *Parser) parseFnDecl() (*ast.FunctionDecl, error) {
 	var err error
 
-	_, ok := p.expect(token.FN)
+	fn, ok := p.expect(token.FN)
 	if !ok {
 		return nil, fmt.Errorf("expected 'fn'")
 	}
@@ -338,7 +582,7 @@ func (p *Parser) parseFnDecl() (*ast.FunctionDecl, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				name.Kind,
+				name,
 			),
 		}
 		p.collector.ReportAndSave(expectedIdentifier)
@@ -362,163 +606,402 @@ func (p *Parser) parseFnDecl() (*ast.FunctionDecl, error) {
 
 	fnScope := ast.NewScope(p.moduleScope)
 	fnDecl := &ast.FunctionDecl{
+		Loc:     p.spanFrom(fn.Pos),
 		Scope:   fnScope,
 		Name:    name,
 		Params:  params,
 		Block:   block,
 		RetType: returnType,
 	}
+	// Functions are declared on the module scope by sema, once every file of
+	// the module is parsed
+	return fnDecl, nil
+}
 
-	err = p.moduleScope.Insert(name.Name(), fnDecl)
-	if err != nil {
-		if err == ast.ERR_SYMBOL_ALREADY_DEFINED_ON_SCOPE {
-			functionRedeclaration := diagnostics.Diag{
-				Message: fmt.Sprintf(
-					"%s:%d:%d: function '%s' already declared on scope",
-					name.Pos.Filename,
-					name.Pos.Line,
-					name.Pos.Column,
-					name.Name(),
-				),
-			}
-			p.collector.ReportAndSave(functionRedeclaration)
+func (p *Parser) parseTestDecl() (*ast.TestDecl, error) {
+	test, ok := p.expect(token.TEST)
+	if !ok {
+		return nil, fmt.Errorf("expected 'test'")
+	}
+
+	name, ok := p.expect(token.STRING_LITERAL)
+	if !ok {
+		pos := name.Pos
+		expectedTestName := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected test name, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				name,
+			),
 		}
+		p.collector.ReportAndSave(expectedTestName)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	block, err := p.parseBlock()
+	if err != nil {
 		return nil, err
 	}
 
-	return fnDecl, nil
+	return &ast.TestDecl{Loc: p.spanFrom(test.Pos), Test: test, Name: name, Block: block}, nil
 }
 
-// Useful for testing
-func parseFnDeclFrom(filename, input string, moduleScope *ast.Scope) (*ast.FunctionDecl, error) {
-	collector := diagnostics.New()
+func (p *Parser) parseBenchDecl() (*ast.BenchDecl, error) {
+	bench, ok := p.expect(token.BENCH)
+	if !ok {
+		return nil, fmt.Errorf("expected 'bench'")
+	}
 
-	src := []byte(input)
-	lexer := lexer.New(filename, src, collector)
-	parser := NewWithLex(lexer, collector)
-	parser.moduleScope = moduleScope
+	name, ok := p.expect(token.STRING_LITERAL)
+	if !ok {
+		pos := name.Pos
+		expectedBenchName := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected benchmark name, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				name,
+			),
+		}
+		p.collector.ReportAndSave(expectedBenchName)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
 
-	fnDecl, err := parser.parseFnDecl()
+	block, err := p.parseBlock()
 	if err != nil {
 		return nil, err
 	}
 
-	return fnDecl, nil
+	return &ast.BenchDecl{Loc: p.spanFrom(bench.Pos), Bench: bench, Name: name, Block: block}, nil
 }
 
-func (p *Parser) parseFunctionParams() (*ast.FieldList, error) {
-	var params []*ast.Field
-	isVariadic := false
+// Useful for testing
+func ParseConstDeclFrom(input, filename string) (*ast.ConstDecl, error) {
+	collector := diagnostics.New()
 
-	openParen, ok := p.expect(token.OPEN_PAREN)
+	src := []byte(input)
+	lex := lexer.New(filename, src, collector)
+	parser := NewWithLex(lex, collector)
+
+	constDecl, err := parser.parseConstDecl()
+	return constDecl, err
+}
+
+func (p *Parser) parseConstDecl() (*ast.ConstDecl, error) {
+	constTok, ok := p.expect(token.CONST)
 	if !ok {
-		pos := openParen.Pos
-		expectedOpenParen := diagnostics.Diag{
+		return nil, fmt.Errorf("expected 'const'")
+	}
+
+	name, ok := p.expect(token.ID)
+	if !ok {
+		pos := name.Pos
+		expectedName := diagnostics.Diag{
 			Message: fmt.Sprintf(
-				"%s:%d:%d: expected (, not %s",
+				"%s:%d:%d: expected constant name, not %s",
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				openParen.Kind,
+				name,
 			),
 		}
-		p.collector.ReportAndSave(expectedOpenParen)
+		p.collector.ReportAndSave(expectedName)
 		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
 
-	for {
-		if p.lex.NextIs(token.CLOSE_PAREN) {
-			break
-		}
-		if p.lex.NextIs(token.DOT_DOT_DOT) {
-			isVariadic = true
-			tok := p.lex.Peek()
-			pos := tok.Pos
-			p.lex.Skip()
-
-			if !p.lex.NextIs(token.CLOSE_PAREN) {
-				// TODO(errors):
-				// "fn name(a int, ...,) {}" because of the comma
-				unexpectedDotDotDot := diagnostics.Diag{
-					Message: fmt.Sprintf(
-						"%s:%d:%d: ... is only allowed at the end of parameter list",
-						pos.Filename,
-						pos.Line,
-						pos.Column,
-					),
-				}
-				p.collector.ReportAndSave(unexpectedDotDotDot)
-				return nil, diagnostics.COMPILER_ERROR_FOUND
-			}
-			break
-		}
-
-		name, ok := p.expect(token.ID)
-		if !ok {
-			pos := name.Pos
-			expectedCloseParenOrId := diagnostics.Diag{
-				Message: fmt.Sprintf(
-					"%s:%d:%d: expected parameter or ), not %s",
-					pos.Filename,
-					pos.Line,
-					pos.Column,
-					name.Kind,
-				),
-			}
-			p.collector.ReportAndSave(expectedCloseParenOrId)
-			return nil, diagnostics.COMPILER_ERROR_FOUND
-		}
-		paramType, err := p.parseExprType()
+	var ty ast.ExprType
+	if !p.lex.NextIs(token.EQUAL) {
+		var err error
+		ty, err = p.parseExprType()
 		if err != nil {
 			tok := p.lex.Peek()
 			pos := tok.Pos
-			expectedParamType := diagnostics.Diag{
+			expectedTypeOrEqual := diagnostics.Diag{
 				Message: fmt.Sprintf(
-					"%s:%d:%d: expected parameter type for '%s', not %s",
+					"%s:%d:%d: expected type or =, not %s",
 					pos.Filename,
 					pos.Line,
 					pos.Column,
-					name.Lexeme,
-					tok.Kind,
+					tok,
 				),
 			}
-			p.collector.ReportAndSave(expectedParamType)
+			p.collector.ReportAndSave(expectedTypeOrEqual)
 			return nil, diagnostics.COMPILER_ERROR_FOUND
 		}
-		params = append(params, &ast.Field{Name: name, Type: paramType})
-
-		if p.lex.NextIs(token.COMMA) {
-			p.lex.Skip() // ,
-			continue
-		}
 	}
 
-	closeParen, ok := p.expect(token.CLOSE_PAREN)
+	equal, ok := p.expect(token.EQUAL)
 	if !ok {
-		pos := closeParen.Pos
-		expectedCloseParen := diagnostics.Diag{
+		pos := equal.Pos
+		expectedEqual := diagnostics.Diag{
 			Message: fmt.Sprintf(
-				"%s:%d:%d: expected ), not %s",
+				"%s:%d:%d: expected =, not %s",
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				closeParen.Kind,
+				equal,
 			),
 		}
-		p.collector.ReportAndSave(expectedCloseParen)
+		p.collector.ReportAndSave(expectedEqual)
 		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
 
-	return &ast.FieldList{
-		Open:       openParen,
-		Fields:     params,
-		Close:      closeParen,
-		IsVariadic: isVariadic,
-	}, nil
-}
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	loc := p.spanFrom(constTok.Pos)
 
-func (p *Parser) parseReturnType(isPrototype bool) (ast.ExprType, error) {
-	if (isPrototype && p.lex.NextIs(token.SEMICOLON)) ||
+	semicolon, ok := p.expectSemicolon()
+	if !ok {
+		pos := semicolon.Pos
+		expectedSemicolon := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ; at the end of statement, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				semicolon,
+			),
+		}
+		p.collector.ReportAndSave(expectedSemicolon)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	return &ast.ConstDecl{Loc: loc, Const: constTok, Name: name, Type: ty, Value: value}, nil
+}
+
+func (p *Parser) parseStaticAssert() (*ast.StaticAssert, error) {
+	staticAssert, ok := p.expect(token.STATIC_ASSERT)
+	if !ok {
+		return nil, fmt.Errorf("expected 'static_assert'")
+	}
+
+	openParen, ok := p.expect(token.OPEN_PAREN)
+	if !ok {
+		pos := openParen.Pos
+		expectedOpenParen := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected (, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				openParen,
+			),
+		}
+		p.collector.ReportAndSave(expectedOpenParen)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	cond, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	comma, ok := p.expect(token.COMMA)
+	if !ok {
+		pos := comma.Pos
+		expectedComma := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected , and a message, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				comma,
+			),
+		}
+		p.collector.ReportAndSave(expectedComma)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	message, ok := p.expect(token.STRING_LITERAL)
+	if !ok {
+		pos := message.Pos
+		expectedMessage := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected message, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				message,
+			),
+		}
+		p.collector.ReportAndSave(expectedMessage)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	closeParen, ok := p.expect(token.CLOSE_PAREN)
+	if !ok {
+		pos := closeParen.Pos
+		expectedCloseParen := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ), not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				closeParen,
+			),
+		}
+		p.collector.ReportAndSave(expectedCloseParen)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	loc := p.spanFrom(staticAssert.Pos)
+
+	semicolon, ok := p.expectSemicolon()
+	if !ok {
+		pos := semicolon.Pos
+		expectedSemicolon := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ; at the end of statement, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				semicolon,
+			),
+		}
+		p.collector.ReportAndSave(expectedSemicolon)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	return &ast.StaticAssert{Loc: loc, StaticAssert: staticAssert, Cond: cond, Message: message}, nil
+}
+
+// Useful for testing
+func parseFnDeclFrom(filename, input string, moduleScope *ast.Scope) (*ast.FunctionDecl, error) {
+	collector := diagnostics.New()
+
+	src := []byte(input)
+	lexer := lexer.New(filename, src, collector)
+	parser := NewWithLex(lexer, collector)
+	parser.moduleScope = moduleScope
+
+	fnDecl, err := parser.parseFnDecl()
+	if err != nil {
+		return nil, err
+	}
+
+	return fnDecl, nil
+}
+
+func (p *Parser) parseFunctionParams() (*ast.FieldList, error) {
+	var params []*ast.Field
+	isVariadic := false
+
+	openParen, ok := p.expect(token.OPEN_PAREN)
+	if !ok {
+		pos := openParen.Pos
+		expectedOpenParen := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected (, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				openParen,
+			),
+		}
+		p.collector.ReportAndSave(expectedOpenParen)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	for {
+		if p.lex.NextIs(token.CLOSE_PAREN) {
+			break
+		}
+		if p.lex.NextIs(token.DOT_DOT_DOT) {
+			isVariadic = true
+			tok := p.lex.Peek()
+			pos := tok.Pos
+			p.lex.Skip()
+
+			if !p.lex.NextIs(token.CLOSE_PAREN) {
+				// TODO(errors):
+				// "fn name(a int, ...,) {}" because of the comma
+				unexpectedDotDotDot := diagnostics.Diag{
+					Message: fmt.Sprintf(
+						"%s:%d:%d: ... is only allowed at the end of parameter list",
+						pos.Filename,
+						pos.Line,
+						pos.Column,
+					),
+				}
+				p.collector.ReportAndSave(unexpectedDotDotDot)
+				return nil, diagnostics.COMPILER_ERROR_FOUND
+			}
+			break
+		}
+
+		name, ok := p.expect(token.ID)
+		if !ok {
+			pos := name.Pos
+			expectedCloseParenOrId := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected parameter or ), not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name,
+				),
+			}
+			p.collector.ReportAndSave(expectedCloseParenOrId)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		paramType, err := p.parseExprType()
+		if err != nil {
+			tok := p.lex.Peek()
+			pos := tok.Pos
+			expectedParamType := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected parameter type for '%s', not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name.Lexeme,
+					tok,
+				),
+			}
+			p.collector.ReportAndSave(expectedParamType)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		params = append(params, &ast.Field{Loc: p.spanFrom(name.Pos), Name: name, Type: paramType})
+
+		if p.lex.NextIs(token.COMMA) {
+			p.lex.Skip() // ,
+			continue
+		}
+	}
+
+	closeParen, ok := p.expect(token.CLOSE_PAREN)
+	if !ok {
+		pos := closeParen.Pos
+		expectedCloseParen := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ), not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				closeParen,
+			),
+		}
+		p.collector.ReportAndSave(expectedCloseParen)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	return &ast.FieldList{
+		Loc:        p.spanFrom(openParen.Pos),
+		Open:       openParen,
+		Fields:     params,
+		Close:      closeParen,
+		IsVariadic: isVariadic,
+	}, nil
+}
+
+func (p *Parser) parseReturnType(isPrototype bool) (ast.ExprType, error) {
+	// Prototypes may be followed by the name of the symbol, such as
+	// 'fn print(s *u8) = "puts";'
+	if (isPrototype && (p.lex.NextIs(token.SEMICOLON) || p.lex.NextIs(token.EQUAL))) ||
 		p.lex.NextIs(token.OPEN_CURLY) {
 		return &ast.BasicType{Kind: token.VOID_TYPE}, nil
 	}
@@ -533,7 +1016,7 @@ func (p *Parser) parseReturnType(isPrototype bool) (ast.ExprType, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				tok.Kind,
+				tok,
 			),
 		}
 		p.collector.ReportAndSave(expectedReturnTy)
@@ -551,6 +1034,39 @@ func (p *Parser) expect(expectedKind token.Kind) (*token.Token, bool) {
 	return tok, true
 }
 
+// Consumes the semicolon at the end of a statement or declaration. As in Go,
+// it may be omitted before a closing }, so a block fits on a single line, such
+// as "fn f() int { return 0 }". It may also be omitted at the end of the file.
+func (p *Parser) expectSemicolon() (*token.Token, bool) {
+	next := p.lex.Peek()
+	if next.Kind == token.CLOSE_CURLY || next.Kind == token.EOF {
+		return next, true
+	}
+	return p.expect(token.SEMICOLON)
+}
+
+// Skips the semicolons inserted by the lexer at the end of lines, such as the
+// one after the closing } of a block
+func (p *Parser) skipAutoSemicolons() {
+	for p.lex.Peek().IsAutoSemicolon() {
+		p.lex.Skip()
+	}
+}
+
+// Skips the semicolon inserted at the end of a line if the next line starts
+// with a token of the given kind, so "elif" and "else" may be on the line
+// after the closing } of the previous block
+func (p *Parser) skipAutoSemicolonBefore(kind token.Kind) {
+	if p.lex.Peek().IsAutoSemicolon() && p.lex.Peek1().Kind == kind {
+		p.lex.Skip()
+	}
+}
+
+// Returns the span that goes from start to the end of the last consumed token
+func (p *Parser) spanFrom(start token.Pos) ast.Span {
+	return ast.Span{Start: start, End: p.lex.Pos()}
+}
+
 func (p *Parser) parseExprType() (ast.ExprType, error) {
 	tok := p.lex.Peek()
 	switch tok.Kind {
@@ -560,17 +1076,160 @@ func (p *Parser) parseExprType() (ast.ExprType, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &ast.PointerType{Type: ty}, nil
+		return &ast.PointerType{Loc: p.spanFrom(tok.Pos), Type: ty}, nil
+	case token.QUESTION:
+		p.lex.Skip() // ?
+		ty, err := p.parseExprType()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.OptionalType{Loc: p.spanFrom(tok.Pos), Type: ty}, nil
+	case token.BANG:
+		p.lex.Skip() // !
+		ty, err := p.parseExprType()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ErrorUnionType{Loc: p.spanFrom(tok.Pos), Type: ty}, nil
 	case token.ID:
 		p.lex.Skip()
-		return &ast.IdType{Name: tok}, nil
+		return &ast.IdType{Loc: p.spanFrom(tok.Pos), Name: tok}, nil
+	case token.OPEN_PAREN:
+		return p.parseTupleType()
+	case token.OPEN_BRACKET:
+		return p.parseArrayOrSliceType()
 	default:
 		if tok.Kind.IsBasicType() {
 			p.lex.Skip()
-			return &ast.BasicType{Kind: tok.Kind}, nil
+			return &ast.BasicType{Loc: p.spanFrom(tok.Pos), Kind: tok.Kind}, nil
+		}
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+}
+
+func (p *Parser) parseTupleType() (ast.ExprType, error) {
+	openParen, ok := p.expect(token.OPEN_PAREN)
+	if !ok {
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	var types []ast.ExprType
+	for {
+		ty, err := p.parseExprType()
+		if err != nil {
+			return nil, err
 		}
+		types = append(types, ty)
+
+		if !p.lex.NextIs(token.COMMA) {
+			break
+		}
+		p.lex.Skip() // ,
+	}
+
+	closeParen, ok := p.expect(token.CLOSE_PAREN)
+	if !ok {
+		pos := closeParen.Pos
+		expectedCloseParen := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ), not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				closeParen,
+			),
+		}
+		p.collector.ReportAndSave(expectedCloseParen)
 		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
+
+	// "(int)" is the same as "int"
+	if len(types) == 1 {
+		return types[0], nil
+	}
+	return &ast.TupleType{Loc: p.spanFrom(openParen.Pos), Types: types}, nil
+}
+
+func (p *Parser) parseArrayOrSliceType() (ast.ExprType, error) {
+	openBracket, ok := p.expect(token.OPEN_BRACKET)
+	if !ok {
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	// []T
+	if p.lex.NextIs(token.CLOSE_BRACKET) {
+		p.lex.Skip() // ]
+		ty, err := p.parseExprType()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.SliceType{Loc: p.spanFrom(openBracket.Pos), Type: ty}, nil
+	}
+
+	// [N]T
+	length := p.lex.Peek()
+	lengthExpr, err := p.parseExpr()
+	if err != nil {
+		pos := length.Pos
+		expectedArrayLength := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected array length or ], not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				length,
+			),
+		}
+		p.collector.ReportAndSave(expectedArrayLength)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	arrayTy := &ast.ArrayType{}
+	// Lengths other than integer literals are evaluated by semantic analysis
+	if literal, ok := lengthExpr.(*ast.LiteralExpr); ok && length.Kind == token.INTEGER_LITERAL {
+		lengthValue, err := strconv.Atoi(string(literal.Value))
+		if err != nil {
+			pos := length.Pos
+			invalidArrayLength := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: invalid array length %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					length.Lexeme,
+				),
+			}
+			p.collector.ReportAndSave(invalidArrayLength)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		arrayTy.Len = lengthValue
+	} else {
+		arrayTy.LenExpr = lengthExpr
+	}
+
+	closeBracket, ok := p.expect(token.CLOSE_BRACKET)
+	if !ok {
+		pos := closeBracket.Pos
+		expectedCloseBracket := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ], not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				closeBracket,
+			),
+		}
+		p.collector.ReportAndSave(expectedCloseBracket)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	ty, err := p.parseExprType()
+	if err != nil {
+		return nil, err
+	}
+	arrayTy.Type = ty
+	arrayTy.Loc = p.spanFrom(openBracket.Pos)
+	return arrayTy, nil
 }
 
 func (p *Parser) parseStmt() (ast.Stmt, error) {
@@ -578,12 +1237,16 @@ func (p *Parser) parseStmt() (ast.Stmt, error) {
 	switch tok.Kind {
 	case token.RETURN:
 		p.lex.Skip()
-		returnStmt := &ast.ReturnStmt{Return: tok, Value: &ast.VoidExpr{}}
-		if p.lex.NextIs(token.SEMICOLON) {
-			p.lex.Skip()
+		returnStmt := &ast.ReturnStmt{
+			Loc:    p.spanFrom(tok.Pos),
+			Return: tok,
+			Value:  &ast.VoidExpr{Loc: ast.Span{Start: p.lex.Pos(), End: p.lex.Pos()}},
+		}
+		if p.lex.NextIs(token.SEMICOLON) || p.lex.NextIs(token.CLOSE_CURLY) {
+			p.expectSemicolon()
 			return returnStmt, nil
 		}
-		returnValue, err := p.parseExpr()
+		returnValue, err := p.parseReturnValue()
 		if err != nil {
 			tok := p.lex.Peek()
 			pos := tok.Pos
@@ -593,65 +1256,189 @@ func (p *Parser) parseStmt() (ast.Stmt, error) {
 					pos.Filename,
 					pos.Line,
 					pos.Column,
-					tok.Kind,
+					tok,
+				),
+			}
+			p.collector.ReportAndSave(expectedSemicolon)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		returnStmt.Value = returnValue
+		returnStmt.Loc = p.spanFrom(tok.Pos)
+
+		_, ok := p.expectSemicolon()
+		if !ok {
+			tok := p.lex.Peek()
+			pos := tok.Pos
+			expectedSemicolon := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected ; at the end of statement, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					tok,
+				),
+			}
+			p.collector.ReportAndSave(expectedSemicolon)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+
+		return returnStmt, nil
+	case token.ID:
+		idStmt, err := p.ParseIdStmt()
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := idStmt.(ast.Expr); ok && p.lex.NextIs(token.CATCH) {
+			return p.parseCatchStmt(value)
+		}
+		semicolon, ok := p.expectSemicolon()
+		if !ok {
+			pos := semicolon.Pos
+			expectedSemicolon := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected ; at the end of statement, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					semicolon,
 				),
 			}
 			p.collector.ReportAndSave(expectedSemicolon)
 			return nil, diagnostics.COMPILER_ERROR_FOUND
 		}
-		returnStmt.Value = returnValue
+		return idStmt, err
+	case token.IF:
+		condStmt, err := p.parseCondStmt()
+		return condStmt, err
+	case token.FOR:
+		// C-style for loops always start with "for ("
+		if p.lex.Peek1().Kind == token.OPEN_PAREN {
+			forLoop, err := p.parseForLoop()
+			return forLoop, err
+		}
+		rangeForLoop, err := p.parseRangeForLoop()
+		return rangeForLoop, err
+	case token.WHILE:
+		whileLoop, err := p.parseWhileLoop()
+		return whileLoop, err
+	case token.ASSERT:
+		assert, err := p.parseAssert()
+		return assert, err
+	case token.CONST:
+		constDecl, err := p.parseConstDecl()
+		return constDecl, err
+	case token.STATIC_ASSERT:
+		staticAssert, err := p.parseStaticAssert()
+		return staticAssert, err
+	case token.TRY:
+		try, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		semicolon, ok := p.expectSemicolon()
+		if !ok {
+			pos := semicolon.Pos
+			expectedSemicolon := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected ; at the end of statement, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					semicolon,
+				),
+			}
+			p.collector.ReportAndSave(expectedSemicolon)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		return try.(*ast.TryExpr), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (p *Parser) parseAssert() (*ast.AssertStmt, error) {
+	assert, ok := p.expect(token.ASSERT)
+	if !ok {
+		return nil, fmt.Errorf("expected 'assert'")
+	}
+
+	openParen, ok := p.expect(token.OPEN_PAREN)
+	if !ok {
+		pos := openParen.Pos
+		expectedOpenParen := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected (, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				openParen,
+			),
+		}
+		p.collector.ReportAndSave(expectedOpenParen)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	cond, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	closeParen, ok := p.expect(token.CLOSE_PAREN)
+	if !ok {
+		pos := closeParen.Pos
+		expectedCloseParen := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ), not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				closeParen,
+			),
+		}
+		p.collector.ReportAndSave(expectedCloseParen)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	loc := p.spanFrom(assert.Pos)
+
+	semicolon, ok := p.expectSemicolon()
+	if !ok {
+		pos := semicolon.Pos
+		expectedSemicolon := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ; at the end of statement, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				semicolon,
+			),
+		}
+		p.collector.ReportAndSave(expectedSemicolon)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
 
-		_, ok := p.expect(token.SEMICOLON)
-		if !ok {
-			tok := p.lex.Peek()
-			pos := tok.Pos
-			expectedSemicolon := diagnostics.Diag{
-				Message: fmt.Sprintf(
-					"%s:%d:%d: expected ; at the end of statement, not %s",
-					pos.Filename,
-					pos.Line,
-					pos.Column,
-					tok.Kind,
-				),
-			}
-			p.collector.ReportAndSave(expectedSemicolon)
-			return nil, diagnostics.COMPILER_ERROR_FOUND
-		}
+	return &ast.AssertStmt{Loc: loc, Assert: assert, Cond: cond}, nil
+}
 
-		return returnStmt, nil
-	case token.ID:
-		idStmt, err := p.ParseIdStmt()
+func (p *Parser) parseReturnValue() (ast.Expr, error) {
+	start := p.lex.Peek().Pos
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.lex.NextIs(token.COMMA) {
+		return value, nil
+	}
+
+	exprs := []ast.Expr{value}
+	for p.lex.NextIs(token.COMMA) {
+		p.lex.Skip() // ,
+		expr, err := p.parseExpr()
 		if err != nil {
 			return nil, err
 		}
-		semicolon, ok := p.expect(token.SEMICOLON)
-		if !ok {
-			pos := semicolon.Pos
-			expectedSemicolon := diagnostics.Diag{
-				Message: fmt.Sprintf(
-					"%s:%d:%d: expected ; at the end of statement, not %s",
-					pos.Filename,
-					pos.Line,
-					pos.Column,
-					semicolon.Kind,
-				),
-			}
-			p.collector.ReportAndSave(expectedSemicolon)
-			return nil, diagnostics.COMPILER_ERROR_FOUND
-		}
-		return idStmt, err
-	case token.IF:
-		condStmt, err := p.parseCondStmt()
-		return condStmt, err
-	case token.FOR:
-		forLoop, err := p.parseForLoop()
-		return forLoop, err
-	case token.WHILE:
-		whileLoop, err := p.parseWhileLoop()
-		return whileLoop, err
-	default:
-		return nil, nil
+		exprs = append(exprs, expr)
 	}
+	return &ast.TupleExpr{Loc: p.spanFrom(start), Exprs: exprs}, nil
 }
 
 func (p *Parser) parseBlock() (*ast.BlockStmt, error) {
@@ -663,6 +1450,7 @@ func (p *Parser) parseBlock() (*ast.BlockStmt, error) {
 	var statements []ast.Stmt
 
 	for {
+		p.skipAutoSemicolons()
 		tok := p.lex.Peek()
 		if tok.Kind == token.CLOSE_CURLY {
 			break
@@ -689,7 +1477,7 @@ func (p *Parser) parseBlock() (*ast.BlockStmt, error) {
 				pos.Filename,
 				pos.Line,
 				pos.Column,
-				closeCurly.Kind,
+				closeCurly,
 			),
 		}
 		p.collector.ReportAndSave(expectedStatementOrCloseCurly)
@@ -697,6 +1485,7 @@ func (p *Parser) parseBlock() (*ast.BlockStmt, error) {
 	}
 
 	return &ast.BlockStmt{
+		Loc:        p.spanFrom(openCurly.Pos),
 		OpenCurly:  openCurly.Pos,
 		Statements: statements,
 		CloseCurly: closeCurly.Pos,
@@ -720,6 +1509,7 @@ func (p *Parser) ParseIdStmt() (ast.Stmt, error) {
 func (p *Parser) parseVar() (ast.Stmt, error) {
 	variables := make([]*ast.VarStmt, 0)
 	isDecl := false
+	start := p.lex.Peek().Pos
 
 VarDecl:
 	for {
@@ -730,6 +1520,7 @@ VarDecl:
 		}
 
 		variable := &ast.VarStmt{
+			Loc:            p.spanFrom(name.Pos),
 			Name:           name,
 			Type:           nil,
 			Value:          nil,
@@ -755,6 +1546,7 @@ VarDecl:
 		}
 		variable.Type = ty
 		variable.NeedsInference = false
+		variable.Loc = p.spanFrom(name.Pos)
 
 		next = p.lex.Peek()
 		switch next.Kind {
@@ -765,14 +1557,33 @@ VarDecl:
 		case token.COMMA:
 			p.lex.Skip()
 			continue
+		case token.SEMICOLON:
+			// Declaration without a value, such as "x i32;", which must be
+			// assigned before being used
+			if len(variables) == 1 {
+				return variable, nil
+			}
 		}
 	}
 
-	exprs, err := p.parseExprList([]token.Kind{token.SEMICOLON, token.CLOSE_PAREN})
+	exprs, err := p.parseExprList([]token.Kind{token.SEMICOLON, token.CLOSE_PAREN, token.CLOSE_CURLY})
 	if err != nil {
 		return nil, err
 	}
 
+	// Tuple destructuring, such as "q, r := divmod(7, 2);"
+	if len(variables) > 1 && len(exprs) == 1 {
+		for i := range variables {
+			variables[i].Decl = isDecl
+		}
+		return &ast.MultiVarStmt{
+			Loc:       p.spanFrom(start),
+			IsDecl:    isDecl,
+			Variables: variables,
+			Tuple:     exprs[0],
+		}, nil
+	}
+
 	// TODO(errors)
 	if len(variables) != len(exprs) {
 		return nil, fmt.Errorf("%d != %d", len(variables), len(exprs))
@@ -783,9 +1594,10 @@ VarDecl:
 	}
 
 	if len(variables) == 1 {
+		variables[0].Loc = p.spanFrom(start)
 		return variables[0], nil
 	}
-	return &ast.MultiVarStmt{IsDecl: isDecl, Variables: variables}, nil
+	return &ast.MultiVarStmt{Loc: p.spanFrom(start), IsDecl: isDecl, Variables: variables}, nil
 }
 
 // Useful for testing
@@ -804,6 +1616,7 @@ func parseVarFrom(filename, input string) (ast.Stmt, error) {
 }
 
 func (p *Parser) parseCondStmt() (*ast.CondStmt, error) {
+	start := p.lex.Peek().Pos
 	ifCond, err := p.parseIfCond()
 	if err != nil {
 		return nil, err
@@ -819,7 +1632,12 @@ func (p *Parser) parseCondStmt() (*ast.CondStmt, error) {
 		return nil, err
 	}
 
-	return &ast.CondStmt{IfStmt: ifCond, ElifStmts: elifConds, ElseStmt: elseCond}, nil
+	return &ast.CondStmt{
+		Loc:       p.spanFrom(start),
+		IfStmt:    ifCond,
+		ElifStmts: elifConds,
+		ElseStmt:  elseCond,
+	}, nil
 }
 
 func (p *Parser) parseIfCond() (*ast.IfElifCond, error) {
@@ -829,7 +1647,7 @@ func (p *Parser) parseIfCond() (*ast.IfElifCond, error) {
 		return nil, fmt.Errorf("expected 'if'")
 	}
 
-	ifExpr, err := p.parseExpr()
+	unwrap, ifExpr, err := p.parseCondExpr()
 	if err != nil {
 		return nil, err
 	}
@@ -838,17 +1656,42 @@ func (p *Parser) parseIfCond() (*ast.IfElifCond, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.IfElifCond{If: &ifToken.Pos, Expr: ifExpr, Block: ifBlock}, nil
+	return &ast.IfElifCond{
+		Loc:    p.spanFrom(ifToken.Pos),
+		If:     &ifToken.Pos,
+		Unwrap: unwrap,
+		Expr:   ifExpr,
+		Block:  ifBlock,
+	}, nil
+}
+
+// Parses the condition of "if" and "elif", which may unwrap an optional, such
+// as "if x := opt"
+func (p *Parser) parseCondExpr() (*ast.VarStmt, ast.Expr, error) {
+	var unwrap *ast.VarStmt
+	if p.lex.NextIs(token.ID) && p.lex.Peek1().Kind == token.COLON_EQUAL {
+		name := p.lex.Peek()
+		p.lex.Skip() // name
+		unwrap = &ast.VarStmt{Loc: p.spanFrom(name.Pos), Decl: true, Name: name, NeedsInference: true}
+		p.lex.Skip() // :=
+	}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, nil, err
+	}
+	return unwrap, expr, nil
 }
 
 func (p *Parser) parseElifConds() ([]*ast.IfElifCond, error) {
 	var elifConds []*ast.IfElifCond
 	for {
+		p.skipAutoSemicolonBefore(token.ELIF)
 		elifToken, ok := p.expect(token.ELIF)
 		if !ok {
 			break
 		}
-		elifExpr, err := p.parseExpr()
+		unwrap, elifExpr, err := p.parseCondExpr()
 		if err != nil {
 			return nil, err
 		}
@@ -858,13 +1701,20 @@ func (p *Parser) parseElifConds() ([]*ast.IfElifCond, error) {
 		}
 		elifConds = append(
 			elifConds,
-			&ast.IfElifCond{If: &elifToken.Pos, Expr: elifExpr, Block: elifBlock},
+			&ast.IfElifCond{
+				Loc:    p.spanFrom(elifToken.Pos),
+				If:     &elifToken.Pos,
+				Unwrap: unwrap,
+				Expr:   elifExpr,
+				Block:  elifBlock,
+			},
 		)
 	}
 	return elifConds, nil
 }
 
 func (p *Parser) parseElseCond() (*ast.ElseCond, error) {
+	p.skipAutoSemicolonBefore(token.ELSE)
 	elseToken, ok := p.expect(token.ELSE)
 	if !ok {
 		return nil, nil
@@ -874,14 +1724,38 @@ func (p *Parser) parseElseCond() (*ast.ElseCond, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.ElseCond{Else: &elseToken.Pos, Block: elseBlock}, nil
+	return &ast.ElseCond{Loc: p.spanFrom(elseToken.Pos), Else: &elseToken.Pos, Block: elseBlock}, nil
 }
 
 func (p *Parser) parseExpr() (ast.Expr, error) {
-	return p.parseLogical()
+	return p.parseOrElse()
+}
+
+// "orelse" and "catch" have the lowest precedence, so "opt orelse a + b" is
+// the same as "opt orelse (a + b)". They are right-associative, so
+// "a orelse b orelse 0" is the same as "a orelse (b orelse 0)", which
+// evaluates "b" only if "a" is nil, and "0" only if both are.
+func (p *Parser) parseOrElse() (ast.Expr, error) {
+	start := p.lex.Peek().Pos
+	lhs, err := p.parseLogical()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.lex.NextIs(token.ORELSE) && !p.lex.NextIs(token.CATCH) {
+		return lhs, nil
+	}
+	op := p.lex.Peek()
+	p.lex.Skip() // orelse or catch
+	rhs, err := p.parseOrElse()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.BinaryExpr{Loc: p.spanFrom(start), Left: lhs, Op: op.Kind, Right: rhs}, nil
 }
 
 func (p *Parser) parseLogical() (ast.Expr, error) {
+	start := p.lex.Peek().Pos
 	lhs, err := p.parseComparasion()
 	if err != nil {
 		return nil, err
@@ -896,7 +1770,7 @@ func (p *Parser) parseLogical() (ast.Expr, error) {
 			if err != nil {
 				return nil, err
 			}
-			lhs = &ast.BinaryExpr{Left: lhs, Op: next.Kind, Right: rhs}
+			lhs = &ast.BinaryExpr{Loc: p.spanFrom(start), Left: lhs, Op: next.Kind, Right: rhs}
 		} else {
 			break
 		}
@@ -905,6 +1779,7 @@ func (p *Parser) parseLogical() (ast.Expr, error) {
 }
 
 func (p *Parser) parseComparasion() (ast.Expr, error) {
+	start := p.lex.Peek().Pos
 	lhs, err := p.parseTerm()
 	if err != nil {
 		return nil, err
@@ -918,7 +1793,7 @@ func (p *Parser) parseComparasion() (ast.Expr, error) {
 			if err != nil {
 				return nil, err
 			}
-			lhs = &ast.BinaryExpr{Left: lhs, Op: next.Kind, Right: rhs}
+			lhs = &ast.BinaryExpr{Loc: p.spanFrom(start), Left: lhs, Op: next.Kind, Right: rhs}
 		} else {
 			break
 		}
@@ -927,6 +1802,7 @@ func (p *Parser) parseComparasion() (ast.Expr, error) {
 }
 
 func (p *Parser) parseTerm() (ast.Expr, error) {
+	start := p.lex.Peek().Pos
 	lhs, err := p.parseFactor()
 	if err != nil {
 		return nil, err
@@ -940,7 +1816,7 @@ func (p *Parser) parseTerm() (ast.Expr, error) {
 			if err != nil {
 				return nil, err
 			}
-			lhs = &ast.BinaryExpr{Left: lhs, Op: next.Kind, Right: rhs}
+			lhs = &ast.BinaryExpr{Loc: p.spanFrom(start), Left: lhs, Op: next.Kind, Right: rhs}
 		} else {
 			break
 		}
@@ -949,7 +1825,8 @@ func (p *Parser) parseTerm() (ast.Expr, error) {
 }
 
 func (p *Parser) parseFactor() (ast.Expr, error) {
-	lhs, err := p.parseUnary()
+	start := p.lex.Peek().Pos
+	lhs, err := p.parseCast()
 	if err != nil {
 		return nil, err
 	}
@@ -958,11 +1835,11 @@ func (p *Parser) parseFactor() (ast.Expr, error) {
 		next := p.lex.Peek()
 		if _, ok := ast.FACTOR[next.Kind]; ok {
 			p.lex.Skip()
-			rhs, err := p.parseUnary()
+			rhs, err := p.parseCast()
 			if err != nil {
 				return nil, err
 			}
-			lhs = &ast.BinaryExpr{Left: lhs, Op: next.Kind, Right: rhs}
+			lhs = &ast.BinaryExpr{Loc: p.spanFrom(start), Left: lhs, Op: next.Kind, Right: rhs}
 		} else {
 			break
 		}
@@ -971,6 +1848,38 @@ func (p *Parser) parseFactor() (ast.Expr, error) {
 
 }
 
+// Casts bind tighter than binary operators, but looser than unary ones, so
+// "-x as u8 * 2" is the same as "((-x) as u8) * 2"
+func (p *Parser) parseCast() (ast.Expr, error) {
+	start := p.lex.Peek().Pos
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.lex.NextIs(token.AS) {
+		p.lex.Skip() // as
+		ty, err := p.parseExprType()
+		if err != nil {
+			tok := p.lex.Peek()
+			pos := tok.Pos
+			expectedType := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected type after as, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					tok,
+				),
+			}
+			p.collector.ReportAndSave(expectedType)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		expr = &ast.CastExpr{Loc: p.spanFrom(start), Value: expr, Type: ty}
+	}
+	return expr, nil
+}
+
 func (p *Parser) parseUnary() (ast.Expr, error) {
 	next := p.lex.Peek()
 	if _, ok := ast.UNARY[next.Kind]; ok {
@@ -979,10 +1888,89 @@ func (p *Parser) parseUnary() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &ast.UnaryExpr{Op: next.Kind, Value: rhs}, nil
+		return &ast.UnaryExpr{Loc: p.spanFrom(next.Pos), Op: next.Kind, Value: rhs}, nil
+	}
+	if next.Kind == token.TRY {
+		p.lex.Skip() // try
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.TryExpr{Loc: p.spanFrom(next.Pos), Value: value}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *Parser) parsePostfix() (ast.Expr, error) {
+	start := p.lex.Peek().Pos
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.lex.NextIs(token.OPEN_BRACKET) {
+		expr, err = p.parseIndexExpr(start, expr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+func (p *Parser) parseIndexExpr(start token.Pos, value ast.Expr) (*ast.IndexExpr, error) {
+	openBracket, ok := p.expect(token.OPEN_BRACKET)
+	if !ok {
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	var index ast.Expr
+	var err error
+
+	indexStart := p.lex.Peek().Pos
+	if !p.isRangeOp(p.lex.Peek().Kind) {
+		index, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Slicing, such as "arr[1..3]", "arr[..3]" or "arr[1..]"
+	if p.isRangeOp(p.lex.Peek().Kind) {
+		op := p.lex.Peek()
+		p.lex.Skip() // .. or ...
+		rangeExpr := &ast.RangeExpr{Start: index, Inclusive: op.Kind == token.DOT_DOT_DOT}
+		if !p.lex.NextIs(token.CLOSE_BRACKET) {
+			rangeExpr.End, err = p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+		}
+		rangeExpr.Loc = p.spanFrom(indexStart)
+		index = rangeExpr
+	}
+
+	closeBracket, ok := p.expect(token.CLOSE_BRACKET)
+	if !ok {
+		pos := closeBracket.Pos
+		expectedCloseBracket := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected ], not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				closeBracket,
+			),
+		}
+		p.collector.ReportAndSave(expectedCloseBracket)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
 
-	return p.parsePrimary()
+	return &ast.IndexExpr{Loc: p.spanFrom(start), Value: value, Open: openBracket.Pos, Index: index}, nil
+}
+
+func (p *Parser) isRangeOp(kind token.Kind) bool {
+	return kind == token.DOT_DOT || kind == token.DOT_DOT_DOT
 }
 
 func (p *Parser) parsePrimary() (ast.Expr, error) {
@@ -1001,6 +1989,7 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 		}
 
 		p.lex.Skip()
+		idExpr.Loc = p.spanFrom(tok.Pos)
 		return idExpr, nil
 	case token.OPEN_PAREN:
 		p.lex.Skip() // (
@@ -1013,23 +2002,73 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 			return nil, fmt.Errorf("expected closing parenthesis")
 		}
 		return expr, nil
+	case token.OPEN_BRACKET:
+		p.lex.Skip() // [
+		values, err := p.parseExprList([]token.Kind{token.CLOSE_BRACKET})
+		if err != nil {
+			return nil, err
+		}
+		closeBracket, ok := p.expect(token.CLOSE_BRACKET)
+		if !ok {
+			pos := closeBracket.Pos
+			expectedCloseBracket := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected ], not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					closeBracket,
+				),
+			}
+			p.collector.ReportAndSave(expectedCloseBracket)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		return &ast.ArrayLiteral{Loc: p.spanFrom(tok.Pos), Open: tok.Pos, Values: values}, nil
+	case token.ERROR:
+		return p.parseErrorExpr()
 	default:
 		if _, ok := token.LITERAL_KIND[tok.Kind]; ok {
 			p.lex.Skip()
 			return &ast.LiteralExpr{
+				Loc:   p.spanFrom(tok.Pos),
 				Type:  &ast.BasicType{Kind: tok.Kind},
 				Value: tok.Lexeme,
 			}, nil
 		}
 		return nil, fmt.Errorf(
 			"invalid token for expression parsing: %s %s %s",
-			tok.Kind,
+			tok,
 			tok.Lexeme,
 			tok.Pos,
 		)
 	}
 }
 
+// Parses a named error, such as "error.NotFound"
+func (p *Parser) parseErrorExpr() (*ast.ErrorExpr, error) {
+	errorTok, _ := p.expect(token.ERROR)
+
+	name, ok := p.expect(token.DOT)
+	if ok {
+		name, ok = p.expect(token.ID)
+	}
+	if !ok {
+		pos := name.Pos
+		expectedErrorName := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected error name, such as error.NotFound, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				name,
+			),
+		}
+		p.collector.ReportAndSave(expectedErrorName)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	return &ast.ErrorExpr{Loc: p.spanFrom(errorTok.Pos), Name: name}, nil
+}
+
 func (p *Parser) parseExprList(possibleEnds []token.Kind) ([]ast.Expr, error) {
 	var exprs []ast.Expr
 Var:
@@ -1066,10 +2105,23 @@ func (parser *Parser) parseFnCall() (*ast.FunctionCall, error) {
 		return nil, fmt.Errorf("expected '('")
 	}
 
-	args, err := parser.parseExprList([]token.Kind{token.CLOSE_PAREN})
+	var args []ast.Expr
+	if ast.TYPE_BUILTINS[name.Name()] && !parser.lex.NextIs(token.CLOSE_PAREN) {
+		typeArg, err := parser.parseTypeExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, typeArg)
+		if parser.lex.NextIs(token.COMMA) {
+			parser.lex.Skip() // ,
+		}
+	}
+
+	rest, err := parser.parseExprList([]token.Kind{token.CLOSE_PAREN})
 	if err != nil {
 		return nil, err
 	}
+	args = append(args, rest...)
 
 	_, ok = parser.expect(token.CLOSE_PAREN)
 	// TODO(errors)
@@ -1077,7 +2129,28 @@ func (parser *Parser) parseFnCall() (*ast.FunctionCall, error) {
 		return nil, fmt.Errorf("expected ')'")
 	}
 
-	return &ast.FunctionCall{Name: name, Args: args}, nil
+	return &ast.FunctionCall{Loc: parser.spanFrom(name.Pos), Name: name, Args: args}, nil
+}
+
+func (parser *Parser) parseTypeExpr() (*ast.TypeExpr, error) {
+	start := parser.lex.Peek().Pos
+	ty, err := parser.parseExprType()
+	if err != nil {
+		tok := parser.lex.Peek()
+		pos := tok.Pos
+		expectedType := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected type, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				tok,
+			),
+		}
+		parser.collector.ReportAndSave(expectedType)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	return &ast.TypeExpr{Loc: parser.spanFrom(start), Type: ty}, nil
 }
 
 func (parser *Parser) parseFieldAccess() *ast.FieldAccess {
@@ -1086,7 +2159,7 @@ func (parser *Parser) parseFieldAccess() *ast.FieldAccess {
 	if !ok {
 		log.Fatal("expected ID")
 	}
-	left := &ast.IdExpr{Name: id}
+	left := &ast.IdExpr{Loc: parser.spanFrom(id.Pos), Name: id}
 
 	_, ok = parser.expect(token.DOT)
 	// TODO(errors)
@@ -1098,11 +2171,11 @@ func (parser *Parser) parseFieldAccess() *ast.FieldAccess {
 	if err != nil {
 		log.Fatal(err)
 	}
-	return &ast.FieldAccess{Left: left, Right: right}
+	return &ast.FieldAccess{Loc: parser.spanFrom(id.Pos), Left: left, Right: right}
 }
 
 func (parser *Parser) parseForLoop() (*ast.ForLoop, error) {
-	_, ok := parser.expect(token.FOR)
+	forTok, ok := parser.expect(token.FOR)
 	// TODO(errors)
 	if !ok {
 		return nil, fmt.Errorf("expected 'for'")
@@ -1151,7 +2224,13 @@ func (parser *Parser) parseForLoop() (*ast.ForLoop, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.ForLoop{Init: init, Cond: cond, Update: update, Block: block}, nil
+	return &ast.ForLoop{
+		Loc:    parser.spanFrom(forTok.Pos),
+		Init:   init,
+		Cond:   cond,
+		Update: update,
+		Block:  block,
+	}, nil
 }
 
 // Useful for testing
@@ -1166,6 +2245,110 @@ func ParseForLoopFrom(input, filename string) (*ast.ForLoop, error) {
 	return forLoop, err
 }
 
+func (p *Parser) parseRangeForLoop() (*ast.RangeForLoop, error) {
+	forTok, ok := p.expect(token.FOR)
+	if !ok {
+		return nil, fmt.Errorf("expected 'for'")
+	}
+
+	var loopVariables []*ast.VarStmt
+	for {
+		name, ok := p.expect(token.ID)
+		if !ok {
+			pos := name.Pos
+			expectedLoopVariable := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: expected ( or loop variable, not %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name,
+				),
+			}
+			p.collector.ReportAndSave(expectedLoopVariable)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		loopVariables = append(
+			loopVariables,
+			&ast.VarStmt{Loc: p.spanFrom(name.Pos), Decl: true, Name: name, NeedsInference: true},
+		)
+		if len(loopVariables) == 2 || !p.lex.NextIs(token.COMMA) {
+			break
+		}
+		p.lex.Skip() // ,
+	}
+
+	in, ok := p.expect(token.IN)
+	if !ok {
+		pos := in.Pos
+		expectedIn := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected in, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				in,
+			),
+		}
+		p.collector.ReportAndSave(expectedIn)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	iterableStart := p.lex.Peek().Pos
+	iterable, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if op := p.lex.Peek(); p.isRangeOp(op.Kind) {
+		p.lex.Skip() // .. or ...
+		end, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		iterable = &ast.RangeExpr{
+			Loc:       p.spanFrom(iterableStart),
+			Start:     iterable,
+			End:       end,
+			Inclusive: op.Kind == token.DOT_DOT_DOT,
+		}
+	}
+
+	block, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	forLoop := &ast.RangeForLoop{Loc: p.spanFrom(forTok.Pos), Iterable: iterable, Block: block}
+	if len(loopVariables) == 2 {
+		forLoop.Index = loopVariables[0]
+	}
+	forLoop.Value = loopVariables[len(loopVariables)-1]
+	return forLoop, nil
+}
+
+// Useful for testing
+func ParseRangeForLoopFrom(input, filename string) (*ast.RangeForLoop, error) {
+	collector := diagnostics.New()
+
+	src := []byte(input)
+	lex := lexer.New(filename, src, collector)
+	parser := NewWithLex(lex, collector)
+
+	forLoop, err := parser.parseRangeForLoop()
+	return forLoop, err
+}
+
+func ParseStmtFrom(input, filename string) (ast.Stmt, error) {
+	collector := diagnostics.New()
+
+	src := []byte(input)
+	lex := lexer.New(filename, src, collector)
+	parser := NewWithLex(lex, collector)
+
+	stmt, err := parser.parseStmt()
+	return stmt, err
+}
+
 func ParseWhileLoopFrom(input, filename string) (*ast.WhileLoop, error) {
 	collector := diagnostics.New()
 
@@ -1177,8 +2360,19 @@ func ParseWhileLoopFrom(input, filename string) (*ast.WhileLoop, error) {
 	return whileLoop, err
 }
 
+// Parses "value catch { ... }", where value is a call, such as
+// "write(s) catch { return 1; }"
+func (p *Parser) parseCatchStmt(value ast.Expr) (*ast.CatchStmt, error) {
+	p.lex.Skip() // catch
+	block, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.CatchStmt{Loc: p.spanFrom(value.Span().Start), Value: value, Block: block}, nil
+}
+
 func (p *Parser) parseWhileLoop() (*ast.WhileLoop, error) {
-	_, ok := p.expect(token.WHILE)
+	while, ok := p.expect(token.WHILE)
 	if !ok {
 		return nil, fmt.Errorf("expected 'while'")
 	}
@@ -1192,5 +2386,5 @@ func (p *Parser) parseWhileLoop() (*ast.WhileLoop, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.WhileLoop{Cond: expr, Block: block}, nil
+	return &ast.WhileLoop{Loc: p.spanFrom(while.Pos), Cond: expr, Block: block}, nil
 }
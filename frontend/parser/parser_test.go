@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/HicaroD/Telia/diagnostics"
@@ -422,6 +423,30 @@ func TestFunctionDecl(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "fn pair() (int, bool) {}",
+			node: &ast.FunctionDecl{
+				Scope: nil,
+				Name:  token.New([]byte("pair"), token.ID, token.NewPosition(filename, 4, 1)),
+				Params: &ast.FieldList{
+					Open:       token.New(nil, token.OPEN_PAREN, token.NewPosition(filename, 8, 1)),
+					Fields:     nil,
+					Close:      token.New(nil, token.CLOSE_PAREN, token.NewPosition(filename, 9, 1)),
+					IsVariadic: false,
+				},
+				RetType: &ast.TupleType{
+					Types: []ast.ExprType{
+						&ast.BasicType{Kind: token.INT_TYPE},
+						&ast.BasicType{Kind: token.BOOL_TYPE},
+					},
+				},
+				Block: &ast.BlockStmt{
+					OpenCurly:  token.NewPosition(filename, 23, 1),
+					Statements: nil,
+					CloseCurly: token.NewPosition(filename, 24, 1),
+				},
+			},
+		},
 		// TODO(tests): test variadic arguments on functions
 	}
 	for _, test := range tests {
@@ -436,6 +461,7 @@ func TestFunctionDecl(t *testing.T) {
 			}
 			test.node.Scope = ast.NewScope(fileScope)
 
+			ast.ClearSpans(fnDecl)
 			if !reflect.DeepEqual(fnDecl, test.node) {
 				t.Fatal("Function declarations are not the same")
 			}
@@ -518,6 +544,106 @@ func TestForLoop(t *testing.T) {
 				t.Fatal(err)
 			}
 
+			ast.ClearSpans(forLoop)
+			if !reflect.DeepEqual(forLoop, test.node) {
+				t.Fatalf("\nexp: %s\ngot: %s\n", test.node, forLoop)
+			}
+		})
+	}
+}
+
+type rangeForLoopTest struct {
+	input string
+	node  *ast.RangeForLoop
+}
+
+func TestRangeForLoop(t *testing.T) {
+	filename := "test.tt"
+	tests := []rangeForLoopTest{
+		{
+			input: "for i in 0..10 {}",
+			node: &ast.RangeForLoop{
+				Value: &ast.VarStmt{
+					Decl:           true,
+					Name:           token.New([]byte("i"), token.ID, token.NewPosition(filename, 5, 1)),
+					NeedsInference: true,
+				},
+				Iterable: &ast.RangeExpr{
+					Start: &ast.LiteralExpr{
+						Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+						Value: []byte("0"),
+					},
+					End: &ast.LiteralExpr{
+						Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+						Value: []byte("10"),
+					},
+					Inclusive: false,
+				},
+				Block: &ast.BlockStmt{
+					OpenCurly:  token.NewPosition(filename, 16, 1),
+					Statements: nil,
+					CloseCurly: token.NewPosition(filename, 17, 1),
+				},
+			},
+		},
+		{
+			input: "for i in 1...n {}",
+			node: &ast.RangeForLoop{
+				Value: &ast.VarStmt{
+					Decl:           true,
+					Name:           token.New([]byte("i"), token.ID, token.NewPosition(filename, 5, 1)),
+					NeedsInference: true,
+				},
+				Iterable: &ast.RangeExpr{
+					Start: &ast.LiteralExpr{
+						Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+						Value: []byte("1"),
+					},
+					End: &ast.IdExpr{
+						Name: token.New([]byte("n"), token.ID, token.NewPosition(filename, 14, 1)),
+					},
+					Inclusive: true,
+				},
+				Block: &ast.BlockStmt{
+					OpenCurly:  token.NewPosition(filename, 16, 1),
+					Statements: nil,
+					CloseCurly: token.NewPosition(filename, 17, 1),
+				},
+			},
+		},
+		{
+			input: "for i, x in values {}",
+			node: &ast.RangeForLoop{
+				Index: &ast.VarStmt{
+					Decl:           true,
+					Name:           token.New([]byte("i"), token.ID, token.NewPosition(filename, 5, 1)),
+					NeedsInference: true,
+				},
+				Value: &ast.VarStmt{
+					Decl:           true,
+					Name:           token.New([]byte("x"), token.ID, token.NewPosition(filename, 8, 1)),
+					NeedsInference: true,
+				},
+				Iterable: &ast.IdExpr{
+					Name: token.New([]byte("values"), token.ID, token.NewPosition(filename, 13, 1)),
+				},
+				Block: &ast.BlockStmt{
+					OpenCurly:  token.NewPosition(filename, 20, 1),
+					Statements: nil,
+					CloseCurly: token.NewPosition(filename, 21, 1),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestRangeForLoop('%s')", test.input), func(t *testing.T) {
+			forLoop, err := ParseRangeForLoopFrom(test.input, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ast.ClearSpans(forLoop)
 			if !reflect.DeepEqual(forLoop, test.node) {
 				t.Fatalf("\nexp: %s\ngot: %s\n", test.node, forLoop)
 			}
@@ -525,6 +651,138 @@ func TestForLoop(t *testing.T) {
 	}
 }
 
+type attributesTest struct {
+	input      string
+	attributes []*ast.Attribute
+}
+
+func TestAttributes(t *testing.T) {
+	filename := "test.tt"
+	tests := []attributesTest{
+		{
+			input: "@inline",
+			attributes: []*ast.Attribute{
+				{
+					At:   token.NewPosition(filename, 1, 1),
+					Name: token.New([]byte("inline"), token.ID, token.NewPosition(filename, 2, 1)),
+				},
+			},
+		},
+		{
+			input: "@noinline @export(\"add\")",
+			attributes: []*ast.Attribute{
+				{
+					At:   token.NewPosition(filename, 1, 1),
+					Name: token.New([]byte("noinline"), token.ID, token.NewPosition(filename, 2, 1)),
+				},
+				{
+					At:   token.NewPosition(filename, 11, 1),
+					Name: token.New([]byte("export"), token.ID, token.NewPosition(filename, 12, 1)),
+					Args: []ast.Expr{
+						&ast.LiteralExpr{
+							Type:  &ast.BasicType{Kind: token.STRING_LITERAL},
+							Value: []byte("add"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestAttributes('%s')", test.input), func(t *testing.T) {
+			attributes, err := ParseAttributesFrom(test.input, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, attribute := range attributes {
+				ast.ClearSpans(attribute)
+			}
+			if !reflect.DeepEqual(attributes, test.attributes) {
+				t.Fatalf("\nexp: %s\ngot: %s\n", test.attributes, attributes)
+			}
+		})
+	}
+}
+
+type constDeclTest struct {
+	input string
+	node  *ast.ConstDecl
+}
+
+func TestConstDecl(t *testing.T) {
+	filename := "test.tt"
+	tests := []constDeclTest{
+		{
+			input: "const N = 10;",
+			node: &ast.ConstDecl{
+				Const: token.New([]byte("const"), token.CONST, token.NewPosition(filename, 1, 1)),
+				Name:  token.New([]byte("N"), token.ID, token.NewPosition(filename, 7, 1)),
+				Value: &ast.LiteralExpr{
+					Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+					Value: []byte("10"),
+				},
+			},
+		},
+		{
+			input: "const SIZE u8 = N * 2;",
+			node: &ast.ConstDecl{
+				Const: token.New([]byte("const"), token.CONST, token.NewPosition(filename, 1, 1)),
+				Name:  token.New([]byte("SIZE"), token.ID, token.NewPosition(filename, 7, 1)),
+				Type:  &ast.BasicType{Kind: token.U8_TYPE},
+				Value: &ast.BinaryExpr{
+					Left: &ast.IdExpr{
+						Name: token.New([]byte("N"), token.ID, token.NewPosition(filename, 17, 1)),
+					},
+					Op: token.STAR,
+					Right: &ast.LiteralExpr{
+						Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+						Value: []byte("2"),
+					},
+				},
+			},
+		},
+		{
+			input: "const BUF [N + 1]u8 = 0;",
+			node: &ast.ConstDecl{
+				Const: token.New([]byte("const"), token.CONST, token.NewPosition(filename, 1, 1)),
+				Name:  token.New([]byte("BUF"), token.ID, token.NewPosition(filename, 7, 1)),
+				Type: &ast.ArrayType{
+					LenExpr: &ast.BinaryExpr{
+						Left: &ast.IdExpr{
+							Name: token.New([]byte("N"), token.ID, token.NewPosition(filename, 12, 1)),
+						},
+						Op: token.PLUS,
+						Right: &ast.LiteralExpr{
+							Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+							Value: []byte("1"),
+						},
+					},
+					Type: &ast.BasicType{Kind: token.U8_TYPE},
+				},
+				Value: &ast.LiteralExpr{
+					Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+					Value: []byte("0"),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestConstDecl('%s')", test.input), func(t *testing.T) {
+			constDecl := parseBothStyles(t, test.input, func(input string) (*ast.ConstDecl, error) {
+				return ParseConstDeclFrom(input, filename)
+			})
+
+			ast.ClearSpans(constDecl)
+			if !reflect.DeepEqual(constDecl, test.node) {
+				t.Fatalf("\nexp: %s\ngot: %s\n", test.node, constDecl)
+			}
+		})
+	}
+}
+
 type whileLoopTest struct {
 	input string
 	node  *ast.WhileLoop
@@ -556,6 +814,7 @@ func TestWhileLoop(t *testing.T) {
 				t.Fatal(err)
 			}
 
+			ast.ClearSpans(whileLoop)
 			if !reflect.DeepEqual(whileLoop, test.node) {
 				t.Fatalf("\nexp: %s\ngot: %s\n", test.node, whileLoop)
 			}
@@ -563,6 +822,57 @@ func TestWhileLoop(t *testing.T) {
 	}
 }
 
+type catchStmtTest struct {
+	input string
+	node  *ast.CatchStmt
+}
+
+func TestCatchStmt(t *testing.T) {
+	filename := "test.tt"
+	tests := []catchStmtTest{
+		{
+			input: "write(s) catch { return 1; }",
+			node: &ast.CatchStmt{
+				Value: &ast.FunctionCall{
+					Name: token.New([]byte("write"), token.ID, token.NewPosition(filename, 1, 1)),
+					Args: []ast.Expr{
+						&ast.IdExpr{
+							Name: token.New([]byte("s"), token.ID, token.NewPosition(filename, 7, 1)),
+						},
+					},
+				},
+				Block: &ast.BlockStmt{
+					OpenCurly: token.NewPosition(filename, 16, 1),
+					Statements: []ast.Stmt{
+						&ast.ReturnStmt{
+							Return: token.New([]byte("return"), token.RETURN, token.NewPosition(filename, 18, 1)),
+							Value: &ast.LiteralExpr{
+								Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+								Value: []byte("1"),
+							},
+						},
+					},
+					CloseCurly: token.NewPosition(filename, 28, 1),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestCatchStmt('%s')", test.input), func(t *testing.T) {
+			stmt, err := ParseStmtFrom(test.input, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ast.ClearSpans(stmt)
+			if !reflect.DeepEqual(stmt, test.node) {
+				t.Fatalf("\nexp: %s\ngot: %s\n", test.node, stmt)
+			}
+		})
+	}
+}
+
 // TODO(tests)
 // type externDeclTest struct {
 // 	input string
@@ -624,6 +934,7 @@ func TestLiteralExpr(t *testing.T) {
 			if err != nil {
 				t.Errorf("TestLiteralExpr('%s'): unexpected error '%v'", test.input, err)
 			}
+			ast.ClearSpans(actualNode)
 			if !reflect.DeepEqual(test.node, actualNode) {
 				t.Errorf(
 					"TestLiteralExpr('%s'): expression node differs\nexpected: '%v', but got '%v'\n",
@@ -636,6 +947,63 @@ func TestLiteralExpr(t *testing.T) {
 	}
 }
 
+func TestIndexExpr(t *testing.T) {
+	filename := "test.tt"
+	tests := []exprTest{
+		{
+			input: "[1, 2]",
+			node: &ast.ArrayLiteral{
+				Open: token.NewPosition(filename, 1, 1),
+				Values: []ast.Expr{
+					&ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}, Value: []byte("1")},
+					&ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}, Value: []byte("2")},
+				},
+			},
+		},
+		{
+			input: "a[0]",
+			node: &ast.IndexExpr{
+				Value: &ast.IdExpr{Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1))},
+				Open:  token.NewPosition(filename, 2, 1),
+				Index: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}, Value: []byte("0")},
+			},
+		},
+		{
+			input: "a[1..]",
+			node: &ast.IndexExpr{
+				Value: &ast.IdExpr{Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1))},
+				Open:  token.NewPosition(filename, 2, 1),
+				Index: &ast.RangeExpr{
+					Start: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}, Value: []byte("1")},
+				},
+			},
+		},
+		{
+			input: "a[..n]",
+			node: &ast.IndexExpr{
+				Value: &ast.IdExpr{Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1))},
+				Open:  token.NewPosition(filename, 2, 1),
+				Index: &ast.RangeExpr{
+					End: &ast.IdExpr{Name: token.New([]byte("n"), token.ID, token.NewPosition(filename, 5, 1))},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestIndexExpr('%s')", test.input), func(t *testing.T) {
+			actualNode, err := ParseExprFrom(test.input, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast.ClearSpans(actualNode)
+			if !reflect.DeepEqual(test.node, actualNode) {
+				t.Fatalf("\nexp: %s\ngot: %s\n", test.node, actualNode)
+			}
+		})
+	}
+}
+
 func TestUnaryExpr(t *testing.T) {
 	filename := "test.tt"
 	tests := []exprTest{
@@ -666,6 +1034,7 @@ func TestUnaryExpr(t *testing.T) {
 			if err != nil {
 				t.Errorf("TestUnaryExpr('%s'): unexpected error '%v'", test.input, err)
 			}
+			ast.ClearSpans(actualNode)
 			if !reflect.DeepEqual(test.node, actualNode) {
 				t.Errorf(
 					"TestUnaryExpr('%s'): expression node differs\nexpected: '%v', but got '%v'\n",
@@ -800,6 +1169,27 @@ func TestBinaryExpr(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "7 % 2 + 1",
+			node: &ast.BinaryExpr{
+				Left: &ast.BinaryExpr{
+					Left: &ast.LiteralExpr{
+						Value: []byte("7"),
+						Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+					},
+					Op: token.PERCENT,
+					Right: &ast.LiteralExpr{
+						Value: []byte("2"),
+						Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+					},
+				},
+				Op: token.PLUS,
+				Right: &ast.LiteralExpr{
+					Value: []byte("1"),
+					Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				},
+			},
+		},
 		{
 			input: "6 / (3 - 1)",
 			node: &ast.BinaryExpr{
@@ -1183,34 +1573,133 @@ func TestBinaryExpr(t *testing.T) {
 			},
 		},
 		{
-			input: "1 + multiply_by_2(10)",
+			input: "a orelse b orelse 1 + 2",
 			node: &ast.BinaryExpr{
-				Left: &ast.LiteralExpr{
-					Value: []byte("1"),
-					Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				Left: &ast.IdExpr{
+					Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
 				},
-				Op: token.PLUS,
-				Right: &ast.FunctionCall{
-					Name: token.New([]byte("multiply_by_2"), token.ID, token.NewPosition(filename, 5, 1)),
-					Args: []ast.Expr{
-						&ast.LiteralExpr{
-							Value: []byte("10"),
+				Op: token.ORELSE,
+				Right: &ast.BinaryExpr{
+					Left: &ast.IdExpr{
+						Name: token.New([]byte("b"), token.ID, token.NewPosition(filename, 10, 1)),
+					},
+					Op: token.ORELSE,
+					Right: &ast.BinaryExpr{
+						Left: &ast.LiteralExpr{
+							Value: []byte("1"),
+							Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+						},
+						Op: token.PLUS,
+						Right: &ast.LiteralExpr{
+							Value: []byte("2"),
 							Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
 						},
 					},
 				},
 			},
 		},
-	}
-
-	for _, test := range tests {
-		t.Run(fmt.Sprintf("TestBinaryExpr('%s')", test.input), func(t *testing.T) {
-			actualNode, err := ParseExprFrom(test.input, filename)
-			if err != nil {
-				t.Errorf("unexpected error '%v'", err)
-			}
-			if !reflect.DeepEqual(test.node, actualNode) {
-				t.Errorf(
+		{
+			input: "try parse(s) + 1 catch error_code",
+			node: &ast.BinaryExpr{
+				Left: &ast.BinaryExpr{
+					Left: &ast.TryExpr{
+						Value: &ast.FunctionCall{
+							Name: token.New([]byte("parse"), token.ID, token.NewPosition(filename, 5, 1)),
+							Args: []ast.Expr{
+								&ast.IdExpr{
+									Name: token.New([]byte("s"), token.ID, token.NewPosition(filename, 11, 1)),
+								},
+							},
+						},
+					},
+					Op: token.PLUS,
+					Right: &ast.LiteralExpr{
+						Value: []byte("1"),
+						Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+					},
+				},
+				Op: token.CATCH,
+				Right: &ast.IdExpr{
+					Name: token.New([]byte("error_code"), token.ID, token.NewPosition(filename, 24, 1)),
+				},
+			},
+		},
+		{
+			input: "-x as u8 * 2",
+			node: &ast.BinaryExpr{
+				Left: &ast.CastExpr{
+					Value: &ast.UnaryExpr{
+						Op: token.MINUS,
+						Value: &ast.IdExpr{
+							Name: token.New([]byte("x"), token.ID, token.NewPosition(filename, 2, 1)),
+						},
+					},
+					Type: &ast.BasicType{Kind: token.U8_TYPE},
+				},
+				Op: token.STAR,
+				Right: &ast.LiteralExpr{
+					Value: []byte("2"),
+					Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				},
+			},
+		},
+		{
+			input: "1 + multiply_by_2(10)",
+			node: &ast.BinaryExpr{
+				Left: &ast.LiteralExpr{
+					Value: []byte("1"),
+					Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				},
+				Op: token.PLUS,
+				Right: &ast.FunctionCall{
+					Name: token.New([]byte("multiply_by_2"), token.ID, token.NewPosition(filename, 5, 1)),
+					Args: []ast.Expr{
+						&ast.LiteralExpr{
+							Value: []byte("10"),
+							Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: "offsetof((u8, int), 1) * 2",
+			node: &ast.BinaryExpr{
+				Left: &ast.FunctionCall{
+					Name: token.New([]byte("offsetof"), token.ID, token.NewPosition(filename, 1, 1)),
+					Args: []ast.Expr{
+						&ast.TypeExpr{
+							Type: &ast.TupleType{
+								Types: []ast.ExprType{
+									&ast.BasicType{Kind: token.U8_TYPE},
+									&ast.BasicType{Kind: token.INT_TYPE},
+								},
+							},
+						},
+						&ast.LiteralExpr{
+							Value: []byte("1"),
+							Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+						},
+					},
+				},
+				Op: token.STAR,
+				Right: &ast.LiteralExpr{
+					Value: []byte("2"),
+					Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestBinaryExpr('%s')", test.input), func(t *testing.T) {
+			actualNode, err := ParseExprFrom(test.input, filename)
+			if err != nil {
+				t.Errorf("unexpected error '%v'", err)
+			}
+			ast.ClearSpans(actualNode)
+			if !reflect.DeepEqual(test.node, actualNode) {
+				t.Errorf(
 					"expression node differs\nexpected: '%v' '%v'\ngot:      '%v' '%v'\n",
 					test.node,
 					reflect.TypeOf(test.node),
@@ -1260,6 +1749,7 @@ func TestFieldAccessExpr(t *testing.T) {
 			if err != nil {
 				t.Errorf("unexpected error '%v'", err)
 			}
+			ast.ClearSpans(actualNode)
 			if !reflect.DeepEqual(test.node, actualNode) {
 				t.Errorf(
 					"expression node differs\nexpected: '%v' '%v'\ngot:      '%v' '%v'\n",
@@ -1332,6 +1822,20 @@ func TestVar(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "age int;",
+			varDecl: &ast.VarStmt{
+				Decl: true,
+				Name: token.New(
+					[]byte("age"),
+					token.ID,
+					token.NewPosition(filename, 1, 1),
+				),
+				Type:           &ast.BasicType{Kind: token.INT_TYPE},
+				NeedsInference: false,
+				Value:          nil,
+			},
+		},
 		// This code is not valid semantically (depends!), but
 		// the parser needs to be able to analyze it.
 		{
@@ -1446,14 +1950,74 @@ func TestVar(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "name ?*u8 := nil;",
+			varDecl: &ast.VarStmt{
+				Decl:           true,
+				Name:           token.New([]byte("name"), token.ID, token.NewPosition(filename, 1, 1)),
+				Type:           &ast.OptionalType{Type: &ast.PointerType{Type: &ast.BasicType{Kind: token.U8_TYPE}}},
+				NeedsInference: false,
+				Value: &ast.LiteralExpr{
+					Type:  &ast.BasicType{Kind: token.NIL_LITERAL},
+					Value: []byte("nil"),
+				},
+			},
+		},
+		{
+			input: "result !void := error.NotFound;",
+			varDecl: &ast.VarStmt{
+				Decl:           true,
+				Name:           token.New([]byte("result"), token.ID, token.NewPosition(filename, 1, 1)),
+				Type:           &ast.ErrorUnionType{Type: &ast.BasicType{Kind: token.VOID_TYPE}},
+				NeedsInference: false,
+				Value: &ast.ErrorExpr{
+					Name: token.New([]byte("NotFound"), token.ID, token.NewPosition(filename, 23, 1)),
+				},
+			},
+		},
+		{
+			input: "q, r := divmod(7, 2);",
+			varDecl: &ast.MultiVarStmt{
+				IsDecl: true,
+				Variables: []*ast.VarStmt{
+					{
+						Decl:           true,
+						Name:           token.New([]byte("q"), token.ID, token.NewPosition(filename, 1, 1)),
+						Type:           nil,
+						NeedsInference: true,
+						Value:          nil,
+					},
+					{
+						Decl:           true,
+						Name:           token.New([]byte("r"), token.ID, token.NewPosition(filename, 4, 1)),
+						Type:           nil,
+						NeedsInference: true,
+						Value:          nil,
+					},
+				},
+				Tuple: &ast.FunctionCall{
+					Name: token.New([]byte("divmod"), token.ID, token.NewPosition(filename, 9, 1)),
+					Args: []ast.Expr{
+						&ast.LiteralExpr{
+							Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+							Value: []byte("7"),
+						},
+						&ast.LiteralExpr{
+							Type:  &ast.BasicType{Kind: token.INTEGER_LITERAL},
+							Value: []byte("2"),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("TestVar('%s')", test.input), func(t *testing.T) {
-			varDecl, err := parseVarFrom(filename, test.input)
-			if err != nil {
-				t.Fatal(err)
-			}
+			varDecl := parseBothStyles(t, test.input, func(input string) (ast.Stmt, error) {
+				return parseVarFrom(filename, input)
+			})
+			ast.ClearSpans(varDecl)
 			if !reflect.DeepEqual(varDecl, test.varDecl) {
 				t.Fatalf("\nexp: %s\ngot: %s\n", test.varDecl, varDecl)
 			}
@@ -1461,7 +2025,268 @@ func TestVar(t *testing.T) {
 	}
 }
 
+func TestSemicolonInsertion(t *testing.T) {
+	filename := "test.tt"
+	tests := []string{
+		"fn main() i32 { a := 1; b := a + 2; return b; }",
+		"fn f() { g(); return; }\nfn g() {}",
+		"fn f() int { if a { return 1; } elif b { return 2; } else { c(); } return 3; }",
+		"fn f() { for (i := 0; i < 10; i = i + 1) { g(i); } while true { h(); } }",
+		"fn f() (int, int) { q, r := divmod(7, 2); return q, r; }",
+		"extern libc { fn puts(s *u8) i32; @cold fn abort(); fn printf(format *u8, ...) i32; }",
+		"extern m link \"m\" { fn print(s *u8) i32 = \"puts\"; fn quit() = \"abort\"; err *u8 = \"stderr\"; errno i32; }",
+		"@inline\n@export(\"f\")\nfn f() {}",
+		"const N [2]u8 = 0;\nstatic_assert(N == 0, \"zero\");",
+		"test \"name\" { assert(true); }\nbench \"name\" { x := 1; }",
+		"fn f() int { return 0 }",
+	}
+
+	for _, input := range tests {
+		t.Run(fmt.Sprintf("TestSemicolonInsertion('%s')", input), func(t *testing.T) {
+			parseBothStyles(t, input, func(input string) (*ast.Program, error) {
+				collector := diagnostics.New()
+				lex := lexer.New(filename, []byte(input), collector)
+				return New(collector).ParseFileAsProgram(lex)
+			})
+		})
+	}
+}
+
+type ownLineTest struct {
+	input    string
+	sameLine string
+}
+
+func TestElifAndElseOnTheirOwnLine(t *testing.T) {
+	filename := "test.tt"
+	tests := []ownLineTest{
+		{
+			input:    "fn f() {\n  if a {\n  }\n  else {\n  }\n}",
+			sameLine: "fn f() { if a {} else {} }",
+		},
+		{
+			input:    "fn f() {\n  if a {\n    b()\n  }\n\n  elif c {\n  }\n  // comment\n  else {\n  }\n  d()\n}",
+			sameLine: "fn f() { if a { b(); } elif c {} else {} d(); }",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestElifAndElseOnTheirOwnLine('%s')", test.input), func(t *testing.T) {
+			parse := func(input string) *ast.Program {
+				collector := diagnostics.New()
+				lex := lexer.New(filename, []byte(input), collector)
+				program, err := New(collector).ParseFileAsProgram(lex)
+				if err != nil {
+					t.Fatalf("unable to parse %q: %s", input, err)
+				}
+				return program
+			}
+
+			got := parse(test.input)
+			exp := parse(test.sameLine)
+			if !equalIgnoringPositions(reflect.ValueOf(exp), reflect.ValueOf(got)) {
+				t.Fatalf("\nexp: %v\ngot: %v\n", exp, got)
+			}
+		})
+	}
+}
+
+// Parses an input written with explicit semicolons and checks that the same
+// tree is parsed when each of them is replaced by a newline. The tree parsed
+// from the input as written is returned.
+func parseBothStyles[T any](t *testing.T, input string, parse func(input string) (T, error)) T {
+	t.Helper()
+
+	explicit, err := parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutSemicolons := semicolonsToNewlines(t, input)
+	inserted, err := parse(withoutSemicolons)
+	if err != nil {
+		t.Fatalf("unable to parse %q: %s", withoutSemicolons, err)
+	}
+
+	// Replacing semicolons by newlines moves tokens to other lines
+	if !equalIgnoringPositions(reflect.ValueOf(explicit), reflect.ValueOf(inserted)) {
+		t.Fatalf("\nexp: %v\ngot: %v\n", explicit, inserted)
+	}
+	return explicit
+}
+
+// Replaces every semicolon that ends a statement by a newline. Semicolons
+// inside parentheses, such as the ones of C-style for loops, are kept.
+func semicolonsToNewlines(t *testing.T, input string) string {
+	lex := lexer.New("test.tt", []byte(input), diagnostics.New())
+	tokens, err := lex.Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitAfter(input, "\n")
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case token.OPEN_PAREN:
+			depth++
+		case token.CLOSE_PAREN:
+			depth--
+		case token.SEMICOLON:
+			if depth == 0 && !tok.IsAutoSemicolon() {
+				line := lines[tok.Pos.Line-1]
+				lines[tok.Pos.Line-1] = line[:tok.Pos.Column-1] + "\n" + line[tok.Pos.Column:]
+			}
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+var posType = reflect.TypeOf(token.Pos{})
+
+// Reports whether two trees are equal, ignoring every position, including the
+// ones of spans and tokens
+func equalIgnoringPositions(a, b reflect.Value) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		// Nodes of a scope are also part of the tree
+		if _, ok := a.Interface().(*ast.Scope); ok {
+			return true
+		}
+		return equalIgnoringPositions(a.Elem(), b.Elem())
+	case reflect.Struct:
+		if a.Type() != b.Type() {
+			return false
+		}
+		if a.Type() == posType {
+			return true
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !equalIgnoringPositions(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalIgnoringPositions(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
 // TODO(tests)
+type spanTest struct {
+	input string
+	spans []string
+}
+
+func TestSpans(t *testing.T) {
+	filename := "test.tt"
+	tests := []spanTest{
+		{
+			input: "@inline fn add(a int, b *u8) (int, int) { return a + 1, (b - 2) * 3; }",
+			spans: []string{
+				`File "@inline fn add(a int, b *u8) (int, int) { return a + 1, (b - 2) * 3; }"`,
+				`FunctionDecl "@inline fn add(a int, b *u8) (int, int) { return a + 1, (b - 2) * 3; }"`,
+				`Attribute "@inline"`,
+				`FieldList "(a int, b *u8)"`,
+				`Field "a int"`,
+				`BasicType "int"`,
+				`Field "b *u8"`,
+				`PointerType "*u8"`,
+				`BasicType "u8"`,
+				`TupleType "(int, int)"`,
+				`BasicType "int"`,
+				`BasicType "int"`,
+				`BlockStmt "{ return a + 1, (b - 2) * 3; }"`,
+				`ReturnStmt "return a + 1, (b - 2) * 3"`,
+				`TupleExpr "a + 1, (b - 2) * 3"`,
+				`BinaryExpr "a + 1"`,
+				`IdExpr "a"`,
+				`LiteralExpr "1"`,
+				`BinaryExpr "(b - 2) * 3"`,
+				`BinaryExpr "b - 2"`,
+				`IdExpr "b"`,
+				`LiteralExpr "2"`,
+				`LiteralExpr "3"`,
+			},
+		},
+		{
+			input: `fn f() { x, y := -a[1..n], "s"; if x { return; } else { g(y); } }`,
+			spans: []string{
+				`File "fn f() { x, y := -a[1..n], \"s\"; if x { return; } else { g(y); } }"`,
+				`FunctionDecl "fn f() { x, y := -a[1..n], \"s\"; if x { return; } else { g(y); } }"`,
+				`FieldList "()"`,
+				`BlockStmt "{ x, y := -a[1..n], \"s\"; if x { return; } else { g(y); } }"`,
+				`MultiVarStmt "x, y := -a[1..n], \"s\""`,
+				`VarStmt "x"`,
+				`UnaryExpr "-a[1..n]"`,
+				`IndexExpr "a[1..n]"`,
+				`IdExpr "a"`,
+				`RangeExpr "1..n"`,
+				`LiteralExpr "1"`,
+				`IdExpr "n"`,
+				`VarStmt "y"`,
+				`LiteralExpr "\"s\""`,
+				`CondStmt "if x { return; } else { g(y); }"`,
+				`IfElifCond "if x { return; }"`,
+				`IdExpr "x"`,
+				`BlockStmt "{ return; }"`,
+				`ReturnStmt "return"`,
+				`VoidExpr ""`,
+				`ElseCond "else { g(y); }"`,
+				`BlockStmt "{ g(y); }"`,
+				`FunctionCall "g(y)"`,
+				`IdExpr "y"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestSpans('%s')", test.input), func(t *testing.T) {
+			collector := diagnostics.New()
+			lex := lexer.New(filename, []byte(test.input), collector)
+			program, err := New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var spans []string
+			ast.Inspect(program, func(node ast.Node) bool {
+				if node == nil || node.Span().IsZero() {
+					return true
+				}
+				span := node.Span()
+				if span.Start.Line != 1 || span.End.Line != 1 {
+					t.Fatalf("expected single line span, but got %s", span)
+				}
+				text := test.input[span.Start.Column-1 : span.End.Column-1]
+				spans = append(spans, fmt.Sprintf("%s %q", reflect.TypeOf(node).Elem().Name(), text))
+				return true
+			})
+
+			if !reflect.DeepEqual(spans, test.spans) {
+				t.Fatalf("\nexp: %q\ngot: %q\n", test.spans, spans)
+			}
+		})
+	}
+}
+
 func TestFuncCallStmt(t *testing.T) {}
 
 // TODO(tests)
@@ -1507,6 +2332,114 @@ func TestSyntaxErrors(t *testing.T) {
 				},
 			},
 		},
+		// Attributes
+		{
+			input: "@inline fn name(){}\nextern libc { @cold fn abort(); }",
+			diags: nil, // no errors,
+		},
+		{
+			input: "@inline extern libc {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:9: expected function declaration after attributes, not extern",
+				},
+			},
+		},
+		{
+			input: "@ fn name(){}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:3: expected attribute name, not fn",
+				},
+			},
+		},
+		// Test blocks
+		{
+			input: "test \"name\" { assert(true); }",
+			diags: nil, // no errors,
+		},
+		{
+			input: "test {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:6: expected test name, not {",
+				},
+			},
+		},
+		{
+			input: "test \"name\" { assert true; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:22: expected (, not true",
+				},
+			},
+		},
+		{
+			input: "fn name\nfn other() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:8: expected (, not newline",
+				},
+			},
+		},
+		{
+			input: "test \"name\" { assert(true) assert(false) }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:28: expected ; at the end of statement, not assert",
+				},
+			},
+		},
+		// Benchmark blocks
+		{
+			input: "bench \"name\" { x := 1; }",
+			diags: nil, // no errors,
+		},
+		{
+			input: "bench {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:7: expected benchmark name, not {",
+				},
+			},
+		},
+		// Constants and static assertions
+		{
+			input: "const N = 1;\nstatic_assert(N > 0, \"positive\");\nfn f() { const M u8 = 2; static_assert(true, \"\"); }",
+			diags: nil, // no errors,
+		},
+		{
+			input: "const = 1;",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:7: expected constant name, not =",
+				},
+			},
+		},
+		{
+			input: "const N := 1;",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:9: expected type or =, not :=",
+				},
+			},
+		},
+		{
+			input: "static_assert(true);",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:19: expected , and a message, not )",
+				},
+			},
+		},
+		{
+			input: "static_assert(true, 1);",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: expected message, not integer literal",
+				},
+			},
+		},
 		// Function declaration
 		{
 			input: "fn name(){}",
@@ -1638,6 +2571,46 @@ func TestSyntaxErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: // no formatting
+			`extern m link "m" {
+				fn print(s *u8) i32 = "puts";
+				stderr *u8;
+			}`,
+			diags: nil, // no errors
+		},
+		{
+			input: "extern m link m {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:15: expected library name, not identifier",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn print() = puts; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:28: expected symbol name, not identifier",
+				},
+			},
+		},
+		{
+			input: "extern libc { stderr; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: expected type for 'stderr', not ;",
+				},
+			},
+		},
+		{
+			input: "extern libc { stderr *u8 fn f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:26: expected ; at the end of variable, not fn",
+				},
+			},
+		},
 		{
 			input: "extern {}",
 			diags: []diagnostics.Diag{
@@ -1807,11 +2780,11 @@ func TestSyntaxErrorsOnBlock(t *testing.T) {
 		},
 		{
 			input: `{
-			return
+			return +
 			}`,
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:3:4: expected expression or ;, not }",
+					Message: "test.tt:2:11: expected expression or ;, not +",
 				},
 			},
 		},
@@ -1821,17 +2794,17 @@ func TestSyntaxErrorsOnBlock(t *testing.T) {
 			`,
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:3:4: expected expression or ;, not end of file",
+					Message: "test.tt:3:4: expected statement or }, not end of file",
 				},
 			},
 		},
 		{
 			input: `{
-			return 10
+			return 10 20
 			}`,
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:3:4: expected ; at the end of statement, not }",
+					Message: "test.tt:2:14: expected ; at the end of statement, not integer literal",
 				},
 			},
 		},
@@ -1867,47 +2840,3 @@ func TestSyntaxErrorsOnBlock(t *testing.T) {
 		})
 	}
 }
-
-func TestFunctionRedeclarationErrors(t *testing.T) {
-	filename := "test.t"
-
-	tests := []diagErrorTest{
-		{
-			input: "fn do_nothing() {}\nfn do_nothing() {}",
-			diags: []diagnostics.Diag{
-				{
-					// TODO: show the first declaration position and the other
-					Message: "test.t:2:4: function 'do_nothing' already declared on scope",
-				},
-			},
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(fmt.Sprintf("TestFunctionRedeclarationErrors('%s')", test.input), func(t *testing.T) {
-			collector := diagnostics.New()
-
-			src := []byte(test.input)
-			lex := lexer.New(filename, src, collector)
-			parser := New(collector)
-
-			_, err := parser.ParseFileAsProgram(lex)
-			if err == nil {
-				t.Fatal("expected to have syntax errors, but got nothing")
-			}
-
-			if len(test.diags) != len(parser.collector.Diags) {
-				t.Fatalf(
-					"expected to have %d diag(s), but got %d\n\ngot: %s\nexp: %s\n",
-					len(test.diags),
-					len(parser.collector.Diags),
-					parser.collector.Diags,
-					test.diags,
-				)
-			}
-			if !reflect.DeepEqual(test.diags, parser.collector.Diags) {
-				t.Fatalf("\nexpected diags: %v\ngot diags: %v\n", test.diags, parser.collector)
-			}
-		})
-	}
-}
@@ -1,10 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/HicaroD/Telia/backend/codegen/llvm"
+	"github.com/HicaroD/Telia/backend/header"
+	"github.com/HicaroD/Telia/bindgen"
 	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/dump"
+	"github.com/HicaroD/Telia/format"
 	"github.com/HicaroD/Telia/frontend/ast"
 	"github.com/HicaroD/Telia/frontend/lexer"
 	"github.com/HicaroD/Telia/frontend/parser"
@@ -16,34 +28,55 @@ func main() {
 
 	switch args.Command {
 	case COMMAND_BUILD:
-		var program *ast.Program
-		var err error
-
-		collector := diagnostics.New()
+		program := check(args)
 
-		if args.IsModuleBuild {
-			program, err = buildModule(args, collector)
-		} else {
-			program, err = buildFile(args, collector)
+		if args.Lib {
+			err := buildLibrary(args, program)
+			// TODO(errors)
+			if err != nil {
+				log.Fatal(err)
+			}
+			break
 		}
 
+		// TODO: define flag for setting the back-end
+		// Currently I only have one type of back-end, but, in the future, I
+		// could have more
+		codegen := llvm.NewCG(args.Path)
+		err := codegen.Generate(program)
 		// TODO(errors)
 		if err != nil {
 			log.Fatal(err)
 		}
+	case COMMAND_TEST:
+		program := check(args)
 
-		sema := sema.New(collector)
-		err = sema.Check(program)
+		err := runTests(args, program)
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			// TODO(errors)
+			log.Fatal(err)
+		}
+	case COMMAND_DUMP:
+		err := runDump(args)
 		// TODO(errors)
 		if err != nil {
 			log.Fatal(err)
 		}
-
-		// TODO: define flag for setting the back-end
-		// Currently I only have one type of back-end, but, in the future, I
-		// could have more
-		codegen := llvm.NewCG(args.Path)
-		err = codegen.Generate(program)
+	case COMMAND_FMT:
+		formatted, err := runFmt(args)
+		// TODO(errors)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if args.FmtCheck && !formatted {
+			os.Exit(1)
+		}
+	case COMMAND_BINDGEN:
+		err := runBindgen(args)
 		// TODO(errors)
 		if err != nil {
 			log.Fatal(err)
@@ -51,6 +84,225 @@ func main() {
 	}
 }
 
+// Prints the bindings of a C header, such as "mathlib.h", or writes them to
+// the output file if one is given
+func runBindgen(args CliResult) error {
+	src, err := os.ReadFile(args.Path)
+	if err != nil {
+		return err
+	}
+	bindings, err := bindgen.Generate(args.Path, src, args.Link)
+	if err != nil {
+		return fmt.Errorf(
+			"%s\nmacros are not expanded, so the header may need to be preprocessed first, such as with \"clang -E -dD %s\"",
+			err,
+			args.Path,
+		)
+	}
+	if args.Output == "" {
+		_, err := os.Stdout.Write(bindings)
+		return err
+	}
+	return os.WriteFile(args.Output, bindings, 0644)
+}
+
+// Parses and analyzes the program
+func check(args CliResult) *ast.Program {
+	var program *ast.Program
+	var err error
+
+	collector := diagnostics.New()
+
+	if args.IsModuleBuild {
+		program, err = buildModule(args, collector)
+	} else {
+		program, err = buildFile(args, collector)
+	}
+
+	// TODO(errors)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sema := sema.NewWithLayout(collector, llvm.NewDataLayout())
+	err = sema.Check(program)
+	// TODO(errors)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return program
+}
+
+// Builds "libname.a", or "libname.so" if shared, on the current directory.
+// The header is written to "name.h" if requested.
+func buildLibrary(args CliResult, program *ast.Program) error {
+	name := strings.TrimSuffix(filepath.Base(args.Path), filepath.Ext(args.Path))
+
+	library := "lib" + name + ".a"
+	if args.Shared {
+		library = "lib" + name + ".so"
+	}
+	codegen := llvm.NewCG(args.Path)
+	err := codegen.GenerateLibrary(program, library, args.Shared)
+	if err != nil {
+		return err
+	}
+
+	if args.EmitHeader {
+		return os.WriteFile(name+".h", header.Generate(program, name), 0644)
+	}
+	return nil
+}
+
+// Builds the test binary on a temporary directory and runs it
+func runTests(args CliResult, program *ast.Program) error {
+	dir, err := os.MkdirTemp("", "telia-test")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "test")
+	codegen := llvm.NewCG(args.Path)
+	err = codegen.GenerateTests(program, binary, args.Bench)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(binary)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Prints the tokens, the AST or the scopes of the program. Paths are relative
+// to the directory being dumped.
+func runDump(args CliResult) error {
+	base := args.Path
+	if !args.IsModuleBuild {
+		base = filepath.Dir(args.Path)
+	}
+	dumper := dump.New(base)
+
+	var tree *dump.Node
+	switch args.Dump {
+	case DUMP_TOKENS:
+		files, err := tokenize(args)
+		if err != nil {
+			return err
+		}
+		tree = dumper.Tokens(files)
+	case DUMP_AST:
+		collector := diagnostics.New()
+		var program *ast.Program
+		var err error
+		if args.IsModuleBuild {
+			program, err = buildModule(args, collector)
+		} else {
+			program, err = buildFile(args, collector)
+		}
+		if err != nil {
+			return err
+		}
+		tree = dumper.AST(program)
+	case DUMP_SCOPES:
+		program := check(args)
+		tree = dumper.Scopes(program)
+	}
+
+	if args.DumpJSON {
+		return dump.WriteJSON(os.Stdout, tree)
+	}
+	return dump.WriteText(os.Stdout, tree)
+}
+
+// Formats every file of the program. With --check, the files that are not
+// formatted are listed instead of printed and false is returned if there is
+// any. With -w, they are overwritten.
+func runFmt(args CliResult) (bool, error) {
+	paths, err := sourceFiles(args)
+	if err != nil {
+		return false, err
+	}
+
+	collector := diagnostics.New()
+	formatted := true
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		out, err := format.Source(path, src, collector)
+		if err != nil {
+			return false, err
+		}
+
+		changed := !bytes.Equal(src, out)
+		if changed {
+			formatted = false
+		}
+		switch {
+		case args.FmtCheck:
+			if changed {
+				fmt.Println(path)
+			}
+		case !args.FmtWrite:
+			os.Stdout.Write(out)
+		}
+		if args.FmtWrite && changed {
+			err := os.WriteFile(path, out, 0644)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	return formatted, nil
+}
+
+// Returns the path of every source file of the program
+func sourceFiles(args CliResult) ([]string, error) {
+	if !args.IsModuleBuild {
+		return []string{args.Path}, nil
+	}
+
+	var paths []string
+	err := filepath.WalkDir(args.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && filepath.Ext(path) == ".t" {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// Returns the tokens of every file of the program, in the same order the
+// parser reads them
+func tokenize(args CliResult) ([]dump.TokenFile, error) {
+	paths, err := sourceFiles(args)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := diagnostics.New()
+	var files []dump.TokenFile
+	for _, path := range paths {
+		parentDirName := filepath.Base(filepath.Dir(path))
+		lex, err := lexer.NewFromFilePath(parentDirName, path, collector)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := lex.Tokenize()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dump.TokenFile{Path: path, Tokens: tokens})
+	}
+	return files, nil
+}
+
 func buildModule(cliResult CliResult, collector *diagnostics.Collector) (*ast.Program, error) {
 	p := parser.New(collector)
 	program, err := p.ParseModuleDir(cliResult.Path)
@@ -0,0 +1,241 @@
+package sema
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+)
+
+// Variables declared without a value, such as "x i32;", that may not be
+// assigned yet at some point of a body
+type unassigned map[*ast.VarStmt]bool
+
+// Reports variables read before being definitely assigned, such as "x" on
+// "x i32; if c { x = 1; } return x;". The body must be already analyzed, so
+// names are resolved on the scopes created by the analysis, just like the
+// backend does.
+func (sema *sema) analyzeDefiniteAssignment(block *ast.BlockStmt, scope *ast.Scope) error {
+	_, _, err := sema.assignBlock(block, scope, unassigned{})
+	return err
+}
+
+// Returns the variables that may be unassigned at the end of the block, and
+// whether the end of the block is reachable at all
+func (sema *sema) assignBlock(
+	block *ast.BlockStmt,
+	scope *ast.Scope,
+	state unassigned,
+) (unassigned, bool, error) {
+	for _, stmt := range block.Statements {
+		var err error
+		state, err = sema.assignStmt(stmt, scope, state)
+		if err != nil {
+			return nil, false, err
+		}
+		if stmtTerminates(stmt) {
+			return nil, false, nil
+		}
+	}
+	return state, true, nil
+}
+
+func (sema *sema) assignStmt(
+	stmt ast.Stmt,
+	scope *ast.Scope,
+	state unassigned,
+) (unassigned, error) {
+	switch statement := stmt.(type) {
+	case *ast.VarStmt:
+		err := sema.checkAssignedReads(statement.Value, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		if statement.Decl && statement.Value == nil {
+			next := maps.Clone(state)
+			next[statement] = true
+			return next, nil
+		}
+		return assignVar(statement, scope, state), nil
+	case *ast.MultiVarStmt:
+		// Every value is evaluated before any variable is assigned
+		err := sema.checkAssignedReads(statement.Tuple, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		for _, variable := range statement.Variables {
+			err := sema.checkAssignedReads(variable.Value, scope, state)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, variable := range statement.Variables {
+			state = assignVar(variable, scope, state)
+		}
+		return state, nil
+	case *ast.CondStmt:
+		return sema.assignCondStmt(statement, scope, state)
+	case *ast.ForLoop:
+		state, err := sema.assignStmt(statement.Init, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		err = sema.checkAssignedReads(statement.Cond, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		body, reachable, err := sema.assignBlock(statement.Block, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		if reachable {
+			_, err = sema.assignStmt(statement.Update, scope, body)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// The body may never run, so the assignments inside of it don't count
+		return state, nil
+	case *ast.RangeForLoop:
+		err := sema.checkAssignedReads(statement.Iterable, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		_, _, err = sema.assignBlock(statement.Block, statement.Scope, state)
+		return state, err
+	case *ast.WhileLoop:
+		err := sema.checkAssignedReads(statement.Cond, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		_, _, err = sema.assignBlock(statement.Block, scope, state)
+		return state, err
+	case *ast.CatchStmt:
+		err := sema.checkAssignedReads(statement.Value, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		// The block only runs if there is an error
+		_, _, err = sema.assignBlock(statement.Block, scope, state)
+		return state, err
+	case *ast.ReturnStmt:
+		return state, sema.checkAssignedReads(statement.Value, scope, state)
+	case *ast.AssertStmt:
+		return state, sema.checkAssignedReads(statement.Cond, scope, state)
+	case *ast.FunctionCall, *ast.FieldAccess, *ast.TryExpr:
+		return state, sema.checkAssignedReads(statement, scope, state)
+	}
+	// Constants and static assertions are evaluated at compile time
+	return state, nil
+}
+
+// Variables assigned on a branch are only assigned after the statement if
+// they are assigned on every branch that reaches its end
+func (sema *sema) assignCondStmt(
+	condStmt *ast.CondStmt,
+	scope *ast.Scope,
+	state unassigned,
+) (unassigned, error) {
+	after := unassigned{}
+	reachable := false
+	merge := func(branch unassigned, branchReachable bool) {
+		if branchReachable {
+			maps.Copy(after, branch)
+			reachable = true
+		}
+	}
+
+	conds := append([]*ast.IfElifCond{condStmt.IfStmt}, condStmt.ElifStmts...)
+	for _, cond := range conds {
+		err := sema.checkAssignedReads(cond.Expr, scope, state)
+		if err != nil {
+			return nil, err
+		}
+		branch, branchReachable, err := sema.assignBlock(cond.Block, cond.Scope, state)
+		if err != nil {
+			return nil, err
+		}
+		merge(branch, branchReachable)
+	}
+
+	if condStmt.ElseStmt == nil {
+		// Nothing runs when every condition is false
+		merge(state, true)
+	} else {
+		branch, branchReachable, err := sema.assignBlock(condStmt.ElseStmt.Block, condStmt.ElseStmt.Scope, state)
+		if err != nil {
+			return nil, err
+		}
+		merge(branch, branchReachable)
+	}
+
+	if !reachable {
+		// Every branch returns, so nothing after the statement runs
+		return unassigned{}, nil
+	}
+	return after, nil
+}
+
+// States are shared between branches, so they are copied before changing
+func assignVar(variable *ast.VarStmt, scope *ast.Scope, state unassigned) unassigned {
+	if variable.Decl {
+		return state
+	}
+	symbol, err := scope.LookupAcrossScopes(variable.Name.Name())
+	declared, ok := symbol.(*ast.VarStmt)
+	if err != nil || !ok || !state[declared] {
+		return state
+	}
+	next := maps.Clone(state)
+	delete(next, declared)
+	return next
+}
+
+func (sema *sema) checkAssignedReads(node ast.Node, scope *ast.Scope, state unassigned) error {
+	if len(state) == 0 || node == nil {
+		return nil
+	}
+
+	var err error
+	ast.Inspect(node, func(node ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.FieldAccess:
+			// Only the arguments of prototype calls are variables, names
+			// such as "stderr" on "libc.stderr" belong to the extern
+			if call, ok := n.Right.(*ast.FunctionCall); ok {
+				for _, arg := range call.Args {
+					err = sema.checkAssignedReads(arg, scope, state)
+					if err != nil {
+						break
+					}
+				}
+			}
+			return false
+		case *ast.IdExpr:
+			symbol, lookupErr := scope.LookupAcrossScopes(n.Name.Name())
+			variable, ok := symbol.(*ast.VarStmt)
+			if lookupErr != nil || !ok || !state[variable] {
+				return false
+			}
+			pos := n.Name.Pos
+			unassignedRead := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: '%s' used before being assigned",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					n.Name.Name(),
+				),
+			}
+			sema.collector.ReportAndSave(unassignedRead)
+			err = diagnostics.COMPILER_ERROR_FOUND
+			return false
+		}
+		return true
+	})
+	return err
+}
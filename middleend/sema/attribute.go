@@ -0,0 +1,213 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+type attributeTarget int
+
+const (
+	TARGET_FUNCTION attributeTarget = 1 << iota
+	TARGET_PROTOTYPE
+)
+
+func (target attributeTarget) String() string {
+	switch target {
+	case TARGET_FUNCTION:
+		return "functions"
+	case TARGET_PROTOTYPE:
+		return "prototypes"
+	default:
+		return "declarations"
+	}
+}
+
+type attributeSpec struct {
+	// Number of string literal arguments, such as the message on
+	// "@deprecated("use g instead")"
+	args    int
+	targets attributeTarget
+}
+
+var attributes = map[string]attributeSpec{
+	"inline":     {args: 0, targets: TARGET_FUNCTION},
+	"noinline":   {args: 0, targets: TARGET_FUNCTION},
+	"cold":       {args: 0, targets: TARGET_FUNCTION | TARGET_PROTOTYPE},
+	"export":     {args: 1, targets: TARGET_FUNCTION},
+	"deprecated": {args: 1, targets: TARGET_FUNCTION | TARGET_PROTOTYPE},
+	"must_use":   {args: 0, targets: TARGET_FUNCTION | TARGET_PROTOTYPE},
+	// Pointers of the prototype are never null, so they are not optional
+	"nonnull": {args: 0, targets: TARGET_PROTOTYPE},
+}
+
+func (sema *sema) analyzeAttributes(
+	declAttributes []*ast.Attribute,
+	target attributeTarget,
+) error {
+	seen := map[string]bool{}
+	for _, attribute := range declAttributes {
+		name := attribute.Name.Name()
+		pos := attribute.At
+
+		spec, ok := attributes[name]
+		if !ok {
+			unknownAttribute := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: unknown attribute '@%s'",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name,
+				),
+			}
+			sema.collector.ReportAndSave(unknownAttribute)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+
+		if spec.targets&target == 0 {
+			wrongTarget := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: attribute '@%s' can't be applied to %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name,
+					target,
+				),
+			}
+			sema.collector.ReportAndSave(wrongTarget)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+
+		if seen[name] {
+			duplicatedAttribute := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: duplicated attribute '@%s'",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name,
+				),
+			}
+			sema.collector.ReportAndSave(duplicatedAttribute)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+		seen[name] = true
+
+		if len(attribute.Args) != spec.args {
+			wrongNumberOfArgs := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: attribute '@%s' expects %d argument(s), but got %d",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					name,
+					spec.args,
+					len(attribute.Args),
+				),
+			}
+			sema.collector.ReportAndSave(wrongNumberOfArgs)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+
+		for _, arg := range attribute.Args {
+			if _, ok := attributeStringArg(arg); !ok {
+				expectedStringLiteral := diagnostics.Diag{
+					Message: fmt.Sprintf(
+						"%s:%d:%d: attribute '@%s' expects a string literal argument",
+						pos.Filename,
+						pos.Line,
+						pos.Column,
+						name,
+					),
+				}
+				sema.collector.ReportAndSave(expectedStringLiteral)
+				return diagnostics.COMPILER_ERROR_FOUND
+			}
+		}
+	}
+
+	if seen["inline"] && seen["noinline"] {
+		pos := ast.FindAttribute(declAttributes, "noinline").At
+		conflictingAttributes := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: attributes '@inline' and '@noinline' can't be used together",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+			),
+		}
+		sema.collector.ReportAndSave(conflictingAttributes)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	return nil
+}
+
+func attributeStringArg(arg ast.Expr) (string, bool) {
+	literal, ok := arg.(*ast.LiteralExpr)
+	if !ok {
+		return "", false
+	}
+	// Attribute arguments are not analyzed as regular expressions, so its type
+	// is still the literal kind
+	ty, ok := literal.Type.(*ast.BasicType)
+	if !ok || ty.Kind != token.STRING_LITERAL {
+		return "", false
+	}
+	return string(literal.Value), true
+}
+
+// Warnings are reported, but they don't stop the compilation
+func (sema *sema) warnDeprecatedCall(
+	call *ast.FunctionCall,
+	calleeAttributes []*ast.Attribute,
+) {
+	deprecated := ast.FindAttribute(calleeAttributes, "deprecated")
+	if deprecated == nil {
+		return
+	}
+
+	pos := call.Name.Pos
+	message := fmt.Sprintf(
+		"%s:%d:%d: '%s' is deprecated",
+		pos.Filename,
+		pos.Line,
+		pos.Column,
+		call.Name.Name(),
+	)
+	if len(deprecated.Args) == 1 {
+		if reason, ok := attributeStringArg(deprecated.Args[0]); ok {
+			message += ": " + reason
+		}
+	}
+	sema.collector.ReportAndSave(diagnostics.Diag{
+		Severity: diagnostics.WARNING,
+		Message:  message,
+	})
+}
+
+func (sema *sema) warnUnusedResult(
+	call *ast.FunctionCall,
+	calleeAttributes []*ast.Attribute,
+) {
+	if ast.FindAttribute(calleeAttributes, "must_use") == nil {
+		return
+	}
+
+	pos := call.Name.Pos
+	unusedResult := diagnostics.Diag{
+		Severity: diagnostics.WARNING,
+		Message: fmt.Sprintf(
+			"%s:%d:%d: result of '%s' is unused",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			call.Name.Name(),
+		),
+	}
+	sema.collector.ReportAndSave(unusedResult)
+}
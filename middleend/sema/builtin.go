@@ -0,0 +1,108 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Functions that can be called without being declared, by their number of
+// arguments. A function declared with the same name takes precedence over the
+// builtin.
+//
+//	len(s)         - length of a string, array or slice, as int
+//	cstr(s)        - copy of a string as a NUL-terminated *u8, such as for
+//	                 passing it to a C function
+//	sizeof(T)      - size of T in bytes, including padding
+//	alignof(T)     - alignment of T in bytes
+//	offsetof(T, i) - offset in bytes of the element i of the tuple type T
+//	typename(T)    - name of T as a string, such as "[]int"
+//
+// The last four are evaluated at compile time, see analyzeTypeBuiltinCall.
+var builtins = map[string]int{
+	"len":      1,
+	"cstr":     1,
+	"sizeof":   1,
+	"alignof":  1,
+	"offsetof": 2,
+	"typename": 1,
+}
+
+func (sema *sema) analyzeBuiltinCall(call *ast.FunctionCall, scope *ast.Scope) error {
+	name := call.Name.Name()
+	if len(call.Args) != builtins[name] {
+		plural := ""
+		if builtins[name] > 1 {
+			plural = "s"
+		}
+		pos := call.Name.Pos
+		wrongNumberOfArgs := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected %d argument%s in call to '%s', but got %d",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				builtins[name],
+				plural,
+				name,
+				len(call.Args),
+			),
+		}
+		sema.collector.ReportAndSave(wrongNumberOfArgs)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	if ast.TYPE_BUILTINS[name] {
+		return sema.analyzeTypeBuiltinCall(call, scope)
+	}
+
+	arg := call.Args[0]
+	var argTy ast.ExprType
+	var err error
+	valid := false
+	switch name {
+	case "len":
+		argTy, err = sema.checkExpr(&call.Args[0], nil, scope)
+		if err != nil {
+			return err
+		}
+		switch argTy.(type) {
+		case *ast.ArrayType, *ast.SliceType:
+			valid = true
+		default:
+			valid = argTy.IsString()
+		}
+		call.Type = &ast.BasicType{Kind: token.INT_TYPE}
+	case "cstr":
+		argTy, err = sema.checkExpr(&call.Args[0], &ast.BasicType{Kind: token.STRING_TYPE}, scope)
+		if err != nil {
+			return err
+		}
+		valid = argTy.IsString()
+		call.Type = &ast.PointerType{Type: &ast.BasicType{Kind: token.U8_TYPE}}
+	}
+
+	if !valid {
+		return sema.reportInvalidBuiltinArg(arg, argTy, name)
+	}
+
+	call.Builtin = true
+	return nil
+}
+
+func (sema *sema) reportInvalidBuiltinArg(arg ast.Expr, argTy ast.ExprType, name string) error {
+	pos := arg.Span().Start
+	invalidArg := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: invalid argument of type %s for '%s'",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			argTy,
+			name,
+		),
+	}
+	sema.collector.ReportAndSave(invalidArg)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
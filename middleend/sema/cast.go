@@ -0,0 +1,126 @@
+package sema
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Analyzes "value as T". Numeric types can be cast to any other numeric type,
+// widening, narrowing or changing its signedness. Booleans and numeric types
+// can be cast to each other, where true is 1 and any value other than 0 is
+// true. Pointers can only be cast to other pointers.
+func (sema *sema) inferCastType(cast *ast.CastExpr, scope *ast.Scope) (ast.ExprType, error) {
+	err := sema.resolveType(cast.Type, cast.Span().Start, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	fromTy, err := sema.checkExpr(&cast.Value, nil, scope)
+	if err != nil {
+		return nil, err
+	}
+	cast.From = fromTy
+
+	if !canCast(fromTy, cast.Type) {
+		pos := cast.Span().Start
+		invalidCast := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't cast %s to %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				fromTy,
+				cast.Type,
+			),
+		}
+		sema.collector.ReportAndSave(invalidCast)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	sema.warnLossyLiteralCast(cast)
+	return cast.Type, nil
+}
+
+func canCast(from, to ast.ExprType) bool {
+	switch to := to.(type) {
+	case *ast.BasicType:
+		from, ok := from.(*ast.BasicType)
+		if !ok {
+			return false
+		}
+		isScalar := func(ty *ast.BasicType) bool { return ty.IsNumeric() || ty.IsBoolean() }
+		return isScalar(from) && isScalar(to)
+	case *ast.PointerType:
+		_, ok := from.(*ast.PointerType)
+		return ok
+	default:
+		return false
+	}
+}
+
+// Integer literals which don't fit on the type they are cast to, such as
+// "300 as u8" or "-1 as u32", end up with a different value
+func (sema *sema) warnLossyLiteralCast(cast *ast.CastExpr) {
+	value, ok := integerLiteralValue(cast.Value)
+	if !ok {
+		return
+	}
+	to, ok := cast.Type.(*ast.BasicType)
+	if !ok || !to.IsNumeric() || fitsOn(value, to.Kind) {
+		return
+	}
+
+	pos := cast.Span().Start
+	lossyCast := diagnostics.Diag{
+		Severity: diagnostics.WARNING,
+		Message: fmt.Sprintf(
+			"%s:%d:%d: %s doesn't fit on %s, the cast changes its value",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			value,
+			to,
+		),
+	}
+	sema.collector.ReportAndSave(lossyCast)
+}
+
+// Returns the value of integer literals, such as "10" and "-10"
+func integerLiteralValue(expr ast.Expr) (*big.Int, bool) {
+	negative := false
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.MINUS {
+		negative = true
+		expr = unary.Value
+	}
+	literal, ok := expr.(*ast.LiteralExpr)
+	if !ok {
+		return nil, false
+	}
+	basic, ok := literal.Type.(*ast.BasicType)
+	if !ok || (!basic.IsNumeric() && basic.Kind != token.INTEGER_LITERAL) {
+		return nil, false
+	}
+
+	// Constants are folded into literals, where negative values are in two's
+	// complement
+	value := constFromExpr(literal).Int
+	if negative {
+		value.Neg(value)
+	}
+	return value, true
+}
+
+func fitsOn(value *big.Int, kind token.Kind) bool {
+	bitSize := uint(kind.BitSize())
+	min, max := new(big.Int), new(big.Int).Lsh(big.NewInt(1), bitSize)
+	if kind.IsSigned() {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	max.Sub(max, big.NewInt(1))
+	return value.Cmp(min) >= 0 && value.Cmp(max) <= 0
+}
@@ -0,0 +1,914 @@
+package sema
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Maximum number of statements and expressions a single compile-time
+// evaluation may go through. It stops functions that never return from
+// hanging the compiler.
+const MAX_CONST_EVAL_STEPS = 1_000_000
+
+// Integer or boolean known at compile time. Integers come from literals
+// without a type until they meet a typed value, such as a parameter or a
+// typed constant.
+type constValue struct {
+	Type ast.ExprType // nil for untyped integers
+	Int  *big.Int
+	Bool bool
+}
+
+func (value *constValue) isBool() bool { return value.Int == nil }
+
+func (value *constValue) String() string {
+	if value.isBool() {
+		return fmt.Sprintf("%t", value.Bool)
+	}
+	return value.Int.String()
+}
+
+func (value *constValue) typeName() string {
+	if value.Type == nil {
+		return "untyped integer"
+	}
+	return fmt.Sprintf("%s", value.Type)
+}
+
+// Local variables of a function being evaluated at compile time
+type constEnv struct {
+	parent *constEnv
+	vars   map[string]*constValue
+}
+
+func newConstEnv(parent *constEnv) *constEnv {
+	return &constEnv{parent: parent, vars: make(map[string]*constValue)}
+}
+
+func (env *constEnv) lookup(name string) (*constValue, *constEnv) {
+	for current := env; current != nil; current = current.parent {
+		if value, ok := current.vars[name]; ok {
+			return value, current
+		}
+	}
+	return nil, nil
+}
+
+type constEvaluator struct {
+	sema *sema
+	// Position of the constant, static assertion or type being evaluated,
+	// used when the expression has no position of its own
+	pos   token.Pos
+	steps int
+}
+
+// Evaluates an integer or boolean expression at compile time. Calls to Telia
+// functions are evaluated by interpreting their body.
+func (sema *sema) evalConstExpr(
+	expr ast.Expr,
+	pos token.Pos,
+	scope *ast.Scope,
+) (*constValue, error) {
+	evaluator := &constEvaluator{sema: sema, pos: pos}
+	return evaluator.eval(expr, nil, scope)
+}
+
+func (sema *sema) analyzeConstDecl(constDecl *ast.ConstDecl, scope *ast.Scope) error {
+	err := sema.evalConstDecl(constDecl, scope)
+	if err != nil {
+		return err
+	}
+
+	err = scope.Insert(constDecl.Name.Name(), constDecl)
+	if err != nil {
+		if err == ast.ERR_SYMBOL_ALREADY_DEFINED_ON_SCOPE {
+			pos := constDecl.Name.Pos
+			constantRedeclaration := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: '%s' already declared on scope",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					constDecl.Name.Name(),
+				),
+			}
+			sema.collector.ReportAndSave(constantRedeclaration)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+		return err
+	}
+	return nil
+}
+
+// Evaluates the value of a constant and folds it into a literal
+func (sema *sema) evalConstDecl(constDecl *ast.ConstDecl, scope *ast.Scope) error {
+	delete(sema.pendingConsts, constDecl)
+	sema.evaluatingConsts[constDecl] = true
+	defer delete(sema.evaluatingConsts, constDecl)
+
+	if constDecl.Type != nil && !constDecl.Type.IsNumeric() && !constDecl.Type.IsBoolean() {
+		pos := constDecl.Name.Pos
+		invalidConstantType := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: invalid constant type %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				constDecl.Type,
+			),
+		}
+		sema.collector.ReportAndSave(invalidConstantType)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	evaluator := &constEvaluator{sema: sema, pos: constDecl.Name.Pos}
+	value, err := evaluator.eval(constDecl.Value, nil, scope)
+	if err != nil {
+		return err
+	}
+	// Constants without a type, such as "const N = 10", stay untyped until
+	// they are used
+	value, err = evaluator.convert(value, constDecl.Type)
+	if err != nil {
+		return err
+	}
+	constDecl.Type = value.Type
+	folded := constToExpr(value)
+	folded.Loc = constDecl.Value.Span()
+	constDecl.Value = folded
+	return nil
+}
+
+func (sema *sema) analyzeStaticAssert(staticAssert *ast.StaticAssert, scope *ast.Scope) error {
+	pos := staticAssert.StaticAssert.Pos
+	cond, err := sema.evalConstExpr(staticAssert.Cond, pos, scope)
+	if err != nil {
+		return err
+	}
+	if !cond.isBool() {
+		expectedBool := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected bool on static_assert, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				cond.typeName(),
+			),
+		}
+		sema.collector.ReportAndSave(expectedBool)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	if !cond.Bool {
+		staticAssertionFailed := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: static assertion failed: %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				staticAssert.Message.Lexeme,
+			),
+		}
+		sema.collector.ReportAndSave(staticAssertionFailed)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	return nil
+}
+
+// Evaluates lengths of array types that are not integer literals, such as
+// "[N * 2]int". Diagnostics are reported at "pos", usually the name of what is
+// being declared with the type.
+func (sema *sema) resolveType(ty ast.ExprType, pos token.Pos, scope *ast.Scope) error {
+	switch exprTy := ty.(type) {
+	case *ast.PointerType:
+		return sema.resolveType(exprTy.Type, pos, scope)
+	case *ast.SliceType:
+		return sema.resolveType(exprTy.Type, pos, scope)
+	case *ast.OptionalType:
+		return sema.resolveType(exprTy.Type, pos, scope)
+	case *ast.ErrorUnionType:
+		return sema.resolveType(exprTy.Type, pos, scope)
+	case *ast.TupleType:
+		for _, elemTy := range exprTy.Types {
+			err := sema.resolveType(elemTy, pos, scope)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.ArrayType:
+		if exprTy.LenExpr != nil {
+			length, err := sema.evalConstExpr(exprTy.LenExpr, pos, scope)
+			if err != nil {
+				return err
+			}
+			if length.isBool() || length.Int.Sign() < 0 || !length.Int.IsInt64() {
+				invalidArrayLength := diagnostics.Diag{
+					Message: fmt.Sprintf(
+						"%s:%d:%d: invalid array length %s",
+						pos.Filename,
+						pos.Line,
+						pos.Column,
+						length,
+					),
+				}
+				sema.collector.ReportAndSave(invalidArrayLength)
+				return diagnostics.COMPILER_ERROR_FOUND
+			}
+			exprTy.Len = int(length.Int.Int64())
+			// Once evaluated, the type is just like "[N]T"
+			exprTy.LenExpr = nil
+		}
+		return sema.resolveType(exprTy.Type, pos, scope)
+	}
+	return nil
+}
+
+func (ev *constEvaluator) report(pos token.Pos, format string, args ...any) error {
+	notConstant := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: %s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			fmt.Sprintf(format, args...),
+		),
+	}
+	ev.sema.collector.ReportAndSave(notConstant)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+func (ev *constEvaluator) step() error {
+	ev.steps++
+	if ev.steps > MAX_CONST_EVAL_STEPS {
+		return ev.report(
+			ev.pos,
+			"compile-time evaluation exceeded %d steps",
+			MAX_CONST_EVAL_STEPS,
+		)
+	}
+	return nil
+}
+
+func (ev *constEvaluator) eval(
+	expr ast.Expr,
+	env *constEnv,
+	scope *ast.Scope,
+) (*constValue, error) {
+	err := ev.step()
+	if err != nil {
+		return nil, err
+	}
+
+	switch expression := expr.(type) {
+	case *ast.LiteralExpr:
+		return ev.evalLiteral(expression)
+	case *ast.IdExpr:
+		name := expression.Name.Name()
+		if value, _ := env.lookup(name); value != nil {
+			return value, nil
+		}
+		symbol, err := scope.LookupAcrossScopes(name)
+		if err != nil {
+			return nil, ev.report(expression.Name.Pos, "'%s' not defined on scope", name)
+		}
+		constDecl, ok := symbol.(*ast.ConstDecl)
+		if !ok {
+			return nil, ev.report(expression.Name.Pos, "'%s' is not a constant", name)
+		}
+		if ev.sema.evaluatingConsts[constDecl] {
+			return nil, ev.report(expression.Name.Pos, "initialization cycle on constant '%s'", name)
+		}
+		if declScope, pending := ev.sema.pendingConsts[constDecl]; pending {
+			err := ev.sema.evalConstDecl(constDecl, declScope)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// Constants are folded into literals as soon as they are analyzed
+		return constFromExpr(constDecl.Value.(*ast.LiteralExpr)), nil
+	case *ast.UnaryExpr:
+		value, err := ev.eval(expression.Value, env, scope)
+		if err != nil {
+			return nil, err
+		}
+		switch expression.Op {
+		case token.MINUS:
+			if value.isBool() {
+				return nil, ev.report(ev.pos, "can't use - on %s", value.typeName())
+			}
+			result := &constValue{Type: value.Type, Int: new(big.Int).Neg(value.Int)}
+			return ev.checkOverflow(result)
+		case token.NOT:
+			if !value.isBool() {
+				return nil, ev.report(ev.pos, "can't use not on %s", value.typeName())
+			}
+			return &constValue{Type: value.Type, Bool: !value.Bool}, nil
+		}
+	case *ast.BinaryExpr:
+		return ev.evalBinary(expression, env, scope)
+	case *ast.FunctionCall:
+		return ev.evalCall(expression, env, scope)
+	case *ast.CastExpr:
+		value, err := ev.eval(expression.Value, env, scope)
+		if err != nil {
+			return nil, err
+		}
+		return ev.cast(value, expression.Type)
+	}
+	return nil, ev.report(expr.Span().Start, "%s is not allowed at compile time", describeNonConst(expr))
+}
+
+func (ev *constEvaluator) evalLiteral(literal *ast.LiteralExpr) (*constValue, error) {
+	ty, ok := literal.Type.(*ast.BasicType)
+	if !ok {
+		return nil, ev.report(literal.Span().Start, "%s is not allowed at compile time", describeNonConst(literal))
+	}
+	boolTy := &ast.BasicType{Kind: token.BOOL_TYPE}
+	switch ty.Kind {
+	case token.TRUE_BOOL_LITERAL:
+		return &constValue{Type: boolTy, Bool: true}, nil
+	case token.FALSE_BOOL_LITERAL:
+		return &constValue{Type: boolTy, Bool: false}, nil
+	case token.BOOL_TYPE:
+		// Boolean literals already analyzed hold "1" or "0"
+		return &constValue{Type: boolTy, Bool: string(literal.Value) == "1"}, nil
+	case token.STRING_LITERAL, token.STRING_TYPE, token.NIL_LITERAL:
+		return nil, ev.report(literal.Span().Start, "%s is not allowed at compile time", describeNonConst(literal))
+	}
+	// Integer literals are untyped, even after being analyzed with a type,
+	// where negative values are in two's complement
+	if ty.Kind != token.INTEGER_LITERAL {
+		return &constValue{Int: constFromExpr(literal).Int}, nil
+	}
+	value, ok := new(big.Int).SetString(string(literal.Value), 10)
+	if !ok {
+		return nil, ev.report(literal.Span().Start, "invalid integer literal %s", literal.Value)
+	}
+	return &constValue{Int: value}, nil
+}
+
+func (ev *constEvaluator) evalBinary(
+	binary *ast.BinaryExpr,
+	env *constEnv,
+	scope *ast.Scope,
+) (*constValue, error) {
+	lhs, err := ev.eval(binary.Left, env, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// "and" and "or" short-circuit, just like at runtime
+	if binary.Op == token.AND || binary.Op == token.OR {
+		if !lhs.isBool() {
+			return nil, ev.report(ev.pos, "can't use %s on %s", binary.Op, lhs.typeName())
+		}
+		if (binary.Op == token.AND && !lhs.Bool) || (binary.Op == token.OR && lhs.Bool) {
+			return lhs, nil
+		}
+		rhs, err := ev.eval(binary.Right, env, scope)
+		if err != nil {
+			return nil, err
+		}
+		if !rhs.isBool() {
+			return nil, ev.report(ev.pos, "can't use %s on %s", binary.Op, rhs.typeName())
+		}
+		return rhs, nil
+	}
+
+	rhs, err := ev.eval(binary.Right, env, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if lhs.isBool() || rhs.isBool() {
+		if !lhs.isBool() || !rhs.isBool() {
+			return nil, ev.report(ev.pos, "mismatched types: %s %s %s", lhs.typeName(), binary.Op, rhs.typeName())
+		}
+		switch binary.Op {
+		case token.EQUAL_EQUAL:
+			return &constValue{Type: lhs.Type, Bool: lhs.Bool == rhs.Bool}, nil
+		case token.BANG_EQUAL:
+			return &constValue{Type: lhs.Type, Bool: lhs.Bool != rhs.Bool}, nil
+		}
+		return nil, ev.report(ev.pos, "can't use %s on %s", binary.Op, lhs.typeName())
+	}
+
+	// Operands are mixed just like at runtime, where the narrower integer is
+	// widened to the type of the other one
+	ty := lhs.Type
+	switch {
+	case lhs.Type == nil:
+		ty = rhs.Type
+	case rhs.Type == nil, ast.SameType(lhs.Type, rhs.Type), widens(rhs.Type, lhs.Type):
+		// The type of the left operand
+	case widens(lhs.Type, rhs.Type):
+		ty = rhs.Type
+	default:
+		return nil, ev.report(ev.pos, "mismatched types: %s %s %s", lhs.typeName(), binary.Op, rhs.typeName())
+	}
+
+	if _, ok := ast.COMPARASION[binary.Op]; ok {
+		cmp := lhs.Int.Cmp(rhs.Int)
+		var result bool
+		switch binary.Op {
+		case token.EQUAL_EQUAL:
+			result = cmp == 0
+		case token.BANG_EQUAL:
+			result = cmp != 0
+		case token.LESS:
+			result = cmp < 0
+		case token.LESS_EQ:
+			result = cmp <= 0
+		case token.GREATER:
+			result = cmp > 0
+		case token.GREATER_EQ:
+			result = cmp >= 0
+		}
+		return &constValue{Type: &ast.BasicType{Kind: token.BOOL_TYPE}, Bool: result}, nil
+	}
+
+	result := new(big.Int)
+	switch binary.Op {
+	case token.PLUS:
+		result.Add(lhs.Int, rhs.Int)
+	case token.MINUS:
+		result.Sub(lhs.Int, rhs.Int)
+	case token.STAR:
+		result.Mul(lhs.Int, rhs.Int)
+	case token.SLASH:
+		if rhs.Int.Sign() == 0 {
+			return nil, ev.report(ev.pos, "division by zero")
+		}
+		// Truncated division, just like at runtime
+		result.Quo(lhs.Int, rhs.Int)
+	case token.PERCENT:
+		if rhs.Int.Sign() == 0 {
+			return nil, ev.report(ev.pos, "division by zero")
+		}
+		// The remainder has the sign of the dividend, just like at runtime
+		result.Rem(lhs.Int, rhs.Int)
+	default:
+		return nil, ev.report(ev.pos, "can't use %s on %s", binary.Op, lhs.typeName())
+	}
+	return ev.checkOverflow(&constValue{Type: ty, Int: result})
+}
+
+func (ev *constEvaluator) evalCall(
+	call *ast.FunctionCall,
+	env *constEnv,
+	scope *ast.Scope,
+) (*constValue, error) {
+	name := call.Name.Name()
+	symbol, err := scope.LookupAcrossScopes(name)
+	if _, ok := builtins[name]; ok && err != nil {
+		err := ev.sema.analyzeBuiltinCall(call, scope)
+		if err != nil {
+			return nil, err
+		}
+		if call.Folded == nil {
+			return nil, ev.report(call.Name.Pos, "'%s' can't be called at compile time", name)
+		}
+		return ev.evalLiteral(call.Folded)
+	}
+	if err != nil {
+		return nil, ev.report(call.Name.Pos, "function '%s' not defined on scope", name)
+	}
+	function, ok := symbol.(*ast.FunctionDecl)
+	if !ok {
+		return nil, ev.report(call.Name.Pos, "'%s' can't be called at compile time", name)
+	}
+	ev.sema.markUsed(function)
+	if len(call.Args) != len(function.Params.Fields) || function.Params.IsVariadic {
+		return nil, ev.report(
+			call.Name.Pos,
+			"function '%s' expects %d argument(s), but got %d",
+			name,
+			len(function.Params.Fields),
+			len(call.Args),
+		)
+	}
+
+	locals := newConstEnv(nil)
+	for i, param := range function.Params.Fields {
+		arg, err := ev.eval(call.Args[i], env, scope)
+		if err != nil {
+			return nil, err
+		}
+		arg, err = ev.convert(arg, param.Type)
+		if err != nil {
+			return nil, err
+		}
+		locals.vars[param.Name.Name()] = arg
+	}
+
+	// Functions not analyzed yet have no scope, but global symbols are still
+	// reachable from the caller
+	functionScope := function.Scope
+	if functionScope == nil {
+		functionScope = scope
+	}
+
+	returned, value, err := ev.execBlock(function.Block, function.RetType, locals, functionScope)
+	if err != nil {
+		return nil, err
+	}
+	if !returned || value == nil {
+		return nil, ev.report(call.Name.Pos, "function '%s' returns no value at compile time", name)
+	}
+	return value, nil
+}
+
+// Executes the statements of a block, returning true if a return statement was
+// reached
+func (ev *constEvaluator) execBlock(
+	block *ast.BlockStmt,
+	retType ast.ExprType,
+	env *constEnv,
+	scope *ast.Scope,
+) (bool, *constValue, error) {
+	blockEnv := newConstEnv(env)
+	for _, stmt := range block.Statements {
+		returned, value, err := ev.execStmt(stmt, retType, blockEnv, scope)
+		if err != nil || returned {
+			return returned, value, err
+		}
+	}
+	return false, nil, nil
+}
+
+func (ev *constEvaluator) execStmt(
+	stmt ast.Stmt,
+	retType ast.ExprType,
+	env *constEnv,
+	scope *ast.Scope,
+) (bool, *constValue, error) {
+	err := ev.step()
+	if err != nil {
+		return false, nil, err
+	}
+
+	switch statement := stmt.(type) {
+	case *ast.ReturnStmt:
+		if statement.Value.IsVoid() {
+			return true, nil, nil
+		}
+		value, err := ev.eval(statement.Value, env, scope)
+		if err != nil {
+			return false, nil, err
+		}
+		value, err = ev.convert(value, retType)
+		return true, value, err
+	case *ast.VarStmt:
+		err := ev.execVar(statement, env, scope)
+		return false, nil, err
+	case *ast.MultiVarStmt:
+		if statement.Tuple != nil {
+			break
+		}
+		// Every value is evaluated before any assignment, so "a, b = b, a"
+		// swaps values
+		values := make([]*constValue, len(statement.Variables))
+		for i, variable := range statement.Variables {
+			value, err := ev.eval(variable.Value, env, scope)
+			if err != nil {
+				return false, nil, err
+			}
+			values[i] = value
+		}
+		for i, variable := range statement.Variables {
+			err := ev.assign(variable, values[i], env)
+			if err != nil {
+				return false, nil, err
+			}
+		}
+		return false, nil, nil
+	case *ast.ConstDecl:
+		value, err := ev.eval(statement.Value, env, scope)
+		if err != nil {
+			return false, nil, err
+		}
+		value, err = ev.convert(value, statement.Type)
+		if err != nil {
+			return false, nil, err
+		}
+		env.vars[statement.Name.Name()] = value
+		return false, nil, nil
+	case *ast.StaticAssert:
+		return false, nil, nil
+	case *ast.AssertStmt:
+		cond, err := ev.evalCond(statement.Cond, env, scope)
+		if err != nil {
+			return false, nil, err
+		}
+		if !cond {
+			pos := statement.Assert.Pos
+			return false, nil, ev.report(pos, "assertion failed during compile-time evaluation")
+		}
+		return false, nil, nil
+	case *ast.FunctionCall:
+		_, err := ev.evalCall(statement, env, scope)
+		return false, nil, err
+	case *ast.CondStmt:
+		branches := append([]*ast.IfElifCond{statement.IfStmt}, statement.ElifStmts...)
+		for _, branch := range branches {
+			cond, err := ev.evalCond(branch.Expr, env, scope)
+			if err != nil {
+				return false, nil, err
+			}
+			if cond {
+				return ev.execBlock(branch.Block, retType, env, scope)
+			}
+		}
+		if statement.ElseStmt != nil {
+			return ev.execBlock(statement.ElseStmt.Block, retType, env, scope)
+		}
+		return false, nil, nil
+	case *ast.WhileLoop:
+		for {
+			cond, err := ev.evalCond(statement.Cond, env, scope)
+			if err != nil || !cond {
+				return false, nil, err
+			}
+			returned, value, err := ev.execBlock(statement.Block, retType, env, scope)
+			if err != nil || returned {
+				return returned, value, err
+			}
+		}
+	case *ast.ForLoop:
+		loopEnv := newConstEnv(env)
+		if statement.Init != nil {
+			_, _, err := ev.execStmt(statement.Init, retType, loopEnv, scope)
+			if err != nil {
+				return false, nil, err
+			}
+		}
+		for {
+			cond, err := ev.evalCond(statement.Cond, loopEnv, scope)
+			if err != nil || !cond {
+				return false, nil, err
+			}
+			returned, value, err := ev.execBlock(statement.Block, retType, loopEnv, scope)
+			if err != nil || returned {
+				return returned, value, err
+			}
+			if statement.Update != nil {
+				_, _, err := ev.execStmt(statement.Update, retType, loopEnv, scope)
+				if err != nil {
+					return false, nil, err
+				}
+			}
+		}
+	case *ast.RangeForLoop:
+		rangeExpr, ok := statement.Iterable.(*ast.RangeExpr)
+		if !ok {
+			break
+		}
+		start, err := ev.eval(rangeExpr.Start, env, scope)
+		if err != nil {
+			return false, nil, err
+		}
+		end, err := ev.eval(rangeExpr.End, env, scope)
+		if err != nil {
+			return false, nil, err
+		}
+		if start.isBool() || end.isBool() {
+			return false, nil, ev.report(rangeExpr.Span().Start, "range bounds must be integers")
+		}
+		ty := start.Type
+		if ty == nil {
+			ty = end.Type
+		}
+		if ty == nil {
+			ty = &ast.BasicType{Kind: token.INT_TYPE}
+		}
+		for i := new(big.Int).Set(start.Int); ; i.Add(i, big.NewInt(1)) {
+			cmp := i.Cmp(end.Int)
+			if cmp > 0 || (cmp == 0 && !rangeExpr.Inclusive) {
+				return false, nil, nil
+			}
+			loopEnv := newConstEnv(env)
+			loopEnv.vars[statement.Value.Name.Name()] = &constValue{Type: ty, Int: new(big.Int).Set(i)}
+			returned, value, err := ev.execBlock(statement.Block, retType, loopEnv, scope)
+			if err != nil || returned {
+				return returned, value, err
+			}
+		}
+	}
+	return false, nil, ev.report(stmt.Span().Start, "%s is not allowed at compile time", describeNonConst(stmt))
+}
+
+// Describes what can't be evaluated at compile time, such as "call to extern
+// 'printf'"
+func describeNonConst(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.FieldAccess:
+		if call, ok := n.Right.(*ast.FunctionCall); ok {
+			return fmt.Sprintf("call to extern '%s'", call.Name.Name())
+		}
+		return "field access"
+	case *ast.LiteralExpr:
+		if isNilLiteral(n) {
+			return "nil"
+		}
+		return "string literal"
+	case *ast.BinaryExpr:
+		return fmt.Sprintf("'%s'", n.Op)
+	case *ast.TryExpr:
+		return "'try'"
+	case *ast.CatchStmt:
+		return "'catch'"
+	case *ast.ErrorExpr:
+		return fmt.Sprintf("error value %s", n)
+	case *ast.TupleExpr:
+		return "tuple"
+	case *ast.ArrayLiteral:
+		return "array literal"
+	case *ast.IndexExpr:
+		return "indexing"
+	case *ast.RangeExpr:
+		return "range outside of a for loop"
+	case *ast.RangeForLoop:
+		return "for loop over an array or slice"
+	case *ast.MultiVarStmt:
+		return "tuple destructuring"
+	}
+	return "expression"
+}
+
+func (ev *constEvaluator) execVar(
+	variable *ast.VarStmt,
+	env *constEnv,
+	scope *ast.Scope,
+) error {
+	value, err := ev.eval(variable.Value, env, scope)
+	if err != nil {
+		return err
+	}
+	return ev.assign(variable, value, env)
+}
+
+func (ev *constEvaluator) assign(variable *ast.VarStmt, value *constValue, env *constEnv) error {
+	name := variable.Name.Name()
+	if variable.Decl {
+		ty := variable.Type
+		if ty == nil && !value.isBool() && value.Type == nil {
+			ty = &ast.BasicType{Kind: token.INT_TYPE}
+		}
+		value, err := ev.convert(value, ty)
+		if err != nil {
+			return err
+		}
+		env.vars[name] = value
+		return nil
+	}
+
+	current, owner := env.lookup(name)
+	if current == nil {
+		return ev.report(variable.Name.Pos, "'%s' can't be assigned at compile time", name)
+	}
+	value, err := ev.convert(value, current.Type)
+	if err != nil {
+		return err
+	}
+	owner.vars[name] = value
+	return nil
+}
+
+func (ev *constEvaluator) evalCond(
+	expr ast.Expr,
+	env *constEnv,
+	scope *ast.Scope,
+) (bool, error) {
+	cond, err := ev.eval(expr, env, scope)
+	if err != nil {
+		return false, err
+	}
+	if !cond.isBool() {
+		return false, ev.report(ev.pos, "expected bool on condition, not %s", cond.typeName())
+	}
+	return cond.Bool, nil
+}
+
+// Gives a type to a value, making sure it fits on it. A nil type keeps the
+// value as it is.
+func (ev *constEvaluator) convert(value *constValue, ty ast.ExprType) (*constValue, error) {
+	if ty == nil {
+		return value, nil
+	}
+	basicTy, ok := ty.(*ast.BasicType)
+	if !ok {
+		return nil, ev.report(ev.pos, "can't use %s at compile time", ty)
+	}
+
+	if value.isBool() {
+		if !basicTy.IsBoolean() {
+			return nil, ev.report(ev.pos, "can't use %s as %s", value.typeName(), ty)
+		}
+		return value, nil
+	}
+	if !basicTy.IsNumeric() {
+		return nil, ev.report(ev.pos, "can't use %s as %s", value.typeName(), ty)
+	}
+	if value.Type != nil && !ast.SameType(value.Type, ty) && !widens(value.Type, ty) {
+		return nil, ev.report(ev.pos, "can't use %s as %s", value.typeName(), ty)
+	}
+	return ev.checkOverflow(&constValue{Type: basicTy, Int: value.Int})
+}
+
+// Converts a value just like "value as T" does at runtime, where integers
+// that don't fit on T wrap around
+func (ev *constEvaluator) cast(value *constValue, ty ast.ExprType) (*constValue, error) {
+	basicTy, ok := ty.(*ast.BasicType)
+	if ok && basicTy.IsBoolean() {
+		if value.isBool() {
+			return &constValue{Type: basicTy, Bool: value.Bool}, nil
+		}
+		return &constValue{Type: basicTy, Bool: value.Int.Sign() != 0}, nil
+	}
+	if !ok || !basicTy.IsNumeric() {
+		return nil, ev.report(ev.pos, "can't cast %s to %s at compile time", value.typeName(), ty)
+	}
+	if value.isBool() {
+		result := big.NewInt(0)
+		if value.Bool {
+			result.SetInt64(1)
+		}
+		return &constValue{Type: basicTy, Int: result}, nil
+	}
+
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(basicTy.Kind.BitSize()))
+	result := new(big.Int).Mod(value.Int, modulus)
+	if _, max := integerBounds(basicTy.Kind); result.Cmp(max) > 0 {
+		result.Sub(result, modulus)
+	}
+	return &constValue{Type: basicTy, Int: result}, nil
+}
+
+func (ev *constEvaluator) checkOverflow(value *constValue) (*constValue, error) {
+	if value.Type == nil {
+		return value, nil
+	}
+	basicTy := value.Type.(*ast.BasicType)
+	min, max := integerBounds(basicTy.Kind)
+	if value.Int.Cmp(min) < 0 || value.Int.Cmp(max) > 0 {
+		return nil, ev.report(ev.pos, "constant %s overflows %s", value.Int, basicTy)
+	}
+	return value, nil
+}
+
+func integerBounds(kind token.Kind) (*big.Int, *big.Int) {
+	bitSize := uint(kind.BitSize())
+	switch kind {
+	case token.INT_TYPE, token.I8_TYPE, token.I16_TYPE, token.I32_TYPE, token.I64_TYPE:
+		max := new(big.Int).Lsh(big.NewInt(1), bitSize-1)
+		min := new(big.Int).Neg(max)
+		return min, max.Sub(max, big.NewInt(1))
+	default:
+		max := new(big.Int).Lsh(big.NewInt(1), bitSize)
+		return big.NewInt(0), max.Sub(max, big.NewInt(1))
+	}
+}
+
+// Builds a literal out of a value, so the backend never has to evaluate
+// constants again. Negative integers are stored in two's complement, just
+// like the backend represents them. Untyped integers are kept as they are,
+// since they never reach the backend.
+func constToExpr(value *constValue) *ast.LiteralExpr {
+	if value.Type == nil {
+		untyped := &ast.BasicType{Kind: token.INTEGER_LITERAL}
+		return &ast.LiteralExpr{Type: untyped, Value: []byte(value.Int.String())}
+	}
+	ty := value.Type.(*ast.BasicType)
+	if value.isBool() {
+		literal := &ast.LiteralExpr{Type: ty, Value: []byte("0")}
+		if value.Bool {
+			literal.Value = []byte("1")
+		}
+		return literal
+	}
+	bits := new(big.Int).Set(value.Int)
+	if bits.Sign() < 0 {
+		bits.Add(bits, new(big.Int).Lsh(big.NewInt(1), uint(ty.Kind.BitSize())))
+	}
+	return &ast.LiteralExpr{Type: ty, Value: []byte(bits.String())}
+}
+
+// Reverses constToExpr
+func constFromExpr(literal *ast.LiteralExpr) *constValue {
+	ty := literal.Type.(*ast.BasicType)
+	if ty.IsBoolean() {
+		return &constValue{Type: ty, Bool: string(literal.Value) == "1"}
+	}
+	value, _ := new(big.Int).SetString(string(literal.Value), 10)
+	if ty.Kind == token.INTEGER_LITERAL {
+		return &constValue{Int: value}
+	}
+	_, max := integerBounds(ty.Kind)
+	if value.Cmp(max) > 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), uint(ty.Kind.BitSize())))
+	}
+	return &constValue{Type: ty, Int: value}
+}
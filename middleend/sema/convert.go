@@ -0,0 +1,177 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Reports whether an expression is known at compile time, such as "-1",
+// "SIZE * 2" or "sizeof(int)". Strings and calls to Telia functions aren't
+// constants, even though constant declarations can evaluate them.
+func (sema *sema) isConstExpr(expr ast.Expr, scope *ast.Scope) bool {
+	switch expression := expr.(type) {
+	case *ast.LiteralExpr:
+		ty, ok := expression.Type.(*ast.BasicType)
+		if !ok {
+			return false
+		}
+		// Constants already folded have a type of their own, so they aren't
+		// folded again
+		switch ty.Kind {
+		case token.INTEGER_LITERAL, token.TRUE_BOOL_LITERAL, token.FALSE_BOOL_LITERAL:
+			return true
+		}
+		return false
+	case *ast.IdExpr:
+		symbol, err := scope.LookupAcrossScopes(expression.Name.Name())
+		_, ok := symbol.(*ast.ConstDecl)
+		return err == nil && ok
+	case *ast.UnaryExpr:
+		return sema.isConstExpr(expression.Value, scope)
+	case *ast.BinaryExpr:
+		if expression.Op == token.ORELSE || expression.Op == token.CATCH {
+			return false
+		}
+		return sema.isConstExpr(expression.Left, scope) && sema.isConstExpr(expression.Right, scope)
+	case *ast.FunctionCall:
+		name := expression.Name.Name()
+		if !ast.TYPE_BUILTINS[name] || name == "typename" {
+			return false
+		}
+		// Builtins can be shadowed by functions
+		_, err := scope.LookupAcrossScopes(name)
+		return err != nil
+	}
+	return false
+}
+
+// Evaluates a constant expression exactly and folds it into a literal.
+// Untyped integers take the expected type if it is an integer type, or int
+// without one, and must fit on it, such as "-129" on i8. Typed constants keep
+// their own type, unless it widens to the expected type.
+func (sema *sema) checkConstExpr(
+	expr *ast.Expr,
+	expectedType ast.ExprType,
+	scope *ast.Scope,
+) (ast.ExprType, error) {
+	pos := (*expr).Span().Start
+	evaluator := &constEvaluator{sema: sema, pos: pos}
+	value, err := evaluator.eval(*expr, nil, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if !value.isBool() {
+		ty := value.Type
+		expected, ok := expectedType.(*ast.BasicType)
+		if ok && expected.IsNumeric() && (ty == nil || widens(ty, expected)) {
+			ty = &ast.BasicType{Kind: expected.Kind}
+		}
+		if ty == nil {
+			ty = &ast.BasicType{Kind: token.INT_TYPE}
+		}
+		if !fitsOn(value.Int, ty.(*ast.BasicType).Kind) {
+			doesNotFit := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: %s doesn't fit on %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					value,
+					ty,
+				),
+			}
+			sema.collector.ReportAndSave(doesNotFit)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+		value = &constValue{Type: ty, Int: value.Int}
+	}
+
+	folded := constToExpr(value)
+	folded.Loc = (*expr).Span()
+	*expr = folded
+	return value.Type, nil
+}
+
+// Checks both operands of a binary expression or range. Untyped constants are
+// checked last, so they take the type of the other operand, such as i8 on
+// "a + 1" and "1 + a" if "a" is i8.
+func (sema *sema) checkOperands(
+	lhs, rhs *ast.Expr,
+	scope *ast.Scope,
+) (ast.ExprType, ast.ExprType, error) {
+	lhsIsConst := sema.isConstExpr(*lhs, scope)
+	rhsIsConst := sema.isConstExpr(*rhs, scope)
+
+	var lhsType, rhsType ast.ExprType
+	var err error
+	if !lhsIsConst {
+		lhsType, err = sema.checkExpr(lhs, nil, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if !rhsIsConst {
+		rhsType, err = sema.checkExpr(rhs, nil, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if lhsIsConst {
+		lhsType, err = sema.checkExpr(lhs, rhsType, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if rhsIsConst {
+		rhsType, err = sema.checkExpr(rhs, lhsType, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return lhsType, rhsType, nil
+}
+
+// Reports whether every value of the integer type from is also a value of the
+// integer type to, so it can be converted implicitly. Unsigned integers widen
+// to wider unsigned and signed integers, but signed integers only widen to
+// wider signed integers.
+func widens(from, to ast.ExprType) bool {
+	fromTy, ok := from.(*ast.BasicType)
+	if !ok || !fromTy.IsNumeric() {
+		return false
+	}
+	toTy, ok := to.(*ast.BasicType)
+	if !ok || !toTy.IsNumeric() {
+		return false
+	}
+	if fromTy.IsSigned() && !toTy.IsSigned() {
+		return false
+	}
+	return fromTy.Kind.BitSize() < toTy.Kind.BitSize()
+}
+
+// Converts an integer to a wider integer type, which never changes its value
+func widen(expr *ast.Expr, from, to ast.ExprType) {
+	*expr = &ast.CastExpr{Loc: (*expr).Span(), Value: *expr, Type: to, From: from}
+}
+
+// Widens the narrower of two operands to the type of the other one, such as
+// "a" on "a + b" if "a" is u8 and "b" is i32. Returns false if their types
+// can't be mixed.
+func widenOperands(lhs, rhs *ast.Expr, lhsType, rhsType ast.ExprType) (ast.ExprType, bool) {
+	switch {
+	case ast.SameType(lhsType, rhsType):
+		return lhsType, true
+	case widens(lhsType, rhsType):
+		widen(lhs, lhsType, rhsType)
+		return rhsType, true
+	case widens(rhsType, lhsType):
+		widen(rhs, rhsType, lhsType)
+		return lhsType, true
+	}
+	return nil, false
+}
@@ -0,0 +1,87 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Declares the functions, externs and constants of a file on the scope of its
+// module, before any of them is checked, so they can be used by every file of
+// the module, no matter where they are declared. Constants are evaluated
+// later.
+func (sema *sema) collectDecls(module *ast.Module, file *ast.File) error {
+	for _, node := range file.Body {
+		var err error
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			err = sema.declare(module.Scope, "function", n.Name, n)
+			if ast.FindAttribute(n.Attributes, "export") == nil {
+				sema.declareSymbol(n.Symbol(), n)
+			}
+		case *ast.ExternDecl:
+			err = sema.declare(module.Scope, "extern", n.Name, n)
+			for _, proto := range n.Prototypes {
+				sema.declareSymbol(proto.Symbol(), proto)
+			}
+		case *ast.ConstDecl:
+			err = sema.declare(module.Scope, "constant", n.Name, n)
+			sema.pendingConsts[n] = module.Scope
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sema *sema) declare(scope *ast.Scope, kind string, name *token.Token, node ast.Node) error {
+	err := scope.Insert(name.Name(), node)
+	if err != ast.ERR_SYMBOL_ALREADY_DEFINED_ON_SCOPE {
+		return err
+	}
+
+	previous, _ := scope.LookupCurrentScope(name.Name())
+	previousPos := declPos(previous)
+	pos := name.Pos
+	redeclaration := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: %s '%s' already declared at %s:%d:%d",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			kind,
+			name.Name(),
+			previousPos.Filename,
+			previousPos.Line,
+			previousPos.Column,
+		),
+	}
+	sema.collector.ReportAndSave(redeclaration)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+// Symbols may be declared more than once, such as the same prototype on
+// different externs, so the first declaration is kept
+func (sema *sema) declareSymbol(symbol string, node ast.Node) {
+	if _, ok := sema.symbols[symbol]; !ok {
+		sema.symbols[symbol] = node
+	}
+}
+
+// Position of the name of a declaration on the scope of a module
+func declPos(node ast.Node) token.Pos {
+	switch n := node.(type) {
+	case *ast.FunctionDecl:
+		return n.Name.Pos
+	case *ast.ExternDecl:
+		return n.Name.Pos
+	case *ast.ConstDecl:
+		return n.Name.Pos
+	case *ast.Proto:
+		return n.Name.Pos
+	}
+	return node.Span().Start
+}
@@ -0,0 +1,158 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Gives the error its code, which is the same for every use of the same name.
+// Zero means success, so codes start at one.
+func (sema *sema) analyzeErrorExpr(errorExpr *ast.ErrorExpr, union *ast.ErrorUnionType) {
+	name := errorExpr.Name.Name()
+	code, ok := sema.errorCodes[name]
+	if !ok {
+		code = uint64(len(sema.errorCodes) + 1)
+		sema.errorCodes[name] = code
+	}
+	errorExpr.Code = code
+	errorExpr.Type = union
+}
+
+func (sema *sema) reportErrorWithoutUnion(errorExpr *ast.ErrorExpr, expectedType ast.ExprType) error {
+	pos := errorExpr.Span().Start
+	message := fmt.Sprintf("can't infer the type of %s", errorExpr)
+	if expectedType != nil {
+		message = fmt.Sprintf("can't use %s as %s", errorExpr, expectedType)
+	}
+	errorWithoutUnion := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: %s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			message,
+		),
+	}
+	sema.collector.ReportAndSave(errorWithoutUnion)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+// Analyzes "try value". The error is returned to the caller, so it is only
+// allowed on functions returning an error union.
+func (sema *sema) analyzeTryExpr(try *ast.TryExpr, scope *ast.Scope) (ast.ExprType, error) {
+	if _, ok := sema.returnTy.(*ast.ErrorUnionType); !ok {
+		pos := try.Span().Start
+		tryWithoutUnion := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: 'try' can only be used on functions returning an error union, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				sema.returnTy,
+			),
+		}
+		sema.collector.ReportAndSave(tryWithoutUnion)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	union, err := sema.inferErrorUnion(&try.Value, token.TRY, scope)
+	if err != nil {
+		return nil, err
+	}
+	try.Type = union
+	return union.Type, nil
+}
+
+// Analyzes "value catch default". Just like "orelse", the default value must
+// be of the underlying type.
+func (sema *sema) inferCatchExprType(expression *ast.BinaryExpr, scope *ast.Scope) (ast.ExprType, error) {
+	union, err := sema.inferErrorUnion(&expression.Left, token.CATCH, scope)
+	if err != nil {
+		return nil, err
+	}
+	expression.OperandType = union
+
+	defaultTy, err := sema.inferStoredExprType(&expression.Right, union.Type, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !ast.SameType(defaultTy, union.Type) {
+		pos := expression.Right.Span().Start
+		mismatchedDefault := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't use %s as default value of %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				defaultTy,
+				union,
+			),
+		}
+		sema.collector.ReportAndSave(mismatchedDefault)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	return union.Type, nil
+}
+
+// Analyzes "value catch { ... }". The value is discarded, so any error union
+// can be handled, including "!void".
+func (sema *sema) analyzeCatchStmt(catch *ast.CatchStmt, scope *ast.Scope, returnTy ast.ExprType) error {
+	union, err := sema.inferErrorUnion(&catch.Value, token.CATCH, scope)
+	if err != nil {
+		return err
+	}
+	catch.Type = union
+	return sema.analyzeBlock(catch.Block, returnTy, scope)
+}
+
+func (sema *sema) inferErrorUnion(
+	expr *ast.Expr,
+	op token.Kind,
+	scope *ast.Scope,
+) (*ast.ErrorUnionType, error) {
+	exprTy, err := sema.checkExpr(expr, nil, scope)
+	if err != nil {
+		return nil, err
+	}
+	union, ok := exprTy.(*ast.ErrorUnionType)
+	if !ok {
+		pos := (*expr).Span().Start
+		notErrorUnion := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected error union on %s, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				op,
+				exprTy,
+			),
+		}
+		sema.collector.ReportAndSave(notErrorUnion)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	return union, nil
+}
+
+// Errors shouldn't be silently ignored, such as on "write(s);" when "write"
+// returns "!void"
+func (sema *sema) warnIgnoredError(call *ast.FunctionCall) {
+	if _, ok := call.Type.(*ast.ErrorUnionType); !ok {
+		return
+	}
+
+	pos := call.Name.Pos
+	ignoredError := diagnostics.Diag{
+		Severity: diagnostics.WARNING,
+		Message: fmt.Sprintf(
+			"%s:%d:%d: error returned by '%s' is ignored, handle it with 'try' or 'catch'",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			call.Name.Name(),
+		),
+	}
+	sema.collector.ReportAndSave(ignoredError)
+}
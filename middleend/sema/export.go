@@ -0,0 +1,131 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Functions exported with "@export("name")" are called from C, so their
+// signature may only use types with a C equivalent and their symbol must be
+// unique across the program. Otherwise LLVM renames one of them, such as
+// "foo.1", and C callers fail to link.
+func (sema *sema) analyzeExport(function *ast.FunctionDecl) error {
+	export := ast.FindAttribute(function.Attributes, "export")
+	if export == nil {
+		return nil
+	}
+	pos := export.At
+
+	types := []ast.ExprType{function.RetType}
+	for _, param := range function.Params.Fields {
+		types = append(types, param.Type)
+	}
+	for i, ty := range types {
+		// Only the return type may be void
+		if isCType(ty) && (i == 0 || !ty.IsVoid()) {
+			continue
+		}
+		notCType := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't export '%s', type %s has no C equivalent",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				function.Name.Name(),
+				ty,
+			),
+		}
+		sema.collector.ReportAndSave(notCType)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	if function.Params.IsVariadic {
+		variadicExport := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't export variadic function '%s'",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				function.Name.Name(),
+			),
+		}
+		sema.collector.ReportAndSave(variadicExport)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	symbol := function.Symbol()
+	if symbol == "main" {
+		exportedMain := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't export '%s' as 'main', the symbol of the entry point",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				function.Name.Name(),
+			),
+		}
+		sema.collector.ReportAndSave(exportedMain)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	if other, ok := sema.symbols[symbol]; ok {
+		kind := "function"
+		if _, ok := other.(*ast.Proto); ok {
+			kind = "prototype"
+		}
+		otherPos := declPos(other)
+		takenSymbol := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't export '%s' as '%s', symbol already used by %s at %s:%d:%d",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				function.Name.Name(),
+				symbol,
+				kind,
+				otherPos.Filename,
+				otherPos.Line,
+				otherPos.Column,
+			),
+		}
+		sema.collector.ReportAndSave(takenSymbol)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	if other, ok := sema.exported[symbol]; ok {
+		otherPos := other.Name.Pos
+		duplicatedSymbol := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: symbol '%s' already exported by '%s' at %s:%d:%d",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				symbol,
+				other.Name.Name(),
+				otherPos.Filename,
+				otherPos.Line,
+				otherPos.Column,
+			),
+		}
+		sema.collector.ReportAndSave(duplicatedSymbol)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	sema.exported[symbol] = function
+	return nil
+}
+
+// Reports whether the type has the same representation in C, such as i32 and
+// int32_t. Optional pointers are nullable pointers, just like in C.
+func isCType(ty ast.ExprType) bool {
+	switch ty := ty.(type) {
+	case *ast.BasicType:
+		return ty.Kind == token.VOID_TYPE || ty.Kind == token.BOOL_TYPE || token.NUMERIC_TYPES[ty.Kind]
+	case *ast.PointerType:
+		return isCType(ty.Type) && !ty.Type.IsVoid()
+	case *ast.OptionalType:
+		_, ok := ty.Type.(*ast.PointerType)
+		return ok && isCType(ty.Type)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,125 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+)
+
+// Analyzes a global variable of a C library, such as "stderr *u8;". As on
+// prototypes, raw pointers are optional because they may be null.
+func (sema *sema) analyzeExternVar(
+	variable *ast.ExternVar,
+	extern *ast.ExternDecl,
+	externScope *ast.Scope,
+) error {
+	err := sema.resolveType(variable.Type, variable.Name.Pos, externScope)
+	if err != nil {
+		return err
+	}
+	if !isSized(variable.Type) {
+		pos := variable.Name.Pos
+		invalidType := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: invalid type %s for variable '%s'",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				variable.Type,
+				variable.Name.Name(),
+			),
+		}
+		sema.collector.ReportAndSave(invalidType)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	variable.Type = optionalPointer(variable.Type)
+
+	err = externScope.Insert(variable.Name.Name(), variable)
+	if err != nil {
+		if err == ast.ERR_SYMBOL_ALREADY_DEFINED_ON_SCOPE {
+			pos := variable.Name.Pos
+			variableRedeclaration := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: '%s' already declared on extern '%s'",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					variable.Name.Name(),
+					extern.Name.Name(),
+				),
+			}
+			sema.collector.ReportAndSave(variableRedeclaration)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+		return err
+	}
+	return nil
+}
+
+// Analyzes the library linked by an extern block, such as 'extern m link "m"'
+func (sema *sema) analyzeExternLink(extern *ast.ExternDecl) error {
+	if extern.Link == nil || len(extern.Link.Lexeme) > 0 {
+		return nil
+	}
+	pos := extern.Link.Pos
+	emptyLibrary := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: library name of extern '%s' can't be empty",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			extern.Name.Name(),
+		),
+	}
+	sema.collector.ReportAndSave(emptyLibrary)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+// Analyzes the access of a variable of an extern block, such as "libc.stderr"
+func (sema *sema) analyzeExternVarAccess(id *ast.IdExpr, extern *ast.ExternDecl) error {
+	symbol, err := extern.Scope.LookupCurrentScope(id.Name.Name())
+	if _, ok := symbol.(*ast.ExternVar); err != nil || !ok {
+		pos := id.Name.Pos
+		variableNotFound := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: variable '%s' not declared on extern '%s'",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				id.Name.Name(),
+				extern.Name.Name(),
+			),
+		}
+		sema.collector.ReportAndSave(variableNotFound)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	return nil
+}
+
+// Returns the variable of an already analyzed field access, such as
+// "libc.stderr", or nil if it isn't a variable of an extern block
+func externVarOf(fieldAccess *ast.FieldAccess, scope *ast.Scope) *ast.ExternVar {
+	idExpr, ok := fieldAccess.Left.(*ast.IdExpr)
+	if !ok {
+		return nil
+	}
+	right, ok := fieldAccess.Right.(*ast.IdExpr)
+	if !ok {
+		return nil
+	}
+	symbol, err := scope.LookupAcrossScopes(idExpr.Name.Name())
+	if err != nil {
+		return nil
+	}
+	extern, ok := symbol.(*ast.ExternDecl)
+	if !ok {
+		return nil
+	}
+	variable, err := extern.Scope.LookupCurrentScope(right.Name.Name())
+	if err != nil {
+		return nil
+	}
+	externVar, _ := variable.(*ast.ExternVar)
+	return externVar
+}
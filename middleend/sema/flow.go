@@ -0,0 +1,83 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Reports functions that may reach their closing brace without returning a
+// value. Void functions return implicitly at the end of their body.
+func (sema *sema) analyzeMissingReturn(function *ast.FunctionDecl) error {
+	if function.RetType.IsVoid() || terminates(function.Block) {
+		return nil
+	}
+	pos := function.Block.CloseCurly
+	missingReturn := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: missing return",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+		),
+	}
+	sema.collector.ReportAndSave(missingReturn)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+// Reports whether every path through a block ends on a return, so the
+// statements after it can't be reached
+func terminates(block *ast.BlockStmt) bool {
+	for _, stmt := range block.Statements {
+		if stmtTerminates(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func stmtTerminates(stmt ast.Stmt) bool {
+	switch statement := stmt.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.CondStmt:
+		// Without "else", the condition may be false and nothing runs
+		if statement.ElseStmt == nil || !terminates(statement.IfStmt.Block) {
+			return false
+		}
+		for _, elif := range statement.ElifStmts {
+			if !terminates(elif.Block) {
+				return false
+			}
+		}
+		return terminates(statement.ElseStmt.Block)
+	// There is no "break", so loops that never stop can only be left by
+	// returning
+	case *ast.WhileLoop:
+		return isTrue(statement.Cond)
+	case *ast.ForLoop:
+		return isTrue(statement.Cond)
+	}
+	return false
+}
+
+func isTrue(expr ast.Expr) bool {
+	literal, ok := expr.(*ast.LiteralExpr)
+	if !ok {
+		return false
+	}
+	ty, ok := literal.Type.(*ast.BasicType)
+	if !ok {
+		return false
+	}
+	switch ty.Kind {
+	case token.TRUE_BOOL_LITERAL:
+		return true
+	case token.BOOL_TYPE:
+		// Already analyzed, true is stored as 1
+		return string(literal.Value) == "1"
+	}
+	return false
+}
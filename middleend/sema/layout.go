@@ -0,0 +1,108 @@
+package sema
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Sizes and alignments of types on the target. They depend on how the backend
+// lowers each type, so it is the backend that provides them.
+type DataLayout interface {
+	SizeOf(ty ast.ExprType) uint64
+	AlignOf(ty ast.ExprType) uint64
+	OffsetOf(tuple *ast.TupleType, index int) uint64
+}
+
+// Evaluates "sizeof", "alignof", "offsetof" and "typename" into a literal.
+// Sizes are integer literals, so they take the type of their context, such as
+// an argument of "malloc".
+func (sema *sema) analyzeTypeBuiltinCall(call *ast.FunctionCall, scope *ast.Scope) error {
+	name := call.Name.Name()
+	typeArg := call.Args[0].(*ast.TypeExpr)
+	ty := typeArg.Type
+	pos := typeArg.Span().Start
+
+	err := sema.resolveType(ty, pos, scope)
+	if err != nil {
+		return err
+	}
+
+	folded := &ast.LiteralExpr{Loc: call.Span(), Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}}
+	call.Builtin = true
+	call.Folded = folded
+	call.Type = &ast.BasicType{Kind: token.INT_TYPE}
+
+	if name == "typename" {
+		folded.Type = &ast.BasicType{Kind: token.STRING_LITERAL}
+		folded.Value = []byte(fmt.Sprintf("%s", ty))
+		call.Type = &ast.BasicType{Kind: token.STRING_TYPE}
+		return nil
+	}
+
+	if sema.layout == nil {
+		noLayout := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't evaluate '%s' without the data layout of a target",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				name,
+			),
+		}
+		sema.collector.ReportAndSave(noLayout)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	if !isSized(ty) {
+		return sema.reportInvalidBuiltinArg(typeArg, ty, name)
+	}
+
+	var value uint64
+	switch name {
+	case "sizeof":
+		value = sema.layout.SizeOf(ty)
+	case "alignof":
+		value = sema.layout.AlignOf(ty)
+	case "offsetof":
+		tuple, ok := ty.(*ast.TupleType)
+		if !ok {
+			return sema.reportInvalidBuiltinArg(typeArg, ty, name)
+		}
+		index, err := sema.evalConstExpr(call.Args[1], call.Args[1].Span().Start, scope)
+		if err != nil {
+			return err
+		}
+		if index.isBool() || index.Int.Sign() < 0 || index.Int.Cmp(big.NewInt(int64(len(tuple.Types)))) >= 0 {
+			pos := call.Args[1].Span().Start
+			invalidElement := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: invalid element %s of %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					index,
+					tuple,
+				),
+			}
+			sema.collector.ReportAndSave(invalidElement)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+		value = sema.layout.OffsetOf(tuple, int(index.Int.Int64()))
+	}
+	folded.Value = []byte(strconv.FormatUint(value, 10))
+	return nil
+}
+
+// Types without a size, such as void, can't be used with "sizeof"
+func isSized(ty ast.ExprType) bool {
+	switch ty.(type) {
+	case *ast.IdType:
+		return false
+	default:
+		return !ty.IsVoid()
+	}
+}
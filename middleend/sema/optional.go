@@ -0,0 +1,241 @@
+package sema
+
+import (
+	"fmt"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Infers the type of a value stored on something of type ty, such as a
+// variable, an argument or a return value. Integers are widened to a wider
+// integer type, such as u8 to i32, and a value of type T stored on an optional
+// ?T is wrapped into an *ast.OptionalExpr, so the backend knows it needs to be
+// converted.
+func (sema *sema) inferStoredExprType(
+	expr *ast.Expr,
+	ty ast.ExprType,
+	scope *ast.Scope,
+) (ast.ExprType, error) {
+	exprTy, err := sema.checkExpr(expr, ty, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	target := ty
+	if unwrappedTy := unwrapType(ty); unwrappedTy != nil {
+		target = unwrappedTy
+	}
+	if widens(exprTy, target) {
+		widen(expr, exprTy, target)
+		exprTy = target
+	}
+
+	switch wrapper := ty.(type) {
+	case *ast.OptionalType:
+		if ast.SameType(exprTy, wrapper.Type) {
+			*expr = &ast.OptionalExpr{Loc: (*expr).Span(), Value: *expr, Type: wrapper}
+			return wrapper, nil
+		}
+	case *ast.ErrorUnionType:
+		if ast.SameType(exprTy, wrapper.Type) {
+			*expr = &ast.ErrorUnionExpr{Loc: (*expr).Span(), Value: *expr, Type: wrapper}
+			return wrapper, nil
+		}
+	}
+	if unwrappedTy := unwrapType(exprTy); unwrappedTy != nil && ast.SameType(unwrappedTy, ty) {
+		return nil, sema.reportUnwrapped(*expr, exprTy)
+	}
+	return exprTy, nil
+}
+
+// Analyzes the condition of "if" and "elif". When it unwraps an optional,
+// such as "if x := opt", the variable is declared on the scope of the block.
+func (sema *sema) analyzeCondExpr(cond *ast.IfElifCond, outterScope *ast.Scope) error {
+	if cond.Unwrap == nil {
+		return sema.analyzeIfExpr(&cond.Expr, outterScope)
+	}
+
+	exprTy, err := sema.checkExpr(&cond.Expr, nil, outterScope)
+	if err != nil {
+		return err
+	}
+	optional, ok := exprTy.(*ast.OptionalType)
+	if !ok {
+		pos := cond.Expr.Span().Start
+		notOptional := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't unwrap value of type %s, it is not optional",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				exprTy,
+			),
+		}
+		sema.collector.ReportAndSave(notOptional)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	cond.Unwrap.Type = optional.Type
+	err = cond.Scope.Insert(cond.Unwrap.Name.Name(), cond.Unwrap)
+	if err != nil {
+		return err
+	}
+	sema.declareLocal(cond.Unwrap)
+	return nil
+}
+
+// Reports whether a binary expression operates on optionals, such as
+// "opt orelse 0" and "opt == nil"
+func isOptionalBinaryExpr(expression *ast.BinaryExpr) bool {
+	switch expression.Op {
+	case token.ORELSE:
+		return true
+	case token.EQUAL_EQUAL, token.BANG_EQUAL:
+		return isNilLiteral(expression.Left) || isNilLiteral(expression.Right)
+	default:
+		return false
+	}
+}
+
+func (sema *sema) inferOptionalBinaryExprType(
+	expression *ast.BinaryExpr,
+	scope *ast.Scope,
+) (ast.ExprType, error) {
+	// "nil == opt" is the same as "opt == nil"
+	optionalExpr, otherExpr := &expression.Left, &expression.Right
+	if expression.Op != token.ORELSE && isNilLiteral(*optionalExpr) {
+		optionalExpr, otherExpr = otherExpr, optionalExpr
+	}
+
+	exprTy, err := sema.checkExpr(optionalExpr, nil, scope)
+	if err != nil {
+		return nil, err
+	}
+	optional, ok := exprTy.(*ast.OptionalType)
+	if !ok {
+		pos := (*optionalExpr).Span().Start
+		notOptional := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected optional on %s, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				expression.Op,
+				exprTy,
+			),
+		}
+		sema.collector.ReportAndSave(notOptional)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	expression.OperandType = optional
+
+	if expression.Op != token.ORELSE {
+		(*otherExpr).(*ast.LiteralExpr).Type = optional
+		return &ast.BasicType{Kind: token.BOOL_TYPE}, nil
+	}
+
+	// The default value must be of the underlying type, so the result is
+	// never nil
+	defaultTy, err := sema.inferStoredExprType(otherExpr, optional.Type, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !ast.SameType(defaultTy, optional.Type) {
+		pos := (*otherExpr).Span().Start
+		mismatchedDefault := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't use %s as default value of %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				defaultTy,
+				optional,
+			),
+		}
+		sema.collector.ReportAndSave(mismatchedDefault)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	return optional.Type, nil
+}
+
+// Optionals and error unions must be unwrapped before being used as operands,
+// such as on "opt + 1"
+func (sema *sema) checkUnwrappedOperands(expression *ast.BinaryExpr, lhsType, rhsType ast.ExprType) error {
+	if unwrapType(lhsType) != nil {
+		return sema.reportUnwrapped(expression.Left, lhsType)
+	}
+	if unwrapType(rhsType) != nil {
+		return sema.reportUnwrapped(expression.Right, rhsType)
+	}
+	return nil
+}
+
+// Returns the underlying type of optionals and error unions, or nil for any
+// other type
+func unwrapType(ty ast.ExprType) ast.ExprType {
+	switch wrapper := ty.(type) {
+	case *ast.OptionalType:
+		return wrapper.Type
+	case *ast.ErrorUnionType:
+		return wrapper.Type
+	default:
+		return nil
+	}
+}
+
+// Raw pointers returned by C functions may be null, so they are optional
+// unless the prototype is marked with "@nonnull". Pointer parameters only
+// accept null if they are declared optional, such as "?*u8", so an unchecked
+// result can't be passed to them.
+func optionalReturnPointer(proto *ast.Proto) {
+	if ast.FindAttribute(proto.Attributes, "nonnull") != nil {
+		return
+	}
+	proto.RetType = optionalPointer(proto.RetType)
+}
+
+func optionalPointer(ty ast.ExprType) ast.ExprType {
+	if _, ok := ty.(*ast.PointerType); ok {
+		return &ast.OptionalType{Type: ty}
+	}
+	return ty
+}
+
+func isNilLiteral(expr ast.Expr) bool {
+	literal, ok := expr.(*ast.LiteralExpr)
+	if !ok {
+		return false
+	}
+	switch ty := literal.Type.(type) {
+	case *ast.BasicType:
+		return ty.Kind == token.NIL_LITERAL
+	case *ast.OptionalType:
+		// Already analyzed
+		return true
+	default:
+		return false
+	}
+}
+
+func (sema *sema) reportUnwrapped(expr ast.Expr, ty ast.ExprType) error {
+	how := "'if' or 'orelse'"
+	if _, ok := ty.(*ast.ErrorUnionType); ok {
+		how = "'try' or 'catch'"
+	}
+
+	pos := expr.Span().Start
+	unwrapped := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: value of type %s must be unwrapped with %s before use",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			ty,
+			how,
+		),
+	}
+	sema.collector.ReportAndSave(unwrapped)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
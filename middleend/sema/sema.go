@@ -5,6 +5,7 @@ import (
 	"log"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/HicaroD/Telia/diagnostics"
 	"github.com/HicaroD/Telia/frontend/ast"
@@ -15,26 +16,89 @@ import (
 
 type sema struct {
 	collector *diagnostics.Collector
+
+	// Return type of the function being analyzed, used by "try" to know
+	// where errors are propagated to
+	returnTy ast.ExprType
+	// Code of each error name, such as "error.NotFound", in the order they
+	// are found
+	errorCodes map[string]uint64
+	// Used by builtins such as "sizeof", nil if there is no target
+	layout DataLayout
+	// Functions exported to C by symbol name
+	exported map[string]*ast.FunctionDecl
+	// Functions not exported and prototypes by symbol name, which exported
+	// functions can't take
+	symbols map[string]ast.Node
+	// Variables, parameters, functions and prototypes used so far
+	used map[ast.Node]bool
+	// Variables declared on the body being analyzed
+	locals []*ast.VarStmt
+	// Constants of modules not evaluated yet, with the scope they are
+	// declared on, so a constant can be used before its declaration
+	pendingConsts map[*ast.ConstDecl]*ast.Scope
+	// Constants being evaluated, used to report cycles such as "const A = B;"
+	// and "const B = A;"
+	evaluatingConsts map[*ast.ConstDecl]bool
 }
 
 func New(collector *diagnostics.Collector) *sema {
-	return &sema{collector}
+	return &sema{
+		collector:  collector,
+		errorCodes: make(map[string]uint64),
+		exported:   make(map[string]*ast.FunctionDecl),
+		symbols:    make(map[string]ast.Node),
+		used:       make(map[ast.Node]bool),
+
+		pendingConsts:    make(map[*ast.ConstDecl]*ast.Scope),
+		evaluatingConsts: make(map[*ast.ConstDecl]bool),
+	}
+}
+
+func NewWithLayout(collector *diagnostics.Collector, layout DataLayout) *sema {
+	sema := New(collector)
+	sema.layout = layout
+	return sema
 }
 
+// The program is checked in passes over every file of every module, so the
+// order of declarations, in a file or across the files of a module, doesn't
+// matter:
+//
+//  1. functions, externs and constants are declared on the scope of their
+//     module
+//  2. constants and static assertions are evaluated. A constant used before
+//     its declaration is evaluated when it is first used
+//  3. signatures of functions and externs are resolved
+//  4. bodies of functions, tests and benchmarks are checked
+//  5. functions and prototypes never called are reported
 func (s *sema) Check(program *ast.Program) error {
-	return s.checkModule(program.Root)
+	passes := []func(*ast.Module, *ast.File) error{
+		s.collectDecls,
+		s.checkConsts,
+		s.checkSignatures,
+		s.checkBodies,
+		s.warnUnusedDecls,
+	}
+	for _, pass := range passes {
+		err := s.checkModule(program.Root, pass)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *sema) checkModule(module *ast.Module) error {
+func (s *sema) checkModule(module *ast.Module, pass func(*ast.Module, *ast.File) error) error {
 	for _, file := range module.Files {
-		err := s.checkFile(file)
+		err := pass(module, file)
 		if err != nil {
 			return err
 		}
 	}
 
 	for _, innerModules := range module.Modules {
-		err := s.checkModule(innerModules)
+		err := s.checkModule(innerModules, pass)
 		if err != nil {
 			return err
 		}
@@ -42,11 +106,33 @@ func (s *sema) checkModule(module *ast.Module) error {
 	return nil
 }
 
-func (s *sema) checkFile(file *ast.File) error {
+func (s *sema) checkConsts(_ *ast.Module, file *ast.File) error {
+	for _, node := range file.Body {
+		switch n := node.(type) {
+		case *ast.ConstDecl:
+			scope, pending := s.pendingConsts[n]
+			if !pending {
+				continue
+			}
+			err := s.evalConstDecl(n, scope)
+			if err != nil {
+				return err
+			}
+		case *ast.StaticAssert:
+			err := s.analyzeStaticAssert(n, file.Scope)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *sema) checkSignatures(_ *ast.Module, file *ast.File) error {
 	for _, node := range file.Body {
 		switch n := node.(type) {
 		case *ast.FunctionDecl:
-			err := s.analyzeFnDecl(n, file.Scope)
+			err := s.analyzeFnSignature(n, file.Scope)
 			if err != nil {
 				return err
 			}
@@ -55,6 +141,32 @@ func (s *sema) checkFile(file *ast.File) error {
 			if err != nil {
 				return err
 			}
+		}
+	}
+	return nil
+}
+
+func (s *sema) checkBodies(_ *ast.Module, file *ast.File) error {
+	for _, node := range file.Body {
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			err := s.analyzeFnDecl(n, file.Scope)
+			if err != nil {
+				return err
+			}
+		case *ast.TestDecl:
+			err := s.analyzeTestDecl(n, file.Scope)
+			if err != nil {
+				return err
+			}
+		case *ast.BenchDecl:
+			err := s.analyzeBenchDecl(n, file.Scope)
+			if err != nil {
+				return err
+			}
+		case *ast.ExternDecl, *ast.ConstDecl, *ast.StaticAssert:
+			// Checked by previous passes
+			continue
 		default:
 			log.Fatalf("unimplemented ast node for sema: %s\n", reflect.TypeOf(n))
 		}
@@ -63,10 +175,31 @@ func (s *sema) checkFile(file *ast.File) error {
 }
 
 func (sema *sema) analyzeExtern(extern *ast.ExternDecl, fileScope *ast.Scope) error {
+	err := sema.analyzeExternLink(extern)
+	if err != nil {
+		return err
+	}
+
 	externScope := ast.NewScope(fileScope)
 	for i := range extern.Prototypes {
+		err := sema.analyzeAttributes(extern.Prototypes[i].Attributes, TARGET_PROTOTYPE)
+		if err != nil {
+			return err
+		}
+
+		err = sema.resolveSignature(
+			extern.Prototypes[i].Name,
+			extern.Prototypes[i].Params,
+			extern.Prototypes[i].RetType,
+			fileScope,
+		)
+		if err != nil {
+			return err
+		}
+		optionalReturnPointer(extern.Prototypes[i])
+
 		prototypeName := extern.Prototypes[i].Name.Name()
-		err := externScope.Insert(prototypeName, extern.Prototypes[i])
+		err = externScope.Insert(prototypeName, extern.Prototypes[i])
 		if err != nil {
 			if err == ast.ERR_SYMBOL_ALREADY_DEFINED_ON_SCOPE {
 				pos := extern.Prototypes[i].Name.Pos
@@ -86,31 +219,34 @@ func (sema *sema) analyzeExtern(extern *ast.ExternDecl, fileScope *ast.Scope) er
 			return err
 		}
 	}
+	for _, variable := range extern.Vars {
+		err := sema.analyzeExternVar(variable, extern, externScope)
+		if err != nil {
+			return err
+		}
+	}
 	extern.Scope = externScope
-	err := fileScope.Insert(extern.Name.Name(), extern)
+	return nil
+}
+
+func (sema *sema) analyzeFnSignature(function *ast.FunctionDecl, fileScope *ast.Scope) error {
+	err := sema.analyzeAttributes(function.Attributes, TARGET_FUNCTION)
 	if err != nil {
-		if err == ast.ERR_SYMBOL_ALREADY_DEFINED_ON_SCOPE {
-			pos := extern.Name.Pos
-			prototypeRedeclaration := diagnostics.Diag{
-				Message: fmt.Sprintf(
-					"%s:%d:%d: extern '%s' already declared on scope",
-					pos.Filename,
-					pos.Line,
-					pos.Column,
-					extern.Name.Name(),
-				),
-			}
-			sema.collector.ReportAndSave(prototypeRedeclaration)
-			return diagnostics.COMPILER_ERROR_FOUND
-		}
 		return err
 	}
-	return nil
+
+	err = sema.resolveSignature(function.Name, function.Params, function.RetType, fileScope)
+	if err != nil {
+		return err
+	}
+	return sema.analyzeExport(function)
 }
 
+// The signature must be already resolved by analyzeFnSignature
 func (sema *sema) analyzeFnDecl(function *ast.FunctionDecl, fileScope *ast.Scope) error {
 	var err error
 
+	sema.returnTy = function.RetType
 	function.Scope = ast.NewScope(fileScope)
 	err = sema.addParametersToScope(function.Params, function.Name.Name(), function.Scope)
 	if err != nil {
@@ -121,9 +257,63 @@ func (sema *sema) analyzeFnDecl(function *ast.FunctionDecl, fileScope *ast.Scope
 	if err != nil {
 		return err
 	}
+	err = sema.analyzeDefiniteAssignment(function.Block, function.Scope)
+	if err != nil {
+		return err
+	}
+	sema.warnUnusedLocals(function.Params)
+	return sema.analyzeMissingReturn(function)
+}
+
+// Test blocks are checked as bodies of functions without parameters and
+// return type
+func (sema *sema) analyzeTestDecl(test *ast.TestDecl, fileScope *ast.Scope) error {
+	sema.returnTy = &ast.BasicType{Kind: token.VOID_TYPE}
+	test.Scope = ast.NewScope(fileScope)
+	err := sema.analyzeBlock(test.Block, sema.returnTy, test.Scope)
+	if err != nil {
+		return err
+	}
+	err = sema.analyzeDefiniteAssignment(test.Block, test.Scope)
+	if err != nil {
+		return err
+	}
+	sema.warnUnusedLocals(nil)
+	return nil
+}
+
+// Benchmark blocks are checked just like test blocks
+func (sema *sema) analyzeBenchDecl(bench *ast.BenchDecl, fileScope *ast.Scope) error {
+	sema.returnTy = &ast.BasicType{Kind: token.VOID_TYPE}
+	bench.Scope = ast.NewScope(fileScope)
+	err := sema.analyzeBlock(bench.Block, sema.returnTy, bench.Scope)
+	if err != nil {
+		return err
+	}
+	err = sema.analyzeDefiniteAssignment(bench.Block, bench.Scope)
+	if err != nil {
+		return err
+	}
+	sema.warnUnusedLocals(nil)
 	return nil
 }
 
+func (sema *sema) resolveSignature(
+	name *token.Token,
+	params *ast.FieldList,
+	retType ast.ExprType,
+	scope *ast.Scope,
+) error {
+	for _, param := range params.Fields {
+		err := sema.resolveType(param.Type, param.Name.Pos, scope)
+		if err != nil {
+			return err
+		}
+	}
+	err := sema.resolveType(retType, name.Pos, scope)
+	return err
+}
+
 func (sema *sema) addParametersToScope(
 	params *ast.FieldList,
 	functionName string,
@@ -159,11 +349,17 @@ func (sema *sema) analyzeBlock(
 	returnTy ast.ExprType,
 	scope *ast.Scope,
 ) error {
+	unreachable := false
 	for i := range block.Statements {
 		err := sema.analyzeStmt(block.Statements[i], scope, returnTy)
 		if err != nil {
 			return err
 		}
+		// Statements after a return are still analyzed, but never generated
+		if !unreachable && i+1 < len(block.Statements) && stmtTerminates(block.Statements[i]) {
+			sema.warnUnreachableCode(block.Statements[i+1])
+			unreachable = true
+		}
 	}
 	return nil
 }
@@ -176,6 +372,21 @@ func (sema *sema) analyzeStmt(
 	switch statement := stmt.(type) {
 	case *ast.FunctionCall:
 		err := sema.analyzeFunctionCall(statement, scope)
+		if err != nil {
+			return err
+		}
+		if statement.Builtin {
+			return nil
+		}
+		function, _ := scope.LookupAcrossScopes(statement.Name.Name())
+		sema.warnUnusedResult(statement, function.(*ast.FunctionDecl).Attributes)
+		sema.warnIgnoredError(statement)
+		return nil
+	case *ast.TryExpr:
+		_, err := sema.analyzeTryExpr(statement, scope)
+		return err
+	case *ast.CatchStmt:
+		err := sema.analyzeCatchStmt(statement, scope, returnTy)
 		return err
 	case *ast.MultiVarStmt, *ast.VarStmt:
 		err := sema.analyzeVarDecl(statement, scope)
@@ -184,23 +395,147 @@ func (sema *sema) analyzeStmt(
 		err := sema.analyzeCondStmt(statement, returnTy, scope)
 		return err
 	case *ast.ReturnStmt:
-		_, err := sema.inferExprTypeWithContext(statement.Value, returnTy, scope)
+		err := sema.analyzeReturnStmt(statement, returnTy, scope)
 		return err
 	case *ast.FieldAccess:
 		err := sema.analyzeFieldAccessExpr(statement, scope)
-		return err
+		if err != nil {
+			return err
+		}
+		if call, proto := sema.prototypeCallOf(statement, scope); proto != nil {
+			sema.warnUnusedResult(call, proto.Attributes)
+		}
+		return nil
 	case *ast.ForLoop:
 		err := sema.analyzeForLoop(statement, scope, returnTy)
 		return err
+	case *ast.RangeForLoop:
+		err := sema.analyzeRangeForLoop(statement, scope, returnTy)
+		return err
 	case *ast.WhileLoop:
 		err := sema.analyzeWhileLoop(statement, scope, returnTy)
 		return err
+	case *ast.AssertStmt:
+		err := sema.analyzeAssert(statement, scope)
+		return err
+	case *ast.ConstDecl:
+		err := sema.analyzeConstDecl(statement, scope)
+		return err
+	case *ast.StaticAssert:
+		err := sema.analyzeStaticAssert(statement, scope)
+		return err
 	default:
 		log.Fatalf("unimplemented statement on sema: %s", statement)
 	}
 	return nil
 }
 
+func (sema *sema) analyzeAssert(assert *ast.AssertStmt, scope *ast.Scope) error {
+	condTy, err := sema.checkExpr(&assert.Cond, nil, scope)
+	if err != nil {
+		return err
+	}
+	if !condTy.IsBoolean() {
+		pos := assert.Assert.Pos
+		expectedBool := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected bool on assert, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				condTy,
+			),
+		}
+		sema.collector.ReportAndSave(expectedBool)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	return nil
+}
+
+func (sema *sema) analyzeReturnStmt(
+	ret *ast.ReturnStmt,
+	returnTy ast.ExprType,
+	scope *ast.Scope,
+) error {
+	tuple, isTuple := ret.Value.(*ast.TupleExpr)
+	if !isTuple {
+		// A function returning a tuple can only return another tuple of the
+		// same type, such as "return divmod(a, b);"
+		if _, ok := returnTy.(*ast.TupleType); ok && !ret.Value.IsVoid() {
+			exprTy, err := sema.checkExpr(&ret.Value, nil, scope)
+			if err != nil {
+				return err
+			}
+			if !ast.SameType(exprTy, returnTy) {
+				return sema.reportWrongNumberOfReturnValues(ret, returnTy, 1)
+			}
+			return nil
+		}
+		_, err := sema.inferStoredExprType(&ret.Value, returnTy, scope)
+		return err
+	}
+
+	tupleTy, ok := returnTy.(*ast.TupleType)
+	if !ok || len(tupleTy.Types) != len(tuple.Exprs) {
+		return sema.reportWrongNumberOfReturnValues(ret, returnTy, len(tuple.Exprs))
+	}
+
+	for i := range tuple.Exprs {
+		exprTy, err := sema.inferStoredExprType(&tuple.Exprs[i], tupleTy.Types[i], scope)
+		if err != nil {
+			return err
+		}
+		if !ast.SameType(exprTy, tupleTy.Types[i]) {
+			pos := ret.Return.Pos
+			mismatchedReturnValue := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: can't use %s as return value %d of type %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					exprTy,
+					i+1,
+					tupleTy.Types[i],
+				),
+			}
+			sema.collector.ReportAndSave(mismatchedReturnValue)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+	}
+	tuple.Type = tupleTy
+	return nil
+}
+
+func (sema *sema) reportWrongNumberOfReturnValues(
+	ret *ast.ReturnStmt,
+	returnTy ast.ExprType,
+	got int,
+) error {
+	expected := 1
+	switch ty := returnTy.(type) {
+	case *ast.TupleType:
+		expected = len(ty.Types)
+	default:
+		if returnTy.IsVoid() {
+			expected = 0
+		}
+	}
+
+	pos := ret.Return.Pos
+	wrongNumberOfReturnValues := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: wrong number of return values, expected %d, but got %d",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			expected,
+			got,
+		),
+	}
+	sema.collector.ReportAndSave(wrongNumberOfReturnValues)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
 func (sema *sema) analyzeVarDecl(
 	variable ast.Stmt,
 	currentScope *ast.Scope,
@@ -229,7 +564,7 @@ func (sema *sema) analyzeMultiVar(
 			if err != nil {
 				if err == ast.ERR_SYMBOL_NOT_FOUND_ON_SCOPE {
 					allVariablesDefined = false
-					break
+					continue
 				}
 				return err
 			}
@@ -277,6 +612,10 @@ func (sema *sema) analyzeMultiVar(
 			return diagnostics.COMPILER_ERROR_FOUND
 		}
 	}
+	if multi.Tuple != nil {
+		return sema.analyzeTupleDestructuring(multi, currentScope)
+	}
+
 	for i := range multi.Variables {
 		if multi.Variables[i].Decl {
 			varName := multi.Variables[i].Name.Name()
@@ -284,6 +623,7 @@ func (sema *sema) analyzeMultiVar(
 			if err != nil {
 				return err
 			}
+			sema.declareLocal(multi.Variables[i])
 		}
 		err := sema.analyzeVariableType(multi.Variables[i], currentScope)
 		// TODO(errors)
@@ -294,6 +634,114 @@ func (sema *sema) analyzeMultiVar(
 	return nil
 }
 
+func (sema *sema) analyzeTupleDestructuring(
+	multi *ast.MultiVarStmt,
+	currentScope *ast.Scope,
+) error {
+	// The tuple is analyzed before declaring the variables, so "a, b := f(a)"
+	// refers to the outer "a"
+	exprTy, err := sema.checkExpr(&multi.Tuple, nil, currentScope)
+	if err != nil {
+		return err
+	}
+
+	tupleTy, ok := exprTy.(*ast.TupleType)
+	if !ok || len(tupleTy.Types) != len(multi.Variables) {
+		values := 1
+		if ok {
+			values = len(tupleTy.Types)
+		}
+		return sema.reportAssignmentMismatch(multi.Variables[0], multi.Tuple, len(multi.Variables), values)
+	}
+
+	for i, variable := range multi.Variables {
+		if variable.Decl {
+			err := currentScope.Insert(variable.Name.Name(), variable)
+			if err != nil {
+				return err
+			}
+			sema.declareLocal(variable)
+		} else {
+			// Existing variables keep their type, so "a, x = f()" is
+			// checked against the declared type of "x"
+			symbol, err := currentScope.LookupAcrossScopes(variable.Name.Name())
+			if err != nil {
+				if err == ast.ERR_SYMBOL_NOT_FOUND_ON_SCOPE {
+					return sema.reportNotDefined(variable.Name)
+				}
+				return err
+			}
+			var declaredTy ast.ExprType
+			switch declared := symbol.(type) {
+			case *ast.VarStmt:
+				declaredTy = declared.Type
+			case *ast.Field:
+				declaredTy = declared.Type
+			}
+			if declaredTy != nil && variable.NeedsInference {
+				variable.Type = declaredTy
+				variable.NeedsInference = false
+			}
+		}
+
+		elemTy := tupleTy.Types[i]
+		if variable.NeedsInference {
+			variable.Type = elemTy
+			continue
+		}
+		if !ast.SameType(variable.Type, elemTy) {
+			pos := variable.Name.Pos
+			mismatchedType := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: can't use %s on variable '%s' of type %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					elemTy,
+					variable.Name.Name(),
+					variable.Type,
+				),
+			}
+			sema.collector.ReportAndSave(mismatchedType)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+	}
+	return nil
+}
+
+func (sema *sema) reportAssignmentMismatch(
+	firstVariable *ast.VarStmt,
+	value ast.Expr,
+	variables, values int,
+) error {
+	variablesWord := "variables"
+	if variables == 1 {
+		variablesWord = "variable"
+	}
+	valuesDesc := fmt.Sprintf("%d values", values)
+	if call, ok := value.(*ast.FunctionCall); ok {
+		valuesDesc = fmt.Sprintf("'%s' returns %d values", call.Name.Name(), values)
+	}
+	if values == 1 {
+		valuesDesc = strings.TrimSuffix(valuesDesc, "s")
+	}
+
+	pos := firstVariable.Name.Pos
+	assignmentMismatch := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: assignment mismatch: %d %s but %s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			variables,
+			variablesWord,
+			valuesDesc,
+		),
+	}
+	sema.collector.ReportAndSave(assignmentMismatch)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
 func (sema *sema) analyzeVar(variable *ast.VarStmt, currentScope *ast.Scope) error {
 	if variable.Decl {
 		// Não pode existir antes
@@ -308,14 +756,28 @@ func (sema *sema) analyzeVar(variable *ast.VarStmt, currentScope *ast.Scope) err
 		if err != nil {
 			return err
 		}
+		sema.declareLocal(variable)
 	} else {
 		// Deve existir antes
-		_, err := currentScope.LookupCurrentScope(variable.Name.Name())
-		// TODO(errors)
+		symbol, err := currentScope.LookupAcrossScopes(variable.Name.Name())
 		if err != nil {
 			if err == ast.ERR_SYMBOL_NOT_FOUND_ON_SCOPE {
-				return fmt.Errorf("'%s' does not exists on the current scope", variable.Name.Name())
+				return sema.reportNotDefined(variable.Name)
 			}
+			return err
+		}
+		// Values are stored with the type of the variable, so "x = 10" is u8
+		// if "x" is u8 and "opt = 10" is wrapped if "opt" is optional
+		var declaredTy ast.ExprType
+		switch declared := symbol.(type) {
+		case *ast.VarStmt:
+			declaredTy = declared.Type
+		case *ast.Field:
+			declaredTy = declared.Type
+		}
+		if declaredTy != nil && variable.NeedsInference {
+			variable.Type = declaredTy
+			variable.NeedsInference = false
 		}
 	}
 
@@ -339,24 +801,51 @@ func (sema *sema) analyzeVariableType(
 				varDecl.Type,
 			)
 		}
-		exprType, _, err := sema.inferExprTypeWithoutContext(varDecl.Value, currentScope)
+		exprType, err := sema.checkExpr(&varDecl.Value, nil, currentScope)
 		// TODO(errors)
 		if err != nil {
 			return err
 		}
+		if tupleTy, ok := exprType.(*ast.TupleType); ok {
+			return sema.reportAssignmentMismatch(varDecl, varDecl.Value, 1, len(tupleTy.Types))
+		}
 		varDecl.Type = exprType
 	} else {
 		// TODO(errors)
 		if varDecl.Type == nil {
 			log.Fatalf("variable does not have a type and it said it does not need inference")
 		}
-		exprTy, err := sema.inferExprTypeWithContext(varDecl.Value, varDecl.Type, currentScope)
+		err := sema.resolveType(varDecl.Type, varDecl.Name.Pos, currentScope)
+		if err != nil {
+			return err
+		}
+		// Declared without a value, such as "x i32;"
+		if varDecl.Value == nil {
+			return nil
+		}
+		exprTy, err := sema.inferStoredExprType(&varDecl.Value, varDecl.Type, currentScope)
 		// TODO(errors): Deal with type mismatch
 		if err != nil {
 			return err
 		}
-		if !reflect.DeepEqual(varDecl.Type, exprTy) {
-			return fmt.Errorf("type mismatch on variable decl, expected %s, got %s", varDecl.Type, exprTy)
+		if tupleTy, ok := exprTy.(*ast.TupleType); ok {
+			return sema.reportAssignmentMismatch(varDecl, varDecl.Value, 1, len(tupleTy.Types))
+		}
+		if !ast.SameType(varDecl.Type, exprTy) {
+			pos := varDecl.Value.Span().Start
+			mismatchedType := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: can't use %s on variable '%s' of type %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					exprTy,
+					varDecl.Name.Name(),
+					varDecl.Type,
+				),
+			}
+			sema.collector.ReportAndSave(mismatchedType)
+			return diagnostics.COMPILER_ERROR_FOUND
 		}
 	}
 	return nil
@@ -394,8 +883,9 @@ func (sema *sema) analyzeCondStmt(
 	outterScope *ast.Scope,
 ) error {
 	ifScope := ast.NewScope(outterScope)
+	condStmt.IfStmt.Scope = ifScope
 
-	err := sema.analyzeIfExpr(condStmt.IfStmt.Expr, outterScope)
+	err := sema.analyzeCondExpr(condStmt.IfStmt, outterScope)
 	// TODO(errors)
 	if err != nil {
 		return err
@@ -409,7 +899,8 @@ func (sema *sema) analyzeCondStmt(
 
 	for i := range condStmt.ElifStmts {
 		elifScope := ast.NewScope(outterScope)
-		err := sema.analyzeIfExpr(condStmt.ElifStmts[i].Expr, elifScope)
+		condStmt.ElifStmts[i].Scope = elifScope
+		err := sema.analyzeCondExpr(condStmt.ElifStmts[i], outterScope)
 		// TODO(errors)
 		if err != nil {
 			return err
@@ -423,6 +914,7 @@ func (sema *sema) analyzeCondStmt(
 
 	if condStmt.ElseStmt != nil {
 		elseScope := ast.NewScope(outterScope)
+		condStmt.ElseStmt.Scope = elseScope
 		err = sema.analyzeBlock(condStmt.ElseStmt.Block, returnTy, elseScope)
 		// TODO(errors)
 		if err != nil {
@@ -440,6 +932,9 @@ func (sema *sema) analyzeFunctionCall(
 	function, err := currentScope.LookupAcrossScopes(functionCall.Name.Name())
 	if err != nil {
 		if err == ast.ERR_SYMBOL_NOT_FOUND_ON_SCOPE {
+			if _, ok := builtins[functionCall.Name.Name()]; ok {
+				return sema.analyzeBuiltinCall(functionCall, currentScope)
+			}
 			pos := functionCall.Name.Pos
 			functionNotDefined := diagnostics.Diag{
 				Message: fmt.Sprintf(
@@ -455,6 +950,7 @@ func (sema *sema) analyzeFunctionCall(
 		}
 		return err
 	}
+	sema.markUsed(function)
 
 	decl, ok := function.(*ast.FunctionDecl)
 	if !ok {
@@ -472,6 +968,8 @@ func (sema *sema) analyzeFunctionCall(
 		return diagnostics.COMPILER_ERROR_FOUND
 	}
 
+	sema.warnDeprecatedCall(functionCall, decl.Attributes)
+
 	if len(functionCall.Args) != len(decl.Params.Fields) {
 		pos := functionCall.Name.Pos
 		// TODO(errors): show which arguments were passed and which types we
@@ -491,26 +989,35 @@ func (sema *sema) analyzeFunctionCall(
 
 	for i := range len(functionCall.Args) {
 		paramType := decl.Params.Fields[i].Type
-		argType, err := sema.inferExprTypeWithContext(functionCall.Args[i], paramType, currentScope)
+		argType, err := sema.inferStoredExprType(&functionCall.Args[i], paramType, currentScope)
 		if err != nil {
 			return err
 		}
-		if !reflect.DeepEqual(argType, paramType) {
+		if !ast.SameType(argType, paramType) {
+			pos := functionCall.Args[i].Span().Start
 			mismatchedArgType := diagnostics.Diag{
-				// TODO(errors): add position of the error
-				Message: fmt.Sprintf("can't use %s on argument of type %s", argType, paramType),
+				Message: fmt.Sprintf(
+					"%s:%d:%d: can't use %s on argument of type %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					argType,
+					paramType,
+				),
 			}
 			sema.collector.ReportAndSave(mismatchedArgType)
 			return diagnostics.COMPILER_ERROR_FOUND
 		}
 	}
 
+	functionCall.Type = decl.RetType
+
 	// TODO: deal with variadic arguments
 	return nil
 }
 
-func (sema *sema) analyzeIfExpr(expr ast.Expr, scope *ast.Scope) error {
-	inferedExprType, _, err := sema.inferExprTypeWithoutContext(expr, scope)
+func (sema *sema) analyzeIfExpr(expr *ast.Expr, scope *ast.Scope) error {
+	inferedExprType, err := sema.checkExpr(expr, nil, scope)
 	// TODO(errors)
 	if err != nil {
 		return err
@@ -523,122 +1030,127 @@ func (sema *sema) analyzeIfExpr(expr ast.Expr, scope *ast.Scope) error {
 	return nil
 }
 
-// TODO: think about the way I'm inferring or getting the expr type correctly
-func (sema *sema) inferExprTypeWithContext(
-	exprNode ast.Expr,
+// Checks an expression and returns its type. The expected type is the type of
+// whatever receives the value, such as a variable or a parameter, or nil when
+// there is none, such as on "x := value". It only gives a type to values that
+// have none on their own, such as untyped constants and nil, so the caller
+// still compares the result with it. Constants are folded into literals, so
+// the expression may be replaced.
+func (sema *sema) checkExpr(
+	expr *ast.Expr,
 	expectedType ast.ExprType,
 	scope *ast.Scope,
 ) (ast.ExprType, error) {
-	switch expression := exprNode.(type) {
-	case *ast.LiteralExpr:
-		switch ty := expression.Type.(type) {
-		case *ast.BasicType:
-			switch ty.Kind {
-			case token.STRING_LITERAL:
-				finalTy := &ast.PointerType{Type: &ast.BasicType{Kind: token.U8_TYPE}}
-				expression.Type = finalTy
-				return finalTy, nil
-			case token.INTEGER_LITERAL, token.INT_TYPE:
-				switch ty := expectedType.(type) {
-				case *ast.BasicType:
-					value := string(expression.Value)
-					bitSize := ty.Kind.BitSize()
-					// TODO(errors)
-					if bitSize == -1 {
-						return nil, fmt.Errorf("not a valid numeric type: %s", ty.Kind)
-					}
-					_, err := strconv.ParseUint(value, 10, bitSize)
-					// TODO(errors)
-					if err != nil {
-						return nil, fmt.Errorf("kind: %s bitSize: %d - integer overflow %s - error: %s", ty.Kind, bitSize, value, err)
-					}
-					finalTy := &ast.BasicType{Kind: ty.Kind}
-					expression.Type = finalTy
-					return finalTy, nil
-				default:
-					log.Fatalf("unimplemented type at integer literal: %s %s", ty, reflect.TypeOf(ty))
-				}
-			case token.TRUE_BOOL_LITERAL:
-				finalTy := &ast.BasicType{Kind: token.BOOL_TYPE}
-				expression.Type = finalTy
-				expression.Value = []byte("1")
-				return finalTy, nil
-			case token.FALSE_BOOL_LITERAL:
-				finalTy := &ast.BasicType{Kind: token.BOOL_TYPE}
-				expression.Type = finalTy
-				expression.Value = []byte("0")
-				return finalTy, nil
-			default:
-				log.Fatalf("unimplemented basic type kind: %s", ty.Kind)
-			}
-		default:
-			log.Fatalf("unimplemented literal expr type on inferExprTypeWithContext: %s", reflect.TypeOf(ty))
+	// Values are checked against the underlying type of the optional and only
+	// nil is an optional on its own
+	if optional, ok := expectedType.(*ast.OptionalType); ok {
+		if isNilLiteral(*expr) {
+			(*expr).(*ast.LiteralExpr).Type = optional
+			return optional, nil
 		}
-	case *ast.IdExpr:
-		symbol, err := scope.LookupAcrossScopes(expression.Name.Name())
-		// TODO(errors)
+		return sema.checkExpr(expr, optional.Type, scope)
+	}
+	// The same goes for error unions, where only errors are unions on their
+	// own
+	if union, ok := expectedType.(*ast.ErrorUnionType); ok {
+		if errorExpr, ok := (*expr).(*ast.ErrorExpr); ok {
+			sema.analyzeErrorExpr(errorExpr, union)
+			return union, nil
+		}
+		return sema.checkExpr(expr, union.Type, scope)
+	}
+	if sema.isConstExpr(*expr, scope) {
+		return sema.checkConstExpr(expr, expectedType, scope)
+	}
+
+	switch expression := (*expr).(type) {
+	case *ast.LiteralExpr:
+		return sema.checkLiteral(expression, expectedType)
+	case *ast.IdExpr:
+		symbol, err := scope.LookupAcrossScopes(expression.Name.Name())
 		if err != nil {
+			if err == ast.ERR_SYMBOL_NOT_FOUND_ON_SCOPE {
+				return nil, sema.reportNotDefined(expression.Name)
+			}
 			return nil, err
 		}
-		switch symTy := symbol.(type) {
+		sema.markUsed(symbol)
+		switch node := symbol.(type) {
 		case *ast.VarStmt:
-			return symTy.Type, nil
+			return node.Type, nil
 		case *ast.Field:
-			return symTy.Type, nil
-		// TODO(errors)
+			return node.Type, nil
 		default:
-			log.Fatalf("expected to be a variable or parameter, but got %s", reflect.TypeOf(symTy))
+			return nil, fmt.Errorf("symbol '%s' is not a variable", node)
 		}
-	case *ast.BinaryExpr:
-		ty, err := sema.inferBinaryExprTypeWithContext(expression, expectedType, scope)
-		// TODO(errors)
+	case *ast.UnaryExpr:
+		ty, err := sema.checkExpr(&expression.Value, nil, scope)
 		if err != nil {
 			return nil, err
 		}
+		switch expression.Op {
+		case token.MINUS:
+			if !ty.IsNumeric() {
+				return nil, sema.reportInvalidOperand(expression, ty)
+			}
+		case token.NOT:
+			if !ty.IsBoolean() {
+				return nil, sema.reportInvalidOperand(expression, ty)
+			}
+		default:
+			log.Fatalf("unimplemented unary expr operator: %s", expression.Op)
+		}
 		return ty, nil
+	case *ast.BinaryExpr:
+		return sema.checkBinaryExpr(expression, scope)
 	case *ast.FunctionCall:
 		err := sema.analyzeFunctionCall(expression, scope)
-		// TODO(errors)
 		if err != nil {
 			return nil, err
 		}
-		function, _ := scope.LookupAcrossScopes(expression.Name.Name())
-		functionDecl := function.(*ast.FunctionDecl)
-		return functionDecl.RetType, nil
-	case *ast.VoidExpr:
-		// TODO(errors)
-		if !expectedType.IsVoid() {
-			return nil, fmt.Errorf("expected return type to be '%s'", expectedType)
-		}
-		return expectedType, nil
-	case *ast.UnaryExpr:
-		switch expression.Op {
-		case token.MINUS:
-			unaryExprType, err := sema.inferExprTypeWithContext(expression.Value, expectedType, scope)
-			// TODO(errors)
+		if expression.Folded != nil {
+			var folded ast.Expr = expression.Folded
+			ty, err := sema.checkExpr(&folded, expectedType, scope)
 			if err != nil {
 				return nil, err
 			}
-			if !unaryExprType.IsNumeric() {
-				return nil, fmt.Errorf("can't use - operator on a non-numeric value")
-			}
-			return unaryExprType, nil
-		default:
-			log.Fatalf("unimplemented unary expr operator: %s", reflect.TypeOf(expression.Op))
+			expression.Folded = folded.(*ast.LiteralExpr)
+			expression.Type = ty
+			return ty, nil
 		}
-	default:
+		return expression.Type, nil
+	case *ast.ArrayLiteral:
+		return sema.checkArrayLiteral(expression, expectedType, scope)
+	case *ast.IndexExpr:
+		return sema.analyzeIndexExpr(expression, scope)
+	case *ast.OptionalExpr:
+		return expression.Type, nil
+	case *ast.ErrorUnionExpr:
+		return expression.Type, nil
+	case *ast.ErrorExpr:
+		return nil, sema.reportErrorWithoutUnion(expression, expectedType)
+	case *ast.TryExpr:
+		return sema.analyzeTryExpr(expression, scope)
+	case *ast.CastExpr:
+		return sema.inferCastType(expression, scope)
+	case *ast.FieldAccess:
+		return sema.inferFieldAccessType(expression, scope)
+	case *ast.VoidExpr:
 		// TODO(errors)
-		log.Fatalf("unimplemented on getExprType: %s", reflect.TypeOf(expression))
+		if expectedType == nil || !expectedType.IsVoid() {
+			return nil, fmt.Errorf("expected return type to be '%s'", expectedType)
+		}
+		return expectedType, nil
 	}
-
-	// NOTE: this line should be unreachable
-	log.Fatalf("unreachable line at getExprTy")
+	// TODO(errors)
+	log.Fatalf("unimplemented expression on sema: %s", reflect.TypeOf(*expr))
 	return nil, nil
 }
 
 // Useful for testing
-func inferExprTypeWithoutContext(
+func checkExprFrom(
 	input, filename string,
+	expectedType ast.ExprType,
 	scope *ast.Scope,
 ) (ast.Expr, ast.ExprType, error) {
 	collector := diagnostics.New()
@@ -649,235 +1161,379 @@ func inferExprTypeWithoutContext(
 	}
 
 	analyzer := New(collector)
-	exprType, _, err := analyzer.inferExprTypeWithoutContext(expr, scope)
+	exprType, err := analyzer.checkExpr(&expr, expectedType, scope)
 	if err != nil {
 		return nil, nil, err
 	}
 	return expr, exprType, nil
 }
 
-// Useful for testing
-func inferExprTypeWithContext(
-	input, filename string,
-	ty ast.ExprType,
+// Integer and boolean literals are constants, so only strings and nil are
+// checked here
+func (sema *sema) checkLiteral(
+	literal *ast.LiteralExpr,
+	expectedType ast.ExprType,
+) (ast.ExprType, error) {
+	ty, ok := literal.Type.(*ast.BasicType)
+	if !ok {
+		// nil already analyzed
+		return literal.Type, nil
+	}
+
+	switch ty.Kind {
+	case token.STRING_LITERAL:
+		// String literals are also C strings, so they can be passed straight
+		// to C functions, such as "libc.puts("hello")"
+		var finalTy ast.ExprType = &ast.BasicType{Kind: token.STRING_TYPE}
+		if isCString(expectedType) {
+			finalTy = &ast.PointerType{Type: &ast.BasicType{Kind: token.U8_TYPE}}
+		}
+		literal.Type = finalTy
+		return finalTy, nil
+	case token.NIL_LITERAL:
+		pos := literal.Span().Start
+		message := fmt.Sprintf("can't use nil as %s", expectedType)
+		if expectedType == nil {
+			message = "can't infer the type of nil"
+		}
+		invalidNil := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				message,
+			),
+		}
+		sema.collector.ReportAndSave(invalidNil)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	// Already analyzed, such as strings and folded constants
+	return ty, nil
+}
+
+// Checks a binary expression. Operands are checked without the expected type,
+// since the context never changes their type, and they are mixed by these
+// rules:
+//
+//  1. An untyped constant operand, such as "1" on "a + 1", takes the type of
+//     the other operand and must fit on it, so "a + 1000" is an error if "a"
+//     is u8.
+//  2. Integers of different types are only mixed if one of them holds every
+//     value of the other, and the narrower one is widened, such as u8 to i32.
+//     Integers of the same width, such as i32 and u32, and signed integers
+//     with unsigned ones, such as i8 and u16, are mismatched.
+//  3. Arithmetic results on the type of the operands and comparisons on bool.
+//     Strings can only be concatenated and compared for equality.
+//  4. The result is widened as any other value when stored on a wider
+//     integer, so "x i64 := a * b" multiplies using the type of "a" and "b".
+func (sema *sema) checkBinaryExpr(
+	expression *ast.BinaryExpr,
 	scope *ast.Scope,
 ) (ast.ExprType, error) {
-	collector := diagnostics.New()
+	if isOptionalBinaryExpr(expression) {
+		return sema.inferOptionalBinaryExprType(expression, scope)
+	}
+	if expression.Op == token.CATCH {
+		return sema.inferCatchExprType(expression, scope)
+	}
 
-	expr, err := parser.ParseExprFrom(input, filename)
+	lhsType, rhsType, err := sema.checkOperands(&expression.Left, &expression.Right, scope)
 	if err != nil {
 		return nil, err
 	}
-
-	analyzer := New(collector)
-	exprType, err := analyzer.inferExprTypeWithContext(expr, ty, scope)
-	if err != nil {
+	if err := sema.checkUnwrappedOperands(expression, lhsType, rhsType); err != nil {
 		return nil, err
 	}
-	return exprType, nil
-}
-
-func (sema *sema) inferExprTypeWithoutContext(
-	expr ast.Expr,
-	scope *ast.Scope,
-) (ast.ExprType, bool, error) {
-	switch expression := expr.(type) {
-	case *ast.LiteralExpr:
-		switch ty := expression.Type.(type) {
-		case *ast.BasicType:
-			// TODO: what if I want a defined string type on my programming
-			// language? I don't think *i8 is correct type for this
-			switch ty.Kind {
-			case token.STRING_LITERAL:
-				finalTy := &ast.PointerType{Type: &ast.BasicType{Kind: token.U8_TYPE}}
-				expression.Type = finalTy
-				return finalTy, false, nil
-			case token.INTEGER_LITERAL:
-				ty, err := sema.inferIntegerType(expression.Value)
-				if err != nil {
-					return nil, false, err
-				}
-				expression.Type = ty
-				return ty, false, nil
-			case token.TRUE_BOOL_LITERAL:
-				finalTy := &ast.BasicType{Kind: token.BOOL_TYPE}
-				expression.Type = finalTy
-				expression.Value = []byte("1")
-				return finalTy, false, nil
-			case token.FALSE_BOOL_LITERAL:
-				finalTy := &ast.BasicType{Kind: token.BOOL_TYPE}
-				expression.Type = finalTy
-				expression.Value = []byte("0")
-				return finalTy, false, nil
-			default:
-				log.Fatalf("unimplemented literal expr: %s", expression)
-			}
-		default:
-			log.Fatalf("unimplemented literal expr type: %s", reflect.TypeOf(ty))
-		}
-	case *ast.IdExpr:
-		variableName := expression.Name.Name()
-		variable, err := scope.LookupAcrossScopes(variableName)
-		// TODO(errors)
-		if err != nil {
-			return nil, false, err
-		}
+	ty, ok := widenOperands(&expression.Left, &expression.Right, lhsType, rhsType)
+	if !ok {
+		return nil, sema.reportMismatchedTypes(expression, lhsType, rhsType)
+	}
+	expression.OperandType = ty
 
-		switch node := variable.(type) {
-		case *ast.VarStmt:
-			return node.Type, true, nil
-		case *ast.Field:
-			return node.Type, true, nil
-		default:
-			return nil, false, fmt.Errorf("symbol '%s' is not a variable", node)
-		}
-	case *ast.UnaryExpr:
-		switch expression.Op {
-		case token.MINUS:
-			switch unaryExpr := expression.Value.(type) {
-			case *ast.LiteralExpr:
-				switch unaryTy := unaryExpr.Type.(type) {
-				case *ast.BasicType:
-					if unaryTy.Kind == token.INTEGER_LITERAL {
-						integerType, err := sema.inferIntegerType(unaryExpr.Value)
-						// TODO(errors)
-						if err != nil {
-							return nil, false, err
-						}
-						unaryExpr.Type = integerType
-						return integerType, false, nil
-					}
-				default:
-					log.Fatalf("unimplemented unary expr type: %s", unaryExpr)
-				}
-			}
-		case token.NOT:
-			unaryExpr, foundContext, err := sema.inferExprTypeWithoutContext(expression.Value, scope)
-			// TODO(errors)
-			if err != nil {
-				return nil, false, err
-			}
-			if !unaryExpr.IsBoolean() {
-				return nil, false, fmt.Errorf("expected boolean expression on not unary expression")
-			}
-			return unaryExpr, foundContext, nil
+	boolTy := &ast.BasicType{Kind: token.BOOL_TYPE}
+	switch expression.Op {
+	case token.AND, token.OR:
+		if ty.IsBoolean() {
+			return boolTy, nil
 		}
-		// TODO(errors)
-		log.Fatalf("unimplemented unary expression on sema")
-	case *ast.BinaryExpr:
-		ty, foundContext, err := sema.inferBinaryExprTypeWithoutContext(expression, scope)
-		// TODO(errors)
-		if err != nil {
-			return nil, false, err
+	case token.EQUAL_EQUAL, token.BANG_EQUAL:
+		return boolTy, nil
+	case token.LESS, token.LESS_EQ, token.GREATER, token.GREATER_EQ:
+		if ty.IsNumeric() {
+			return boolTy, nil
 		}
-		return ty, foundContext, nil
-	case *ast.FunctionCall:
-		err := sema.analyzeFunctionCall(expression, scope)
-		// TODO(errors)
-		if err != nil {
-			return nil, false, err
+	case token.PLUS:
+		if ty.IsNumeric() || ty.IsString() {
+			return ty, nil
 		}
-		// At this point, function should exists!
-		function, err := scope.LookupAcrossScopes(expression.Name.Name())
-		if err != nil {
-			log.Fatalf("panic: at this point, function '%s' should exists in current block", expression.Name)
+	case token.MINUS, token.STAR, token.SLASH, token.PERCENT:
+		if ty.IsNumeric() {
+			return ty, nil
 		}
-		functionDecl := function.(*ast.FunctionDecl)
-		return functionDecl.RetType, true, nil
-	default:
-		log.Fatalf("unimplemented expression on sema: %s", reflect.TypeOf(expression))
 	}
-	// TODO(errors)
-	// NOTE: this should be unreachable
-	log.Fatalf("UNREACHABLE - inferExprType: %s", reflect.TypeOf(expr))
-	return nil, false, nil
+	return nil, sema.reportInvalidOperator(expression, ty)
 }
 
-// TODO: refactor this algorithm
-func (sema *sema) inferBinaryExprTypeWithoutContext(
+func (sema *sema) reportMismatchedTypes(
 	expression *ast.BinaryExpr,
-	scope *ast.Scope,
-) (ast.ExprType, bool, error) {
-	lhsType, lhsFoundContext, err := sema.inferExprTypeWithoutContext(expression.Left, scope)
-	// TODO(errors)
-	if err != nil {
-		return nil, false, err
+	lhsType, rhsType ast.ExprType,
+) error {
+	pos := expression.Span().Start
+	mismatchedTypes := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: mismatched types %s and %s on %s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			lhsType,
+			rhsType,
+			expression.Op,
+		),
 	}
+	sema.collector.ReportAndSave(mismatchedTypes)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
 
-	rhsType, rhsFoundContext, err := sema.inferExprTypeWithoutContext(expression.Right, scope)
-	// TODO(errors)
-	if err != nil {
-		return nil, false, err
+func (sema *sema) reportNotDefined(name *token.Token) error {
+	pos := name.Pos
+	notDefined := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: '%s' not defined on scope",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			name.Name(),
+		),
 	}
+	sema.collector.ReportAndSave(notDefined)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
 
-	if lhsFoundContext && !rhsFoundContext {
-		rhsTypeWithContext, err := sema.inferExprTypeWithContext(expression.Right, lhsType, scope)
-		// TODO(errors)
-		if err != nil {
-			log.Fatal(err)
-		}
-		rhsType = rhsTypeWithContext
+func (sema *sema) reportInvalidOperator(expression *ast.BinaryExpr, ty ast.ExprType) error {
+	pos := expression.Span().Start
+	invalidOperator := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: can't use %s operator on %s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			expression.Op,
+			ty,
+		),
 	}
-	if !lhsFoundContext && rhsFoundContext {
-		lhsTypeWithContext, err := sema.inferExprTypeWithContext(expression.Left, rhsType, scope)
-		// TODO(errors)
-		if err != nil {
-			log.Fatal(err)
+	sema.collector.ReportAndSave(invalidOperator)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+func (sema *sema) reportInvalidOperand(expression *ast.UnaryExpr, ty ast.ExprType) error {
+	pos := expression.Span().Start
+	invalidOperand := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: can't use %s operator on %s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			expression.Op,
+			ty,
+		),
+	}
+	sema.collector.ReportAndSave(invalidOperand)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+// Checks an array literal. Without an expected array type, the first element
+// with a type of its own, such as a variable, decides the element type, such
+// as on "[1, a, 3]". Arrays of constants only are arrays of int.
+func (sema *sema) checkArrayLiteral(
+	array *ast.ArrayLiteral,
+	expectedType ast.ExprType,
+	scope *ast.Scope,
+) (ast.ExprType, error) {
+	arrayTy, ok := expectedType.(*ast.ArrayType)
+	if !ok {
+		if len(array.Values) == 0 {
+			pos := array.Open
+			emptyArray := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: can't infer the type of an empty array literal",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+				),
+			}
+			sema.collector.ReportAndSave(emptyArray)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
+		}
+
+		var elemTy ast.ExprType
+		for i := range array.Values {
+			if sema.isConstExpr(array.Values[i], scope) {
+				continue
+			}
+			valueTy, err := sema.checkExpr(&array.Values[i], nil, scope)
+			if err != nil {
+				return nil, err
+			}
+			if elemTy == nil {
+				elemTy = valueTy
+			}
+		}
+		if elemTy == nil {
+			valueTy, err := sema.checkExpr(&array.Values[0], nil, scope)
+			if err != nil {
+				return nil, err
+			}
+			elemTy = valueTy
 		}
-		lhsType = lhsTypeWithContext
+		arrayTy = &ast.ArrayType{Len: len(array.Values), Type: elemTy}
 	}
 
-	if !reflect.DeepEqual(lhsType, rhsType) {
-		log.Fatalf("mismatched types: %s %s %s", lhsType, expression.Op, rhsType)
+	if len(array.Values) != arrayTy.Len {
+		pos := array.Open
+		wrongLength := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: expected %d elements on array literal of type %s, but got %d",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				arrayTy.Len,
+				arrayTy,
+				len(array.Values),
+			),
+		}
+		sema.collector.ReportAndSave(wrongLength)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
 	}
 
-	switch expression.Op {
-	case token.PLUS, token.MINUS:
-		if lhsType.IsNumeric() && rhsType.IsNumeric() {
-			return lhsType, lhsFoundContext || rhsFoundContext, nil
+	for i := range array.Values {
+		valueTy, err := sema.inferStoredExprType(&array.Values[i], arrayTy.Type, scope)
+		if err != nil {
+			return nil, err
 		}
-	default:
-		if _, ok := token.LOGICAL_OP[expression.Op]; ok {
-			return &ast.BasicType{Kind: token.BOOL_TYPE}, lhsFoundContext || rhsFoundContext, nil
+		if !ast.SameType(valueTy, arrayTy.Type) {
+			pos := array.Open
+			mismatchedElement := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: can't use %s on array literal of type %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					valueTy,
+					arrayTy,
+				),
+			}
+			sema.collector.ReportAndSave(mismatchedElement)
+			return nil, diagnostics.COMPILER_ERROR_FOUND
 		}
 	}
-	// TODO
-	log.Fatalf("UNREACHABLE - inferBinaryExprType")
-	return nil, false, nil
+	array.Type = arrayTy
+	return arrayTy, nil
 }
 
-func (sema *sema) inferBinaryExprTypeWithContext(
-	expression *ast.BinaryExpr,
-	expectedType ast.ExprType,
+func (sema *sema) analyzeIndexExpr(
+	index *ast.IndexExpr,
 	scope *ast.Scope,
 ) (ast.ExprType, error) {
-	lhsType, err := sema.inferExprTypeWithContext(expression.Left, expectedType, scope)
+	valueTy, err := sema.checkExpr(&index.Value, nil, scope)
 	if err != nil {
 		return nil, err
 	}
 
-	rhsType, err := sema.inferExprTypeWithContext(expression.Right, expectedType, scope)
+	// Indexing a string results on its bytes and slicing it results on
+	// another string
+	var elemTy, sliceTy ast.ExprType
+	switch ty := valueTy.(type) {
+	case *ast.ArrayType:
+		elemTy = ty.Type
+		sliceTy = &ast.SliceType{Type: elemTy}
+	case *ast.SliceType:
+		elemTy = ty.Type
+		sliceTy = ty
+	default:
+		if valueTy.IsString() {
+			elemTy = &ast.BasicType{Kind: token.U8_TYPE}
+			sliceTy = valueTy
+			break
+		}
+		pos := index.Open
+		notIndexable := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't index value of type %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				valueTy,
+			),
+		}
+		sema.collector.ReportAndSave(notIndexable)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+
+	if rangeExpr, ok := index.Index.(*ast.RangeExpr); ok {
+		for _, bound := range []*ast.Expr{&rangeExpr.Start, &rangeExpr.End} {
+			if *bound == nil {
+				continue
+			}
+			err := sema.analyzeIndexValue(bound, index.Open, scope)
+			if err != nil {
+				return nil, err
+			}
+		}
+		index.Type = sliceTy
+		return index.Type, nil
+	}
+
+	err = sema.analyzeIndexValue(&index.Index, index.Open, scope)
 	if err != nil {
 		return nil, err
 	}
 
-	if !reflect.DeepEqual(lhsType, rhsType) {
-		return nil, fmt.Errorf("mismatched types: %s %s %s", lhsType, expression.Op, rhsType)
+	// Constant indexes are checked at compile time
+	if arrayTy, ok := valueTy.(*ast.ArrayType); ok {
+		if literal, ok := index.Index.(*ast.LiteralExpr); ok {
+			value, err := strconv.Atoi(string(literal.Value))
+			if err == nil && value >= arrayTy.Len {
+				pos := index.Open
+				outOfBounds := diagnostics.Diag{
+					Message: fmt.Sprintf(
+						"%s:%d:%d: index %d out of bounds for array of length %d",
+						pos.Filename,
+						pos.Line,
+						pos.Column,
+						value,
+						arrayTy.Len,
+					),
+				}
+				sema.collector.ReportAndSave(outOfBounds)
+				return nil, diagnostics.COMPILER_ERROR_FOUND
+			}
+		}
 	}
-	return lhsType, nil
-}
 
-func (sema *sema) inferIntegerType(value []byte) (ast.ExprType, error) {
-	integerType := token.INT_TYPE
-	base := 10
-	intSize := strconv.IntSize
+	index.Type = elemTy
+	return elemTy, nil
+}
 
-	_, err := strconv.ParseUint(string(value), base, intSize)
-	if err == nil {
-		return &ast.BasicType{Kind: integerType}, nil
+func (sema *sema) analyzeIndexValue(value *ast.Expr, pos token.Pos, scope *ast.Scope) error {
+	valueTy, err := sema.checkExpr(value, nil, scope)
+	if err != nil {
+		return err
 	}
-
-	return nil, fmt.Errorf("can't parse integer literal: %s", value)
+	if !valueTy.IsNumeric() {
+		invalidIndex := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: can't use %s as index",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				valueTy,
+			),
+		}
+		sema.collector.ReportAndSave(invalidIndex)
+		return diagnostics.COMPILER_ERROR_FOUND
+	}
+	return nil
 }
 
 func (sema *sema) analyzeFieldAccessExpr(
@@ -918,6 +1574,11 @@ func (sema *sema) analyzeFieldAccessExpr(
 			if err != nil {
 				return err
 			}
+		case *ast.IdExpr:
+			err := sema.analyzeExternVarAccess(right, sym)
+			if err != nil {
+				return err
+			}
 		default:
 			// TODO(errors)
 			return fmt.Errorf("invalid expression %s when accessing field", right)
@@ -926,6 +1587,57 @@ func (sema *sema) analyzeFieldAccessExpr(
 	return nil
 }
 
+// Infers the type of a field access used as a value, such as
+// "libc.getenv("HOME")"
+func (sema *sema) inferFieldAccessType(
+	fieldAccess *ast.FieldAccess,
+	scope *ast.Scope,
+) (ast.ExprType, error) {
+	err := sema.analyzeFieldAccessExpr(fieldAccess, scope)
+	if err != nil {
+		return nil, err
+	}
+	if variable := externVarOf(fieldAccess, scope); variable != nil {
+		return variable.Type, nil
+	}
+	call, proto := sema.prototypeCallOf(fieldAccess, scope)
+	// TODO(errors)
+	if proto == nil {
+		return nil, fmt.Errorf("invalid field access %s", fieldAccess)
+	}
+	return call.Type, nil
+}
+
+// Returns the prototype called by an already analyzed field access, such as
+// "libc.puts("hello")", or nil if it isn't a prototype call
+func (sema *sema) prototypeCallOf(
+	fieldAccess *ast.FieldAccess,
+	scope *ast.Scope,
+) (*ast.FunctionCall, *ast.Proto) {
+	idExpr, ok := fieldAccess.Left.(*ast.IdExpr)
+	if !ok {
+		return nil, nil
+	}
+	call, ok := fieldAccess.Right.(*ast.FunctionCall)
+	if !ok {
+		return nil, nil
+	}
+	symbol, err := scope.LookupAcrossScopes(idExpr.Name.Name())
+	if err != nil {
+		return nil, nil
+	}
+	extern, ok := symbol.(*ast.ExternDecl)
+	if !ok {
+		return nil, nil
+	}
+	prototype, err := extern.Scope.LookupCurrentScope(call.Name.Name())
+	if err != nil {
+		return nil, nil
+	}
+	proto, _ := prototype.(*ast.Proto)
+	return call, proto
+}
+
 func (sema *sema) analyzePrototypeCall(
 	prototypeCall *ast.FunctionCall,
 	callScope *ast.Scope,
@@ -949,6 +1661,10 @@ func (sema *sema) analyzePrototypeCall(
 	}
 
 	if proto, ok := prototype.(*ast.Proto); ok {
+		sema.markUsed(proto)
+		sema.warnDeprecatedCall(prototypeCall, proto.Attributes)
+		prototypeCall.Type = proto.RetType
+
 		if proto.Params.IsVariadic {
 			minimumNumberOfArgs := len(proto.Params.Fields)
 			// TODO(errors)
@@ -957,8 +1673,8 @@ func (sema *sema) analyzePrototypeCall(
 			}
 			for i := range minimumNumberOfArgs {
 				paramType := proto.Params.Fields[i].Type
-				argType, err := sema.inferExprTypeWithContext(
-					prototypeCall.Args[i],
+				argType, err := sema.inferStoredExprType(
+					&prototypeCall.Args[i],
 					paramType,
 					callScope,
 				)
@@ -966,14 +1682,29 @@ func (sema *sema) analyzePrototypeCall(
 				if err != nil {
 					return err
 				}
-				// TODO(errors)
-				if !reflect.DeepEqual(argType, paramType) {
-					log.Fatalf(
-						"mismatched argument type on prototype '%s', expected %s, but got %s",
-						proto.Name,
-						paramType,
-						argType,
-					)
+				if !ast.SameType(argType, paramType) {
+					return sema.reportMismatchedPrototypeArg(prototypeCall, i, argType, paramType)
+				}
+			}
+			for i := minimumNumberOfArgs; i < len(prototypeCall.Args); i++ {
+				// String literals are passed as C strings, such as on
+				// "libc.printf("%s", "hello")"
+				var expectedType ast.ExprType
+				if isStringLiteral(prototypeCall.Args[i]) {
+					expectedType = &ast.PointerType{Type: &ast.BasicType{Kind: token.U8_TYPE}}
+				}
+				argType, err := sema.checkExpr(&prototypeCall.Args[i], expectedType, callScope)
+				if err != nil {
+					return err
+				}
+				// C doesn't know about optionals and error unions, so they
+				// must be unwrapped even without a parameter type to check
+				// against
+				if unwrapType(argType) != nil {
+					return sema.reportUnwrapped(prototypeCall.Args[i], argType)
+				}
+				if argType.IsString() {
+					return sema.reportVariadicString(prototypeCall, i)
 				}
 			}
 		} else {
@@ -982,14 +1713,13 @@ func (sema *sema) analyzePrototypeCall(
 			}
 			for i := range len(prototypeCall.Args) {
 				paramType := proto.Params.Fields[i].Type
-				argType, err := sema.inferExprTypeWithContext(prototypeCall.Args[i], paramType, callScope)
+				argType, err := sema.inferStoredExprType(&prototypeCall.Args[i], paramType, callScope)
 				// TODO(errors)
 				if err != nil {
 					return err
 				}
-				// TODO(errors)
-				if !reflect.DeepEqual(argType, paramType) {
-					log.Fatalf("mismatched argument type on function '%s', expected %s, but got %s", proto.Name, paramType, argType)
+				if !ast.SameType(argType, paramType) {
+					return sema.reportMismatchedPrototypeArg(prototypeCall, i, argType, paramType)
 				}
 			}
 		}
@@ -1000,6 +1730,67 @@ func (sema *sema) analyzePrototypeCall(
 	return nil
 }
 
+// Strings aren't NUL-terminated, so passing one to a *u8 parameter suggests
+// converting it with "cstr"
+func (sema *sema) reportMismatchedPrototypeArg(
+	prototypeCall *ast.FunctionCall,
+	index int,
+	argType, paramType ast.ExprType,
+) error {
+	arg := prototypeCall.Args[index]
+	cType := paramType
+	if optional, ok := cType.(*ast.OptionalType); ok {
+		cType = optional.Type
+	}
+	hint := ""
+	if argType.IsString() && isCString(cType) {
+		hint = cstrHint(arg)
+	}
+
+	pos := arg.Span().Start
+	mismatchedArgType := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: can't use %s on argument of type %s in call to '%s'%s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			argType,
+			paramType,
+			prototypeCall.Name.Name(),
+			hint,
+		),
+	}
+	sema.collector.ReportAndSave(mismatchedArgType)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+// Strings are passed as a pointer and a length, which C variadic functions,
+// such as "printf", don't expect
+func (sema *sema) reportVariadicString(prototypeCall *ast.FunctionCall, index int) error {
+	arg := prototypeCall.Args[index]
+	pos := arg.Span().Start
+	variadicString := diagnostics.Diag{
+		Message: fmt.Sprintf(
+			"%s:%d:%d: can't use string as variadic argument in call to '%s'%s",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			prototypeCall.Name.Name(),
+			cstrHint(arg),
+		),
+	}
+	sema.collector.ReportAndSave(variadicString)
+	return diagnostics.COMPILER_ERROR_FOUND
+}
+
+func cstrHint(arg ast.Expr) string {
+	value := "..."
+	if id, ok := arg.(*ast.IdExpr); ok {
+		value = id.Name.Name()
+	}
+	return fmt.Sprintf(", use cstr(%s) to pass a NUL-terminated copy", value)
+}
+
 func (sema *sema) analyzeForLoop(
 	forLoop *ast.ForLoop,
 	scope *ast.Scope,
@@ -1010,7 +1801,7 @@ func (sema *sema) analyzeForLoop(
 		return err
 	}
 
-	err = sema.analyzeIfExpr(forLoop.Cond, scope)
+	err = sema.analyzeIfExpr(&forLoop.Cond, scope)
 	if err != nil {
 		return err
 	}
@@ -1024,16 +1815,166 @@ func (sema *sema) analyzeForLoop(
 	return err
 }
 
+func (sema *sema) analyzeRangeForLoop(
+	forLoop *ast.RangeForLoop,
+	scope *ast.Scope,
+	returnTy ast.ExprType,
+) error {
+	forLoop.Scope = ast.NewScope(scope)
+
+	switch iterable := forLoop.Iterable.(type) {
+	case *ast.RangeExpr:
+		if forLoop.Index != nil {
+			pos := forLoop.Index.Name.Pos
+			tooManyVariables := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: range over integers permits only one loop variable",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+				),
+			}
+			sema.collector.ReportAndSave(tooManyVariables)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+		ty, err := sema.inferRangeExprType(iterable, forLoop.Value, scope)
+		if err != nil {
+			return err
+		}
+		forLoop.Value.Type = ty
+	default:
+		iterableTy, err := sema.checkExpr(&forLoop.Iterable, nil, scope)
+		if err != nil {
+			return err
+		}
+		switch ty := iterableTy.(type) {
+		case *ast.ArrayType:
+			forLoop.Value.Type = ty.Type
+		case *ast.SliceType:
+			forLoop.Value.Type = ty.Type
+		default:
+			pos := forLoop.Value.Name.Pos
+			notIterable := diagnostics.Diag{
+				Message: fmt.Sprintf(
+					"%s:%d:%d: can't range over value of type %s",
+					pos.Filename,
+					pos.Line,
+					pos.Column,
+					iterableTy,
+				),
+			}
+			sema.collector.ReportAndSave(notIterable)
+			return diagnostics.COMPILER_ERROR_FOUND
+		}
+		if forLoop.Index != nil {
+			forLoop.Index.Type = &ast.BasicType{Kind: token.INT_TYPE}
+		}
+	}
+
+	for _, variable := range []*ast.VarStmt{forLoop.Index, forLoop.Value} {
+		if variable == nil {
+			continue
+		}
+		err := forLoop.Scope.Insert(variable.Name.Name(), variable)
+		if err != nil {
+			if err == ast.ERR_SYMBOL_ALREADY_DEFINED_ON_SCOPE {
+				pos := variable.Name.Pos
+				loopVariableRedeclaration := diagnostics.Diag{
+					Message: fmt.Sprintf(
+						"%s:%d:%d: loop variable '%s' already declared",
+						pos.Filename,
+						pos.Line,
+						pos.Column,
+						variable.Name.Name(),
+					),
+				}
+				sema.collector.ReportAndSave(loopVariableRedeclaration)
+				return diagnostics.COMPILER_ERROR_FOUND
+			}
+			return err
+		}
+		sema.declareLocal(variable)
+	}
+
+	err := sema.analyzeBlock(forLoop.Block, returnTy, forLoop.Scope)
+	return err
+}
+
+// The type of the loop variable is inferred from the bounds, such as "u8" on
+// "0..n" if "n" is "u8". Bounds without context, such as "0..10", are "int",
+// and bounds of different integer types are mixed just like operands.
+func (sema *sema) inferRangeExprType(
+	rangeExpr *ast.RangeExpr,
+	loopVariable *ast.VarStmt,
+	scope *ast.Scope,
+) (ast.ExprType, error) {
+	startTy, endTy, err := sema.checkOperands(&rangeExpr.Start, &rangeExpr.End, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := loopVariable.Name.Pos
+	ty, ok := widenOperands(&rangeExpr.Start, &rangeExpr.End, startTy, endTy)
+	if !ok {
+		mismatchedBounds := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: mismatched types on range bounds: %s and %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				startTy,
+				endTy,
+			),
+		}
+		sema.collector.ReportAndSave(mismatchedBounds)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	if !ty.IsNumeric() {
+		nonNumericBounds := diagnostics.Diag{
+			Message: fmt.Sprintf(
+				"%s:%d:%d: range bounds must be integers, not %s",
+				pos.Filename,
+				pos.Line,
+				pos.Column,
+				ty,
+			),
+		}
+		sema.collector.ReportAndSave(nonNumericBounds)
+		return nil, diagnostics.COMPILER_ERROR_FOUND
+	}
+	return ty, nil
+}
+
 // TODO: need tests for it
 func (sema *sema) analyzeWhileLoop(
 	whileLoop *ast.WhileLoop,
 	scope *ast.Scope,
 	returnTy ast.ExprType,
 ) error {
-	err := sema.analyzeIfExpr(whileLoop.Cond, scope)
+	err := sema.analyzeIfExpr(&whileLoop.Cond, scope)
 	if err != nil {
 		return err
 	}
 	err = sema.analyzeBlock(whileLoop.Block, returnTy, scope)
 	return err
 }
+
+// Reports whether ty is *u8, which is the type of NUL-terminated strings
+// expected by C functions
+func isStringLiteral(expr ast.Expr) bool {
+	literal, ok := expr.(*ast.LiteralExpr)
+	if !ok {
+		return false
+	}
+	ty, ok := literal.Type.(*ast.BasicType)
+	return ok && ty.Kind == token.STRING_LITERAL
+}
+
+func isCString(ty ast.ExprType) bool {
+	pointer, ok := ty.(*ast.PointerType)
+	if !ok {
+		return false
+	}
+	basic, ok := pointer.Type.(*ast.BasicType)
+	return ok && basic.Kind == token.U8_TYPE
+}
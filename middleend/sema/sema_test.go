@@ -2,7 +2,10 @@ package sema
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/HicaroD/Telia/diagnostics"
@@ -23,9 +26,59 @@ func TestVarDeclForInference(t *testing.T) {
 	tests := []varTest{
 		{
 			input:    `name := "Hicaro";`,
+			ty:       &ast.BasicType{Kind: token.STRING_TYPE},
+			inferred: true,
+		},
+		{
+			input:    `name := "Hicaro" + " " + "Dias";`,
+			ty:       &ast.BasicType{Kind: token.STRING_TYPE},
+			inferred: true,
+		},
+		{
+			input:    `name := "Hicaro"[1..3];`,
+			ty:       &ast.BasicType{Kind: token.STRING_TYPE},
+			inferred: true,
+		},
+		{
+			input:    `rem := 7 % 2;`,
+			ty:       &ast.BasicType{Kind: token.INT_TYPE},
+			inferred: true,
+		},
+		{
+			input:    `initial := "Hicaro"[0];`,
+			ty:       &ast.BasicType{Kind: token.U8_TYPE},
+			inferred: true,
+		},
+		{
+			input:    `is_eq := "Hicaro" != "Dias";`,
+			ty:       &ast.BasicType{Kind: token.BOOL_TYPE},
+			inferred: true,
+		},
+		{
+			input:    `length := len("Hicaro");`,
+			ty:       &ast.BasicType{Kind: token.INT_TYPE},
+			inferred: true,
+		},
+		{
+			input:    `name := cstr("Hicaro");`,
 			ty:       &ast.PointerType{Type: &ast.BasicType{Kind: token.U8_TYPE}},
 			inferred: true,
 		},
+		{
+			input:    "small := 18 as u8;",
+			ty:       &ast.BasicType{Kind: token.U8_TYPE},
+			inferred: true,
+		},
+		{
+			input:    "wide := 18 as u8 as i64 * 2;",
+			ty:       &ast.BasicType{Kind: token.I64_TYPE},
+			inferred: true,
+		},
+		{
+			input:    "name := typename(?*u8);",
+			ty:       &ast.BasicType{Kind: token.STRING_TYPE},
+			inferred: true,
+		},
 		{
 			input:    "age := 18;",
 			ty:       &ast.BasicType{Kind: token.INT_TYPE},
@@ -123,6 +176,8 @@ func TestVarDeclForInference(t *testing.T) {
 					variable.NeedsInference,
 				)
 			}
+			// Explicit types, such as the one of a cast, keep their spans
+			ast.ClearSpans(variable.Type)
 			if !reflect.DeepEqual(variable.Type, test.ty) {
 				t.Fatalf("type mismatch, expect %s, but got %s", test.ty, variable.Type)
 			}
@@ -130,397 +185,1837 @@ func TestVarDeclForInference(t *testing.T) {
 	}
 }
 
-type exprInferenceTest struct {
-	scope *ast.Scope
-	tests []struct {
-		input string
-		ty    ast.ExprType
-		value ast.Expr
-	}
+type checkExprTest struct {
+	input    string
+	expected ast.ExprType
+	ty       ast.ExprType
+	value    ast.Expr
 }
 
-func TestExprInferenceWithoutContext(t *testing.T) {
+func TestCheckExpr(t *testing.T) {
 	filename := "test.tt"
-	tests := []exprInferenceTest{
-		{
-			scope: &ast.Scope{
-				Parent: nil,
-				Nodes: map[string]ast.Node{
-					"a": &ast.VarStmt{
-						Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
-						Type: &ast.BasicType{Kind: token.I8_TYPE},
-						Value: ast.LiteralExpr{
-							Type:  &ast.BasicType{Kind: token.I8_TYPE},
-							Value: []byte("1"),
-						},
-						NeedsInference: false,
-					},
+	variable := func(name string, kind token.Kind) *ast.VarStmt {
+		return &ast.VarStmt{
+			Name: token.New([]byte(name), token.ID, token.NewPosition(filename, 1, 1)),
+			Type: &ast.BasicType{Kind: kind},
+		}
+	}
+	scope := &ast.Scope{
+		Parent: nil,
+		Nodes: map[string]ast.Node{
+			"a": variable("a", token.I8_TYPE),
+			"b": variable("b", token.U8_TYPE),
+			"c": variable("c", token.I32_TYPE),
+			"N": &ast.ConstDecl{
+				Name:  token.New([]byte("N"), token.ID, token.NewPosition(filename, 1, 1)),
+				Value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}, Value: []byte("300")},
+			},
+		},
+	}
+
+	tests := []checkExprTest{
+		{
+			input: "true",
+			ty:    &ast.BasicType{Kind: token.BOOL_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("1"),
+				Type:  &ast.BasicType{Kind: token.BOOL_TYPE},
+			},
+		},
+		{
+			input: "false",
+			ty:    &ast.BasicType{Kind: token.BOOL_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("0"),
+				Type:  &ast.BasicType{Kind: token.BOOL_TYPE},
+			},
+		},
+		{
+			input: "1",
+			ty:    &ast.BasicType{Kind: token.INT_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("1"),
+				Type:  &ast.BasicType{Kind: token.INT_TYPE},
+			},
+		},
+		{
+			input: "-a",
+			ty:    &ast.BasicType{Kind: token.I8_TYPE},
+			value: &ast.UnaryExpr{
+				Op: token.MINUS,
+				Value: &ast.IdExpr{
+					Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 2, 1)),
 				},
 			},
-			tests: []struct {
-				input string
-				ty    ast.ExprType
-				value ast.Expr
-			}{
-				{
-					input: "true",
-					ty:    &ast.BasicType{Kind: token.BOOL_TYPE},
-					value: &ast.LiteralExpr{
-						Value: []byte("1"),
-						Type:  &ast.BasicType{Kind: token.BOOL_TYPE},
-					},
+		},
+		{
+			input: "1 + 1",
+			ty:    &ast.BasicType{Kind: token.INT_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("2"),
+				Type:  &ast.BasicType{Kind: token.INT_TYPE},
+			},
+		},
+		{
+			input: "-1",
+			ty:    &ast.BasicType{Kind: token.INT_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("18446744073709551615"),
+				Type:  &ast.BasicType{Kind: token.INT_TYPE},
+			},
+		},
+		{
+			input: "-1 + 1",
+			ty:    &ast.BasicType{Kind: token.INT_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("0"),
+				Type:  &ast.BasicType{Kind: token.INT_TYPE},
+			},
+		},
+		{
+			input: "N",
+			ty:    &ast.BasicType{Kind: token.INT_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("300"),
+				Type:  &ast.BasicType{Kind: token.INT_TYPE},
+			},
+		},
+		{
+			input: "a + 1",
+			ty:    &ast.BasicType{Kind: token.I8_TYPE},
+			value: &ast.BinaryExpr{
+				Left: &ast.IdExpr{
+					Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
 				},
-				{
-					input: "false",
-					ty:    &ast.BasicType{Kind: token.BOOL_TYPE},
-					value: &ast.LiteralExpr{
-						Value: []byte("0"),
-						Type:  &ast.BasicType{Kind: token.BOOL_TYPE},
-					},
+				Op:          token.PLUS,
+				OperandType: &ast.BasicType{Kind: token.I8_TYPE},
+				Right: &ast.LiteralExpr{
+					Value: []byte("1"),
+					Type:  &ast.BasicType{Kind: token.I8_TYPE},
 				},
-				{
-					input: "1",
-					ty:    &ast.BasicType{Kind: token.INT_TYPE},
-					value: &ast.LiteralExpr{
-						Value: []byte("1"),
-						Type:  &ast.BasicType{Kind: token.INT_TYPE},
-					},
+			},
+		},
+		{
+			input: "1 + a",
+			ty:    &ast.BasicType{Kind: token.I8_TYPE},
+			value: &ast.BinaryExpr{
+				Left: &ast.LiteralExpr{
+					Value: []byte("1"),
+					Type:  &ast.BasicType{Kind: token.I8_TYPE},
 				},
-				{
-					input: "1 + 1",
-					ty:    &ast.BasicType{Kind: token.INT_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.LiteralExpr{
-							Value: []byte("1"),
-							Type:  &ast.BasicType{Kind: token.INT_TYPE},
-						},
-						Op: token.PLUS,
-						Right: &ast.LiteralExpr{
-							Value: []byte("1"),
-							Type:  &ast.BasicType{Kind: token.INT_TYPE},
-						},
-					},
+				Op:          token.PLUS,
+				OperandType: &ast.BasicType{Kind: token.I8_TYPE},
+				Right: &ast.IdExpr{
+					Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 5, 1)),
 				},
-				{
-					input: "-1",
-					ty:    &ast.BasicType{Kind: token.INT_TYPE},
-					value: &ast.UnaryExpr{
-						Op: token.MINUS,
-						Value: &ast.LiteralExpr{
-							Type:  &ast.BasicType{Kind: token.INT_TYPE},
-							Value: []byte("1"),
-						},
-					},
+			},
+		},
+		{
+			input: "1 + 2 + a",
+			ty:    &ast.BasicType{Kind: token.I8_TYPE},
+			value: &ast.BinaryExpr{
+				Left: &ast.LiteralExpr{
+					Value: []byte("3"),
+					Type:  &ast.BasicType{Kind: token.I8_TYPE},
 				},
-				{
-					input: "-1 + 1",
-					ty:    &ast.BasicType{Kind: token.INT_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.UnaryExpr{
-							Op: token.MINUS,
-							Value: &ast.LiteralExpr{
-								Type:  &ast.BasicType{Kind: token.INT_TYPE},
-								Value: []byte("1"),
-							},
-						},
-						Op: token.PLUS,
-						Right: &ast.LiteralExpr{
-							Value: []byte("1"),
-							Type:  &ast.BasicType{Kind: token.INT_TYPE},
-						},
-					},
+				Op:          token.PLUS,
+				OperandType: &ast.BasicType{Kind: token.I8_TYPE},
+				Right: &ast.IdExpr{
+					Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 9, 1)),
 				},
-				{
-					input: "a + 1",
-					ty:    &ast.BasicType{Kind: token.I8_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.IdExpr{
-							Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
-						},
-						Op: token.PLUS,
-						Right: &ast.LiteralExpr{
-							Value: []byte("1"),
-							Type:  &ast.BasicType{Kind: token.I8_TYPE},
-						},
+			},
+		},
+		{
+			input: "1 + a + 3",
+			ty:    &ast.BasicType{Kind: token.I8_TYPE},
+			value: &ast.BinaryExpr{
+				Left: &ast.BinaryExpr{
+					Left: &ast.LiteralExpr{
+						Value: []byte("1"),
+						Type:  &ast.BasicType{Kind: token.I8_TYPE},
 					},
-				},
-				{
-					input: "1 + a",
-					ty:    &ast.BasicType{Kind: token.I8_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.LiteralExpr{
-							Value: []byte("1"),
-							Type:  &ast.BasicType{Kind: token.I8_TYPE},
-						},
-						Op: token.PLUS,
-						Right: &ast.IdExpr{
-							Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 5, 1)),
-						},
+					Op:          token.PLUS,
+					OperandType: &ast.BasicType{Kind: token.I8_TYPE},
+					Right: &ast.IdExpr{
+						Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 5, 1)),
 					},
 				},
-				{
-					input: "1 + 2 + a",
-					ty:    &ast.BasicType{Kind: token.I8_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.BinaryExpr{
-							Left: &ast.LiteralExpr{
-								Value: []byte("1"),
-								Type:  &ast.BasicType{Kind: token.I8_TYPE},
-							},
-							Op: token.PLUS,
-							Right: &ast.LiteralExpr{
-								Value: []byte("2"),
-								Type:  &ast.BasicType{Kind: token.I8_TYPE},
-							},
-						},
-						Op: token.PLUS,
-						Right: &ast.IdExpr{
-							Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 9, 1)),
-						},
-					},
+				Op:          token.PLUS,
+				OperandType: &ast.BasicType{Kind: token.I8_TYPE},
+				Right: &ast.LiteralExpr{
+					Value: []byte("3"),
+					Type:  &ast.BasicType{Kind: token.I8_TYPE},
 				},
-				{
-					input: "1 + a + 3",
-					ty:    &ast.BasicType{Kind: token.I8_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.BinaryExpr{
-							Left: &ast.LiteralExpr{
-								Value: []byte("1"),
-								Type:  &ast.BasicType{Kind: token.I8_TYPE},
-							},
-							Op: token.PLUS,
-							Right: &ast.IdExpr{
-								Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 5, 1)),
-							},
-						},
-						Op: token.PLUS,
-						Right: &ast.LiteralExpr{
-							Value: []byte("3"),
-							Type:  &ast.BasicType{Kind: token.I8_TYPE},
-						},
+			},
+		},
+		{
+			input: "b + c",
+			ty:    &ast.BasicType{Kind: token.I32_TYPE},
+			value: &ast.BinaryExpr{
+				Left: &ast.CastExpr{
+					Value: &ast.IdExpr{
+						Name: token.New([]byte("b"), token.ID, token.NewPosition(filename, 1, 1)),
 					},
+					Type: &ast.BasicType{Kind: token.I32_TYPE},
+					From: &ast.BasicType{Kind: token.U8_TYPE},
+				},
+				Op:          token.PLUS,
+				OperandType: &ast.BasicType{Kind: token.I32_TYPE},
+				Right: &ast.IdExpr{
+					Name: token.New([]byte("c"), token.ID, token.NewPosition(filename, 5, 1)),
 				},
 			},
 		},
-	}
-	for _, test := range tests {
-		for _, unit := range test.tests {
-			t.Run(
-				fmt.Sprintf("TestExprInferenceWithoutContext('%s')", unit.input),
-				func(t *testing.T) {
-					actualExpr, actualExprTy, err := inferExprTypeWithoutContext(
-						unit.input,
-						filename,
-						test.scope,
-					)
-					if err != nil {
-						t.Fatal(err)
-					}
-					if !reflect.DeepEqual(actualExpr, unit.value) {
-						t.Fatalf("\nexpected expr: %s\ngot expr: %s\n", unit.value, actualExpr)
-					}
-					if !reflect.DeepEqual(actualExprTy, unit.ty) {
-						t.Fatalf("\nexpected ty: %s\ngot ty: %s\n", unit.ty, actualExprTy)
-					}
-				},
-			)
-		}
-	}
-}
-
-// TODO: test for mismatched types errors
-
-func TestExprInferenceWithContext(t *testing.T) {
-	filename := "test.tt"
-	tests := []exprInferenceTest{
-		{
-			scope: &ast.Scope{
-				Parent: nil,
-				Nodes: map[string]ast.Node{
-					"a": &ast.VarStmt{
-						Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
-						Type: &ast.BasicType{Kind: token.I8_TYPE},
-						Value: ast.LiteralExpr{
-							Type:  &ast.BasicType{Kind: token.I8_TYPE},
-							Value: []byte("1"),
-						},
-						NeedsInference: false,
-					},
+		{
+			input: "a == 2",
+			ty:    &ast.BasicType{Kind: token.BOOL_TYPE},
+			value: &ast.BinaryExpr{
+				Left: &ast.IdExpr{
+					Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
+				},
+				Op:          token.EQUAL_EQUAL,
+				OperandType: &ast.BasicType{Kind: token.I8_TYPE},
+				Right: &ast.LiteralExpr{
+					Value: []byte("2"),
+					Type:  &ast.BasicType{Kind: token.I8_TYPE},
 				},
 			},
-			tests: []struct {
-				input string
-				ty    ast.ExprType
-				value ast.Expr
-			}{
-				{
-					input: "a + 1",
-					ty:    &ast.BasicType{Kind: token.I8_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.IdExpr{
-							Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
-						},
-						Op: token.PLUS,
-						Right: &ast.LiteralExpr{
-							Value: []byte("1"),
-							Type:  &ast.BasicType{Kind: token.INT_TYPE},
-						},
-					},
+		},
+		{
+			input:    "1",
+			expected: &ast.BasicType{Kind: token.U8_TYPE},
+			ty:       &ast.BasicType{Kind: token.U8_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("1"),
+				Type:  &ast.BasicType{Kind: token.U8_TYPE},
+			},
+		},
+		{
+			input:    "-128",
+			expected: &ast.BasicType{Kind: token.I8_TYPE},
+			ty:       &ast.BasicType{Kind: token.I8_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("128"),
+				Type:  &ast.BasicType{Kind: token.I8_TYPE},
+			},
+		},
+		{
+			input:    "200 + 55",
+			expected: &ast.BasicType{Kind: token.U8_TYPE},
+			ty:       &ast.BasicType{Kind: token.U8_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("255"),
+				Type:  &ast.BasicType{Kind: token.U8_TYPE},
+			},
+		},
+		{
+			input:    "N",
+			expected: &ast.BasicType{Kind: token.I16_TYPE},
+			ty:       &ast.BasicType{Kind: token.I16_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("300"),
+				Type:  &ast.BasicType{Kind: token.I16_TYPE},
+			},
+		},
+		{
+			// The operands keep their type, the result is widened when stored
+			input:    "a + 1",
+			expected: &ast.BasicType{Kind: token.I32_TYPE},
+			ty:       &ast.BasicType{Kind: token.I8_TYPE},
+			value: &ast.BinaryExpr{
+				Left: &ast.IdExpr{
+					Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
 				},
-				{
-					input: "1 + a",
-					ty:    &ast.BasicType{Kind: token.I8_TYPE},
-					value: &ast.BinaryExpr{
-						Left: &ast.LiteralExpr{
-							Value: []byte("1"),
-							Type:  &ast.BasicType{Kind: token.INT_TYPE},
-						},
-						Op: token.PLUS,
-						Right: &ast.IdExpr{
-							Name: token.New([]byte("a"), token.ID, token.NewPosition(filename, 1, 1)),
-						},
-					},
+				Op:          token.PLUS,
+				OperandType: &ast.BasicType{Kind: token.I8_TYPE},
+				Right: &ast.LiteralExpr{
+					Value: []byte("1"),
+					Type:  &ast.BasicType{Kind: token.I8_TYPE},
 				},
 			},
 		},
+		{
+			input:    "1 < 2",
+			expected: &ast.BasicType{Kind: token.BOOL_TYPE},
+			ty:       &ast.BasicType{Kind: token.BOOL_TYPE},
+			value: &ast.LiteralExpr{
+				Value: []byte("1"),
+				Type:  &ast.BasicType{Kind: token.BOOL_TYPE},
+			},
+		},
 	}
 	for _, test := range tests {
-		for _, unit := range test.tests {
-			t.Run(
-				fmt.Sprintf("TestExprInferenceWithContext('%s')", unit.input),
-				func(t *testing.T) {
-					actualExprTy, err := inferExprTypeWithContext(
-						unit.input,
-						filename,
-						unit.ty,
-						test.scope,
-					)
-					if err != nil {
-						t.Fatal(err)
-					}
-					if !reflect.DeepEqual(actualExprTy, unit.ty) {
-						t.Fatalf("\nexpected: %s\ngot: %s\n", unit.ty, actualExprTy)
-					}
-				},
-			)
-		}
+		t.Run(
+			fmt.Sprintf("TestCheckExpr('%s', %s)", test.input, test.expected),
+			func(t *testing.T) {
+				actualExpr, actualExprTy, err := checkExprFrom(
+					test.input,
+					filename,
+					test.expected,
+					scope,
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ast.ClearSpans(actualExpr)
+				if !reflect.DeepEqual(actualExpr, test.value) {
+					t.Fatalf("\nexpected expr: %s\ngot expr: %s\n", test.value, actualExpr)
+				}
+				if !reflect.DeepEqual(actualExprTy, test.ty) {
+					t.Fatalf("\nexpected ty: %s\ngot ty: %s\n", test.ty, actualExprTy)
+				}
+			},
+		)
 	}
 }
 
-type semanticErrorTest struct {
+type constDeclTest struct {
 	input string
-	diags []diagnostics.Diag
+	value *ast.LiteralExpr
 }
 
-func TestSemanticErrors(t *testing.T) {
+func TestConstDecl(t *testing.T) {
 	filename := "test.tt"
 
-	tests := []semanticErrorTest{
+	tests := []constDeclTest{
 		{
-			input: "extern libc { fn puts(); fn puts(); }",
-			diags: []diagnostics.Diag{
-				{
-					// TODO: show the first declaration and the other
-					Message: "test.tt:1:29: prototype 'puts' already declared on extern 'libc'",
-				},
-			},
+			input: "const X = 2 * 3 + 1;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}, Value: []byte("7")},
 		},
 		{
-			input: "extern libc { }\nextern libc { }",
-			diags: []diagnostics.Diag{
-				{
-					// TODO: show the first declaration and the other
-					Message: "test.tt:2:8: extern 'libc' already declared on scope",
-				},
-			},
+			input: "const N u8 = 255;\nconst X = N - 5;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.U8_TYPE}, Value: []byte("250")},
 		},
 		{
-			input: "extern libc {}\nfn main() { libc.puts(); }",
-			diags: []diagnostics.Diag{
-				{
-					// TODO: show the first declaration and the other
-					Message: "test.tt:2:18: function 'puts' not declared on extern 'libc'",
-				},
-			},
+			input: "const X i8 = -1;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.I8_TYPE}, Value: []byte("255")},
 		},
 		{
-			input: "fn do_nothing(a int, a int) {}",
+			input: "const X = 7 / 2 == 3 and not (1 > 2);",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.BOOL_TYPE}, Value: []byte("1")},
+		},
+		{
+			input: "fn fact(n int) int { if n <= 1 { return 1; } return n * fact(n - 1); }\nconst X = fact(5);",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INT_TYPE}, Value: []byte("120")},
+		},
+		{
+			input: "fn fib(n int) int { a, b := 0, 1; for i in 0..n { a, b = b, a + b; } return a; }\nconst X = fib(10);",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INT_TYPE}, Value: []byte("55")},
+		},
+		{
+			input: "const X = sizeof(i32) * 2 + alignof(*u8);",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INTEGER_LITERAL}, Value: []byte("16")},
+		},
+		{
+			input: "const X i8 = -7 % 2;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.I8_TYPE}, Value: []byte("255")},
+		},
+		{
+			input: "const X u8 = 200 % 7;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.U8_TYPE}, Value: []byte("4")},
+		},
+		{
+			input: "fn f(a i16, b u8) i32 { return a + b; }\nconst X = f(-5, 250);",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.I32_TYPE}, Value: []byte("245")},
+		},
+		{
+			input: "const X = -1 as u8;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.U8_TYPE}, Value: []byte("255")},
+		},
+		{
+			input: "const X u8 = offsetof((u8, int, bool), 2);",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.U8_TYPE}, Value: []byte("16")},
+		},
+		{
+			input: "fn g() int { return 1; }\nfn f() int { return g(); }\nconst X = f();",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INT_TYPE}, Value: []byte("1")},
+		},
+		{
+			input: "const X = true as int;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.INT_TYPE}, Value: []byte("1")},
+		},
+		{
+			input: "const X = -2 as bool;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.BOOL_TYPE}, Value: []byte("1")},
+		},
+		{
+			input: "const X = 0 as bool;",
+			value: &ast.LiteralExpr{Type: &ast.BasicType{Kind: token.BOOL_TYPE}, Value: []byte("0")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestConstDecl('%s')", test.input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			src := []byte(test.input)
+			lex := lexer.New(filename, src, collector)
+			parser := parser.New(collector)
+
+			program, err := parser.ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sema := NewWithLayout(collector, testLayout{})
+			err = sema.Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+
+			body := program.Root.Files[0].Body
+			constDecl := body[len(body)-1].(*ast.ConstDecl)
+			ast.ClearSpans(constDecl.Value)
+			if !reflect.DeepEqual(constDecl.Value, test.value) {
+				t.Fatalf("\nexp: %s\ngot: %s\n", test.value, constDecl.Value)
+			}
+		})
+	}
+}
+
+func TestDeclarationOrder(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []string{
+		"fn main() { helper(); }\nfn helper() {}",
+		"fn main() { x := double(N); }\nfn double(n int) int { return n * 2; }\nconst N = 4;",
+		"fn main() { a [N]int := [1, 2]; b := f(); }\nfn f() int { return N; }\nconst N = M * 2;\nconst M = 1;",
+		"const A = f();\nfn f() int { return B; }\nconst B = 3;\nstatic_assert(A == B, \"A is B\");",
+		"fn main() { libc.puts(\"hi\"); }\nextern libc { fn puts(s *u8) i32; }",
+		"fn main() { a := libc.errno + 1; }\nextern libc { errno i32; }",
+		"test \"order\" { assert(is_zero(0) == 1); }\nfn is_zero(n int) int { if n == 0 { return 1; } return 0; }",
+	}
+
+	for _, input := range tests {
+		t.Run(fmt.Sprintf("TestDeclarationOrder('%s')", input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			lex := lexer.New(filename, []byte(input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = New(collector).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+		})
+	}
+}
+
+func TestReturnPaths(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []string{
+		"fn f() { }",
+		"fn f(x int) { if x == 1 { return; } }",
+		"fn f(x int) int { if x == 1 { return 1; } return 2; }",
+		"fn f(x int) int { if x == 1 { return 1; } elif x == 2 { return 2; } else { return 3; } }",
+		"fn f(x int) int { if x == 1 { if x == 2 { return 1; } else { return 2; } } else { return 3; } }",
+		"fn f(x int) int { while true { if x == 1 { return x; } } }",
+		"fn f(x int) int { for (i := 0; true; i = i + 1) { if i == x { return i; } } }",
+		"fn f(x int) !void { if x == 1 { return error.Failed; } return; }",
+	}
+
+	for _, input := range tests {
+		t.Run(fmt.Sprintf("TestReturnPaths('%s')", input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			lex := lexer.New(filename, []byte(input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = New(collector).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []struct {
+		input string
+		diags []diagnostics.Diag
+	}{
+		{
+			input: "fn f() int {\n  return 1;\n  return 2;\n  return 3;\n}\nfn main() { _n := f(); }",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:22: parameter 'a' already declared on function 'do_nothing'",
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:3:3: unreachable code",
 				},
 			},
 		},
 		{
-			input: "fn foo(a int, b int) {}\nfn main() { foo(); }",
+			input: "fn f(x int) int { if x == 1 { return 1; } else { return 2; } return 3; }\nfn main() { _n := f(1); }",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:2:13: not enough arguments in call to 'foo'",
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:1:62: unreachable code",
 				},
 			},
 		},
 		{
-			input: "fn foo(a int) {}\nfn main() { foo(\"hello\"); }",
+			input: "fn main() { a := 1; a = 2; b := 3; _c := b; }",
 			diags: []diagnostics.Diag{
 				{
-					Message: "can't use *u8 on argument of type int",
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:1:13: unused variable 'a'",
 				},
 			},
 		},
 		{
-			input: "fn main() { foo(); }",
+			input: "fn f(a int, _b int) {}\nfn main() { f(1, 2); }",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:13: function 'foo' not defined on scope",
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:1:6: unused parameter 'a'",
 				},
 			},
 		},
 		{
-			input: "fn main() { a := 1; a(); }",
+			input: "fn f() {}\nfn _g() {}\n@export(\"h\") fn h() {}\nfn main() {}",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:21: 'a' is not callable",
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:1:4: unused function 'f'",
 				},
 			},
 		},
 		{
-			input: "fn main() { libc.printf(); }",
+			input: "extern libc { fn puts(s *u8) i32; fn abs(n i32) i32; fn _exit(status i32); }\nfn main() { _n := libc.abs(1); }",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:13: 'libc' not defined on scope",
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:1:18: unused prototype 'puts' on extern 'libc'",
 				},
 			},
 		},
-		// Multiple variables
 		{
-			input: "fn main() { a, b := 10, 10; }",
-			diags: nil, // no errors
+			input: "fn two() int { return 2; }\nconst X = two();\nfn main() {}",
+			diags: nil,
 		},
-		{
-			input: "fn main() { a := 1; b := 2; a, b = 10, 10; }",
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestWarnings('%s')", test.input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			lex := lexer.New(filename, []byte(test.input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Warnings don't fail the build
+			err = New(collector).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+			if !reflect.DeepEqual(collector.Diags, test.diags) {
+				t.Fatalf("\nexp: %v\ngot: %v\n", test.diags, collector.Diags)
+			}
+		})
+	}
+}
+
+func TestDefiniteAssignment(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []string{
+		"fn f() int { x int; x = 1; return x; }",
+		"fn f(c bool) int { x int; if c { x = 1; } else { x = 2; } return x; }",
+		"fn f(c bool) int { x int; if c { return 0; } else { x = 2; } return x; }",
+		"fn f(c bool, d bool) int { x int; if c { x = 1; } elif d { x = 2; } else { return 0; } return x; }",
+		"fn f(c bool) int { x int; if c { x = 1; return x; } return 0; }",
+		"fn f(c bool) int { x int; while true { x = 1; if c { return x; } } }",
+		"fn f(c bool) int { x int; x = 1; if c { x = 2; } return x; }",
+		"fn f(c bool) int { x int; y int; x, y = 1, 2; return x + y; }",
+	}
+
+	for _, input := range tests {
+		t.Run(fmt.Sprintf("TestDefiniteAssignment('%s')", input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			lex := lexer.New(filename, []byte(input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = New(collector).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+		})
+	}
+}
+
+func TestImplicitConversions(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []string{
+		"const X = 10;\nfn main() { _y u8 := X; }",
+		"const N = -1;\nfn main() { _a i8 := N; _b i64 := N; }",
+		"fn main() { a u8 := 1; _b int := a; }",
+		"fn main() { a u8 := 1; b i32 := 2; _c i32 := a + b; }",
+		"fn main() { a u8 := 1; _b i64 := a * 2; }",
+		"fn main() { a u8 := 1; a = 255; _b := a; }",
+		"fn main() { _a i8 := -128; _b u64 := 18446744073709551615; }",
+		"fn main() { _a bool := 1 < 2; }",
+		"fn f(n int) bool { return n == 2; }\nfn main() { _a := f(1); }",
+		"fn f(x i64) i64 { return x; }\nfn main() { a i32 := 1; _b := f(a); }",
+		"fn main() { a u16 := 1; for _i in a..1000 {} }",
+		"fn main() { a u8 := 1; _b := [a, 2, 3]; _c [2]i16 := [a, -1]; }",
+	}
+
+	for _, input := range tests {
+		t.Run(fmt.Sprintf("TestImplicitConversions('%s')", input), func(t *testing.T) {
+			collector := diagnostics.New()
+
+			lex := lexer.New(filename, []byte(input), collector)
+			program, err := parser.New(collector).ParseFileAsProgram(lex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = New(collector).Check(program)
+			if err != nil {
+				t.Fatalf("unexpected error: %s %s", err, collector.Diags)
+			}
+			if len(collector.Diags) != 0 {
+				t.Fatalf("unexpected diagnostics: %s", collector.Diags)
+			}
+		})
+	}
+}
+
+type moduleTest struct {
+	files map[string]string
+	diags []diagnostics.Diag
+}
+
+func TestModuleDeclarations(t *testing.T) {
+	tests := []moduleTest{
+		{
+			files: map[string]string{
+				"a.t": "fn main() { helper(libc.puts(\"hi\")); }",
+				"b.t": "fn helper(_n i32) {}\nextern libc { fn puts(s *u8) i32; }",
+			},
+			diags: nil,
+		},
+		{
+			files: map[string]string{
+				"a.t": "fn helper() {}",
+				"b.t": "fn main() {}\n\nfn helper() {}",
+			},
+			diags: []diagnostics.Diag{
+				{
+					Message: "{dir}/b.t:3:4: function 'helper' already declared at {dir}/a.t:1:4",
+				},
+			},
+		},
+		{
+			files: map[string]string{
+				"a.t": "const SIZE = HALF * 2;\nfn main() { _n u8 := SIZE + HALF; }",
+				"b.t": "const HALF = 4;",
+			},
+			diags: nil,
+		},
+		{
+			files: map[string]string{
+				"a.t": "const SIZE = 1;",
+				"b.t": "fn main() {}\nconst SIZE = 2;",
+			},
+			diags: []diagnostics.Diag{
+				{
+					Message: "{dir}/b.t:2:7: constant 'SIZE' already declared at {dir}/a.t:1:7",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("TestModuleDeclarations(%v)", test.files), func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			collector := diagnostics.New()
+			program, err := parser.New(collector).ParseModuleDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = New(collector).Check(program)
+
+			var diags []diagnostics.Diag
+			for _, diag := range test.diags {
+				diag.Message = strings.ReplaceAll(diag.Message, "{dir}", dir)
+				diags = append(diags, diag)
+			}
+			if !reflect.DeepEqual(collector.Diags, diags) {
+				t.Fatalf("\nexp: %v\ngot: %v\n", diags, collector.Diags)
+			}
+		})
+	}
+}
+
+// Layout of a 64-bit target where every type is aligned to its own size
+type testLayout struct{}
+
+func (layout testLayout) SizeOf(ty ast.ExprType) uint64 {
+	switch ty := ty.(type) {
+	case *ast.BasicType:
+		switch ty.Kind {
+		case token.BOOL_TYPE, token.I8_TYPE, token.U8_TYPE:
+			return 1
+		case token.I16_TYPE, token.U16_TYPE:
+			return 2
+		case token.I32_TYPE, token.U32_TYPE:
+			return 4
+		default:
+			return 8
+		}
+	case *ast.TupleType:
+		last := len(ty.Types) - 1
+		size := layout.OffsetOf(ty, last) + layout.SizeOf(ty.Types[last])
+		return alignTo(size, layout.AlignOf(ty))
+	default:
+		return 8
+	}
+}
+
+func (layout testLayout) AlignOf(ty ast.ExprType) uint64 {
+	tuple, ok := ty.(*ast.TupleType)
+	if !ok {
+		return layout.SizeOf(ty)
+	}
+	align := uint64(1)
+	for _, elemTy := range tuple.Types {
+		align = max(align, layout.AlignOf(elemTy))
+	}
+	return align
+}
+
+func (layout testLayout) OffsetOf(tuple *ast.TupleType, index int) uint64 {
+	offset := uint64(0)
+	for i, elemTy := range tuple.Types {
+		offset = alignTo(offset, layout.AlignOf(elemTy))
+		if i == index {
+			break
+		}
+		offset += layout.SizeOf(elemTy)
+	}
+	return offset
+}
+
+func alignTo(offset, align uint64) uint64 {
+	return (offset + align - 1) / align * align
+}
+
+type semanticErrorTest struct {
+	input string
+	diags []diagnostics.Diag
+}
+
+func TestSemanticErrors(t *testing.T) {
+	filename := "test.tt"
+
+	tests := []semanticErrorTest{
+		{
+			input: "extern libc { fn puts(); fn puts(); }",
+			diags: []diagnostics.Diag{
+				{
+					// TODO: show the first declaration and the other
+					Message: "test.tt:1:29: prototype 'puts' already declared on extern 'libc'",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn puts(); puts *u8; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:26: 'puts' already declared on extern 'libc'",
+				},
+			},
+		},
+		{
+			input: "extern libc { nothing void; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:15: invalid type void for variable 'nothing'",
+				},
+			},
+		},
+		{
+			input: "extern m link \"\" { }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:15: library name of extern 'm' can't be empty",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn puts(); }\nfn main() { a := libc.stderr; b := libc.puts; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:23: variable 'stderr' not declared on extern 'libc'",
+				},
+			},
+		},
+		{
+			input: "extern libc { errno i32; }\nfn main() { a u8 := libc.errno; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:21: can't use i32 on variable 'a' of type u8",
+				},
+			},
+		},
+		{
+			input: "extern libc { }\nextern libc { }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:8: extern 'libc' already declared at test.tt:1:8",
+				},
+			},
+		},
+		{
+			input: "fn do_nothing() {}\nfn do_nothing() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:4: function 'do_nothing' already declared at test.tt:1:4",
+				},
+			},
+		},
+		{
+			input: "fn main() { libc.puts(); }\nfn libc() {}\nextern libc { fn puts(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:3:8: extern 'libc' already declared at test.tt:2:4",
+				},
+			},
+		},
+		{
+			input: "extern libc {}\nfn main() { libc.puts(); }",
+			diags: []diagnostics.Diag{
+				{
+					// TODO: show the first declaration and the other
+					Message: "test.tt:2:18: function 'puts' not declared on extern 'libc'",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn puts(s *u8) i32; }\nfn main() { s := \"hi\"; libc.puts(s); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:34: can't use string on argument of type *u8 in call to 'puts', use cstr(s) to pass a NUL-terminated copy",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn printf(format *u8, ...) i32; }\nfn main() { libc.printf(\"%d\" + \"\", 1); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:25: can't use string on argument of type *u8 in call to 'printf', use cstr(...) to pass a NUL-terminated copy",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn printf(format *u8, ...) i32; }\nfn find() ?int { return 41; }\nfn main() { x := find(); libc.printf(\"%d\", x); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:3:44: value of type ?int must be unwrapped with 'if' or 'orelse' before use",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn printf(format *u8, ...) i32; }\nfn checked() !int { return 5; }\nfn main() { libc.printf(\"%d\", checked()); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:3:31: value of type !int must be unwrapped with 'try' or 'catch' before use",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn printf(format *u8, ...) i32; }\nfn main() { s := \"hi\"; libc.printf(\"%s\", s); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:42: can't use string as variadic argument in call to 'printf', use cstr(s) to pass a NUL-terminated copy",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn printf(format *u8, ...) i32; }\nfn main() { libc.printf(\"%s %s\", \"hi\", cstr(\"there\")); }",
 			diags: nil, // no errors
 		},
 		{
-			input: "fn main() { a := 1; a, b = 10, 10; }",
+			input: "fn f() int { return 1; }\nfn main() { f() catch {} }",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:24: 'b' not declared",
+					Message: "test.tt:2:13: expected error union on catch, not int",
 				},
 			},
 		},
 		{
-			input: "fn main() { b := 1; a, b = 10, 10; }",
+			input: "fn v() !void { return; }\nfn n() !int { return 1; }\nfn main() i32 { v() catch { return 1; } n() catch {} return 0; }",
+			diags: nil, // no errors
+		},
+		{
+			input: "extern libc { fn abs(n i32) i32; }\nfn main() { libc.abs(true); }",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:21: 'a' not declared",
+					Message: "test.tt:2:22: can't use bool on argument of type i32 in call to 'abs'",
 				},
 			},
 		},
 		{
-			input: "fn main() { a := 1; b := 2; a, b := 10, 10; }",
+			input: "fn do_nothing(a int, a int) {}",
 			diags: []diagnostics.Diag{
 				{
-					Message: "test.tt:1:29: no new variables declared",
+					Message: "test.tt:1:22: parameter 'a' already declared on function 'do_nothing'",
+				},
+			},
+		},
+		{
+			input: "fn foo(_a int, _b int) {}\nfn main() { foo(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:13: not enough arguments in call to 'foo'",
+				},
+			},
+		},
+		{
+			input: "fn foo(_a int) {}\nfn main() { foo(\"hello\"); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:17: can't use string on argument of type int",
+				},
+			},
+		},
+		{
+			input: "fn main() { x := 1; y := true; z := x + y; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:37: mismatched types int and bool on +",
+				},
+			},
+		},
+		{
+			input: "fn main() { s := \"a\" - \"b\"; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: can't use - operator on string",
+				},
+			},
+		},
+		{
+			input: "fn main() { b := \"a\" < \"b\"; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: can't use < operator on string",
+				},
+			},
+		},
+		{
+			input: "fn main() { x := nil; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: can't infer the type of nil",
+				},
+			},
+		},
+		{
+			input: "fn main() { x int := nil; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:22: can't use nil as int",
+				},
+			},
+		},
+		{
+			input: "fn main() { x ?int := 1; y := x + 1; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:31: value of type ?int must be unwrapped with 'if' or 'orelse' before use",
+				},
+			},
+		},
+		{
+			input: "fn f() ?int { return nil; }\nfn g() ?int { return 1; }\nfn main() { x := f() orelse g() orelse 2; y := x orelse 0; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:3:48: expected optional on orelse, not int",
+				},
+			},
+		},
+		{
+			input: "fn main() { x ?int := 1; y := x orelse true; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:40: can't use bool as default value of ?int",
+				},
+			},
+		},
+		{
+			input: "fn main() { x := 1; if y := x {} }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:29: can't unwrap value of type int, it is not optional",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn getenv(name *u8) *u8; }\nfn main() { p *u8 := libc.getenv(\"HOME\"); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:22: value of type ?*u8 must be unwrapped with 'if' or 'orelse' before use",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn getenv(name *u8) *u8; fn puts(s *u8) i32; }\nfn main() { libc.puts(libc.getenv(\"HOME\")); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:23: value of type ?*u8 must be unwrapped with 'if' or 'orelse' before use",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn puts(s *u8) i32; }\nfn main() { libc.puts(nil); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:23: can't use nil as *u8",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn getenv(name *u8) *u8; fn setenv(name *u8, value ?*u8) i32; }\nfn main() { libc.setenv(\"A\", libc.getenv(\"B\")); libc.setenv(\"A\", nil); }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn f() !int { return 1; }\nfn main() { f(); _x := f() catch 0; }",
+			diags: []diagnostics.Diag{
+				{
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:2:13: error returned by 'f' is ignored, handle it with 'try' or 'catch'",
+				},
+			},
+		},
+		{
+			input: "fn f() !int { return 1; }\nfn main() i32 { x := try f(); return 0; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:22: 'try' can only be used on functions returning an error union, not i32",
+				},
+			},
+		},
+		{
+			input: "fn f() !int { return 1; }\nfn g() int { return f() + 1; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:21: value of type !int must be unwrapped with 'try' or 'catch' before use",
+				},
+			},
+		},
+		{
+			input: "fn f() !int { x := 1; return try x; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:34: expected error union on try, not int",
+				},
+			},
+		},
+		{
+			input: "fn f() !int { return 1; }\nfn main() { x := f() catch true; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:28: can't use bool as default value of !int",
+				},
+			},
+		},
+		{
+			input: "fn f() int { return error.Failed; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: can't use error.Failed as int",
+				},
+			},
+		},
+		{
+			input: "fn main() { e := error.Failed; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: can't infer the type of error.Failed",
+				},
+			},
+		},
+		{
+			input: "fn main() { x := \"a\" as bool; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: can't cast string to bool",
+				},
+			},
+		},
+		{
+			input: "fn main() { p *u8 := \"a\"; n := p as int; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:32: can't cast *u8 to int",
+				},
+			},
+		},
+		{
+			input: "fn main() { _a := 300 as u8; _b := -1 as u32; _c := 255 as u8; _d := -128 as i8; }",
+			diags: []diagnostics.Diag{
+				{
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:1:19: 300 doesn't fit on u8, the cast changes its value",
+				},
+				{
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:1:36: -1 doesn't fit on u32, the cast changes its value",
+				},
+			},
+		},
+		{
+			input: "fn main() { n := len(1); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:22: invalid argument of type int for 'len'",
+				},
+			},
+		},
+		{
+			input: "fn main() { n := len(\"a\", \"b\"); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: expected 1 argument in call to 'len', but got 2",
+				},
+			},
+		},
+		{
+			input: "fn main() { s := cstr([1, 2]); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:23: invalid argument of type [2]int for 'cstr'",
+				},
+			},
+		},
+		{
+			input: "fn main() { c := not 1; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: can't use not on untyped integer",
+				},
+			},
+		},
+		{
+			input: "fn main() { d i32 := true; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:22: can't use bool on variable 'd' of type i32",
+				},
+			},
+		},
+		{
+			input: "fn main() { foo(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:13: function 'foo' not defined on scope",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := 1; a(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: 'a' is not callable",
+				},
+			},
+		},
+		{
+			input: "fn main() { libc.printf(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:13: 'libc' not defined on scope",
+				},
+			},
+		},
+		// Multiple variables
+		{
+			input: "fn main() { _a, _b := 10, 10; }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn main() { _a := 1; _b := 2; _a, _b = 10, 10; }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn main() { a := 1; a, b = 10, 10; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:24: 'b' not declared",
+				},
+			},
+		},
+		{
+			input: "fn main() { b := 1; a, b = 10, 10; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: 'a' not declared",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := 1; b := 2; a, b := 10, 10; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:29: no new variables declared",
+				},
+			},
+		},
+		// Multiple return values
+		{
+			input: "fn f() (int, int) { return 1, 2; }\nfn main() { a, b := f(); a, b = b, a; }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn g() (int, int) { return 1, 2; }\nfn f() (int, int) { return g(); }\nfn main() { a, b := f(); a, b = b, a; }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn f() (int, int) { return 1, 2; }\nfn main() { x bool; a, x := f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:24: can't use int on variable 'x' of type bool",
+				},
+			},
+		},
+		{
+			input: "fn f() (int, int) { return 1, 2; }\nfn main() { a := 1; x := true; a, x = f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:35: can't use int on variable 'x' of type bool",
+				},
+			},
+		},
+		{
+			input: "fn f() (int, int) { return 1, 2; }\nfn main() { a, x = f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:13: 'a' not declared",
+				},
+			},
+		},
+		{
+			input: "fn g() int { return 1; }\nfn f() (int, int) { return g(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:21: wrong number of return values, expected 2, but got 1",
+				},
+			},
+		},
+		{
+			input: "fn f() (int, int) { return 1; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: wrong number of return values, expected 2, but got 1",
+				},
+			},
+		},
+		{
+			input: "fn f() int { return 1, 2; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:14: wrong number of return values, expected 1, but got 2",
+				},
+			},
+		},
+		{
+			input: "fn f() (int, bool) { return true, true; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:22: can't use bool as return value 1 of type int",
+				},
+			},
+		},
+		{
+			input: "fn f() (int, int) { return 1, 2; }\nfn main() { a, b, c := f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:13: assignment mismatch: 3 variables but 'f' returns 2 values",
+				},
+			},
+		},
+		{
+			input: "fn f() (int, int) { return 1, 2; }\nfn main() { a := f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:13: assignment mismatch: 1 variable but 'f' returns 2 values",
+				},
+			},
+		},
+		// Attributes
+		{
+			input: "@foo fn f() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: unknown attribute '@foo'",
+				},
+			},
+		},
+		{
+			input: "extern libc { @inline fn puts(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:15: attribute '@inline' can't be applied to prototypes",
+				},
+			},
+		},
+		{
+			input: "@inline @inline fn f() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:9: duplicated attribute '@inline'",
+				},
+			},
+		},
+		{
+			input: "@export fn f() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: attribute '@export' expects 1 argument(s), but got 0",
+				},
+			},
+		},
+		{
+			input: "@export(\"f\") fn f(s string) {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: can't export 'f', type string has no C equivalent",
+				},
+			},
+		},
+		{
+			input: "@export(\"f\") fn f() (int, int) { return 1, 2; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: can't export 'f', type (int, int) has no C equivalent",
+				},
+			},
+		},
+		{
+			input: "@export(\"f\") fn f(a ?int) {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: can't export 'f', type ?int has no C equivalent",
+				},
+			},
+		},
+		{
+			input: "@export(\"f\") fn f(a ?*u8, b *i32) ?*u8 { return a; }\n@export(\"f\") fn g() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:1: symbol 'f' already exported by 'f' at test.tt:1:17",
+				},
+			},
+		},
+		{
+			input: "fn foo() {}\n@export(\"foo\") fn bar() {}\nfn main() { foo(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:1: can't export 'bar' as 'foo', symbol already used by function at test.tt:1:4",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn puts(s *u8) i32; }\n@export(\"puts\") fn f() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:1: can't export 'f' as 'puts', symbol already used by prototype at test.tt:1:18",
+				},
+			},
+		},
+		{
+			input: "@export(\"main\") fn f() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: can't export 'f' as 'main', the symbol of the entry point",
+				},
+			},
+		},
+		{
+			input: "@export(1) fn f() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: attribute '@export' expects a string literal argument",
+				},
+			},
+		},
+		{
+			input: "@inline @noinline fn f() {}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:9: attributes '@inline' and '@noinline' can't be used together",
+				},
+			},
+		},
+		{
+			input: "@deprecated(\"use g\") fn f() {}\nfn main() { f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:2:13: 'f' is deprecated: use g",
+				},
+			},
+		},
+		{
+			input: "@must_use fn f() int { return 1; }\nfn main() { f(); _x := f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:2:13: result of 'f' is unused",
+				},
+			},
+		},
+		{
+			input: "extern libc { @must_use fn puts(s *u8) i32; }\nfn main() { libc.puts(\"hi\"); }",
+			diags: []diagnostics.Diag{
+				{
+					Severity: diagnostics.WARNING,
+					Message:  "test.tt:2:18: result of 'puts' is unused",
+				},
+			},
+		},
+		// Test blocks and assertions
+		{
+			input: "fn one() int { return 1; }\ntest \"one\" { x := one(); assert(x == 1); }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn one() int { return 1; }\nbench \"one\" { _x := one(); }",
+			diags: nil, // no errors
+		},
+		{
+			input: "bench \"undefined\" { f(); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: function 'f' not defined on scope",
+				},
+			},
+		},
+		{
+			input: "fn main() { assert(1); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:13: expected bool on assert, not int",
+				},
+			},
+		},
+		// Range-based for loops and arrays
+		{
+			input: "fn main() { n u8 := 3; for _i in 0..n {} for _j in 1...3 {} }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn main() { a := [1, 2]; for _i, _x in a {} for _x in a[..1] {} _b := a[1]; }",
+			diags: nil, // no errors
+		},
+		{
+			input: "fn main() { for i, x in 0..3 {} }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:17: range over integers permits only one loop variable",
+				},
+			},
+		},
+		{
+			input: "fn main() { for i in 0..true {} }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:17: mismatched types on range bounds: int and bool",
+				},
+			},
+		},
+		{
+			input: "fn main() { x := 1; for v in x {} }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:25: can't range over value of type int",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := [1, 2]; b := a[2]; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:32: index 2 out of bounds for array of length 2",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := []; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: can't infer the type of an empty array literal",
+				},
+			},
+		},
+		{
+			input: "fn main() { a [3]int := [1, 2]; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:25: expected 3 elements on array literal of type [3]int, but got 2",
+				},
+			},
+		},
+		// Constants
+		{
+			input: "const X u8 = 200 + 100;",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:7: constant 300 overflows u8",
+				},
+			},
+		},
+		{
+			input: "const X = 1 / (2 - 2);",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:7: division by zero",
+				},
+			},
+		},
+		{
+			input: "const X = 1 % 0;",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:7: division by zero",
+				},
+			},
+		},
+		{
+			input: "fn f(x int) int { if x == 1 { return 1; } }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:43: missing return",
+				},
+			},
+		},
+		{
+			input: "fn f(x int) int {\n  if x == 1 {\n    return 1;\n  } elif x == 2 {\n    _y := 3;\n  } else {\n    return 2;\n  }\n}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:9:1: missing return",
+				},
+			},
+		},
+		{
+			input: "fn f(x int) !int { while x > 0 { return x; } }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:46: missing return",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := b; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: 'b' not defined on scope",
+				},
+			},
+		},
+		{
+			input: "fn main() { a int := 1 + b; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:26: 'b' not defined on scope",
+				},
+			},
+		},
+		{
+			input: "fn main() { a = 1; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:13: 'a' not defined on scope",
+				},
+			},
+		},
+		{
+			input: "fn f() int { x int; return x; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:28: 'x' used before being assigned",
+				},
+			},
+		},
+		{
+			input: "fn f(c bool) int {\n  x int;\n  if c {\n    x = 1;\n  }\n  return x;\n}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:6:10: 'x' used before being assigned",
+				},
+			},
+		},
+		{
+			input: "fn f(c bool, d bool) int {\n  x int;\n  if c {\n    x = 1;\n  } elif d {\n    x = 2;\n  }\n  return x;\n}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:8:10: 'x' used before being assigned",
+				},
+			},
+		},
+		{
+			input: "fn f(c bool) int {\n  x int;\n  while c {\n    x = 1;\n  }\n  return x;\n}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:6:10: 'x' used before being assigned",
+				},
+			},
+		},
+		{
+			input: "fn f(c bool) int {\n  x int;\n  while c {\n    y := x + 1;\n    x = y;\n  }\n  return 0;\n}",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:4:10: 'x' used before being assigned",
+				},
+			},
+		},
+		{
+			input: "fn main() { a i8 := 200; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: 200 doesn't fit on i8",
+				},
+			},
+		},
+		{
+			input: "fn main() { a i8 := -129; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: -129 doesn't fit on i8",
+				},
+			},
+		},
+		{
+			input: "fn main() { a u8 := -1; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:21: -1 doesn't fit on u8",
+				},
+			},
+		},
+		{
+			input: "fn main() { a u8 := 1; _b := a + 1000; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:34: 1000 doesn't fit on u8",
+				},
+			},
+		},
+		{
+			input: "fn main() { _a u8 := 200 + 100; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:22: 300 doesn't fit on u8",
+				},
+			},
+		},
+		{
+			input: "fn main() { _a u8 := 1; _a = 256; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:30: 256 doesn't fit on u8",
+				},
+			},
+		},
+		{
+			input: "const X u8 = 200;\nfn main() { _a := X + 100; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:19: constant 300 overflows u8",
+				},
+			},
+		},
+		{
+			input: "fn main() { a i32 := 1; b u32 := 2; _c := a + b; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:43: mismatched types i32 and u32 on +",
+				},
+			},
+		},
+		{
+			input: "fn main() { a i8 := 1; b u16 := 2; _c := a + b; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:42: mismatched types i8 and u16 on +",
+				},
+			},
+		},
+		{
+			input: "fn main() { a i32 := 1; _b u8 := a; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:34: can't use i32 on variable '_b' of type u8",
+				},
+			},
+		},
+		{
+			input: "fn main() { _a int := 1; _a = true; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:31: can't use bool on variable '_a' of type int",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := 9223372036854775808; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: 9223372036854775808 doesn't fit on int",
+				},
+			},
+		},
+		{
+			input: "const X = 1;\nconst X = 2;",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:7: constant 'X' already declared at test.tt:1:7",
+				},
+			},
+		},
+		{
+			input: "const A = B + 1;\nconst B = A;",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:11: initialization cycle on constant 'A'",
+				},
+			},
+		},
+		{
+			input: "fn main() { x := 1; const Y = x + 1; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:31: 'x' is not a constant",
+				},
+			},
+		},
+		{
+			input: "const S *u8 = 0;",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:7: invalid constant type *u8",
+				},
+			},
+		},
+		{
+			input: "fn forever() int { while true {} return 0; }\nconst X = forever();",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:7: compile-time evaluation exceeded 1000000 steps",
+				},
+			},
+		},
+		{
+			input: "fn f(n int) int { return f(n); }\nconst X = f(1);",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:7: compile-time evaluation exceeded 1000000 steps",
+				},
+			},
+		},
+		{
+			input: "extern libc { fn printf(format *u8, ...) i32; }\nfn f() int { libc.printf(\"x\"); return 1; }\nconst X = f();",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:14: call to extern 'printf' is not allowed at compile time",
+				},
+			},
+		},
+		{
+			input: "fn f() int { s := \"x\"; return 1; }\nconst X = f();",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:19: string literal is not allowed at compile time",
+				},
+			},
+		},
+		{
+			input: "fn f() int { a := [1, 2]; return a[0]; }\nconst X = f();",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:19: array literal is not allowed at compile time",
+				},
+			},
+		},
+		{
+			input: "const N = 2;\nfn main() { a [N - 3]int := []; }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:2:13: invalid array length -1",
+				},
+			},
+		},
+		// Type builtins
+		{
+			input: "fn main() { a := sizeof(void); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:25: invalid argument of type void for 'sizeof'",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := offsetof(int, 0); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:27: invalid argument of type int for 'offsetof'",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := offsetof((u8, int), 2); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:38: invalid element 2 of (u8, int)",
+				},
+			},
+		},
+		{
+			input: "fn main() { a := offsetof((u8, int)); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:18: expected 2 arguments in call to 'offsetof', but got 1",
+				},
+			},
+		},
+		// Static assertions
+		{
+			input: "static_assert(1 > 2, \"one is not greater than two\");",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:1: static assertion failed: one is not greater than two",
+				},
+			},
+		},
+		{
+			input: "fn main() { static_assert(1 + 1, \"sum\"); }",
+			diags: []diagnostics.Diag{
+				{
+					Message: "test.tt:1:13: expected bool on static_assert, not untyped integer",
 				},
 			},
 		},
@@ -539,7 +2034,7 @@ func TestSemanticErrors(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			sema := New(collector)
+			sema := NewWithLayout(collector, testLayout{})
 			_ = sema.Check(program)
 
 			if len(collector.Diags) != len(test.diags) {
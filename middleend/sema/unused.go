@@ -0,0 +1,114 @@
+package sema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HicaroD/Telia/diagnostics"
+	"github.com/HicaroD/Telia/frontend/ast"
+	"github.com/HicaroD/Telia/frontend/lexer/token"
+)
+
+// Marks a variable, parameter, function or prototype as used, so it isn't
+// reported by the unused warnings
+func (sema *sema) markUsed(node ast.Node) {
+	sema.used[node] = true
+}
+
+// Keeps track of the variables declared on the body being analyzed, reported
+// at the end of it if they are never used
+func (sema *sema) declareLocal(variable *ast.VarStmt) {
+	sema.locals = append(sema.locals, variable)
+}
+
+// Names starting with "_" are intentionally unused
+func ignoresUnused(name *token.Token) bool {
+	return strings.HasPrefix(name.Name(), "_")
+}
+
+func (sema *sema) warnUnused(kind string, name *token.Token) {
+	pos := name.Pos
+	unused := diagnostics.Diag{
+		Severity: diagnostics.WARNING,
+		Message: fmt.Sprintf(
+			"%s:%d:%d: unused %s '%s'",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+			kind,
+			name.Name(),
+		),
+	}
+	sema.collector.ReportAndSave(unused)
+}
+
+// Reports the parameters and local variables of an already analyzed body
+// that are never used. Assigning a value to a variable doesn't use it.
+func (sema *sema) warnUnusedLocals(params *ast.FieldList) {
+	if params != nil {
+		for _, param := range params.Fields {
+			if !sema.used[param] && !ignoresUnused(param.Name) {
+				sema.warnUnused("parameter", param.Name)
+			}
+		}
+	}
+	for _, variable := range sema.locals {
+		if !sema.used[variable] && !ignoresUnused(variable.Name) {
+			sema.warnUnused("variable", variable.Name)
+		}
+	}
+	sema.locals = nil
+}
+
+// Reports functions and prototypes that are never called. The entry point
+// and functions exported to C are called from outside of the program.
+func (sema *sema) warnUnusedDecls(_ *ast.Module, file *ast.File) error {
+	for _, node := range file.Body {
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			if sema.used[n] || ignoresUnused(n.Name) || n.Name.Name() == "main" {
+				continue
+			}
+			if ast.FindAttribute(n.Attributes, "export") != nil {
+				continue
+			}
+			sema.warnUnused("function", n.Name)
+		case *ast.ExternDecl:
+			for _, proto := range n.Prototypes {
+				if sema.used[proto] || ignoresUnused(proto.Name) {
+					continue
+				}
+				pos := proto.Name.Pos
+				unusedPrototype := diagnostics.Diag{
+					Severity: diagnostics.WARNING,
+					Message: fmt.Sprintf(
+						"%s:%d:%d: unused prototype '%s' on extern '%s'",
+						pos.Filename,
+						pos.Line,
+						pos.Column,
+						proto.Name.Name(),
+						n.Name.Name(),
+					),
+				}
+				sema.collector.ReportAndSave(unusedPrototype)
+			}
+		}
+	}
+	return nil
+}
+
+// Reports the first statement after one that always returns, such as a
+// "return", since it is never executed
+func (sema *sema) warnUnreachableCode(stmt ast.Stmt) {
+	pos := stmt.Span().Start
+	unreachableCode := diagnostics.Diag{
+		Severity: diagnostics.WARNING,
+		Message: fmt.Sprintf(
+			"%s:%d:%d: unreachable code",
+			pos.Filename,
+			pos.Line,
+			pos.Column,
+		),
+	}
+	sema.collector.ReportAndSave(unreachableCode)
+}